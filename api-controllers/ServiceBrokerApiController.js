@@ -9,6 +9,8 @@ const utils = require('../common/utils');
 const config = require('../common/config');
 const catalog = require('../common/models/catalog');
 const FabrikBaseController = require('./FabrikBaseController');
+const EncryptionManager = require('../common/utils/EncryptionManager');
+const VaultClient = require('../common/utils/VaultClient');
 const BadRequest = errors.BadRequest;
 const PreconditionFailed = errors.PreconditionFailed;
 const NotFound = errors.NotFound;
@@ -336,31 +338,60 @@ class ServiceBrokerApiController extends FabrikBaseController {
       .catch(NotFound, notFound);
   }
 
+  getBindingCredentials(secretRef, instanceId) {
+    // Credentials kept in vault are stored as they are, not base64 encoded
+    if (VaultClient.isVaultRef(secretRef)) {
+      return new VaultClient().getSecret(secretRef)
+        .then(data => new EncryptionManager().decryptCredentials(JSON.parse(data.response)));
+    }
+    return eventmesh.apiServerClient.getSecret(secretRef, eventmesh.apiServerClient.getNamespaceId(instanceId))
+      .then(secret => new EncryptionManager().decryptCredentials(utils.decodeBase64(secret.data.response)));
+  }
+
   putBinding(req, res) {
+    const acceptsIncomplete = _.get(req, 'query.accepts_incomplete') === 'true';
     const params = _(req.body)
       .set('binding_id', req.params.binding_id)
       .set('id', req.params.binding_id)
       .set('instance_id', req.params.instance_id)
+      .set('accepts_incomplete', acceptsIncomplete)
       .value();
 
-    function done(bindResponse) {
-      const response = utils.decodeBase64(bindResponse);
+    function done(response) {
       res.status(CONST.HTTP_STATUS_CODE.CREATED).send(response);
     }
 
+    // The interoperator uses the operation id as id of the last operation
+    // of the binding, which is compared when the operation is polled
+    let operationId;
+
+    function accepted() {
+      res.status(CONST.HTTP_STATUS_CODE.ACCEPTED).send({
+        operation: utils.encodeBase64({
+          'type': 'create',
+          'id': operationId
+        })
+      });
+    }
+
     function conflict(err) {
       /* jshint unused:false */
       res.status(CONST.HTTP_STATUS_CODE.CONFLICT).send({});
     }
 
-    return Promise
-      .try(() => {
+    return utils
+      .uuidV4()
+      .then(id => {
+        operationId = id;
         return eventmesh.apiServerClient.createOSBResource({
           resourceGroup: CONST.APISERVER.RESOURCE_GROUPS.INTEROPERATOR,
           resourceType: CONST.APISERVER.RESOURCE_TYPES.INTEROPERATOR_SERVICEBINDINGS,
           resourceId: params.binding_id,
           metadata: {
-            finalizers: [`${CONST.APISERVER.FINALIZERS.BROKER}`]
+            finalizers: [`${CONST.APISERVER.FINALIZERS.BROKER}`],
+            annotations: {
+              [CONST.APISERVER.ANNOTATIONS.OPERATION_ID]: operationId
+            }
           },
           labels: {
             instance_guid: req.params.instance_id
@@ -371,19 +402,21 @@ class ServiceBrokerApiController extends FabrikBaseController {
           }
         });
       })
-      .then(() => eventmesh.apiServerClient.getOSBResourceOperationStatus({
-        resourceGroup: CONST.APISERVER.RESOURCE_GROUPS.INTEROPERATOR,
-        resourceType: CONST.APISERVER.RESOURCE_TYPES.INTEROPERATOR_SERVICEBINDINGS,
-        resourceId: params.binding_id,
-        namespaceId: eventmesh.apiServerClient.getNamespaceId(params.instance_id),
-        start_state: CONST.APISERVER.RESOURCE_STATE.IN_QUEUE,
-        started_at: new Date(),
-        timeout_in_sec: CONST.OSB_OPERATION.OSB_SYNC_OPERATION_TIMEOUT_IN_SEC
-      }))
-      .then(operationStatus => {
-        const secretName = operationStatus.response.secretRef;
-        return eventmesh.apiServerClient.getSecret(secretName, eventmesh.apiServerClient.getNamespaceId(params.instance_id))
-          .then(secret => done(secret.data.response));
+      .then(() => {
+        if (acceptsIncomplete) {
+          return accepted();
+        }
+        return eventmesh.apiServerClient.getOSBResourceOperationStatus({
+          resourceGroup: CONST.APISERVER.RESOURCE_GROUPS.INTEROPERATOR,
+          resourceType: CONST.APISERVER.RESOURCE_TYPES.INTEROPERATOR_SERVICEBINDINGS,
+          resourceId: params.binding_id,
+          namespaceId: eventmesh.apiServerClient.getNamespaceId(params.instance_id),
+          start_state: CONST.APISERVER.RESOURCE_STATE.IN_QUEUE,
+          started_at: new Date(),
+          timeout_in_sec: CONST.OSB_OPERATION.OSB_SYNC_OPERATION_TIMEOUT_IN_SEC
+        })
+          .then(operationStatus => this.getBindingCredentials(operationStatus.response.secretRef, params.instance_id))
+          .then(done);
       })
       .catch(Conflict, conflict)
       .catch(Timeout, err => {
@@ -391,7 +424,94 @@ class ServiceBrokerApiController extends FabrikBaseController {
       });
   }
 
+  getBinding(req, res) {
+    function notFound() {
+      res.status(CONST.HTTP_STATUS_CODE.NOT_FOUND).send({});
+    }
+
+    return eventmesh.apiServerClient.getResource({
+      resourceGroup: CONST.APISERVER.RESOURCE_GROUPS.INTEROPERATOR,
+      resourceType: CONST.APISERVER.RESOURCE_TYPES.INTEROPERATOR_SERVICEBINDINGS,
+      resourceId: req.params.binding_id,
+      namespaceId: eventmesh.apiServerClient.getNamespaceId(req.params.instance_id)
+    })
+      .then(resource => {
+        // Bindings are only returned once the bind operation succeeded. While
+        // their credentials are rotated, the current credentials are returned.
+        const rotating = _.get(resource, 'status.rotation.state') === CONST.APISERVER.ROTATION_STATE.IN_PROGRESS;
+        if ((_.get(resource, 'status.state') !== CONST.APISERVER.RESOURCE_STATE.SUCCEEDED && !rotating) ||
+          !_.isEmpty(_.get(resource, 'metadata.deletionTimestamp'))) {
+          return notFound();
+        }
+        return this.getBindingCredentials(_.get(resource, 'status.response.secretRef'), req.params.instance_id)
+          .then(response => res.status(CONST.HTTP_STATUS_CODE.OK).send(response));
+      })
+      .catch(NotFound, notFound);
+  }
+
+  getLastBindingOperation(req, res) {
+    const encodedOp = _.get(req, 'query.operation', undefined);
+    const operation = encodedOp === undefined ? {} : utils.decodeBase64(encodedOp);
+    const bindingId = req.params.binding_id;
+    const namespaceId = eventmesh.apiServerClient.getNamespaceId(req.params.instance_id);
+
+    function done(resource) {
+      // The interoperator keeps the last operation of asynchronous bindings,
+      // the state of the binding is used otherwise. A last operation with
+      // another id is an earlier one, the polled operation is not started yet.
+      const lastOperation = _.get(resource, 'status.lastOperation');
+      if (lastOperation && _.get(operation, 'id') && lastOperation.id !== operation.id) {
+        logger.debug(`Operation ${operation.id} of binding ${bindingId} not started yet, last operation is ${lastOperation.id}`);
+        return res.status(CONST.HTTP_STATUS_CODE.OK).send({
+          state: CONST.OPERATION.IN_PROGRESS
+        });
+      }
+      const body = lastOperation ? {
+        state: lastOperation.state,
+        description: lastOperation.description
+      } : {
+        state: _.get(resource, 'status.state'),
+        description: _.get(resource, 'status.error')
+      };
+      if (body.state !== CONST.OPERATION.SUCCEEDED && body.state !== CONST.OPERATION.FAILED) {
+        body.state = CONST.OPERATION.IN_PROGRESS;
+      }
+      body.description = body.description || undefined;
+      logger.debug('returning ..', body);
+      return Promise.try(() => {
+        if (_.get(operation, 'type') === 'delete' && body.state === CONST.OPERATION.SUCCEEDED) {
+          return this.removeFinalizersFromOSBResource(
+            CONST.APISERVER.RESOURCE_TYPES.INTEROPERATOR_SERVICEBINDINGS,
+            bindingId,
+            namespaceId
+          );
+        }
+      })
+        .then(() => res.status(CONST.HTTP_STATUS_CODE.OK).send(body));
+    }
+
+    function notFound(err) {
+      if (_.get(operation, 'type') === 'delete') {
+        return res.status(CONST.HTTP_STATUS_CODE.GONE).send({});
+      }
+      res.status(CONST.HTTP_STATUS_CODE.OK).send({
+        state: CONST.OPERATION.FAILED,
+        description: `Binding '${bindingId}' failed because "${err.message}"`
+      });
+    }
+
+    return eventmesh.apiServerClient.getResource({
+      resourceGroup: CONST.APISERVER.RESOURCE_GROUPS.INTEROPERATOR,
+      resourceType: CONST.APISERVER.RESOURCE_TYPES.INTEROPERATOR_SERVICEBINDINGS,
+      resourceId: bindingId,
+      namespaceId: namespaceId
+    })
+      .then(done.bind(this))
+      .catch(NotFound, notFound);
+  }
+
   deleteBinding(req, res) {
+    const acceptsIncomplete = _.get(req, 'query.accepts_incomplete') === 'true';
     const params = _(req.query)
       .set('binding_id', req.params.binding_id)
       .set('id', req.params.binding_id)
@@ -407,37 +527,66 @@ class ServiceBrokerApiController extends FabrikBaseController {
         .then(() => res.status(CONST.HTTP_STATUS_CODE.OK).send({}));
     }
 
+    let operationId;
+
+    function accepted() {
+      res.status(CONST.HTTP_STATUS_CODE.ACCEPTED).send({
+        operation: utils.encodeBase64({
+          'type': 'delete',
+          'id': operationId
+        })
+      });
+    }
+
     function gone(err) {
       /* jshint unused:false */
       res.status(CONST.HTTP_STATUS_CODE.GONE).send({});
     }
     // Delete resource before patching state to delete
     // As interoperator reacts on state change and deletionTimeStamp
-    return eventmesh.apiServerClient.deleteResource({
-      resourceGroup: CONST.APISERVER.RESOURCE_GROUPS.INTEROPERATOR,
-      resourceType: CONST.APISERVER.RESOURCE_TYPES.INTEROPERATOR_SERVICEBINDINGS,
-      resourceId: params.binding_id,
-      namespaceId: eventmesh.apiServerClient.getNamespaceId(params.instance_id)
-    })
+    return utils
+      .uuidV4()
+      .then(id => {
+        operationId = id;
+        return eventmesh.apiServerClient.deleteResource({
+          resourceGroup: CONST.APISERVER.RESOURCE_GROUPS.INTEROPERATOR,
+          resourceType: CONST.APISERVER.RESOURCE_TYPES.INTEROPERATOR_SERVICEBINDINGS,
+          resourceId: params.binding_id,
+          namespaceId: eventmesh.apiServerClient.getNamespaceId(params.instance_id)
+        });
+      })
       .then(() => eventmesh.apiServerClient.updateOSBResource({
         resourceGroup: CONST.APISERVER.RESOURCE_GROUPS.INTEROPERATOR,
         resourceType: CONST.APISERVER.RESOURCE_TYPES.INTEROPERATOR_SERVICEBINDINGS,
         resourceId: params.binding_id,
         namespaceId: eventmesh.apiServerClient.getNamespaceId(params.instance_id),
+        metadata: {
+          annotations: {
+            [CONST.APISERVER.ANNOTATIONS.OPERATION_ID]: operationId
+          }
+        },
+        spec: {
+          accepts_incomplete: acceptsIncomplete
+        },
         status: {
           state: CONST.APISERVER.RESOURCE_STATE.DELETE
         }
       }))
-      .then(() => eventmesh.apiServerClient.getOSBResourceOperationStatus({
-        resourceGroup: CONST.APISERVER.RESOURCE_GROUPS.INTEROPERATOR,
-        resourceType: CONST.APISERVER.RESOURCE_TYPES.INTEROPERATOR_SERVICEBINDINGS,
-        resourceId: params.binding_id,
-        namespaceId: eventmesh.apiServerClient.getNamespaceId(params.instance_id),
-        start_state: CONST.APISERVER.RESOURCE_STATE.DELETE,
-        started_at: new Date(),
-        timeout_in_sec: CONST.OSB_OPERATION.OSB_SYNC_OPERATION_TIMEOUT_IN_SEC
-      }))
-      .then(done.bind(this))
+      .then(() => {
+        if (acceptsIncomplete) {
+          return accepted();
+        }
+        return eventmesh.apiServerClient.getOSBResourceOperationStatus({
+          resourceGroup: CONST.APISERVER.RESOURCE_GROUPS.INTEROPERATOR,
+          resourceType: CONST.APISERVER.RESOURCE_TYPES.INTEROPERATOR_SERVICEBINDINGS,
+          resourceId: params.binding_id,
+          namespaceId: eventmesh.apiServerClient.getNamespaceId(params.instance_id),
+          start_state: CONST.APISERVER.RESOURCE_STATE.DELETE,
+          started_at: new Date(),
+          timeout_in_sec: CONST.OSB_OPERATION.OSB_SYNC_OPERATION_TIMEOUT_IN_SEC
+        })
+          .then(done.bind(this));
+      })
       .catch(NotFound, gone)
       .catch(Timeout, err => {
         res.status(CONST.HTTP_STATUS_CODE.TOO_MANY_REQUESTS).send({});
@@ -39,6 +39,10 @@ instanceRouter.route('/last_operation')
   .get(controller.handler('getLastInstanceOperation'))
   .all(commonMiddleware.methodNotAllowed(['GET']));
 instanceRouter.route('/service_bindings/:binding_id')
+  .get(controller.handler('getBinding'))
   .put([middleware.checkBlockingOperationInProgress(), middleware.validateSchemaForRequest('service_binding', 'create'), controller.handler('putBinding')])
   .delete(middleware.checkBlockingOperationInProgress(), controller.handler('deleteBinding'))
-  .all(commonMiddleware.methodNotAllowed(['PUT', 'DELETE']));
+  .all(commonMiddleware.methodNotAllowed(['GET', 'PUT', 'DELETE']));
+instanceRouter.route('/service_bindings/:binding_id/last_operation')
+  .get(controller.handler('getLastBindingOperation'))
+  .all(commonMiddleware.methodNotAllowed(['GET']));
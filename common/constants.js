@@ -386,6 +386,12 @@ module.exports = Object.freeze({
     FINALIZERS: {
       BROKER: 'broker.servicefabrik.io'
     },
+    ANNOTATIONS: {
+      OPERATION_ID: 'interoperator.servicefabrik.io/operationid'
+    },
+    ROTATION_STATE: {
+      IN_PROGRESS: 'in progress'
+    },
     TASK_TYPE: {
       SERVICE_INSTANCE_BACKUP: 'ServiceInstanceBackupTask',
       SERVICE_INSTANCE_UPDATE: 'ServiceInstanceUpdateTask',
@@ -416,8 +422,20 @@ module.exports = Object.freeze({
   },
   ENCRYPTION: {
     AES_256_ALGORITHM: 'aes-256-ctr',
+    AES_256_GCM_ALGORITHM: 'aes-256-gcm',
     INPUT_ENCODING: 'utf8',
-    OUTPUT_ENCODING: 'hex'
+    OUTPUT_ENCODING: 'hex',
+    KEY_SIZE: 32,
+    CREDENTIAL_ENVELOPE: 'sfEnvelope',
+    CREDENTIAL_ENVELOPE_VERSION: 'v1',
+    KEY_PROVIDER: {
+      FILE: 'file',
+      KMS: 'kms'
+    }
+  },
+  VAULT: {
+    SECRET_REF_PREFIX: 'vault:',
+    TOKEN_ENV: 'VAULT_TOKEN'
   },
   PLATFORM: {
     CF: 'cloudfoundry',
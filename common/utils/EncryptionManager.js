@@ -1,7 +1,12 @@
 'use strict';
 
+const _ = require('lodash');
+const fs = require('fs');
 const crypto = require('crypto');
+const Promise = require('bluebird');
 const config = require('../config');
+const errors = require('../errors');
+const HttpClient = require('./HttpClient');
 const CONST = require('../constants');
 const RsaKeyGenerator = require('./RsaKeyGenerator');
 
@@ -17,10 +22,81 @@ class EncryptionManager {
     return decipher.update(text, CONST.ENCRYPTION.OUTPUT_ENCODING, CONST.ENCRYPTION.INPUT_ENCODING);
   }
 
+  /**
+   * Decrypts binding credentials encrypted by the interoperator, i.e. an
+   * envelope {"sfEnvelope": {...}}. Credentials which are not encrypted
+   * are returned as they are.
+   */
+  decryptCredentials(credentials) {
+    const envelope = _.get(credentials, CONST.ENCRYPTION.CREDENTIAL_ENVELOPE);
+    if (_.isUndefined(envelope)) {
+      return Promise.resolve(credentials);
+    }
+    return Promise
+      .try(() => {
+        if (envelope.version !== CONST.ENCRYPTION.CREDENTIAL_ENVELOPE_VERSION) {
+          throw new errors.InternalServerError(`Unsupported credential envelope version ${envelope.version}`);
+        }
+        const encryptedKey = Buffer.from(envelope.encryptedKey, 'base64');
+        switch (envelope.keyProvider) {
+          case CONST.ENCRYPTION.KEY_PROVIDER.FILE:
+            return this.unwrapKeyWithFile(encryptedKey, envelope.keyID);
+          case CONST.ENCRYPTION.KEY_PROVIDER.KMS:
+            return this.unwrapKeyWithKms(encryptedKey, envelope.keyID);
+          default:
+            throw new errors.InternalServerError(`Unsupported key provider ${envelope.keyProvider}`);
+        }
+      })
+      .then(key => {
+        const plaintext = openGcm(key, Buffer.from(envelope.nonce, 'base64'), Buffer.from(envelope.ciphertext, 'base64'));
+        return JSON.parse(plaintext.toString(CONST.ENCRYPTION.INPUT_ENCODING));
+      });
+  }
+
+  unwrapKeyWithFile(encryptedKey, keyId) {
+    const keyFile = _.get(config, 'apiserver.credential_encryption.key_file');
+    const content = fs.readFileSync(keyFile);
+    const key = content.length === CONST.ENCRYPTION.KEY_SIZE ? content : Buffer.from(content.toString().trim(), 'base64');
+    if (key.length !== CONST.ENCRYPTION.KEY_SIZE) {
+      throw new errors.InternalServerError(`Key encryption key in ${keyFile} is not ${CONST.ENCRYPTION.KEY_SIZE} bytes`);
+    }
+    const expectedKeyId = crypto.createHash('sha256').update(key).digest().slice(0, 8).toString('hex');
+    if (keyId !== expectedKeyId) {
+      throw new errors.InternalServerError(`Unknown key encryption key ${keyId}`);
+    }
+    return openGcm(key, encryptedKey.slice(0, GCM_NONCE_SIZE), encryptedKey.slice(GCM_NONCE_SIZE));
+  }
+
+  unwrapKeyWithKms(encryptedKey, keyId) {
+    const client = new HttpClient({
+      baseUrl: _.get(config, 'apiserver.credential_encryption.endpoint'),
+      json: true
+    });
+    return client
+      .request({
+        method: 'POST',
+        url: '/v1/unwrap',
+        body: {
+          ciphertext: encryptedKey.toString('base64'),
+          keyID: keyId
+        }
+      }, CONST.HTTP_STATUS_CODE.OK)
+      .then(res => Buffer.from(res.body.plaintext, 'base64'));
+  }
+
   async generateSshKeyPair(tempUser) { // jshint ignore: line
     const sshKeyGenerator = new RsaKeyGenerator(tempUser);
     return await sshKeyGenerator.createKeyPair(); // jshint ignore: line
   }
 }
 
+const GCM_NONCE_SIZE = 12;
+const GCM_TAG_SIZE = 16;
+
+function openGcm(key, nonce, sealed) {
+  const decipher = crypto.createDecipheriv(CONST.ENCRYPTION.AES_256_GCM_ALGORITHM, key, nonce);
+  decipher.setAuthTag(sealed.slice(sealed.length - GCM_TAG_SIZE));
+  return Buffer.concat([decipher.update(sealed.slice(0, sealed.length - GCM_TAG_SIZE)), decipher.final()]);
+}
+
 module.exports = EncryptionManager;
@@ -0,0 +1,54 @@
+'use strict';
+
+const _ = require('lodash');
+const fs = require('fs');
+const Promise = require('bluebird');
+const config = require('../config');
+const HttpClient = require('./HttpClient');
+const CONST = require('../constants');
+
+/**
+ * Reads binding credentials which the interoperator keeps in a Vault KV
+ * version 2 secrets engine.
+ */
+class VaultClient extends HttpClient {
+  constructor() {
+    super({
+      baseUrl: _.get(config, 'apiserver.binding_secret_store.vault.address'),
+      json: true
+    });
+  }
+
+  /**
+   * The token is read on each request as it may be renewed in the file.
+   */
+  getToken() {
+    const tokenFile = _.get(config, 'apiserver.binding_secret_store.vault.token_file');
+    if (_.isEmpty(tokenFile)) {
+      return process.env[CONST.VAULT.TOKEN_ENV];
+    }
+    return _.trim(fs.readFileSync(tokenFile, 'utf8'));
+  }
+
+  /**
+   * Returns the data of the secret referenced by vault:<path>, where path
+   * is the read path of the secret below /v1, e.g. secret/data/<name>.
+   */
+  getSecret(secretRef) {
+    return Promise
+      .try(() => this.request({
+        method: 'GET',
+        url: `/v1/${_.trimStart(secretRef.substring(CONST.VAULT.SECRET_REF_PREFIX.length), '/')}`,
+        headers: {
+          'X-Vault-Token': this.getToken()
+        }
+      }, CONST.HTTP_STATUS_CODE.OK))
+      .then(res => _.get(res, 'body.data.data'));
+  }
+
+  static isVaultRef(secretRef) {
+    return _.startsWith(secretRef, CONST.VAULT.SECRET_REF_PREFIX);
+  }
+}
+
+module.exports = VaultClient;
@@ -17,8 +17,11 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"time"
+
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
@@ -26,18 +29,28 @@ import (
 // List of templates to be provided for a service plan
 const (
 	ProvisionAction            = "provision"
+	UpdateAction               = "update"
 	StatusAction               = "status"
 	BindAction                 = "bind"
 	SourcesAction              = "sources"
 	ClusterLabelSelectorAction = "clusterSelector"
 )
 
+// List of operations on instances and bindings of a service plan
+const (
+	ProvisionOperation   = "provision"
+	UpdateOperation      = "update"
+	DeprovisionOperation = "deprovision"
+	BindOperation        = "bind"
+	UnbindOperation      = "unbind"
+)
+
 // TemplateSpec is the specifcation of a template
 type TemplateSpec struct {
-	// +kubebuilder:validation:Enum=provision;status;bind;sources;clusterSelector
+	// +kubebuilder:validation:Enum=provision;update;status;bind;sources;clusterSelector
 	Action string `yaml:"action" json:"action"`
 
-	// +kubebuilder:validation:Enum=gotemplate;helm
+	// +kubebuilder:validation:Enum=gotemplate;helm;kustomize;jsonnet;cue
 	Type           string `yaml:"type" json:"type"`
 	URL            string `yaml:"url,omitempty" json:"url,omitempty"`
 	Content        string `yaml:"content,omitempty" json:"content,omitempty"`
@@ -69,6 +82,18 @@ type ServiceSchemas struct {
 	Binding  ServiceBindingSchema  `json:"binding,omitempty"`
 }
 
+// OperationTimeouts defines how long the operations on instances and
+// bindings of a plan may stay in progress, as durations like 30m. Default
+// applies to the operations without own timeout.
+type OperationTimeouts struct {
+	Default     string `yaml:"default,omitempty" json:"default,omitempty"`
+	Provision   string `yaml:"provision,omitempty" json:"provision,omitempty"`
+	Update      string `yaml:"update,omitempty" json:"update,omitempty"`
+	Deprovision string `yaml:"deprovision,omitempty" json:"deprovision,omitempty"`
+	Bind        string `yaml:"bind,omitempty" json:"bind,omitempty"`
+	Unbind      string `yaml:"unbind,omitempty" json:"unbind,omitempty"`
+}
+
 // SFPlanSpec defines the desired state of SFPlan
 type SFPlanSpec struct {
 	Name          string                `json:"name"`
@@ -83,6 +108,17 @@ type SFPlanSpec struct {
 	ServiceID     string                `json:"serviceId"`
 	RawContext    *runtime.RawExtension `json:"context,omitempty"`
 	Manager       *runtime.RawExtension `json:"manager,omitempty"`
+
+	// Compute resources required by an instance of the plan.
+	// Used by the resource-aware scheduler to find a cluster
+	// with enough capacity.
+	ResourceRequests corev1.ResourceList `json:"resourceRequests,omitempty"`
+	// Tolerations of the instances of the plan for the taints of
+	// the SFClusters.
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// Timeouts of the operations on the instances and bindings of the
+	// plan. Operations still in progress after their timeout fail.
+	Timeouts *OperationTimeouts `json:"timeouts,omitempty"`
 	// Add supported_platform field
 }
 
@@ -127,3 +163,38 @@ func (sfPlan *SFPlan) GetTemplate(action string) (*TemplateSpec, error) {
 	}
 	return nil, errors.NewTemplateNotFound(action, sfPlan.Spec.ID, nil)
 }
+
+// GetOperationTimeout fetches the timeout of the given operation. It is 0
+// if the plan declares no timeout for the operation.
+func (sfPlan *SFPlan) GetOperationTimeout(operation string) (time.Duration, error) {
+	timeouts := sfPlan.Spec.Timeouts
+	if timeouts == nil {
+		return 0, nil
+	}
+	var timeout string
+	switch operation {
+	case ProvisionOperation:
+		timeout = timeouts.Provision
+	case UpdateOperation:
+		timeout = timeouts.Update
+	case DeprovisionOperation:
+		timeout = timeouts.Deprovision
+	case BindOperation:
+		timeout = timeouts.Bind
+	case UnbindOperation:
+		timeout = timeouts.Unbind
+	default:
+		return 0, errors.NewInputError("GetOperationTimeout", "operation", nil)
+	}
+	if timeout == "" {
+		timeout = timeouts.Default
+	}
+	if timeout == "" {
+		return 0, nil
+	}
+	duration, err := time.ParseDuration(timeout)
+	if err != nil {
+		return 0, errors.NewInputError("GetOperationTimeout", "timeouts."+operation, err)
+	}
+	return duration, nil
+}
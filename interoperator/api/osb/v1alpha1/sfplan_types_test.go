@@ -19,6 +19,7 @@ package v1alpha1
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/onsi/gomega"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -165,3 +166,71 @@ func TestStorageSfPlan(t *testing.T) {
 	g.Expect(c.Delete(context.TODO(), fetched)).NotTo(gomega.HaveOccurred())
 	g.Expect(c.Get(context.TODO(), key, fetched)).To(gomega.HaveOccurred())
 }
+
+func TestSFPlan_GetOperationTimeout(t *testing.T) {
+	timeouts := &OperationTimeouts{
+		Default:     "1h",
+		Provision:   "30m",
+		Deprovision: "invalid",
+	}
+	tests := []struct {
+		name      string
+		timeouts  *OperationTimeouts
+		operation string
+		want      time.Duration
+		wantErr   bool
+	}{
+		{
+			name:      "return 0 without timeouts",
+			operation: ProvisionOperation,
+			want:      0,
+		},
+		{
+			name:      "return timeout of the operation",
+			timeouts:  timeouts,
+			operation: ProvisionOperation,
+			want:      time.Minute * 30,
+		},
+		{
+			name:      "return default timeout",
+			timeouts:  timeouts,
+			operation: BindOperation,
+			want:      time.Hour,
+		},
+		{
+			name:      "return 0 without default timeout",
+			timeouts:  &OperationTimeouts{Provision: "30m"},
+			operation: UnbindOperation,
+			want:      0,
+		},
+		{
+			name:      "fail for invalid timeout",
+			timeouts:  timeouts,
+			operation: DeprovisionOperation,
+			wantErr:   true,
+		},
+		{
+			name:      "fail for unknown operation",
+			timeouts:  timeouts,
+			operation: "unknown",
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sfPlan := &SFPlan{
+				Spec: SFPlanSpec{
+					Timeouts: tt.timeouts,
+				},
+			}
+			got, err := sfPlan.GetOperationTimeout(tt.operation)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SFPlan.GetOperationTimeout() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("SFPlan.GetOperationTimeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -49,6 +49,9 @@ type SFServiceBindingStatus struct {
 	Response    BindingResponse      `yaml:"response,omitempty" json:"response,omitempty"`
 	AppliedSpec SFServiceBindingSpec `yaml:"appliedSpec,omitempty" json:"appliedSpec,omitempty"`
 	Resources   []Source             `yaml:"resources,omitempty" json:"resources,omitempty"`
+	Rotation    *BindingRotation     `yaml:"rotation,omitempty" json:"rotation,omitempty"`
+
+	LastOperation *BindingLastOperation `yaml:"lastOperation,omitempty" json:"lastOperation,omitempty"`
 }
 
 // BindingResponse defines the details of the binding response
@@ -56,6 +59,33 @@ type BindingResponse struct {
 	SecretRef string `yaml:"secretRef,omitempty" json:"secretRef,omitempty"`
 }
 
+// BindingRotation defines the state of the last credential rotation of
+// the binding. The previous credentials and resources are kept until
+// RevokeAfter. A failed rotation keeps the current credentials. The
+// rotation fails if it is still in progress after Deadline.
+type BindingRotation struct {
+	ID                string       `yaml:"id" json:"id"`
+	State             string       `yaml:"state,omitempty" json:"state,omitempty"`
+	Error             string       `yaml:"error,omitempty" json:"error,omitempty"`
+	PreviousSecretRef string       `yaml:"previousSecretRef,omitempty" json:"previousSecretRef,omitempty"`
+	PreviousResources []Source     `yaml:"previousResources,omitempty" json:"previousResources,omitempty"`
+	RevokeAfter       *metav1.Time `yaml:"revokeAfter,omitempty" json:"revokeAfter,omitempty"`
+	StartedAt         *metav1.Time `yaml:"startedAt,omitempty" json:"startedAt,omitempty"`
+	Deadline          *metav1.Time `yaml:"deadline,omitempty" json:"deadline,omitempty"`
+}
+
+// BindingLastOperation defines the state of the last asynchronous bind or
+// unbind operation of the binding, which OSB clients poll. The operation
+// fails if it is still in progress after Deadline.
+type BindingLastOperation struct {
+	ID          string       `yaml:"id" json:"id"`
+	Type        string       `yaml:"type" json:"type"`
+	State       string       `yaml:"state,omitempty" json:"state,omitempty"`
+	Description string       `yaml:"description,omitempty" json:"description,omitempty"`
+	StartedAt   *metav1.Time `yaml:"startedAt,omitempty" json:"startedAt,omitempty"`
+	Deadline    *metav1.Time `yaml:"deadline,omitempty" json:"deadline,omitempty"`
+}
+
 // +kubebuilder:object:root=true
 // +genclient
 // +genclient:noStatus
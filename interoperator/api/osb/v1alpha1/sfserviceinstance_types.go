@@ -17,8 +17,11 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"encoding/json"
+
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -44,6 +47,12 @@ type SFServiceInstanceStatus struct {
 	Description  string                `yaml:"description,omitempty" json:"description,omitempty"`
 	AppliedSpec  SFServiceInstanceSpec `yaml:"appliedSpec,omitempty" json:"appliedSpec,omitempty"`
 	Resources    []Source              `yaml:"resources,omitempty" json:"resources,omitempty"`
+	Pruned       []Source              `yaml:"pruned,omitempty" json:"pruned,omitempty"`
+
+	// The operation in progress fails after OperationDeadline, which is
+	// set if the plan declares a timeout for the operation
+	OperationStartedAt *metav1.Time `yaml:"operationStartedAt,omitempty" json:"operationStartedAt,omitempty"`
+	OperationDeadline  *metav1.Time `yaml:"operationDeadline,omitempty" json:"operationDeadline,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -100,3 +109,19 @@ func (r *SFServiceInstance) GetClusterID() (string, error) {
 	}
 	return r.Spec.ClusterID, nil
 }
+
+// GetTolerations fetches the tolerations passed in the tolerations
+// parameter of the SFServiceInstance
+func (r *SFServiceInstance) GetTolerations() ([]corev1.Toleration, error) {
+	if r == nil || r.Spec.RawParameters == nil || len(r.Spec.RawParameters.Raw) == 0 {
+		return nil, nil
+	}
+	params := struct {
+		Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	}{}
+	err := json.Unmarshal(r.Spec.RawParameters.Raw, &params)
+	if err != nil {
+		return nil, errors.NewUnmarshalError("failed to read tolerations of SFServiceInstance "+r.GetName(), err)
+	}
+	return params.Tolerations, nil
+}
@@ -18,9 +18,11 @@ package v1alpha1
 
 import (
 	"context"
+	"reflect"
 	"testing"
 
 	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -209,3 +211,61 @@ func TestSFServiceInstance_SetState(t *testing.T) {
 		})
 	}
 }
+
+func TestSFServiceInstance_GetTolerations(t *testing.T) {
+	tests := []struct {
+		name       string
+		parameters string
+		want       []corev1.Toleration
+		wantErr    bool
+	}{
+		{
+			name:       "return nil if parameters are not set",
+			parameters: "",
+			want:       nil,
+			wantErr:    false,
+		},
+		{
+			name:       "return nil if tolerations are not passed",
+			parameters: `{"foo": "bar"}`,
+			want:       nil,
+			wantErr:    false,
+		},
+		{
+			name:       "return tolerations",
+			parameters: `{"tolerations": [{"key": "maintenance", "operator": "Exists", "effect": "NoSchedule"}]}`,
+			want: []corev1.Toleration{
+				{
+					Key:      "maintenance",
+					Operator: corev1.TolerationOpExists,
+					Effect:   corev1.TaintEffectNoSchedule,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:       "fail if tolerations are invalid",
+			parameters: `{"tolerations": "invalid"}`,
+			want:       nil,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &SFServiceInstance{}
+			if tt.parameters != "" {
+				r.Spec.RawParameters = &runtime.RawExtension{
+					Raw: []byte(tt.parameters),
+				}
+			}
+			got, err := r.GetTolerations()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SFServiceInstance.GetTolerations() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SFServiceInstance.GetTolerations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
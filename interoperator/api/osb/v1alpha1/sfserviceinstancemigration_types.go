@@ -0,0 +1,131 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SFServiceInstanceMigrationSpec defines the desired state of SFServiceInstanceMigration
+type SFServiceInstanceMigrationSpec struct {
+	// ID of the SFServiceInstance to migrate. The SFServiceInstance needs
+	// to exist in the same namespace as the SFServiceInstanceMigration.
+	InstanceID string `json:"instanceId"`
+	// ID of the SFCluster the SFServiceInstance is moved to
+	TargetClusterID string `json:"targetClusterId"`
+}
+
+// SFServiceInstanceMigrationPhase is a valid value for SFServiceInstanceMigrationStatus.Phase
+type SFServiceInstanceMigrationPhase string
+
+// These are the phases of a SFServiceInstanceMigration, in the order
+// they are run
+const (
+	// MigrationPending means the migration has not started yet. The
+	// SFServiceInstance is waited for to be in succeeded state.
+	MigrationPending SFServiceInstanceMigrationPhase = "Pending"
+	// MigrationProvisioningTarget means the SFServiceInstance is being
+	// replicated to the target cluster to render the provision templates
+	MigrationProvisioningTarget SFServiceInstanceMigrationPhase = "ProvisioningTarget"
+	// MigrationWaitingForTarget means the provisioning on the target
+	// cluster is waited for to succeed
+	MigrationWaitingForTarget SFServiceInstanceMigrationPhase = "WaitingForTarget"
+	// MigrationMovingBindings means the SFServiceBindings and their secrets
+	// are copied to the target cluster. The ClusterID of the SFServiceInstance
+	// is set to the target cluster at the end of this phase.
+	MigrationMovingBindings SFServiceInstanceMigrationPhase = "MovingBindings"
+	// MigrationDeprovisioningSource means the SFServiceInstance is being
+	// deprovisioned from the source cluster
+	MigrationDeprovisioningSource SFServiceInstanceMigrationPhase = "DeprovisioningSource"
+	// MigrationSucceeded means the SFServiceInstance is moved to the target cluster
+	MigrationSucceeded SFServiceInstanceMigrationPhase = "Succeeded"
+	// MigrationFailed means the migration can not proceed. Message has the reason.
+	MigrationFailed SFServiceInstanceMigrationPhase = "Failed"
+)
+
+// SFServiceInstanceMigrationStatus defines the observed state of SFServiceInstanceMigration
+type SFServiceInstanceMigrationStatus struct {
+	Phase SFServiceInstanceMigrationPhase `json:"phase,omitempty"`
+	// ID of the SFCluster the SFServiceInstance was deployed on when the
+	// migration started
+	SourceClusterID string `json:"sourceClusterId,omitempty"`
+	// IDs of the SFServiceBindings copied to the target cluster
+	Bindings           []string    `json:"bindings,omitempty"`
+	Message            string      `json:"message,omitempty"`
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="instance",type=string,JSONPath=`.spec.instanceId`
+// +kubebuilder:printcolumn:name="source",type=string,JSONPath=`.status.sourceClusterId`
+// +kubebuilder:printcolumn:name="target",type=string,JSONPath=`.spec.targetClusterId`
+// +kubebuilder:printcolumn:name="phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// SFServiceInstanceMigration is the Schema for the sfserviceinstancemigrations API.
+// It moves a SFServiceInstance and its SFServiceBindings from the SFCluster
+// they are deployed on to another SFCluster.
+type SFServiceInstanceMigration struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SFServiceInstanceMigrationSpec   `json:"spec,omitempty"`
+	Status SFServiceInstanceMigrationStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// SFServiceInstanceMigrationList contains a list of SFServiceInstanceMigration
+type SFServiceInstanceMigrationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SFServiceInstanceMigration `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SFServiceInstanceMigration{}, &SFServiceInstanceMigrationList{})
+}
+
+// GetPhase fetches the phase of the SFServiceInstanceMigration.
+// Returns MigrationPending if the phase is not set.
+func (r *SFServiceInstanceMigration) GetPhase() SFServiceInstanceMigrationPhase {
+	if r == nil || r.Status.Phase == "" {
+		return MigrationPending
+	}
+	return r.Status.Phase
+}
+
+// SetPhase updates the phase of the SFServiceInstanceMigration along
+// with the message. LastTransitionTime is updated only if the phase
+// is changed.
+func (r *SFServiceInstanceMigration) SetPhase(phase SFServiceInstanceMigrationPhase, message string) {
+	if r == nil {
+		return
+	}
+	if r.Status.Phase != phase {
+		r.Status.Phase = phase
+		r.Status.LastTransitionTime = metav1.Now()
+	}
+	r.Status.Message = message
+}
+
+// IsCompleted returns true if the migration has succeeded or failed
+func (r *SFServiceInstanceMigration) IsCompleted() bool {
+	phase := r.GetPhase()
+	return phase == MigrationSucceeded || phase == MigrationFailed
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestStorageSFServiceInstanceMigration(t *testing.T) {
+	key := types.NamespacedName{
+		Name:      "foo",
+		Namespace: "default",
+	}
+	created := &SFServiceInstanceMigration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Spec: SFServiceInstanceMigrationSpec{
+			InstanceID:      "instance-id",
+			TargetClusterID: "2",
+		},
+	}
+	g := gomega.NewGomegaWithT(t)
+
+	// Test Create
+	fetched := &SFServiceInstanceMigration{}
+	g.Expect(c.Create(context.TODO(), created)).NotTo(gomega.HaveOccurred())
+
+	g.Expect(c.Get(context.TODO(), key, fetched)).NotTo(gomega.HaveOccurred())
+	g.Expect(fetched).To(gomega.Equal(created))
+	g.Expect(fetched.GetPhase()).To(gomega.Equal(MigrationPending))
+
+	// Test Updating the status
+	updated := fetched.DeepCopy()
+	updated.Status.SourceClusterID = "1"
+	updated.SetPhase(MigrationProvisioningTarget, "")
+	g.Expect(c.Status().Update(context.TODO(), updated)).NotTo(gomega.HaveOccurred())
+
+	g.Expect(c.Get(context.TODO(), key, fetched)).NotTo(gomega.HaveOccurred())
+	g.Expect(fetched.GetPhase()).To(gomega.Equal(MigrationProvisioningTarget))
+	g.Expect(fetched.Status.SourceClusterID).To(gomega.Equal("1"))
+
+	// Test Delete
+	g.Expect(c.Delete(context.TODO(), fetched)).NotTo(gomega.HaveOccurred())
+	g.Expect(c.Get(context.TODO(), key, fetched)).To(gomega.HaveOccurred())
+}
+
+func TestSFServiceInstanceMigration_SetPhase(t *testing.T) {
+	tests := []struct {
+		name       string
+		phase      SFServiceInstanceMigrationPhase
+		message    string
+		transition bool
+		completed  bool
+	}{
+		{
+			name:       "not update the transition time if the phase is not changed",
+			phase:      MigrationWaitingForTarget,
+			message:    "waiting",
+			transition: false,
+			completed:  false,
+		},
+		{
+			name:       "update the transition time if the phase is changed",
+			phase:      MigrationMovingBindings,
+			message:    "",
+			transition: true,
+			completed:  false,
+		},
+		{
+			name:       "be completed on success",
+			phase:      MigrationSucceeded,
+			message:    "",
+			transition: true,
+			completed:  true,
+		},
+		{
+			name:       "be completed on failure",
+			phase:      MigrationFailed,
+			message:    "failed",
+			transition: true,
+			completed:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+			lastTransitionTime := metav1.NewTime(metav1.Now().Add(-time.Minute))
+			r := &SFServiceInstanceMigration{
+				Status: SFServiceInstanceMigrationStatus{
+					Phase:              MigrationWaitingForTarget,
+					LastTransitionTime: lastTransitionTime,
+				},
+			}
+			r.SetPhase(tt.phase, tt.message)
+			g.Expect(r.GetPhase()).To(gomega.Equal(tt.phase))
+			g.Expect(r.Status.Message).To(gomega.Equal(tt.message))
+			g.Expect(r.Status.LastTransitionTime.Equal(&lastTransitionTime)).To(gomega.Equal(!tt.transition))
+			g.Expect(r.IsCompleted()).To(gomega.Equal(tt.completed))
+		})
+	}
+}
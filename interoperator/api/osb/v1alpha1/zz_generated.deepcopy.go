@@ -21,6 +21,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -39,6 +40,29 @@ func (in *APIVersionKind) DeepCopy() *APIVersionKind {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BindingLastOperation) DeepCopyInto(out *BindingLastOperation) {
+	*out = *in
+	if in.StartedAt != nil {
+		in, out := &in.StartedAt, &out.StartedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Deadline != nil {
+		in, out := &in.Deadline, &out.Deadline
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BindingLastOperation.
+func (in *BindingLastOperation) DeepCopy() *BindingLastOperation {
+	if in == nil {
+		return nil
+	}
+	out := new(BindingLastOperation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BindingResponse) DeepCopyInto(out *BindingResponse) {
 	*out = *in
@@ -54,6 +78,38 @@ func (in *BindingResponse) DeepCopy() *BindingResponse {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BindingRotation) DeepCopyInto(out *BindingRotation) {
+	*out = *in
+	if in.PreviousResources != nil {
+		in, out := &in.PreviousResources, &out.PreviousResources
+		*out = make([]Source, len(*in))
+		copy(*out, *in)
+	}
+	if in.RevokeAfter != nil {
+		in, out := &in.RevokeAfter, &out.RevokeAfter
+		*out = (*in).DeepCopy()
+	}
+	if in.StartedAt != nil {
+		in, out := &in.StartedAt, &out.StartedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Deadline != nil {
+		in, out := &in.Deadline, &out.Deadline
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BindingRotation.
+func (in *BindingRotation) DeepCopy() *BindingRotation {
+	if in == nil {
+		return nil
+	}
+	out := new(BindingRotation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DashboardClient) DeepCopyInto(out *DashboardClient) {
 	*out = *in
@@ -69,6 +125,21 @@ func (in *DashboardClient) DeepCopy() *DashboardClient {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperationTimeouts) DeepCopyInto(out *OperationTimeouts) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperationTimeouts.
+func (in *OperationTimeouts) DeepCopy() *OperationTimeouts {
+	if in == nil {
+		return nil
+	}
+	out := new(OperationTimeouts)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SFPlan) DeepCopyInto(out *SFPlan) {
 	*out = *in
@@ -156,6 +227,25 @@ func (in *SFPlanSpec) DeepCopyInto(out *SFPlanSpec) {
 		*out = new(runtime.RawExtension)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ResourceRequests != nil {
+		in, out := &in.ResourceRequests, &out.ResourceRequests
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Timeouts != nil {
+		in, out := &in.Timeouts, &out.Timeouts
+		*out = new(OperationTimeouts)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SFPlanSpec.
@@ -309,6 +399,16 @@ func (in *SFServiceBindingStatus) DeepCopyInto(out *SFServiceBindingStatus) {
 		*out = make([]Source, len(*in))
 		copy(*out, *in)
 	}
+	if in.Rotation != nil {
+		in, out := &in.Rotation, &out.Rotation
+		*out = new(BindingRotation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastOperation != nil {
+		in, out := &in.LastOperation, &out.LastOperation
+		*out = new(BindingLastOperation)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SFServiceBindingStatus.
@@ -380,6 +480,101 @@ func (in *SFServiceInstanceList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SFServiceInstanceMigration) DeepCopyInto(out *SFServiceInstanceMigration) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SFServiceInstanceMigration.
+func (in *SFServiceInstanceMigration) DeepCopy() *SFServiceInstanceMigration {
+	if in == nil {
+		return nil
+	}
+	out := new(SFServiceInstanceMigration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SFServiceInstanceMigration) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SFServiceInstanceMigrationList) DeepCopyInto(out *SFServiceInstanceMigrationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SFServiceInstanceMigration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SFServiceInstanceMigrationList.
+func (in *SFServiceInstanceMigrationList) DeepCopy() *SFServiceInstanceMigrationList {
+	if in == nil {
+		return nil
+	}
+	out := new(SFServiceInstanceMigrationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SFServiceInstanceMigrationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SFServiceInstanceMigrationSpec) DeepCopyInto(out *SFServiceInstanceMigrationSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SFServiceInstanceMigrationSpec.
+func (in *SFServiceInstanceMigrationSpec) DeepCopy() *SFServiceInstanceMigrationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SFServiceInstanceMigrationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SFServiceInstanceMigrationStatus) DeepCopyInto(out *SFServiceInstanceMigrationStatus) {
+	*out = *in
+	if in.Bindings != nil {
+		in, out := &in.Bindings, &out.Bindings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SFServiceInstanceMigrationStatus.
+func (in *SFServiceInstanceMigrationStatus) DeepCopy() *SFServiceInstanceMigrationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SFServiceInstanceMigrationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SFServiceInstanceSpec) DeepCopyInto(out *SFServiceInstanceSpec) {
 	*out = *in
@@ -419,6 +614,19 @@ func (in *SFServiceInstanceStatus) DeepCopyInto(out *SFServiceInstanceStatus) {
 		*out = make([]Source, len(*in))
 		copy(*out, *in)
 	}
+	if in.Pruned != nil {
+		in, out := &in.Pruned, &out.Pruned
+		*out = make([]Source, len(*in))
+		copy(*out, *in)
+	}
+	if in.OperationStartedAt != nil {
+		in, out := &in.OperationStartedAt, &out.OperationStartedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.OperationDeadline != nil {
+		in, out := &in.OperationDeadline, &out.OperationDeadline
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SFServiceInstanceStatus.
@@ -32,7 +32,7 @@ import (
 )
 
 // GetKubeConfig return the kubeconfig of the cluster
-func (cluster *SFCluster) GetKubeConfig(c kubernetes.Client) (*rest.Config, error) {
+func (cluster *SFCluster) GetKubeConfig(c kubernetes.Reader) (*rest.Config, error) {
 	var secretKey = types.NamespacedName{
 		Name:      cluster.Spec.SecretRef,
 		Namespace: cluster.GetNamespace(),
@@ -66,7 +66,7 @@ func (cluster *SFCluster) GetKubeConfig(c kubernetes.Client) (*rest.Config, erro
 //go:generate mockgen -destination ./mock_sfcluster/mock_sfcluster.go github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/resource/v1alpha1 SFClusterInterface
 type SFClusterInterface interface {
 	metav1.Object
-	GetKubeConfig(c kubernetes.Client) (*rest.Config, error)
+	GetKubeConfig(c kubernetes.Reader) (*rest.Config, error)
 }
 
 var _ SFClusterInterface = &SFCluster{}
@@ -163,7 +163,7 @@ func (mr *MockSFClusterInterfaceMockRecorder) GetInitializers() *gomock.Call {
 }
 
 // GetKubeConfig mocks base method
-func (m *MockSFClusterInterface) GetKubeConfig(arg0 client.Client) (*rest.Config, error) {
+func (m *MockSFClusterInterface) GetKubeConfig(arg0 client.Reader) (*rest.Config, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetKubeConfig", arg0)
 	ret0, _ := ret[0].(*rest.Config)
@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -26,13 +27,97 @@ type SFClusterSpec struct {
 	// member cluster. The secret needs to exist in the same namespace
 	// as the SFCluster and should have a "kubeconfig" key.
 	SecretRef string `json:"secretRef"`
+
+	// Taints of the cluster. New instances are not scheduled on a cluster
+	// with a NoSchedule or NoExecute taint unless they tolerate it, and
+	// clusters with untolerated PreferNoSchedule taints are avoided.
+	Taints []corev1.Taint `json:"taints,omitempty"`
+}
+
+// SFClusterConditionType is a valid value for SFClusterCondition.Type
+type SFClusterConditionType string
+
+// These are valid conditions of a SFCluster
+const (
+	// SFClusterReady means the api server of the member cluster is reachable
+	SFClusterReady SFClusterConditionType = "Ready"
+	// SFClusterKubeconfigValid means the kubeconfig secret of the SFCluster
+	// exists and could be parsed
+	SFClusterKubeconfigValid SFClusterConditionType = "KubeconfigValid"
+	// SFClusterCRDsRegistered means the service fabrik CRDs are registered
+	// in the member cluster
+	SFClusterCRDsRegistered SFClusterConditionType = "CRDsRegistered"
+	// SFClusterProvisionerDeployed means the provisioner is deployed and
+	// available in the member cluster
+	SFClusterProvisionerDeployed SFClusterConditionType = "ProvisionerDeployed"
+)
+
+// SFClusterCondition contains details for the current condition of a SFCluster
+type SFClusterCondition struct {
+	Type               SFClusterConditionType `json:"type"`
+	Status             corev1.ConditionStatus `json:"status"`
+	LastTransitionTime metav1.Time            `json:"lastTransitionTime,omitempty"`
+	Reason             string                 `json:"reason,omitempty"`
+	Message            string                 `json:"message,omitempty"`
 }
 
 // SFClusterStatus defines the observed state of SFCluster
 type SFClusterStatus struct {
+	Conditions    []SFClusterCondition `json:"conditions,omitempty"`
+	ServerVersion string               `json:"serverVersion,omitempty"`
+
+	// Sum of the allocatable resources of all the nodes of the cluster
+	Allocatable corev1.ResourceList `json:"allocatable,omitempty"`
+	// Sum of the resource requests of all the pods running in the cluster
+	Requested corev1.ResourceList `json:"requested,omitempty"`
+
+	InstancesCount int `json:"instancesCount"`
+	BindingsCount  int `json:"bindingsCount"`
+}
+
+// GetCondition returns the condition of type conditionType.
+// Returns nil if the condition is not set.
+func (status *SFClusterStatus) GetCondition(conditionType SFClusterConditionType) *SFClusterCondition {
+	if status == nil {
+		return nil
+	}
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == conditionType {
+			return &status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// SetCondition sets the condition in the status. LastTransitionTime is
+// updated only if the status of the condition is changed.
+func (status *SFClusterStatus) SetCondition(condition SFClusterCondition) {
+	if status == nil {
+		return
+	}
+	existing := status.GetCondition(condition.Type)
+	if existing == nil {
+		if condition.LastTransitionTime.IsZero() {
+			condition.LastTransitionTime = metav1.Now()
+		}
+		status.Conditions = append(status.Conditions, condition)
+		return
+	}
+	if existing.Status != condition.Status {
+		existing.Status = condition.Status
+		existing.LastTransitionTime = metav1.Now()
+	}
+	existing.Reason = condition.Reason
+	existing.Message = condition.Message
 }
 
 // +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="version",type=string,JSONPath=`.status.serverVersion`
+// +kubebuilder:printcolumn:name="instances",type=integer,JSONPath=`.status.instancesCount`
+// +kubebuilder:printcolumn:name="bindings",type=integer,JSONPath=`.status.bindingsCount`
+// +kubebuilder:printcolumn:name="age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // SFCluster is the Schema for the sfclusters API
 type SFCluster struct {
@@ -20,6 +20,7 @@ import (
 	"context"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
@@ -143,6 +144,47 @@ func TestSFCluster_GetKubeConfig(t *testing.T) {
 	}
 }
 
+func TestSFClusterStatus_SetCondition(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	status := &SFClusterStatus{}
+
+	g.Expect(status.GetCondition(SFClusterReady)).To(gomega.BeNil())
+
+	status.SetCondition(SFClusterCondition{
+		Type:   SFClusterReady,
+		Status: corev1.ConditionFalse,
+		Reason: "ClusterUnreachable",
+	})
+	condition := status.GetCondition(SFClusterReady)
+	g.Expect(condition).NotTo(gomega.BeNil())
+	g.Expect(condition.Status).To(gomega.Equal(corev1.ConditionFalse))
+	g.Expect(condition.LastTransitionTime.IsZero()).To(gomega.BeFalse())
+
+	// LastTransitionTime is retained if status is not changed
+	transitionTime := metav1.NewTime(condition.LastTransitionTime.Add(-time.Hour))
+	condition.LastTransitionTime = transitionTime
+	status.SetCondition(SFClusterCondition{
+		Type:    SFClusterReady,
+		Status:  corev1.ConditionFalse,
+		Reason:  "ClusterUnreachable",
+		Message: "timeout",
+	})
+	condition = status.GetCondition(SFClusterReady)
+	g.Expect(condition.Message).To(gomega.Equal("timeout"))
+	g.Expect(condition.LastTransitionTime).To(gomega.Equal(transitionTime))
+
+	// LastTransitionTime is updated if status is changed
+	status.SetCondition(SFClusterCondition{
+		Type:   SFClusterReady,
+		Status: corev1.ConditionTrue,
+	})
+	condition = status.GetCondition(SFClusterReady)
+	g.Expect(condition.Status).To(gomega.Equal(corev1.ConditionTrue))
+	g.Expect(condition.Reason).To(gomega.BeEmpty())
+	g.Expect(condition.LastTransitionTime).NotTo(gomega.Equal(transitionTime))
+	g.Expect(status.Conditions).To(gomega.HaveLen(1))
+}
+
 func _getDummyCluster() *SFCluster {
 	return &SFCluster{
 		ObjectMeta: metav1.ObjectMeta{
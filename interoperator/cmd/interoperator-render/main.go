@@ -0,0 +1,118 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// interoperator-render renders the templates of a plan without a cluster.
+//
+//	interoperator-render -f service.yaml -f plan.yaml -f instance.yaml [-f binding.yaml] [-f sources.yaml]
+//
+// The files hold the SFService and SFPlan, a sample SFServiceInstance
+// and optionally a sample SFServiceBinding. Any other object is a source
+// object read by the status template and by the lookup function of
+// gotemplate. The rendered files are printed, written to --output-dir or
+// compared with the files in --diff.
+//
+// The rand functions of gotemplate, like randAlphaNum, fail unless a seed
+// is given with --rand-seed or the RAND_SEED environment variable, as in
+// the interoperator. The values are the same for the same seed, so the
+// seed of a landscape must not be used to render templates locally.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/offline"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+)
+
+type fileList []string
+
+func (f *fileList) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *fileList) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+func main() {
+	var files fileList
+	var namespace, outputDir, diffDir, randSeed string
+	flag.Var(&files, "f", "File with SFService, SFPlan, SFServiceInstance, SFServiceBinding or source objects. Can be repeated.")
+	flag.StringVar(&namespace, "namespace", "", "Namespace the templates are rendered for. Defaults to the namespace of the instance.")
+	flag.StringVar(&outputDir, "output-dir", "", "Write the rendered files to this directory instead of printing them.")
+	flag.StringVar(&diffDir, "diff", "", "Compare the rendered files with the files in this directory, as written by --output-dir.")
+	flag.StringVar(&randSeed, "rand-seed", os.Getenv(constants.RandSeedEnvKey), "Seed of the rand functions of gotemplate. Defaults to $"+constants.RandSeedEnvKey+".")
+	flag.Parse()
+
+	// The rand functions read the seed from the environment
+	if err := os.Setenv(constants.RandSeedEnvKey, randSeed); err != nil {
+		exit(err)
+	}
+
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "at least one file is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	objs := &offline.Objects{
+		Namespace: namespace,
+	}
+	for _, path := range files {
+		file, err := os.Open(path)
+		if err != nil {
+			exit(err)
+		}
+		err = objs.Read(file)
+		file.Close()
+		if err != nil {
+			exit(fmt.Errorf("%s: %v", path, err))
+		}
+	}
+
+	results, err := offline.Render(objs)
+	if err != nil {
+		exit(err)
+	}
+
+	switch {
+	case diffDir != "":
+		diff, err := offline.Diff(results, diffDir)
+		if err != nil {
+			exit(err)
+		}
+		if diff != "" {
+			fmt.Print(diff)
+			os.Exit(1)
+		}
+	case outputDir != "":
+		err = offline.Write(results, outputDir)
+		if err != nil {
+			exit(err)
+		}
+	default:
+		fmt.Print(offline.Print(results))
+	}
+}
+
+func exit(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
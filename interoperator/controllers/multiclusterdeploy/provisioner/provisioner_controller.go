@@ -62,8 +62,9 @@ type ReconcileProvisioner struct {
 5. Namespace creation in target cluster
 6. SFCluster deploy in target cluster
 7. Kubeconfig secret in target cluster
-8. Create clusterrolebinding in target cluster
-9. Deploy provisioner in target cluster
+8. Secrets referred by the env of the provisioner in target cluster
+9. Create clusterrolebinding in target cluster
+10. Deploy provisioner in target cluster
 */
 func (r *ReconcileProvisioner) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	ctx := context.Background()
@@ -133,13 +134,20 @@ func (r *ReconcileProvisioner) Reconcile(req ctrl.Request) (ctrl.Result, error)
 		return ctrl.Result{}, err
 	}
 
-	// 8. Deploy cluster rolebinding
+	// 8. Creating/Updating secrets referred by the env of the provisioner
+	// in target cluster
+	err = r.reconcileEnvSecrets(deplomentInstance, clusterID, targetClient)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// 9. Deploy cluster rolebinding
 	err = r.reconcileClusterRoleBinding(namespace, clusterID, targetClient)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
-	// 9. Create Deployment in target cluster for provisioner
+	// 10. Create Deployment in target cluster for provisioner
 	err = r.reconcileDeployment(deplomentInstance, clusterID, targetClient)
 	if err != nil {
 		return ctrl.Result{}, err
@@ -152,14 +160,7 @@ func (r *ReconcileProvisioner) registerSFCrds(clusterID string, targetClient cli
 	ctx := context.Background()
 	log := r.Log.WithValues("clusterID", clusterID)
 
-	SFCrdNames := []string{
-		"sfplans.osb.servicefabrik.io",
-		"sfservices.osb.servicefabrik.io",
-		"sfserviceinstances.osb.servicefabrik.io",
-		"sfservicebindings.osb.servicefabrik.io",
-		"sfclusters.resource.servicefabrik.io",
-	}
-	for _, sfcrdname := range SFCrdNames {
+	for _, sfcrdname := range constants.SFCrdNames {
 		// Get crd registered in master cluster
 		sfCRDInstance := &apiextensionsv1beta1.CustomResourceDefinition{}
 
@@ -344,6 +345,61 @@ func (r *ReconcileProvisioner) reconcileSfClusterSecret(namespace string, secret
 	return nil
 }
 
+// reconcileEnvSecrets copies the secrets referred by the env of the
+// provisioner containers, like the RAND_SEED key, to the target cluster
+func (r *ReconcileProvisioner) reconcileEnvSecrets(deploymentInstance *appsv1.Deployment, clusterID string, targetClient client.Client) error {
+	ctx := context.Background()
+	log := r.Log.WithValues("clusterID", clusterID)
+
+	copied := make(map[string]bool)
+	for _, container := range deploymentInstance.Spec.Template.Spec.Containers {
+		for _, env := range container.Env {
+			if env.ValueFrom == nil || env.ValueFrom.SecretKeyRef == nil || copied[env.ValueFrom.SecretKeyRef.Name] {
+				continue
+			}
+			secretName := env.ValueFrom.SecretKeyRef.Name
+			copied[secretName] = true
+
+			secret := &corev1.Secret{}
+			err := r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: deploymentInstance.GetNamespace()}, secret)
+			if err != nil {
+				if apiErrors.IsNotFound(err) && env.ValueFrom.SecretKeyRef.Optional != nil && *env.ValueFrom.SecretKeyRef.Optional {
+					continue
+				}
+				log.Error(err, "Failed to get secret of provisioner in master", "secret", secretName)
+				return err
+			}
+
+			targetSecret := &corev1.Secret{}
+			err = targetClient.Get(ctx, types.NamespacedName{Name: secretName, Namespace: secret.GetNamespace()}, targetSecret)
+			if err != nil && !apiErrors.IsNotFound(err) {
+				log.Error(err, "Error occurred while getting secret of provisioner in target cluster", "secret", secretName)
+				return err
+			}
+			notFound := err != nil
+			targetSecret.SetName(secret.GetName())
+			targetSecret.SetNamespace(secret.GetNamespace())
+			targetSecret.SetLabels(secret.GetLabels())
+			targetSecret.Type = secret.Type
+			targetSecret.Data = make(map[string][]byte)
+			for key, val := range secret.Data {
+				targetSecret.Data[key] = val
+			}
+			if notFound {
+				log.Info("Secret of provisioner in target cluster not found, Creating...", "secret", secretName)
+				err = targetClient.Create(ctx, targetSecret)
+			} else {
+				err = targetClient.Update(ctx, targetSecret)
+			}
+			if err != nil {
+				log.Error(err, "Error occurred while updating secret of provisioner in target cluster", "secret", secretName)
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (r *ReconcileProvisioner) reconcileDeployment(deploymentInstance *appsv1.Deployment, clusterID string, targetClient client.Client) error {
 	ctx := context.Background()
 	log := r.Log.WithValues("clusterID", clusterID)
@@ -462,7 +518,7 @@ func (r *ReconcileProvisioner) SetupWithManager(mgr ctrl.Manager) error {
 		r.Log = ctrl.Log.WithName("mcd").WithName("provisioner")
 	}
 	if r.clusterRegistry == nil {
-		clusterRegistry, err := registry.New(mgr.GetConfig(), mgr.GetScheme(), mgr.GetRESTMapper())
+		clusterRegistry, err := registry.ForManager(mgr)
 		if err != nil {
 			return err
 		}
@@ -35,8 +35,10 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
 	ctrlrun "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
@@ -692,3 +694,68 @@ func TestReconcileProvisioner_reconcileClusterRoleBinding(t *testing.T) {
 		g.Expect(c2.Get(context.TODO(), types.NamespacedName{Name: "provisioner-clusterrolebinding", Namespace: "default"}, targetClusterRoleBinding)).NotTo(gomega.HaveOccurred())
 	}
 }
+
+func TestReconcileProvisioner_reconcileEnvSecrets(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	optional := true
+
+	deployment := deploymentInstance.DeepCopy()
+	deployment.Spec.Template.Spec.Containers[0].Env = []corev1.EnvVar{
+		{
+			Name:  "POD_NAMESPACE",
+			Value: "default",
+		},
+		{
+			Name: "RAND_SEED",
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "rand-seed"},
+					Key:                  "seed",
+				},
+			},
+		},
+		{
+			Name: "OPTIONAL",
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "optional"},
+					Key:                  "key",
+					Optional:             &optional,
+				},
+			},
+		},
+	}
+	randSeed := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "rand-seed",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"seed": []byte("secret"),
+		},
+	}
+
+	master := fake.NewFakeClientWithScheme(scheme.Scheme, randSeed)
+	r := &ReconcileProvisioner{
+		Client: master,
+		Log:    ctrlrun.Log.WithName("mcd").WithName("provisioner"),
+	}
+
+	// Created in the target cluster
+	target := fake.NewFakeClientWithScheme(scheme.Scheme)
+	g.Expect(r.reconcileEnvSecrets(deployment, "2", target)).NotTo(gomega.HaveOccurred())
+	targetSecret := &corev1.Secret{}
+	g.Expect(target.Get(context.TODO(), types.NamespacedName{Name: "rand-seed", Namespace: "default"}, targetSecret)).NotTo(gomega.HaveOccurred())
+	g.Expect(targetSecret.Data).To(gomega.Equal(randSeed.Data))
+
+	// Updated in the target cluster
+	randSeed.Data["seed"] = []byte("rotated")
+	g.Expect(master.Update(context.TODO(), randSeed)).NotTo(gomega.HaveOccurred())
+	g.Expect(r.reconcileEnvSecrets(deployment, "2", target)).NotTo(gomega.HaveOccurred())
+	g.Expect(target.Get(context.TODO(), types.NamespacedName{Name: "rand-seed", Namespace: "default"}, targetSecret)).NotTo(gomega.HaveOccurred())
+	g.Expect(targetSecret.Data["seed"]).To(gomega.Equal([]byte("rotated")))
+
+	// Fails if a required secret is missing in the master cluster
+	g.Expect(master.Delete(context.TODO(), randSeed)).NotTo(gomega.HaveOccurred())
+	g.Expect(r.reconcileEnvSecrets(deployment, "2", target)).To(gomega.HaveOccurred())
+}
@@ -20,7 +20,9 @@ package multiclusterdeploy
 
 import (
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/controllers/multiclusterdeploy/provisioner"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/controllers/multiclusterdeploy/sfclusterstatus"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/controllers/multiclusterdeploy/sfservicebindingreplicator"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/controllers/multiclusterdeploy/sfserviceinstancemigration"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/controllers/multiclusterdeploy/sfserviceinstancereplicator"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/controllers/multiclusterdeploy/sfservicesreplicator"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/controllers/multiclusterdeploy/watchmanager"
@@ -48,6 +50,14 @@ func SetupWithManager(mgr ctrl.Manager) error {
 		return err
 	}
 
+	if err = (&sfclusterstatus.ReconcileSFClusterStatus{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("mcd").WithName("sfclusterstatus"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create sfcluster status controller", "controller", "ReconcileSFClusterStatus")
+		return err
+	}
+
 	if err = (&sfservicebindingreplicator.BindingReplicator{
 		Client: mgr.GetClient(),
 		Log:    ctrl.Log.WithName("mcd").WithName("replicator").WithName("binding"),
@@ -64,6 +74,14 @@ func SetupWithManager(mgr ctrl.Manager) error {
 		return err
 	}
 
+	if err = (&sfserviceinstancemigration.InstanceMigrator{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("mcd").WithName("migrator").WithName("instance"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create instance migrator", "controller", "InstanceMigrator")
+		return err
+	}
+
 	if err = (&sfservicesreplicator.ReconcileSFServices{
 		Client: mgr.GetClient(),
 		Log:    ctrl.Log.WithName("mcd").WithName("replicator").WithName("service"),
@@ -0,0 +1,398 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sfclusterstatus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
+	resourcev1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/resource/v1alpha1"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/config"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/provisioner"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/cluster/registry"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// ReconcileSFClusterStatus periodically probes the SFClusters and
+// records their health, capacity and utilization in the status
+type ReconcileSFClusterStatus struct {
+	client.Client
+	Log             logr.Logger
+	scheme          *runtime.Scheme
+	clusterRegistry registry.ClusterRegistry
+	provisioner     provisioner.Provisioner
+	cfgManager      config.Config
+}
+
+// Reconcile probes the SFCluster and updates its status.
+/* Functions of this method
+1. Validate the kubeconfig of the cluster
+2. Check connectivity and fetch the server version
+3. Check the SF CRDs are registered in the cluster
+4. Check the provisioner is deployed in the cluster
+5. Compute the allocatable and requested resources of the cluster
+6. Count the instances and bindings on the cluster
+*/
+func (r *ReconcileSFClusterStatus) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("sfcluster", req.NamespacedName)
+
+	cluster := &resourcev1alpha1.SFCluster{}
+	err := r.Get(ctx, req.NamespacedName, cluster)
+	if err != nil {
+		if apiErrors.IsNotFound(err) {
+			// Object not found, return.
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get SFCluster...")
+		return ctrl.Result{}, err
+	}
+	clusterID := cluster.GetName()
+	status := cluster.Status.DeepCopy()
+
+	targetClient := r.probeConnectivity(clusterID, status)
+	if targetClient != nil {
+		r.probeCRDs(clusterID, targetClient, status)
+		r.probeProvisioner(clusterID, targetClient, status)
+		r.probeCapacity(clusterID, targetClient, status)
+	} else {
+		setCondition(status, resourcev1alpha1.SFClusterCRDsRegistered, corev1.ConditionUnknown,
+			"ClusterUnreachable", "")
+		setCondition(status, resourcev1alpha1.SFClusterProvisionerDeployed, corev1.ConditionUnknown,
+			"ClusterUnreachable", "")
+	}
+
+	err = r.countInstancesAndBindings(clusterID, status)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !apiequality.Semantic.DeepEqual(&cluster.Status, status) {
+		cluster.Status = *status
+		err = r.Status().Update(ctx, cluster)
+		if err != nil {
+			log.Error(err, "failed to update status of sfcluster", "clusterID", clusterID)
+			return ctrl.Result{}, err
+		}
+		log.Info("updated status of sfcluster", "clusterID", clusterID)
+	}
+
+	return ctrl.Result{RequeueAfter: r.getReconcileInterval()}, nil
+}
+
+// probeConnectivity sets the KubeconfigValid and Ready conditions and
+// returns a client for the cluster if it is reachable
+func (r *ReconcileSFClusterStatus) probeConnectivity(clusterID string, status *resourcev1alpha1.SFClusterStatus) client.Client {
+	log := r.Log.WithValues("clusterID", clusterID)
+
+	cluster, err := r.clusterRegistry.GetCluster(clusterID)
+	if err != nil {
+		log.Error(err, "failed to get sfcluster")
+		setCondition(status, resourcev1alpha1.SFClusterKubeconfigValid, corev1.ConditionUnknown,
+			"ClusterNotFound", err.Error())
+		setCondition(status, resourcev1alpha1.SFClusterReady, corev1.ConditionUnknown,
+			"ClusterNotFound", err.Error())
+		return nil
+	}
+
+	cfg, err := cluster.GetKubeConfig(r)
+	if err != nil {
+		log.Error(err, "failed to get kubeconfig of sfcluster")
+		setCondition(status, resourcev1alpha1.SFClusterKubeconfigValid, corev1.ConditionFalse,
+			"KubeconfigInvalid", err.Error())
+		setCondition(status, resourcev1alpha1.SFClusterReady, corev1.ConditionUnknown,
+			"KubeconfigInvalid", "")
+		return nil
+	}
+	setCondition(status, resourcev1alpha1.SFClusterKubeconfigValid, corev1.ConditionTrue, "", "")
+
+	cfg = rest.CopyConfig(cfg)
+	cfg.Timeout = constants.ClusterProbeTimeout
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		log.Error(err, "failed to create discovery client for sfcluster")
+		setCondition(status, resourcev1alpha1.SFClusterReady, corev1.ConditionFalse,
+			"ClusterUnreachable", err.Error())
+		return nil
+	}
+	version, err := discoveryClient.ServerVersion()
+	if err != nil {
+		log.Error(err, "failed to get server version of sfcluster")
+		setCondition(status, resourcev1alpha1.SFClusterReady, corev1.ConditionFalse,
+			"ClusterUnreachable", err.Error())
+		return nil
+	}
+	status.ServerVersion = version.GitVersion
+
+	targetClient, err := r.clusterRegistry.GetClient(clusterID)
+	if err != nil {
+		log.Error(err, "failed to get client for sfcluster")
+		setCondition(status, resourcev1alpha1.SFClusterReady, corev1.ConditionFalse,
+			"ClusterUnreachable", err.Error())
+		return nil
+	}
+	setCondition(status, resourcev1alpha1.SFClusterReady, corev1.ConditionTrue, "", "")
+	return targetClient
+}
+
+func (r *ReconcileSFClusterStatus) probeCRDs(clusterID string, targetClient client.Client, status *resourcev1alpha1.SFClusterStatus) {
+	ctx := context.Background()
+	log := r.Log.WithValues("clusterID", clusterID)
+
+	for _, sfcrdname := range constants.SFCrdNames {
+		crd := &apiextensionsv1beta1.CustomResourceDefinition{}
+		err := targetClient.Get(ctx, types.NamespacedName{Name: sfcrdname}, crd)
+		if err != nil {
+			if apiErrors.IsNotFound(err) {
+				setCondition(status, resourcev1alpha1.SFClusterCRDsRegistered, corev1.ConditionFalse,
+					"CRDNotFound", fmt.Sprintf("CRD %s not registered", sfcrdname))
+				return
+			}
+			log.Error(err, "failed to get CRD in target cluster", "CRD", sfcrdname)
+			setCondition(status, resourcev1alpha1.SFClusterCRDsRegistered, corev1.ConditionUnknown,
+				"ProbeFailed", err.Error())
+			return
+		}
+	}
+	setCondition(status, resourcev1alpha1.SFClusterCRDsRegistered, corev1.ConditionTrue, "", "")
+}
+
+func (r *ReconcileSFClusterStatus) probeProvisioner(clusterID string, targetClient client.Client, status *resourcev1alpha1.SFClusterStatus) {
+	ctx := context.Background()
+	log := r.Log.WithValues("clusterID", clusterID)
+
+	deploymentInstance, err := r.provisioner.Get()
+	if err != nil {
+		log.Error(err, "failed to get provisioner deployment in master cluster")
+		setCondition(status, resourcev1alpha1.SFClusterProvisionerDeployed, corev1.ConditionUnknown,
+			"ProbeFailed", err.Error())
+		return
+	}
+
+	deployment := &appsv1.Deployment{}
+	err = targetClient.Get(ctx, types.NamespacedName{
+		Name:      deploymentInstance.GetName(),
+		Namespace: deploymentInstance.GetNamespace(),
+	}, deployment)
+	if err != nil {
+		if apiErrors.IsNotFound(err) {
+			setCondition(status, resourcev1alpha1.SFClusterProvisionerDeployed, corev1.ConditionFalse,
+				"ProvisionerNotFound", "")
+			return
+		}
+		log.Error(err, "failed to get provisioner deployment in target cluster")
+		setCondition(status, resourcev1alpha1.SFClusterProvisionerDeployed, corev1.ConditionUnknown,
+			"ProbeFailed", err.Error())
+		return
+	}
+	if deployment.Status.AvailableReplicas == 0 {
+		setCondition(status, resourcev1alpha1.SFClusterProvisionerDeployed, corev1.ConditionFalse,
+			"ProvisionerUnavailable", "no replica of provisioner is available")
+		return
+	}
+	setCondition(status, resourcev1alpha1.SFClusterProvisionerDeployed, corev1.ConditionTrue, "", "")
+}
+
+func (r *ReconcileSFClusterStatus) probeCapacity(clusterID string, targetClient client.Client, status *resourcev1alpha1.SFClusterStatus) {
+	ctx := context.Background()
+	log := r.Log.WithValues("clusterID", clusterID)
+
+	nodes := &corev1.NodeList{}
+	err := targetClient.List(ctx, nodes, &client.ListOptions{})
+	if err != nil {
+		// Not failing here. Retain the last computed capacity
+		log.Error(err, "failed to list nodes in target cluster")
+		return
+	}
+
+	allocatable := make(corev1.ResourceList)
+	for _, node := range nodes.Items {
+		addResourceList(allocatable, node.Status.Allocatable)
+	}
+	status.Allocatable = allocatable
+
+	pods := &corev1.PodList{}
+	err = targetClient.List(ctx, pods, &client.ListOptions{})
+	if err != nil {
+		// Not failing here. Retain the last computed requests
+		log.Error(err, "failed to list pods in target cluster")
+		return
+	}
+
+	requested := make(corev1.ResourceList)
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		for _, container := range pod.Spec.Containers {
+			addResourceList(requested, container.Resources.Requests)
+		}
+	}
+	status.Requested = requested
+}
+
+// addResourceList adds the resources in add to list
+func addResourceList(list, add corev1.ResourceList) {
+	for name, quantity := range add {
+		if val, ok := list[name]; ok {
+			val.Add(quantity)
+			list[name] = val
+		} else {
+			list[name] = quantity.DeepCopy()
+		}
+	}
+}
+
+func (r *ReconcileSFClusterStatus) countInstancesAndBindings(clusterID string, status *resourcev1alpha1.SFClusterStatus) error {
+	ctx := context.Background()
+	log := r.Log.WithValues("clusterID", clusterID)
+
+	instances := &osbv1alpha1.SFServiceInstanceList{}
+	err := r.List(ctx, instances, &client.ListOptions{})
+	if err != nil {
+		log.Error(err, "failed to list all sfserviceinstances")
+		return err
+	}
+
+	instancesOnCluster := make(map[types.NamespacedName]bool)
+	for _, instance := range instances.Items {
+		if instance.Spec.ClusterID == clusterID {
+			instancesOnCluster[types.NamespacedName{
+				Name:      instance.GetName(),
+				Namespace: instance.GetNamespace(),
+			}] = true
+		}
+	}
+
+	bindings := &osbv1alpha1.SFServiceBindingList{}
+	err = r.List(ctx, bindings, &client.ListOptions{})
+	if err != nil {
+		log.Error(err, "failed to list all sfservicebindings")
+		return err
+	}
+
+	bindingsCount := 0
+	for _, binding := range bindings.Items {
+		if instancesOnCluster[types.NamespacedName{
+			Name:      binding.Spec.InstanceID,
+			Namespace: binding.GetNamespace(),
+		}] {
+			bindingsCount++
+		}
+	}
+
+	status.InstancesCount = len(instancesOnCluster)
+	status.BindingsCount = bindingsCount
+	return nil
+}
+
+func (r *ReconcileSFClusterStatus) getReconcileInterval() time.Duration {
+	interoperatorCfg := r.cfgManager.GetConfig()
+	interval, err := time.ParseDuration(interoperatorCfg.ClusterReconcileInterval)
+	if err != nil {
+		r.Log.Error(err, "invalid clusterReconcileInterval. using default",
+			"clusterReconcileInterval", interoperatorCfg.ClusterReconcileInterval)
+		interval, _ = time.ParseDuration(constants.DefaultClusterReconcileInterval)
+	}
+	return interval
+}
+
+func setCondition(status *resourcev1alpha1.SFClusterStatus, conditionType resourcev1alpha1.SFClusterConditionType,
+	conditionStatus corev1.ConditionStatus, reason, message string) {
+	status.SetCondition(resourcev1alpha1.SFClusterCondition{
+		Type:    conditionType,
+		Status:  conditionStatus,
+		Reason:  reason,
+		Message: message,
+	})
+}
+
+// SetupWithManager registers the SFCluster status controller with manager
+// and setups the watches.
+func (r *ReconcileSFClusterStatus) SetupWithManager(mgr ctrl.Manager) error {
+	r.scheme = mgr.GetScheme()
+
+	err := apiextensionsv1beta1.SchemeBuilder.AddToScheme(r.scheme)
+	if err != nil {
+		return err
+	}
+
+	if r.Log == nil {
+		r.Log = ctrl.Log.WithName("mcd").WithName("sfclusterstatus")
+	}
+	if r.clusterRegistry == nil {
+		clusterRegistry, err := registry.ForManager(mgr)
+		if err != nil {
+			return err
+		}
+		r.clusterRegistry = clusterRegistry
+	}
+
+	if r.provisioner == nil {
+		provisionerMgr, err := provisioner.New(mgr.GetConfig(), mgr.GetScheme(), mgr.GetRESTMapper())
+		if err != nil {
+			return err
+		}
+		r.provisioner = provisionerMgr
+	}
+
+	if r.cfgManager == nil {
+		cfgManager, err := config.New(mgr.GetConfig(), mgr.GetScheme(), mgr.GetRESTMapper())
+		if err != nil {
+			return err
+		}
+		r.cfgManager = cfgManager
+	}
+	interoperatorCfg := r.cfgManager.GetConfig()
+
+	// Status updates do not change the generation. Ignore them to
+	// avoid reconciling on own updates. The clusters are probed
+	// periodically anyway.
+	ignoreStatusUpdates := predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return e.MetaOld.GetGeneration() != e.MetaNew.GetGeneration()
+		},
+	}
+
+	builder := ctrl.NewControllerManagedBy(mgr).
+		Named("mcd_sfclusterstatus").
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: interoperatorCfg.ProvisionerWorkerCount,
+		}).
+		For(&resourcev1alpha1.SFCluster{}).
+		WithEventFilter(ignoreStatusUpdates)
+
+	return builder.Complete(r)
+}
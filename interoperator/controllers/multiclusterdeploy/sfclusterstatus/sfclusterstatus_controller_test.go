@@ -0,0 +1,143 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sfclusterstatus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
+	resourcev1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/resource/v1alpha1"
+	mock_v1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/resource/v1alpha1/mock_sfcluster"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/config"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/provisioner/mock_provisioner"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/cluster/registry/mock_registry"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
+
+	"github.com/golang/mock/gomock"
+	"github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestReconcile(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	g.Expect(apiextensionsv1beta1.AddToScheme(scheme.Scheme)).NotTo(gomega.HaveOccurred())
+
+	cfgManager, err := config.New(cfg, scheme.Scheme, nil)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	mockProvisioner := mock_provisioner.NewMockProvisioner(mockCtrl)
+	mockClusterRegistry := mock_registry.NewMockClusterRegistry(mockCtrl)
+	mockCluster := mock_v1alpha1.NewMockSFClusterInterface(mockCtrl)
+
+	r := &ReconcileSFClusterStatus{
+		Client:          k8sClient,
+		Log:             testLog,
+		scheme:          scheme.Scheme,
+		clusterRegistry: mockClusterRegistry,
+		provisioner:     mockProvisioner,
+		cfgManager:      cfgManager,
+	}
+
+	cluster := &resourcev1alpha1.SFCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "1",
+			Namespace: "default",
+		},
+		Spec: resourcev1alpha1.SFClusterSpec{
+			SecretRef: "my-secret",
+		},
+	}
+	instance := &osbv1alpha1.SFServiceInstance{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "instance-id",
+			Namespace: "default",
+		},
+		Spec: osbv1alpha1.SFServiceInstanceSpec{
+			ServiceID: "service-id",
+			PlanID:    "plan-id",
+			ClusterID: "1",
+		},
+	}
+	binding := &osbv1alpha1.SFServiceBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "binding-id",
+			Namespace: "default",
+		},
+		Spec: osbv1alpha1.SFServiceBindingSpec{
+			ServiceID:  "service-id",
+			PlanID:     "plan-id",
+			InstanceID: "instance-id",
+		},
+	}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "provisioner",
+			Namespace: "default",
+		},
+	}
+	g.Expect(k8sClient.Create(context.TODO(), cluster)).NotTo(gomega.HaveOccurred())
+	g.Expect(k8sClient.Create(context.TODO(), instance)).NotTo(gomega.HaveOccurred())
+	g.Expect(k8sClient.Create(context.TODO(), binding)).NotTo(gomega.HaveOccurred())
+	defer k8sClient.Delete(context.TODO(), binding)
+	defer k8sClient.Delete(context.TODO(), instance)
+	defer k8sClient.Delete(context.TODO(), cluster)
+
+	clusterKey := types.NamespacedName{Name: "1", Namespace: "default"}
+	req := ctrl.Request{NamespacedName: clusterKey}
+
+	// Cluster reachable, provisioner not deployed
+	mockClusterRegistry.EXPECT().GetCluster("1").Return(mockCluster, nil).Times(1)
+	mockCluster.EXPECT().GetKubeConfig(gomock.Any()).Return(cfg, nil).Times(1)
+	mockClusterRegistry.EXPECT().GetClient("1").Return(k8sClient, nil).Times(1)
+	mockProvisioner.EXPECT().Get().Return(deployment, nil).Times(1)
+
+	result, err := r.Reconcile(req)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(result.RequeueAfter).To(gomega.Equal(5 * time.Minute))
+
+	g.Expect(k8sClient.Get(context.TODO(), clusterKey, cluster)).NotTo(gomega.HaveOccurred())
+	g.Expect(cluster.Status.ServerVersion).NotTo(gomega.BeEmpty())
+	g.Expect(cluster.Status.InstancesCount).To(gomega.Equal(1))
+	g.Expect(cluster.Status.BindingsCount).To(gomega.Equal(1))
+	g.Expect(cluster.Status.GetCondition(resourcev1alpha1.SFClusterKubeconfigValid).Status).To(gomega.Equal(corev1.ConditionTrue))
+	g.Expect(cluster.Status.GetCondition(resourcev1alpha1.SFClusterReady).Status).To(gomega.Equal(corev1.ConditionTrue))
+	g.Expect(cluster.Status.GetCondition(resourcev1alpha1.SFClusterCRDsRegistered).Status).To(gomega.Equal(corev1.ConditionTrue))
+	g.Expect(cluster.Status.GetCondition(resourcev1alpha1.SFClusterProvisionerDeployed).Status).To(gomega.Equal(corev1.ConditionFalse))
+
+	// Invalid kubeconfig
+	mockClusterRegistry.EXPECT().GetCluster("1").Return(mockCluster, nil).Times(1)
+	mockCluster.EXPECT().GetKubeConfig(gomock.Any()).Return(nil, errors.NewClusterRegistryError("secret not found", nil)).Times(1)
+
+	_, err = r.Reconcile(req)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	g.Expect(k8sClient.Get(context.TODO(), clusterKey, cluster)).NotTo(gomega.HaveOccurred())
+	g.Expect(cluster.Status.GetCondition(resourcev1alpha1.SFClusterKubeconfigValid).Status).To(gomega.Equal(corev1.ConditionFalse))
+	g.Expect(cluster.Status.GetCondition(resourcev1alpha1.SFClusterReady).Status).To(gomega.Equal(corev1.ConditionUnknown))
+	g.Expect(cluster.Status.GetCondition(resourcev1alpha1.SFClusterProvisionerDeployed).Status).To(gomega.Equal(corev1.ConditionUnknown))
+}
@@ -18,21 +18,22 @@ package sfservicebindingreplicator
 
 import (
 	"context"
+	"reflect"
 
 	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/controllers/multiclusterdeploy/watchmanager"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/config"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/secretstore"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/cluster/registry"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
 
 	"github.com/go-logr/logr"
-	corev1 "k8s.io/api/core/v1"
 	apiErrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 )
@@ -45,6 +46,8 @@ type BindingReplicator struct {
 	Log             logr.Logger
 	scheme          *runtime.Scheme
 	clusterRegistry registry.ClusterRegistry
+
+	secretStoreConfig config.SecretStoreConfig
 }
 
 // Reconcile reads that state of the cluster for a SFServiceInstanceReplicator object and makes changes based on the state read
@@ -146,6 +149,18 @@ func (r *BindingReplicator) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		}
 	}
 
+	// A rotation is carried out on the sister cluster, its status is copied
+	// back like the status of a bind
+	if state == "succeeded" && binding.GetAnnotations()[constants.RotateKey] != "" {
+		rotating, err := r.replicateRotation(targetClient, binding)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if rotating {
+			binding.SetState("in progress")
+		}
+	}
+
 	state = binding.GetState()
 
 	//TODO: change it to in progress
@@ -190,76 +205,21 @@ func (r *BindingReplicator) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 				//delete the secret
 				log.Info("unbind on sister cluster completed, deleting secret from master cluster..",
 					"clusterID", clusterID, "bindingID", bindingID, "state", state)
-				secretName := replica.Status.Response.SecretRef
-				if secretName == "" {
-					secretName = "sf-" + binding.GetName()
-				}
-				bindingSecret := &corev1.Secret{}
-				bindingSecret.SetName(secretName)
-				bindingSecret.SetNamespace(binding.GetNamespace())
-				err = r.Delete(ctx, bindingSecret)
+				err = r.deleteBindSecret(binding)
 				if err != nil {
-					if apiErrors.IsNotFound(err) {
-						log.Error(err, "Seems like binding secret is already deleted", "bindinID", bindingID)
-					} else {
-						log.Error(err, "Failed to delete secret in master cluster", "binding", bindingID,
-							"clusterID ", clusterID, "state ", state)
-						// Error deleting the object - requeue the request.
-						return ctrl.Result{}, err
-					}
-				}
-			} else {
-				replicaSecret := &corev1.Secret{}
-				secretName := replica.Status.Response.SecretRef
-				if secretName == "" {
-					secretName = "sf-" + binding.GetName()
-				}
-				err = targetClient.Get(ctx, types.NamespacedName{
-					Name:      secretName,
-					Namespace: replica.GetNamespace(),
-				}, replicaSecret)
-				if err != nil {
-					log.Error(err, "Failed to get secret from sister cluster", "binding", bindingID,
+					log.Error(err, "Failed to delete secret in master cluster", "binding", bindingID,
 						"clusterID ", clusterID, "state ", state)
+					// Error deleting the object - requeue the request.
 					return ctrl.Result{}, err
 				}
-				bindingSecret := &corev1.Secret{}
-				bindingSecret.Data = make(map[string][]byte)
-				bindingSecret.SetName(replicaSecret.GetName())
-				bindingSecret.SetNamespace(replicaSecret.GetNamespace())
-				for k, v := range replicaSecret.Data {
-					bindingSecret.Data[k] = v
-				}
-				if err = controllerutil.SetControllerReference(binding, bindingSecret, r.scheme); err != nil {
-					log.Error(err, "failed to set owner reference for secret", "binding", bindingID)
-					return ctrl.Result{}, err
-				}
+			} else {
 				log.Info("bind on sister cluster completed, replicating secret to master cluster..",
 					"clusterID", clusterID, "bindingID", bindingID, "state", state)
-				err = r.Get(ctx, types.NamespacedName{
-					Name:      bindingSecret.GetName(),
-					Namespace: bindingSecret.GetNamespace(),
-				}, bindingSecret)
+				err = r.replicateBindSecret(targetClient, binding)
 				if err != nil {
-					if apiErrors.IsNotFound(err) {
-						err = r.Create(ctx, bindingSecret)
-						if err != nil {
-							log.Error(err, "Error occurred while replicating secret to master cluster ",
-								"bindingID ", bindingID, "state", state)
-							return ctrl.Result{}, err
-						}
-					} else {
-						log.Error(err, "Error occurred while replicating secret to master cluster ",
-							"bindingID ", bindingID, "state", state)
-						return ctrl.Result{}, err
-					}
-				} else {
-					err = r.Update(ctx, bindingSecret)
-					if err != nil {
-						log.Error(err, "Error occurred while replicating secret to master cluster ",
-							"bindingID ", bindingID, "state", state)
-						return ctrl.Result{}, err
-					}
+					log.Error(err, "Error occurred while replicating secret to master cluster ",
+						"bindingID ", bindingID, "clusterID ", clusterID, "state", state)
+					return ctrl.Result{}, err
 				}
 			}
 		}
@@ -275,6 +235,37 @@ func (r *BindingReplicator) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	return ctrl.Result{}, nil
 }
 
+// replicateBindSecret copies the credentials of the binding from the secret
+// store of the sister cluster to the one of the master cluster, where they
+// are owned by the binding. Credentials in vault are read and written
+// through the vault store, like the sfservicebinding controller does.
+func (r *BindingReplicator) replicateBindSecret(targetClient client.Client, binding *osbv1alpha1.SFServiceBinding) error {
+	sourceStore, err := secretstore.New(targetClient, r.scheme, r.secretStoreConfig)
+	if err != nil {
+		return err
+	}
+	masterStore, err := secretstore.New(r, r.scheme, r.secretStoreConfig)
+	if err != nil {
+		return err
+	}
+	secretName := secretstore.BindSecretName(binding.GetName())
+	data, err := sourceStore.Get(binding, secretName)
+	if err != nil {
+		return err
+	}
+	return masterStore.Put(binding, secretName, data)
+}
+
+// deleteBindSecret deletes the credentials of the binding from the secret
+// store of the master cluster once it is unbound in the sister cluster
+func (r *BindingReplicator) deleteBindSecret(binding *osbv1alpha1.SFServiceBinding) error {
+	masterStore, err := secretstore.New(r, r.scheme, r.secretStoreConfig)
+	if err != nil {
+		return err
+	}
+	return masterStore.Delete(binding, secretstore.BindSecretName(binding.GetName()))
+}
+
 func (r *BindingReplicator) setInProgress(binding *osbv1alpha1.SFServiceBinding) error {
 	bindingID := binding.GetName()
 	state := binding.GetState()
@@ -310,6 +301,51 @@ func (r *BindingReplicator) setInProgress(binding *osbv1alpha1.SFServiceBinding)
 	return nil
 }
 
+// replicateRotation replicates the rotate annotation of the binding to the
+// replica in the sister cluster, where the credentials are rotated. It is
+// true once the rotation of the replica progressed beyond the rotation in
+// the status of the binding.
+func (r *BindingReplicator) replicateRotation(targetClient client.Client, binding *osbv1alpha1.SFServiceBinding) (bool, error) {
+	ctx := context.Background()
+	bindingID := binding.GetName()
+	rotationID := binding.GetAnnotations()[constants.RotateKey]
+	log := r.Log.WithValues("bindingID", bindingID, "rotationID", rotationID)
+
+	replica := &osbv1alpha1.SFServiceBinding{}
+	err := targetClient.Get(ctx, types.NamespacedName{
+		Name:      bindingID,
+		Namespace: binding.GetNamespace(),
+	}, replica)
+	if err != nil {
+		log.Error(err, "Failed to fetch SFServiceBinding from sister cluster for rotation")
+		return false, err
+	}
+
+	annotations := replica.GetAnnotations()
+	if annotations[constants.RotateKey] != rotationID {
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		annotations[constants.RotateKey] = rotationID
+		replica.SetAnnotations(annotations)
+		err = targetClient.Update(ctx, replica)
+		if err != nil {
+			log.Error(err, "Failed to replicate rotation to sister cluster")
+			return false, err
+		}
+		log.Info("Replicated rotation to sister cluster")
+		return false, nil
+	}
+
+	// The replica is updated to render the bind again, its status is copied
+	// once it is in progress
+	rotation := replica.Status.Rotation
+	if rotation == nil || rotation.ID != rotationID || replica.GetState() == "update" {
+		return false, nil
+	}
+	return !reflect.DeepEqual(rotation, binding.Status.Rotation), nil
+}
+
 func replicateSFServiceBindingResourceData(source *osbv1alpha1.SFServiceBinding, dest *osbv1alpha1.SFServiceBinding) {
 	dest.SetName(source.GetName())
 	dest.SetNamespace(source.GetNamespace())
@@ -327,8 +363,14 @@ func (r *BindingReplicator) SetupWithManager(mgr ctrl.Manager) error {
 	if r.Log == nil {
 		r.Log = ctrl.Log.WithName("mcd").WithName("replicator").WithName("binding")
 	}
+	cfgManager, err := config.New(mgr.GetConfig(), mgr.GetScheme(), mgr.GetRESTMapper())
+	if err != nil {
+		return err
+	}
+	r.secretStoreConfig = cfgManager.GetConfig().BindingSecretStore
+
 	if r.clusterRegistry == nil {
-		clusterRegistry, err := registry.New(mgr.GetConfig(), mgr.GetScheme(), mgr.GetRESTMapper())
+		clusterRegistry, err := registry.ForManager(mgr)
 		if err != nil {
 			return err
 		}
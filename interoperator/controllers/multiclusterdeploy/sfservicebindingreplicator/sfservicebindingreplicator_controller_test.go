@@ -19,10 +19,14 @@ package sfservicebindingreplicator
 import (
 	"context"
 	"fmt"
+	"net/http/httptest"
+	"os"
 	"testing"
 	"time"
 
 	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/config"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/secretstore/secretstoretest"
 	mock_clusterRegistry "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/cluster/registry/mock_registry"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
 
@@ -31,10 +35,12 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/util/retry"
 	ctrlrun "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
@@ -446,3 +452,118 @@ func TestReconcileMultiClusterUnbind(t *testing.T) {
 	defer c.Delete(context.TODO(), bindingSecret)
 	defer c2.Delete(context.TODO(), replica)
 }
+
+func TestBindingReplicator_replicateRotation(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	testScheme := runtime.NewScheme()
+	g.Expect(osbv1alpha1.AddToScheme(testScheme)).To(gomega.Succeed())
+
+	rotatedBinding := &osbv1alpha1.SFServiceBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        bindingID,
+			Namespace:   namespace,
+			Annotations: map[string]string{constants.RotateKey: "1"},
+		},
+		Spec: specBinding,
+		Status: osbv1alpha1.SFServiceBindingStatus{
+			State: "succeeded",
+		},
+	}
+	replica := &osbv1alpha1.SFServiceBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      bindingID,
+			Namespace: namespace,
+		},
+		Spec: specBinding,
+		Status: osbv1alpha1.SFServiceBindingStatus{
+			State: "succeeded",
+		},
+	}
+	targetClient := fake.NewFakeClientWithScheme(testScheme, replica)
+	r := &BindingReplicator{
+		Log: ctrlrun.Log.WithName("mcd").WithName("replicator").WithName("binding"),
+	}
+
+	// The rotate annotation is replicated to the sister cluster
+	rotating, err := r.replicateRotation(targetClient, rotatedBinding)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(rotating).To(gomega.BeFalse())
+	g.Expect(targetClient.Get(context.TODO(), bindingKey, replica)).To(gomega.Succeed())
+	g.Expect(replica.GetAnnotations()).To(gomega.HaveKeyWithValue(constants.RotateKey, "1"))
+
+	// The status is not copied before the replica renders the bind again
+	replica.SetState("update")
+	replica.Status.Rotation = &osbv1alpha1.BindingRotation{ID: "1", State: "in progress"}
+	g.Expect(targetClient.Update(context.TODO(), replica)).To(gomega.Succeed())
+	rotating, err = r.replicateRotation(targetClient, rotatedBinding)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(rotating).To(gomega.BeFalse())
+
+	// The status is copied while the replica rotates
+	replica.SetState("in progress")
+	g.Expect(targetClient.Update(context.TODO(), replica)).To(gomega.Succeed())
+	rotating, err = r.replicateRotation(targetClient, rotatedBinding)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(rotating).To(gomega.BeTrue())
+
+	// and until the binding has the rotation status of the replica
+	rotatedBinding.Status.Rotation = &osbv1alpha1.BindingRotation{ID: "1", State: "in progress"}
+	rotating, err = r.replicateRotation(targetClient, rotatedBinding)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(rotating).To(gomega.BeFalse())
+}
+
+func TestBindingReplicator_replicateBindSecretVault(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	testScheme := runtime.NewScheme()
+	g.Expect(osbv1alpha1.AddToScheme(testScheme)).To(gomega.Succeed())
+	g.Expect(corev1.AddToScheme(testScheme)).To(gomega.Succeed())
+
+	vault := secretstoretest.NewVaultStandIn()
+	vault.Secrets["interoperator/default/sf-binding-id"] = map[string]string{"response": "credentials"}
+	server := httptest.NewServer(vault)
+	defer server.Close()
+	os.Setenv(constants.VaultTokenEnvKey, secretstoretest.VaultToken)
+	defer os.Unsetenv(constants.VaultTokenEnvKey)
+
+	sisterBinding := &osbv1alpha1.SFServiceBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      bindingID,
+			Namespace: namespace,
+		},
+		Spec: specBinding,
+		Status: osbv1alpha1.SFServiceBindingStatus{
+			State: "succeeded",
+			Response: osbv1alpha1.BindingResponse{
+				SecretRef: "vault:secret/data/interoperator/default/sf-binding-id",
+			},
+		},
+	}
+	masterClient := fake.NewFakeClientWithScheme(testScheme, sisterBinding.DeepCopy())
+	targetClient := fake.NewFakeClientWithScheme(testScheme, sisterBinding.DeepCopy())
+	r := &BindingReplicator{
+		Client: masterClient,
+		Log:    ctrlrun.Log.WithName("mcd").WithName("replicator").WithName("binding"),
+		scheme: testScheme,
+		secretStoreConfig: config.SecretStoreConfig{
+			Type: constants.VaultSecretStoreType,
+			Vault: config.VaultConfig{
+				Address: server.URL,
+			},
+		},
+	}
+
+	// The credentials are read from vault, no secret is looked up in the
+	// sister cluster or created in the master cluster
+	g.Expect(r.replicateBindSecret(targetClient, sisterBinding)).To(gomega.Succeed())
+	secrets := &corev1.SecretList{}
+	g.Expect(masterClient.List(context.TODO(), secrets)).To(gomega.Succeed())
+	g.Expect(secrets.Items).To(gomega.BeEmpty())
+	g.Expect(vault.Secrets).To(gomega.Equal(map[string]map[string]string{
+		"interoperator/default/sf-binding-id": {"response": "credentials"},
+	}))
+
+	// The credentials are deleted from vault after the unbind
+	g.Expect(r.deleteBindSecret(sisterBinding)).To(gomega.Succeed())
+	g.Expect(vault.Secrets).To(gomega.BeEmpty())
+}
@@ -0,0 +1,648 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sfserviceinstancemigration
+
+import (
+	"context"
+	"reflect"
+
+	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/config"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/secretstore"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/cluster/registry"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/utils"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// InstanceMigrator moves a SFServiceInstance from the SFCluster it is
+// deployed on to the target cluster of a SFServiceInstanceMigration
+type InstanceMigrator struct {
+	client.Client
+	Log             logr.Logger
+	scheme          *runtime.Scheme
+	clusterRegistry registry.ClusterRegistry
+
+	secretStoreConfig config.SecretStoreConfig
+}
+
+// Reconcile runs the current phase of the SFServiceInstanceMigration. The
+// SFServiceInstance is provisioned on the target cluster, the SFServiceBindings
+// and their secrets are copied to the target cluster once the provisioning
+// succeeded, the ClusterID of the SFServiceInstance is set to the target cluster
+// and finally the SFServiceInstance is deprovisioned from the source cluster.
+// If the migration fails before the ClusterID is set to the target cluster,
+// the replicas created in the target cluster are deleted.
+func (r *InstanceMigrator) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("sfserviceinstancemigration", req.NamespacedName)
+
+	migration := &osbv1alpha1.SFServiceInstanceMigration{}
+	err := r.Get(ctx, req.NamespacedName, migration)
+	if err != nil {
+		if apiErrors.IsNotFound(err) {
+			// Object not found, return.
+			return ctrl.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		return ctrl.Result{}, err
+	}
+	status := migration.Status.DeepCopy()
+
+	if migration.IsCompleted() || !migration.GetDeletionTimestamp().IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	instance := &osbv1alpha1.SFServiceInstance{}
+	err = r.Get(ctx, types.NamespacedName{
+		Name:      migration.Spec.InstanceID,
+		Namespace: migration.GetNamespace(),
+	}, instance)
+	if err != nil {
+		if !apiErrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		migration.SetPhase(osbv1alpha1.MigrationFailed, "SFServiceInstance "+migration.Spec.InstanceID+" not found")
+		err = nil
+	}
+
+	phase := migration.GetPhase()
+	log.Info("Reconciling migration", "instanceID", migration.Spec.InstanceID, "phase", phase)
+
+	switch phase {
+	case osbv1alpha1.MigrationPending:
+		err = r.start(migration, instance)
+	case osbv1alpha1.MigrationProvisioningTarget:
+		err = r.provisionTarget(migration, instance)
+	case osbv1alpha1.MigrationWaitingForTarget:
+		err = r.waitForTarget(migration, instance)
+	case osbv1alpha1.MigrationMovingBindings:
+		err = r.moveBindings(migration, instance)
+	case osbv1alpha1.MigrationDeprovisioningSource:
+		err = r.deprovisionSource(migration, instance)
+	}
+	if err == nil && migration.GetPhase() == osbv1alpha1.MigrationFailed && targetReplicated(status.Phase) {
+		err = r.cleanupTarget(migration)
+	}
+	if err != nil {
+		log.Error(err, "Migration failed to proceed, will retry", "instanceID", migration.Spec.InstanceID, "phase", phase)
+		return ctrl.Result{}, err
+	}
+
+	if !reflect.DeepEqual(status, &migration.Status) {
+		err = r.Status().Update(ctx, migration)
+		if err != nil {
+			log.Error(err, "Failed to update migration status", "instanceID", migration.Spec.InstanceID,
+				"phase", migration.GetPhase())
+			return ctrl.Result{}, err
+		}
+		log.Info("Migration status updated", "instanceID", migration.Spec.InstanceID,
+			"phase", migration.GetPhase(), "message", migration.Status.Message)
+	}
+
+	if migration.IsCompleted() {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{RequeueAfter: constants.MigrationPollInterval}, nil
+}
+
+// start validates the migration and records the source cluster. The
+// migration waits for the SFServiceInstance to be in succeeded state and
+// for the other migrations of the same SFServiceInstance to complete.
+func (r *InstanceMigrator) start(migration *osbv1alpha1.SFServiceInstanceMigration, instance *osbv1alpha1.SFServiceInstance) error {
+	ctx := context.Background()
+
+	sourceClusterID, err := instance.GetClusterID()
+	if err != nil {
+		migration.SetPhase(osbv1alpha1.MigrationFailed, err.Error())
+		return nil
+	}
+	targetClusterID := migration.Spec.TargetClusterID
+	if sourceClusterID == targetClusterID {
+		migration.SetPhase(osbv1alpha1.MigrationFailed, "SFServiceInstance is already deployed on cluster "+targetClusterID)
+		return nil
+	}
+	if sourceClusterID == constants.DefaultMasterClusterID || targetClusterID == constants.DefaultMasterClusterID {
+		migration.SetPhase(osbv1alpha1.MigrationFailed, "migration from or to the master cluster is not supported")
+		return nil
+	}
+	_, err = r.clusterRegistry.GetCluster(targetClusterID)
+	if err != nil {
+		if errors.SFClusterNotFound(err) {
+			migration.SetPhase(osbv1alpha1.MigrationFailed, err.Error())
+			return nil
+		}
+		return err
+	}
+
+	if !instance.GetDeletionTimestamp().IsZero() {
+		migration.SetPhase(osbv1alpha1.MigrationFailed, "SFServiceInstance is being deleted")
+		return nil
+	}
+	if instance.GetState() != "succeeded" {
+		migration.SetPhase(osbv1alpha1.MigrationPending, "waiting for SFServiceInstance to be in succeeded state")
+		return nil
+	}
+
+	migrations := &osbv1alpha1.SFServiceInstanceMigrationList{}
+	err = r.List(ctx, migrations, client.InNamespace(migration.GetNamespace()))
+	if err != nil {
+		return err
+	}
+	for _, item := range migrations.Items {
+		if item.GetName() != migration.GetName() && item.Spec.InstanceID == migration.Spec.InstanceID &&
+			item.GetPhase() != osbv1alpha1.MigrationPending && !item.IsCompleted() {
+			migration.SetPhase(osbv1alpha1.MigrationPending, "waiting for migration "+item.GetName()+" to complete")
+			return nil
+		}
+	}
+
+	migration.Status.SourceClusterID = sourceClusterID
+	migration.SetPhase(osbv1alpha1.MigrationProvisioningTarget, "")
+	return nil
+}
+
+// provisionTarget replicates the SFServiceInstance to the target cluster for
+// the provisioner there to render the provision templates. If the replica
+// already exists, it is updated instead.
+func (r *InstanceMigrator) provisionTarget(migration *osbv1alpha1.SFServiceInstanceMigration, instance *osbv1alpha1.SFServiceInstance) error {
+	ctx := context.Background()
+	log := r.Log.WithValues("instanceID", instance.GetName())
+	targetClusterID := migration.Spec.TargetClusterID
+
+	targetClient, err := r.clusterRegistry.GetClient(targetClusterID)
+	if err != nil {
+		return err
+	}
+
+	err = createNamespace(targetClient, instance.GetNamespace())
+	if err != nil {
+		log.Error(err, "Failed to create namespace in target cluster", "clusterID", targetClusterID)
+		return err
+	}
+
+	replica := &osbv1alpha1.SFServiceInstance{}
+	err = targetClient.Get(ctx, types.NamespacedName{
+		Name:      instance.GetName(),
+		Namespace: instance.GetNamespace(),
+	}, replica)
+	if err != nil {
+		if !apiErrors.IsNotFound(err) {
+			return err
+		}
+		copyInstance(instance, replica, targetClusterID)
+		replica.SetState("in_queue")
+		err = targetClient.Create(ctx, replica)
+	} else {
+		copyInstance(instance, replica, targetClusterID)
+		replica.SetState("update")
+		err = targetClient.Update(ctx, replica)
+	}
+	if err != nil {
+		log.Error(err, "Failed to replicate SFServiceInstance to target cluster", "clusterID", targetClusterID)
+		return err
+	}
+	log.Info("Replicated SFServiceInstance to target cluster", "clusterID", targetClusterID, "state", replica.GetState())
+
+	migration.SetPhase(osbv1alpha1.MigrationWaitingForTarget, "")
+	return nil
+}
+
+// waitForTarget waits for the provisioner in the target cluster to
+// process the replica of the SFServiceInstance
+func (r *InstanceMigrator) waitForTarget(migration *osbv1alpha1.SFServiceInstanceMigration, instance *osbv1alpha1.SFServiceInstance) error {
+	targetClusterID := migration.Spec.TargetClusterID
+	replica, err := r.getReplica(targetClusterID, instance)
+	if err != nil {
+		if apiErrors.IsNotFound(err) {
+			migration.SetPhase(osbv1alpha1.MigrationProvisioningTarget, "SFServiceInstance not found in target cluster")
+			return nil
+		}
+		return err
+	}
+
+	if !replica.GetDeletionTimestamp().IsZero() || replica.GetState() == "delete" {
+		migration.SetPhase(osbv1alpha1.MigrationFailed, "SFServiceInstance is being deleted in target cluster")
+		return nil
+	}
+
+	switch replica.GetState() {
+	case "succeeded":
+		migration.SetPhase(osbv1alpha1.MigrationMovingBindings, "")
+	case "failed":
+		migration.SetPhase(osbv1alpha1.MigrationFailed, "provisioning on target cluster failed: "+replica.Status.Error)
+	default:
+		migration.SetPhase(osbv1alpha1.MigrationWaitingForTarget, "waiting for SFServiceInstance to be in succeeded state in target cluster")
+	}
+	return nil
+}
+
+// moveBindings copies the SFServiceBindings of the SFServiceInstance and their
+// secrets from the source cluster to the target cluster, so that the existing
+// credentials keep working. The bindings are not bound again in the target
+// cluster. Once all the bindings are copied, the ClusterID of the
+// SFServiceInstance is set to the target cluster.
+func (r *InstanceMigrator) moveBindings(migration *osbv1alpha1.SFServiceInstanceMigration, instance *osbv1alpha1.SFServiceInstance) error {
+	ctx := context.Background()
+	log := r.Log.WithValues("instanceID", instance.GetName())
+	sourceClusterID := migration.Status.SourceClusterID
+	targetClusterID := migration.Spec.TargetClusterID
+
+	bindings := &osbv1alpha1.SFServiceBindingList{}
+	err := r.List(ctx, bindings, client.InNamespace(instance.GetNamespace()))
+	if err != nil {
+		return err
+	}
+
+	var succeeded []*osbv1alpha1.SFServiceBinding
+	for i := range bindings.Items {
+		binding := &bindings.Items[i]
+		if binding.Spec.InstanceID != instance.GetName() {
+			continue
+		}
+		switch binding.GetState() {
+		case "succeeded":
+			succeeded = append(succeeded, binding)
+		case "failed":
+			// Failed bindings have no credentials to move
+			continue
+		default:
+			migration.SetPhase(osbv1alpha1.MigrationMovingBindings, "waiting for SFServiceBinding "+
+				binding.GetName()+" in state "+binding.GetState())
+			return nil
+		}
+	}
+
+	sourceClient, err := r.clusterRegistry.GetClient(sourceClusterID)
+	if err != nil {
+		return err
+	}
+	targetClient, err := r.clusterRegistry.GetClient(targetClusterID)
+	if err != nil {
+		return err
+	}
+
+	movedBindings := make([]string, 0, len(succeeded))
+	for _, binding := range succeeded {
+		err = r.moveBinding(sourceClient, targetClient, binding)
+		if err != nil {
+			log.Error(err, "Failed to copy SFServiceBinding to target cluster", "bindingID", binding.GetName(),
+				"clusterID", targetClusterID)
+			return err
+		}
+		movedBindings = append(movedBindings, binding.GetName())
+	}
+	migration.Status.Bindings = movedBindings
+
+	replica, err := r.getReplica(targetClusterID, instance)
+	if err != nil {
+		return err
+	}
+
+	updated := false
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		err := r.Get(ctx, types.NamespacedName{
+			Name:      instance.GetName(),
+			Namespace: instance.GetNamespace(),
+		}, instance)
+		if err != nil {
+			return err
+		}
+		if instance.GetState() != "succeeded" {
+			return nil
+		}
+		// The SFServiceInstance was updated after the provisioning in
+		// the target cluster started
+		spec := instance.Spec.DeepCopy()
+		spec.ClusterID = targetClusterID
+		if !reflect.DeepEqual(spec, &replica.Spec) {
+			updated = true
+			return nil
+		}
+		instance.Spec.ClusterID = targetClusterID
+		replica.Status.DeepCopyInto(&instance.Status)
+		return r.Update(ctx, instance)
+	})
+	if err != nil {
+		log.Error(err, "Failed to set clusterID of SFServiceInstance", "clusterID", targetClusterID)
+		return err
+	}
+
+	if updated {
+		migration.SetPhase(osbv1alpha1.MigrationProvisioningTarget, "SFServiceInstance updated during migration")
+		return nil
+	}
+	if instance.Spec.ClusterID != targetClusterID {
+		migration.SetPhase(osbv1alpha1.MigrationMovingBindings, "waiting for SFServiceInstance to be in succeeded state")
+		return nil
+	}
+	log.Info("Moved SFServiceInstance to target cluster", "clusterID", targetClusterID, "bindings", movedBindings)
+	migration.SetPhase(osbv1alpha1.MigrationDeprovisioningSource, "")
+	return nil
+}
+
+// moveBinding creates the replica of binding in the target cluster and copies
+// its credentials from the secret store of the source cluster to the one of
+// the target cluster, where they are owned by the replica.
+func (r *InstanceMigrator) moveBinding(sourceClient, targetClient client.Client, binding *osbv1alpha1.SFServiceBinding) error {
+	ctx := context.Background()
+
+	sourceStore, err := secretstore.New(sourceClient, r.scheme, r.secretStoreConfig)
+	if err != nil {
+		return err
+	}
+	targetStore, err := secretstore.New(targetClient, r.scheme, r.secretStoreConfig)
+	if err != nil {
+		return err
+	}
+	secretName := secretstore.BindSecretName(binding.GetName())
+	data, err := sourceStore.Get(binding, secretName)
+	if err != nil {
+		return err
+	}
+
+	replica := &osbv1alpha1.SFServiceBinding{}
+	err = targetClient.Get(ctx, types.NamespacedName{
+		Name:      binding.GetName(),
+		Namespace: binding.GetNamespace(),
+	}, replica)
+	if err != nil {
+		if !apiErrors.IsNotFound(err) {
+			return err
+		}
+		copyBinding(binding, replica)
+		err = targetClient.Create(ctx, replica)
+	} else {
+		copyBinding(binding, replica)
+		err = targetClient.Update(ctx, replica)
+	}
+	if err != nil {
+		return err
+	}
+	return targetStore.Put(replica, secretName, data)
+}
+
+// targetReplicated returns true if the replicas in the target cluster may
+// exist in phase while the SFServiceInstance is still on the source cluster
+func targetReplicated(phase osbv1alpha1.SFServiceInstanceMigrationPhase) bool {
+	switch phase {
+	case osbv1alpha1.MigrationProvisioningTarget, osbv1alpha1.MigrationWaitingForTarget,
+		osbv1alpha1.MigrationMovingBindings:
+		return true
+	}
+	return false
+}
+
+// cleanupTarget deletes the replicas of the SFServiceInstance and its
+// SFServiceBindings from the target cluster of a failed migration. The
+// bindings are removed without unbinding, as their credentials are still in
+// use on the source cluster. The SFServiceInstance is deprovisioned by the
+// provisioner of the target cluster.
+func (r *InstanceMigrator) cleanupTarget(migration *osbv1alpha1.SFServiceInstanceMigration) error {
+	ctx := context.Background()
+	log := r.Log.WithValues("instanceID", migration.Spec.InstanceID)
+	targetClusterID := migration.Spec.TargetClusterID
+
+	targetClient, err := r.clusterRegistry.GetClient(targetClusterID)
+	if err != nil {
+		return err
+	}
+
+	bindings := &osbv1alpha1.SFServiceBindingList{}
+	err = targetClient.List(ctx, bindings, client.InNamespace(migration.GetNamespace()))
+	if err != nil {
+		return err
+	}
+	for i := range bindings.Items {
+		binding := &bindings.Items[i]
+		if binding.Spec.InstanceID != migration.Spec.InstanceID {
+			continue
+		}
+		if utils.ContainsString(binding.GetFinalizers(), constants.FinalizerName) {
+			binding.SetFinalizers(utils.RemoveString(binding.GetFinalizers(), constants.FinalizerName))
+			err = targetClient.Update(ctx, binding)
+			if err != nil {
+				return err
+			}
+		}
+		err = targetClient.Delete(ctx, binding)
+		if err != nil && !apiErrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	replica := &osbv1alpha1.SFServiceInstance{}
+	err = targetClient.Get(ctx, types.NamespacedName{
+		Name:      migration.Spec.InstanceID,
+		Namespace: migration.GetNamespace(),
+	}, replica)
+	if err != nil {
+		if apiErrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if replica.GetDeletionTimestamp().IsZero() {
+		if replica.GetState() != "delete" {
+			replica.SetState("delete")
+			err = targetClient.Update(ctx, replica)
+			if err != nil {
+				return err
+			}
+		}
+		err = targetClient.Delete(ctx, replica)
+		if err != nil && !apiErrors.IsNotFound(err) {
+			return err
+		}
+		log.Info("Triggered deprovision in target cluster of failed migration", "clusterID", targetClusterID)
+	}
+	return nil
+}
+
+// deprovisionSource removes the SFServiceBindings from the source cluster and
+// deprovisions the SFServiceInstance there. The bindings are removed without
+// unbinding, as their credentials are in use in the target cluster.
+func (r *InstanceMigrator) deprovisionSource(migration *osbv1alpha1.SFServiceInstanceMigration, instance *osbv1alpha1.SFServiceInstance) error {
+	ctx := context.Background()
+	log := r.Log.WithValues("instanceID", instance.GetName())
+	sourceClusterID := migration.Status.SourceClusterID
+
+	sourceClient, err := r.clusterRegistry.GetClient(sourceClusterID)
+	if err != nil {
+		return err
+	}
+
+	bindings := &osbv1alpha1.SFServiceBindingList{}
+	err = sourceClient.List(ctx, bindings, client.InNamespace(instance.GetNamespace()))
+	if err != nil {
+		return err
+	}
+	for i := range bindings.Items {
+		binding := &bindings.Items[i]
+		if binding.Spec.InstanceID != instance.GetName() {
+			continue
+		}
+		if utils.ContainsString(binding.GetFinalizers(), constants.FinalizerName) {
+			binding.SetFinalizers(utils.RemoveString(binding.GetFinalizers(), constants.FinalizerName))
+			err = sourceClient.Update(ctx, binding)
+			if err != nil {
+				return err
+			}
+		}
+		err = sourceClient.Delete(ctx, binding)
+		if err != nil && !apiErrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	replica, err := r.getReplica(sourceClusterID, instance)
+	if err != nil {
+		if !apiErrors.IsNotFound(err) {
+			return err
+		}
+		err = deleteNamespace(sourceClient, instance.GetNamespace())
+		if err != nil {
+			log.Error(err, "Failed to delete namespace from source cluster", "clusterID", sourceClusterID)
+			return err
+		}
+		log.Info("Deprovisioned SFServiceInstance from source cluster", "clusterID", sourceClusterID)
+		migration.SetPhase(osbv1alpha1.MigrationSucceeded, "")
+		return nil
+	}
+
+	if replica.GetState() == "failed" && replica.GetLabels()[constants.LastOperationKey] == "delete" {
+		migration.SetPhase(osbv1alpha1.MigrationFailed, "deprovisioning on source cluster failed: "+replica.Status.Error)
+		return nil
+	}
+
+	if replica.GetDeletionTimestamp().IsZero() {
+		if replica.GetState() != "delete" {
+			replica.SetState("delete")
+			err = sourceClient.Update(ctx, replica)
+			if err != nil {
+				return err
+			}
+		}
+		err = sourceClient.Delete(ctx, replica)
+		if err != nil && !apiErrors.IsNotFound(err) {
+			return err
+		}
+		log.Info("Triggered deprovision in source cluster", "clusterID", sourceClusterID)
+	}
+	migration.SetPhase(osbv1alpha1.MigrationDeprovisioningSource, "waiting for SFServiceInstance to be deprovisioned from source cluster")
+	return nil
+}
+
+func (r *InstanceMigrator) getReplica(clusterID string, instance *osbv1alpha1.SFServiceInstance) (*osbv1alpha1.SFServiceInstance, error) {
+	targetClient, err := r.clusterRegistry.GetClient(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	replica := &osbv1alpha1.SFServiceInstance{}
+	err = targetClient.Get(context.Background(), types.NamespacedName{
+		Name:      instance.GetName(),
+		Namespace: instance.GetNamespace(),
+	}, replica)
+	if err != nil {
+		return nil, err
+	}
+	return replica, nil
+}
+
+func createNamespace(c client.Client, namespace string) error {
+	ns := &corev1.Namespace{}
+	err := c.Get(context.Background(), types.NamespacedName{
+		Name: namespace,
+	}, ns)
+	if err == nil || !apiErrors.IsNotFound(err) {
+		return err
+	}
+	ns.SetName(namespace)
+	err = c.Create(context.Background(), ns)
+	if err != nil && !apiErrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+func deleteNamespace(c client.Client, namespace string) error {
+	ns := &corev1.Namespace{}
+	ns.SetName(namespace)
+	err := c.Delete(context.Background(), ns)
+	if err != nil && !apiErrors.IsConflict(err) && !apiErrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// copyInstance copies the spec of source to destination for provisioning in
+// clusterID. The status of destination is reset.
+func copyInstance(source, destination *osbv1alpha1.SFServiceInstance, clusterID string) {
+	destination.SetName(source.GetName())
+	destination.SetNamespace(source.GetNamespace())
+	destination.SetLabels(source.GetLabels())
+	destination.SetAnnotations(source.GetAnnotations())
+	source.Spec.DeepCopyInto(&destination.Spec)
+	destination.Spec.ClusterID = clusterID
+	destination.Status = osbv1alpha1.SFServiceInstanceStatus{}
+}
+
+func copyBinding(source, destination *osbv1alpha1.SFServiceBinding) {
+	destination.SetName(source.GetName())
+	destination.SetNamespace(source.GetNamespace())
+	destination.SetLabels(source.GetLabels())
+	destination.SetAnnotations(source.GetAnnotations())
+	source.Spec.DeepCopyInto(&destination.Spec)
+	source.Status.DeepCopyInto(&destination.Status)
+}
+
+// SetupWithManager registers the MCD Instance migrator with manager
+// and setups the watches.
+func (r *InstanceMigrator) SetupWithManager(mgr ctrl.Manager) error {
+	r.scheme = mgr.GetScheme()
+
+	if r.Log == nil {
+		r.Log = ctrl.Log.WithName("mcd").WithName("migrator").WithName("instance")
+	}
+	cfgManager, err := config.New(mgr.GetConfig(), mgr.GetScheme(), mgr.GetRESTMapper())
+	if err != nil {
+		return err
+	}
+	r.secretStoreConfig = cfgManager.GetConfig().BindingSecretStore
+
+	if r.clusterRegistry == nil {
+		clusterRegistry, err := registry.ForManager(mgr)
+		if err != nil {
+			return err
+		}
+		r.clusterRegistry = clusterRegistry
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("mcd_migrator_instance").
+		For(&osbv1alpha1.SFServiceInstanceMigration{}).
+		Complete(r)
+}
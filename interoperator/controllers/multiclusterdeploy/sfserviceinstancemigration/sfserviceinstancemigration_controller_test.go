@@ -0,0 +1,428 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sfserviceinstancemigration
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
+	resourcev1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/resource/v1alpha1"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/config"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/secretstore/secretstoretest"
+	mock_clusterRegistry "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/cluster/registry/mock_registry"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
+
+	"github.com/golang/mock/gomock"
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/util/retry"
+	ctrlrun "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+var c client.Client
+
+const timeout = time.Second * 5
+
+// The migrator polls the member clusters
+const pollTimeout = constants.MigrationPollInterval + timeout
+
+func setupMigrator(t *testing.T, g *gomega.GomegaWithT) func() {
+	ctrl := gomock.NewController(t)
+
+	mgr, err := manager.New(cfg, manager.Options{
+		MetricsBindAddress: "0",
+	})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	c, err = client.New(cfg, client.Options{
+		Scheme: mgr.GetScheme(),
+		Mapper: mgr.GetRESTMapper(),
+	})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	mockClusterRegistry := mock_clusterRegistry.NewMockClusterRegistry(ctrl)
+	mockClusterRegistry.EXPECT().GetClient("2").Return(k8sClient2, nil).AnyTimes()
+	mockClusterRegistry.EXPECT().GetClient("3").Return(k8sClient3, nil).AnyTimes()
+	mockClusterRegistry.EXPECT().GetCluster("3").Return(&resourcev1alpha1.SFCluster{}, nil).AnyTimes()
+	mockClusterRegistry.EXPECT().GetCluster("4").Return(nil, errors.NewSFClusterNotFound("4", nil)).AnyTimes()
+
+	controller := &InstanceMigrator{
+		Client:          mgr.GetClient(),
+		Log:             ctrlrun.Log.WithName("mcd").WithName("migrator").WithName("instance"),
+		clusterRegistry: mockClusterRegistry,
+	}
+	g.Expect(controller.SetupWithManager(mgr)).NotTo(gomega.HaveOccurred())
+	stopMgr, mgrStopped := StartTestManager(mgr, g)
+
+	return func() {
+		close(stopMgr)
+		mgrStopped.Wait()
+		ctrl.Finish()
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	stop := setupMigrator(t, g)
+	defer stop()
+
+	instanceKey := types.NamespacedName{Name: "instance-id", Namespace: "sf-instance-id"}
+	bindingKey := types.NamespacedName{Name: "binding-id", Namespace: "sf-instance-id"}
+	secretKey := types.NamespacedName{Name: "sf-binding-id", Namespace: "sf-instance-id"}
+
+	// The instance and the binding are deployed on cluster 2
+	for _, cl := range []client.Client{c, k8sClient2} {
+		ns := &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "sf-instance-id",
+			},
+		}
+		g.Expect(cl.Create(context.TODO(), ns)).NotTo(gomega.HaveOccurred())
+		g.Expect(cl.Create(context.TODO(), _getDummyInstance("instance-id", "sf-instance-id", "2"))).
+			NotTo(gomega.HaveOccurred())
+		g.Expect(cl.Create(context.TODO(), _getDummyBinding("binding-id", "sf-instance-id", "instance-id"))).
+			NotTo(gomega.HaveOccurred())
+	}
+	g.Expect(k8sClient2.Create(context.TODO(), _getDummySecret(secretKey))).NotTo(gomega.HaveOccurred())
+
+	migration := _getDummyMigration("migration-id", "sf-instance-id", "instance-id", "3")
+	g.Expect(c.Create(context.TODO(), migration)).NotTo(gomega.HaveOccurred())
+	defer c.Delete(context.TODO(), migration)
+
+	// The instance is provisioned on cluster 3
+	replica := &osbv1alpha1.SFServiceInstance{}
+	g.Eventually(func() error {
+		err := k8sClient3.Get(context.TODO(), instanceKey, replica)
+		if err != nil {
+			return err
+		}
+		if replica.GetState() != "in_queue" || replica.Spec.ClusterID != "3" {
+			return fmt.Errorf("replica not in_queue")
+		}
+		return nil
+	}, timeout).Should(gomega.Succeed())
+
+	g.Eventually(func() osbv1alpha1.SFServiceInstanceMigrationPhase {
+		err := c.Get(context.TODO(), _getKey(migration), migration)
+		if err != nil {
+			return ""
+		}
+		return migration.GetPhase()
+	}, timeout).Should(gomega.Equal(osbv1alpha1.MigrationWaitingForTarget))
+	g.Expect(migration.Status.SourceClusterID).To(gomega.Equal("2"))
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		err := k8sClient3.Get(context.TODO(), instanceKey, replica)
+		if err != nil {
+			return err
+		}
+		replica.SetState("succeeded")
+		return k8sClient3.Update(context.TODO(), replica)
+	})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	// The instance is moved to cluster 3 along with the binding and its secret
+	instance := &osbv1alpha1.SFServiceInstance{}
+	g.Eventually(func() string {
+		err := c.Get(context.TODO(), instanceKey, instance)
+		if err != nil {
+			return ""
+		}
+		return instance.Spec.ClusterID
+	}, pollTimeout).Should(gomega.Equal("3"))
+
+	binding := &osbv1alpha1.SFServiceBinding{}
+	g.Expect(k8sClient3.Get(context.TODO(), bindingKey, binding)).NotTo(gomega.HaveOccurred())
+	g.Expect(binding.GetState()).To(gomega.Equal("succeeded"))
+	secret := &corev1.Secret{}
+	g.Expect(k8sClient3.Get(context.TODO(), secretKey, secret)).NotTo(gomega.HaveOccurred())
+	g.Expect(secret.Data).To(gomega.HaveKeyWithValue("password", []byte("secret")))
+
+	// The instance is deprovisioned from cluster 2
+	g.Eventually(func() error {
+		err := k8sClient2.Get(context.TODO(), instanceKey, replica)
+		if err != nil {
+			return err
+		}
+		if replica.GetState() != "delete" || replica.GetDeletionTimestamp().IsZero() {
+			return fmt.Errorf("deprovision not triggered")
+		}
+		return nil
+	}, timeout).Should(gomega.Succeed())
+	g.Eventually(func() bool {
+		err := k8sClient2.Get(context.TODO(), bindingKey, binding)
+		return apiErrors.IsNotFound(err)
+	}, timeout).Should(gomega.BeTrue())
+
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		err := k8sClient2.Get(context.TODO(), instanceKey, replica)
+		if err != nil {
+			return err
+		}
+		replica.SetFinalizers([]string{})
+		return k8sClient2.Update(context.TODO(), replica)
+	})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	g.Eventually(func() osbv1alpha1.SFServiceInstanceMigrationPhase {
+		err := c.Get(context.TODO(), _getKey(migration), migration)
+		if err != nil {
+			return ""
+		}
+		return migration.GetPhase()
+	}, pollTimeout).Should(gomega.Equal(osbv1alpha1.MigrationSucceeded))
+	g.Expect(migration.Status.Bindings).To(gomega.ConsistOf("binding-id"))
+}
+
+func TestReconcileInvalidTarget(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	stop := setupMigrator(t, g)
+	defer stop()
+
+	instance := _getDummyInstance("instance-id2", "default", "2")
+	instance.SetFinalizers(nil)
+	g.Expect(c.Create(context.TODO(), instance)).NotTo(gomega.HaveOccurred())
+	defer c.Delete(context.TODO(), instance)
+
+	tests := []struct {
+		name            string
+		instanceID      string
+		targetClusterID string
+		message         string
+	}{
+		{
+			name:            "fail if the instance does not exist",
+			instanceID:      "instance-id3",
+			targetClusterID: "3",
+			message:         "SFServiceInstance instance-id3 not found",
+		},
+		{
+			name:            "fail if the target cluster does not exist",
+			instanceID:      "instance-id2",
+			targetClusterID: "4",
+			message:         errors.NewSFClusterNotFound("4", nil).Error(),
+		},
+		{
+			name:            "fail if the instance is already on the target cluster",
+			instanceID:      "instance-id2",
+			targetClusterID: "2",
+			message:         "SFServiceInstance is already deployed on cluster 2",
+		},
+		{
+			name:            "fail if the target cluster is the master cluster",
+			instanceID:      "instance-id2",
+			targetClusterID: constants.DefaultMasterClusterID,
+			message:         "migration from or to the master cluster is not supported",
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migration := _getDummyMigration(fmt.Sprintf("migration-%d", i), "default", tt.instanceID, tt.targetClusterID)
+			g.Expect(c.Create(context.TODO(), migration)).NotTo(gomega.HaveOccurred())
+			defer c.Delete(context.TODO(), migration)
+
+			g.Eventually(func() osbv1alpha1.SFServiceInstanceMigrationPhase {
+				err := c.Get(context.TODO(), _getKey(migration), migration)
+				if err != nil {
+					return ""
+				}
+				return migration.GetPhase()
+			}, timeout).Should(gomega.Equal(osbv1alpha1.MigrationFailed))
+			g.Expect(migration.Status.Message).To(gomega.Equal(tt.message))
+		})
+	}
+}
+
+func TestInstanceMigrator_ReconcileInstanceNotFound(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	migration := _getDummyMigration("migration", "default", "instance-id", "3")
+	r := &InstanceMigrator{
+		Client: fake.NewFakeClientWithScheme(scheme.Scheme, migration.DeepCopy()),
+		Log:    ctrlrun.Log.WithName("mcd").WithName("migrator").WithName("instance"),
+	}
+
+	result, err := r.Reconcile(ctrlrun.Request{NamespacedName: _getKey(migration)})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(result).To(gomega.Equal(ctrlrun.Result{}))
+
+	g.Expect(r.Get(context.TODO(), _getKey(migration), migration)).NotTo(gomega.HaveOccurred())
+	g.Expect(migration.GetPhase()).To(gomega.Equal(osbv1alpha1.MigrationFailed))
+	g.Expect(migration.Status.Message).To(gomega.Equal("SFServiceInstance instance-id not found"))
+}
+
+func TestInstanceMigrator_ReconcileTargetFailed(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	migration := _getDummyMigration("migration", "sf-instance-id", "instance-id", "3")
+	migration.Status.SourceClusterID = "2"
+	migration.SetPhase(osbv1alpha1.MigrationWaitingForTarget, "")
+	replica := _getDummyInstance("instance-id", "sf-instance-id", "3")
+	replica.SetState("failed")
+	binding := _getDummyBinding("binding-id", "sf-instance-id", "instance-id")
+	targetClient := fake.NewFakeClientWithScheme(scheme.Scheme, replica, binding)
+
+	mockClusterRegistry := mock_clusterRegistry.NewMockClusterRegistry(ctrl)
+	mockClusterRegistry.EXPECT().GetClient("3").Return(targetClient, nil).AnyTimes()
+
+	r := &InstanceMigrator{
+		Client: fake.NewFakeClientWithScheme(scheme.Scheme, migration.DeepCopy(),
+			_getDummyInstance("instance-id", "sf-instance-id", "2")),
+		Log:             ctrlrun.Log.WithName("mcd").WithName("migrator").WithName("instance"),
+		clusterRegistry: mockClusterRegistry,
+	}
+
+	result, err := r.Reconcile(ctrlrun.Request{NamespacedName: _getKey(migration)})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(result).To(gomega.Equal(ctrlrun.Result{}))
+
+	g.Expect(r.Get(context.TODO(), _getKey(migration), migration)).NotTo(gomega.HaveOccurred())
+	g.Expect(migration.GetPhase()).To(gomega.Equal(osbv1alpha1.MigrationFailed))
+
+	// The replicas are removed from the target cluster
+	err = targetClient.Get(context.TODO(), _getKey(replica), replica)
+	g.Expect(apiErrors.IsNotFound(err)).To(gomega.BeTrue())
+	err = targetClient.Get(context.TODO(), _getKey(binding), binding)
+	g.Expect(apiErrors.IsNotFound(err)).To(gomega.BeTrue())
+}
+
+func TestInstanceMigrator_moveBindingVault(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	vault := secretstoretest.NewVaultStandIn()
+	vault.Secrets["interoperator/sf-instance-id/sf-binding-id"] = map[string]string{"response": "credentials"}
+	server := httptest.NewServer(vault)
+	defer server.Close()
+	os.Setenv(constants.VaultTokenEnvKey, secretstoretest.VaultToken)
+	defer os.Unsetenv(constants.VaultTokenEnvKey)
+
+	binding := _getDummyBinding("binding-id", "sf-instance-id", "instance-id")
+	binding.Status.Response.SecretRef = "vault:secret/data/interoperator/sf-instance-id/sf-binding-id"
+	sourceClient := fake.NewFakeClientWithScheme(scheme.Scheme, binding.DeepCopy())
+	targetClient := fake.NewFakeClientWithScheme(scheme.Scheme)
+
+	r := &InstanceMigrator{
+		Log:    ctrlrun.Log.WithName("mcd").WithName("migrator").WithName("instance"),
+		scheme: scheme.Scheme,
+		secretStoreConfig: config.SecretStoreConfig{
+			Type: constants.VaultSecretStoreType,
+			Vault: config.VaultConfig{
+				Address: server.URL,
+			},
+		},
+	}
+	g.Expect(r.moveBinding(sourceClient, targetClient, binding)).To(gomega.Succeed())
+
+	replica := &osbv1alpha1.SFServiceBinding{}
+	g.Expect(targetClient.Get(context.TODO(), _getKey(binding), replica)).To(gomega.Succeed())
+	g.Expect(replica.Status.Response.SecretRef).To(gomega.Equal(binding.Status.Response.SecretRef))
+
+	// The credentials stay in vault, no secret is created in the target cluster
+	secrets := &corev1.SecretList{}
+	g.Expect(targetClient.List(context.TODO(), secrets)).To(gomega.Succeed())
+	g.Expect(secrets.Items).To(gomega.BeEmpty())
+	g.Expect(vault.Secrets).To(gomega.Equal(map[string]map[string]string{
+		"interoperator/sf-instance-id/sf-binding-id": {"response": "credentials"},
+	}))
+}
+
+func _getDummyInstance(name, namespace, clusterID string) *osbv1alpha1.SFServiceInstance {
+	return &osbv1alpha1.SFServiceInstance{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       name,
+			Namespace:  namespace,
+			Finalizers: []string{"foo"},
+		},
+		Spec: osbv1alpha1.SFServiceInstanceSpec{
+			ServiceID: "service-id",
+			PlanID:    "plan-id",
+			ClusterID: clusterID,
+		},
+		Status: osbv1alpha1.SFServiceInstanceStatus{
+			State: "succeeded",
+		},
+	}
+}
+
+func _getDummyBinding(name, namespace, instanceID string) *osbv1alpha1.SFServiceBinding {
+	return &osbv1alpha1.SFServiceBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       name,
+			Namespace:  namespace,
+			Finalizers: []string{constants.FinalizerName},
+		},
+		Spec: osbv1alpha1.SFServiceBindingSpec{
+			ID:         name,
+			InstanceID: instanceID,
+			PlanID:     "plan-id",
+			ServiceID:  "service-id",
+		},
+		Status: osbv1alpha1.SFServiceBindingStatus{
+			State: "succeeded",
+			Response: osbv1alpha1.BindingResponse{
+				SecretRef: "sf-" + name,
+			},
+		},
+	}
+}
+
+func _getDummySecret(key types.NamespacedName) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.Name,
+			Namespace: key.Namespace,
+		},
+		Data: map[string][]byte{
+			"password": []byte("secret"),
+		},
+	}
+}
+
+func _getDummyMigration(name, namespace, instanceID, targetClusterID string) *osbv1alpha1.SFServiceInstanceMigration {
+	return &osbv1alpha1.SFServiceInstanceMigration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: osbv1alpha1.SFServiceInstanceMigrationSpec{
+			InstanceID:      instanceID,
+			TargetClusterID: targetClusterID,
+		},
+	}
+}
+
+func _getKey(obj metav1.Object) types.NamespacedName {
+	return types.NamespacedName{
+		Name:      obj.GetName(),
+		Namespace: obj.GetNamespace(),
+	}
+}
@@ -286,7 +286,7 @@ func (r *InstanceReplicator) SetupWithManager(mgr ctrl.Manager) error {
 		r.Log = ctrl.Log.WithName("mcd").WithName("replicator").WithName("instance")
 	}
 	if r.clusterRegistry == nil {
-		clusterRegistry, err := registry.New(mgr.GetConfig(), mgr.GetScheme(), mgr.GetRESTMapper())
+		clusterRegistry, err := registry.ForManager(mgr)
 		if err != nil {
 			return err
 		}
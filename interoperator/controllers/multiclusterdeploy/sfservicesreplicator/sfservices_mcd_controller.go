@@ -224,7 +224,7 @@ func (r *ReconcileSFServices) SetupWithManager(mgr ctrl.Manager) error {
 		r.Log = ctrl.Log.WithName("mcd").WithName("replicator").WithName("service")
 	}
 	if r.clusterRegistry == nil {
-		clusterRegistry, err := registry.New(mgr.GetConfig(), mgr.GetScheme(), mgr.GetRESTMapper())
+		clusterRegistry, err := registry.ForManager(mgr)
 		if err != nil {
 			return err
 		}
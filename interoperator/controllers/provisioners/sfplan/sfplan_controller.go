@@ -19,7 +19,6 @@ package sfplan
 import (
 	"context"
 	"fmt"
-	"os"
 	"time"
 
 	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
@@ -37,7 +36,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
-func restartOnWatchUpdate(mgr manager.Manager, initWatches, stop <-chan struct{}) {
+func refreshOnWatchUpdate(mgr manager.Manager, initWatches, stop <-chan struct{}) {
 	log := ctrl.Log.WithName("provisioners").WithName("sfplan")
 	for {
 		select {
@@ -62,8 +61,8 @@ func restartOnWatchUpdate(mgr manager.Manager, initWatches, stop <-chan struct{}
 				log.Error(err, "unable initializing interoperator watch list")
 			}
 			if toUpdate {
-				log.V(0).Info("Watch list changed. Restarting interoperator")
-				os.Exit(1)
+				log.V(0).Info("Watch list changed. Refreshing watches")
+				watches.NotifyWatchUpdate()
 			}
 		case <-stop:
 			// We are done
@@ -200,7 +199,7 @@ func (r *ReconcileSFPlan) SetupWithManager(mgr ctrl.Manager) error {
 	r.scheme = mgr.GetScheme()
 	initWatches := make(chan struct{}, 100)
 	stopWatches := make(chan struct{})
-	go restartOnWatchUpdate(mgr, initWatches, stopWatches)
+	go refreshOnWatchUpdate(mgr, initWatches, stopWatches)
 	r.initWatches = initWatches
 	r.stopWatches = stopWatches
 
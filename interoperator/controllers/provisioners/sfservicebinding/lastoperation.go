@@ -0,0 +1,136 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sfservicebinding
+
+import (
+	"time"
+
+	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/timeouts"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+
+	"k8s.io/apimachinery/pkg/util/uuid"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// getOperationTimeout returns how long an asynchronous bind or unbind
+// operation may stay in progress
+func (r *ReconcileSFServiceBinding) getOperationTimeout() time.Duration {
+	interoperatorCfg := r.cfgManager.GetConfig()
+	timeout, err := time.ParseDuration(interoperatorCfg.BindingOperationTimeout)
+	if err != nil {
+		r.Log.Error(err, "invalid bindingOperationTimeout. using default",
+			"bindingOperationTimeout", interoperatorCfg.BindingOperationTimeout)
+		timeout, _ = time.ParseDuration(constants.DefaultBindingOperationTimeout)
+	}
+	return timeout
+}
+
+// startLastOperation starts a new last operation for the bind or unbind
+// requested by state. Asynchronous operations time out after the timeout
+// declared by the plan or else after the configured timeout. Synchronous
+// operations get a last operation only if the plan declares a timeout. The
+// id of the operation is the one the broker returned to the OSB client, if
+// set as annotation.
+func (r *ReconcileSFServiceBinding) startLastOperation(binding *osbv1alpha1.SFServiceBinding, state string) {
+	operationType := osbv1alpha1.BindOperation
+	if state == "delete" {
+		operationType = osbv1alpha1.UnbindOperation
+	}
+
+	timeout, err := timeouts.ForOperation(r, binding.Spec.ServiceID, binding.Spec.PlanID, operationType)
+	if err != nil {
+		r.Log.Error(err, "failed to get plan timeout. ignoring", "binding", binding.GetName(),
+			"operation", operationType)
+		timeout = 0
+	}
+	if timeout == 0 {
+		if !binding.Spec.AcceptsIncomplete {
+			binding.Status.LastOperation = nil
+			return
+		}
+		timeout = r.getOperationTimeout()
+	}
+
+	operationID := binding.GetAnnotations()[constants.OperationIDKey]
+	if operationID == "" {
+		operationID = string(uuid.NewUUID())
+	}
+	startedAt, deadline := timeouts.Start(timeout)
+	binding.Status.LastOperation = &osbv1alpha1.BindingLastOperation{
+		ID:        operationID,
+		Type:      operationType,
+		State:     "in progress",
+		StartedAt: startedAt,
+		Deadline:  deadline,
+	}
+}
+
+// syncLastOperation completes the last operation once the binding
+// succeeded or failed
+func syncLastOperation(status *osbv1alpha1.SFServiceBindingStatus) {
+	lastOperation := status.LastOperation
+	if lastOperation == nil || lastOperation.State != "in progress" {
+		return
+	}
+	if status.State == "succeeded" || status.State == "failed" {
+		lastOperation.State = status.State
+		lastOperation.Description = status.Error
+	}
+}
+
+// currentLastOperation returns the last operation of the binding, nil if
+// it has none
+func currentLastOperation(binding *osbv1alpha1.SFServiceBinding) *timeouts.Operation {
+	lastOperation := binding.Status.LastOperation
+	if lastOperation == nil {
+		return nil
+	}
+	return &timeouts.Operation{
+		ID:         lastOperation.ID,
+		Kind:       "SFServiceBinding",
+		Type:       lastOperation.Type,
+		PlanID:     binding.Spec.PlanID,
+		InProgress: lastOperation.State == "in progress",
+		StartedAt:  lastOperation.StartedAt,
+		Deadline:   lastOperation.Deadline,
+	}
+}
+
+// lastOperationExpired is true if the last operation is still in progress
+// after its deadline
+func lastOperationExpired(binding *osbv1alpha1.SFServiceBinding) bool {
+	return currentLastOperation(binding).Expired()
+}
+
+// lastOperationResult requeues the binding at the deadline of its last
+// operation
+func lastOperationResult(binding *osbv1alpha1.SFServiceBinding) ctrl.Result {
+	return currentLastOperation(binding).Result()
+}
+
+// failLastOperation marks the binding and its last operation failed once
+// the deadline of the last operation passed
+func (r *ReconcileSFServiceBinding) failLastOperation(binding *osbv1alpha1.SFServiceBinding, retryCount int) error {
+	return timeouts.Fail(r, r.recorder, binding, func() *timeouts.Operation {
+		return currentLastOperation(binding)
+	}, func(err error) {
+		binding.SetState("failed")
+		binding.Status.Error = err.Error()
+		syncLastOperation(&binding.Status)
+	}, retryCount)
+}
@@ -0,0 +1,255 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sfservicebinding
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newLastOperation(state string, deadline time.Time) *osbv1alpha1.BindingLastOperation {
+	startedAt := metav1.NewTime(deadline.Add(-time.Minute * 30))
+	deadlineTime := metav1.NewTime(deadline)
+	return &osbv1alpha1.BindingLastOperation{
+		ID:        "operation-id",
+		Type:      osbv1alpha1.BindOperation,
+		State:     state,
+		StartedAt: &startedAt,
+		Deadline:  &deadlineTime,
+	}
+}
+
+func TestReconcileSFServiceBinding_startLastOperation(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	testScheme := runtime.NewScheme()
+	g.Expect(osbv1alpha1.AddToScheme(testScheme)).To(gomega.Succeed())
+
+	labels := map[string]string{
+		"serviceId": "service-id",
+		"planId":    "plan-id",
+	}
+	service := &osbv1alpha1.SFService{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "service-id",
+			Namespace: "default",
+			Labels:    labels,
+		},
+		Spec: osbv1alpha1.SFServiceSpec{
+			ID: "service-id",
+		},
+	}
+	plan := &osbv1alpha1.SFPlan{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "plan-id",
+			Namespace: "default",
+			Labels:    labels,
+		},
+		Spec: osbv1alpha1.SFPlanSpec{
+			ID:        "plan-id",
+			ServiceID: "service-id",
+			Timeouts: &osbv1alpha1.OperationTimeouts{
+				Bind: "5m",
+			},
+		},
+	}
+	r := &ReconcileSFServiceBinding{
+		Client: fake.NewFakeClientWithScheme(testScheme, service, plan),
+		Log:    testLog,
+		scheme: testScheme,
+	}
+	binding := &osbv1alpha1.SFServiceBinding{
+		Spec: osbv1alpha1.SFServiceBindingSpec{
+			ID:         "binding-id",
+			InstanceID: "instance-id",
+			PlanID:     "plan-id",
+			ServiceID:  "service-id",
+		},
+	}
+
+	// A synchronous bind times out after the timeout of the plan
+	r.startLastOperation(binding, "in_queue")
+	lastOperation := binding.Status.LastOperation
+	g.Expect(lastOperation).NotTo(gomega.BeNil())
+	g.Expect(lastOperation.Type).To(gomega.Equal(osbv1alpha1.BindOperation))
+	g.Expect(lastOperation.State).To(gomega.Equal("in progress"))
+	g.Expect(lastOperation.Deadline.Sub(lastOperation.StartedAt.Time)).To(gomega.Equal(time.Minute * 5))
+	g.Expect(lastOperation.ID).NotTo(gomega.BeEmpty())
+
+	// The operation id returned by the broker is kept
+	binding.SetAnnotations(map[string]string{constants.OperationIDKey: "operation-id"})
+	r.startLastOperation(binding, "in_queue")
+	g.Expect(binding.Status.LastOperation.ID).To(gomega.Equal("operation-id"))
+
+	// A synchronous unbind without timeout of the plan has no last operation
+	r.startLastOperation(binding, "delete")
+	g.Expect(binding.Status.LastOperation).To(gomega.BeNil())
+}
+
+func Test_syncLastOperation(t *testing.T) {
+	deadline := time.Now().Add(time.Minute)
+	tests := []struct {
+		name   string
+		status osbv1alpha1.SFServiceBindingStatus
+		want   *osbv1alpha1.BindingLastOperation
+	}{
+		{
+			name: "ignore binding without last operation",
+			status: osbv1alpha1.SFServiceBindingStatus{
+				State: "succeeded",
+			},
+		},
+		{
+			name: "keep last operation in progress",
+			status: osbv1alpha1.SFServiceBindingStatus{
+				State:         "in progress",
+				LastOperation: newLastOperation("in progress", deadline),
+			},
+			want: newLastOperation("in progress", deadline),
+		},
+		{
+			name: "complete last operation of succeeded binding",
+			status: osbv1alpha1.SFServiceBindingStatus{
+				State:         "succeeded",
+				LastOperation: newLastOperation("in progress", deadline),
+			},
+			want: newLastOperation("succeeded", deadline),
+		},
+		{
+			name: "complete last operation of failed binding with error",
+			status: osbv1alpha1.SFServiceBindingStatus{
+				State:         "failed",
+				Error:         "bind failed",
+				LastOperation: newLastOperation("in progress", deadline),
+			},
+			want: func() *osbv1alpha1.BindingLastOperation {
+				lastOperation := newLastOperation("failed", deadline)
+				lastOperation.Description = "bind failed"
+				return lastOperation
+			}(),
+		},
+		{
+			name: "keep completed last operation",
+			status: osbv1alpha1.SFServiceBindingStatus{
+				State:         "failed",
+				Error:         "unbind failed",
+				LastOperation: newLastOperation("succeeded", deadline),
+			},
+			want: newLastOperation("succeeded", deadline),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+			syncLastOperation(&tt.status)
+			g.Expect(tt.status.LastOperation).To(gomega.Equal(tt.want))
+		})
+	}
+}
+
+func Test_lastOperationExpired(t *testing.T) {
+	tests := []struct {
+		name          string
+		lastOperation *osbv1alpha1.BindingLastOperation
+		want          bool
+		wantRequeue   bool
+	}{
+		{
+			name: "not expired without last operation",
+		},
+		{
+			name:          "not expired before the deadline",
+			lastOperation: newLastOperation("in progress", time.Now().Add(time.Minute)),
+			wantRequeue:   true,
+		},
+		{
+			name:          "expired after the deadline",
+			lastOperation: newLastOperation("in progress", time.Now().Add(-time.Minute)),
+			want:          true,
+			wantRequeue:   true,
+		},
+		{
+			name:          "not expired once completed",
+			lastOperation: newLastOperation("succeeded", time.Now().Add(-time.Minute)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+			binding := &osbv1alpha1.SFServiceBinding{}
+			binding.Status.LastOperation = tt.lastOperation
+			g.Expect(lastOperationExpired(binding)).To(gomega.Equal(tt.want))
+			result := lastOperationResult(binding)
+			g.Expect(result.Requeue || result.RequeueAfter > 0).To(gomega.Equal(tt.wantRequeue))
+		})
+	}
+}
+
+func TestReconcileSFServiceBinding_failLastOperation(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	recorder := record.NewFakeRecorder(10)
+	r := &ReconcileSFServiceBinding{
+		Client:   k8sClient,
+		Log:      testLog,
+		scheme:   scheme.Scheme,
+		recorder: recorder,
+	}
+
+	asyncBinding := &osbv1alpha1.SFServiceBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "async-binding-id",
+			Namespace: "default",
+		},
+		Spec: osbv1alpha1.SFServiceBindingSpec{
+			ID:                "async-binding-id",
+			InstanceID:        "instance-id",
+			PlanID:            "plan-id",
+			ServiceID:         "service-id",
+			AcceptsIncomplete: true,
+		},
+	}
+	g.Expect(k8sClient.Create(context.TODO(), asyncBinding)).NotTo(gomega.HaveOccurred())
+	defer k8sClient.Delete(context.TODO(), asyncBinding)
+
+	// The binding is not failed before the deadline
+	asyncBinding.SetState("in progress")
+	asyncBinding.Status.LastOperation = newLastOperation("in progress", time.Now().Add(time.Minute))
+	g.Expect(k8sClient.Update(context.TODO(), asyncBinding)).NotTo(gomega.HaveOccurred())
+	g.Expect(r.failLastOperation(asyncBinding, 0)).NotTo(gomega.HaveOccurred())
+	g.Expect(asyncBinding.GetState()).To(gomega.Equal("in progress"))
+	g.Expect(recorder.Events).To(gomega.BeEmpty())
+
+	// The binding is failed after the deadline
+	asyncBinding.Status.LastOperation = newLastOperation("in progress", time.Now().Add(-time.Minute))
+	g.Expect(k8sClient.Update(context.TODO(), asyncBinding)).NotTo(gomega.HaveOccurred())
+	g.Expect(r.failLastOperation(asyncBinding, 0)).NotTo(gomega.HaveOccurred())
+	g.Expect(asyncBinding.GetState()).To(gomega.Equal("failed"))
+	g.Expect(asyncBinding.Status.Error).To(gomega.Equal("bind operation timed out after 30m0s"))
+	g.Expect(asyncBinding.Status.LastOperation.State).To(gomega.Equal("failed"))
+	g.Expect(asyncBinding.Status.LastOperation.Description).To(gomega.Equal(asyncBinding.Status.Error))
+	g.Expect(recorder.Events).To(gomega.Receive(gomega.Equal("Warning OperationTimeout bind operation timed out after 30m0s")))
+}
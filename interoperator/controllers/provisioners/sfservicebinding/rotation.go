@@ -0,0 +1,267 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sfservicebinding
+
+import (
+	"context"
+	"time"
+
+	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/secretstore"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/timeouts"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// States of a credential rotation
+const (
+	rotationInProgress = "in progress"
+	rotationOverlap    = "overlap"
+	rotationSucceeded  = "succeeded"
+	rotationFailed     = "failed"
+)
+
+// rotateOperation is the operation of a rotation in timeout errors and
+// metrics
+const rotateOperation = "rotate"
+
+// rotationRequested is true if the rotate annotation requests a rotation
+// which was not started yet and no other rotation is ongoing
+func rotationRequested(binding *osbv1alpha1.SFServiceBinding) bool {
+	rotationID := binding.GetAnnotations()[constants.RotateKey]
+	if rotationID == "" {
+		return false
+	}
+	rotation := binding.Status.Rotation
+	if rotation == nil {
+		return true
+	}
+	return rotation.ID != rotationID && (rotation.State == rotationSucceeded || rotation.State == rotationFailed)
+}
+
+// rotationState returns the state of the last rotation of the binding
+func rotationState(binding *osbv1alpha1.SFServiceBinding) string {
+	if binding.Status.Rotation == nil {
+		return ""
+	}
+	return binding.Status.Rotation.State
+}
+
+// getRotationOverlap returns how long the previous credentials stay
+// valid after a rotation
+func (r *ReconcileSFServiceBinding) getRotationOverlap() time.Duration {
+	interoperatorCfg := r.cfgManager.GetConfig()
+	overlap, err := time.ParseDuration(interoperatorCfg.BindingRotationOverlap)
+	if err != nil {
+		r.Log.Error(err, "invalid bindingRotationOverlap. using default",
+			"bindingRotationOverlap", interoperatorCfg.BindingRotationOverlap)
+		overlap, _ = time.ParseDuration(constants.DefaultBindingRotationOverlap)
+	}
+	return overlap
+}
+
+// getRotationTimeout returns how long a rotation may stay in progress. A
+// rotation binds again, so it times out after the bind timeout declared by
+// the plan or else after the configured timeout.
+func (r *ReconcileSFServiceBinding) getRotationTimeout(binding *osbv1alpha1.SFServiceBinding) time.Duration {
+	timeout, err := timeouts.ForOperation(r, binding.Spec.ServiceID, binding.Spec.PlanID, osbv1alpha1.BindOperation)
+	if err != nil {
+		r.Log.Error(err, "failed to get plan timeout. ignoring", "binding", binding.GetName(),
+			"operation", rotateOperation)
+		timeout = 0
+	}
+	if timeout == 0 {
+		timeout = r.getOperationTimeout()
+	}
+	return timeout
+}
+
+// startRotation keeps the current credentials as previous credentials in
+// the secret store and updates the binding to render the bind template again. The bind
+// template gets the previous credentials as previous.
+func (r *ReconcileSFServiceBinding) startRotation(binding *osbv1alpha1.SFServiceBinding, retryCount int) error {
+	ctx := context.Background()
+
+	bindingID := binding.GetName()
+	namespace := binding.GetNamespace()
+	log := r.Log.WithValues("sfservicebinding", bindingID)
+
+	namespacedName := types.NamespacedName{
+		Name:      bindingID,
+		Namespace: namespace,
+	}
+	err := r.Get(ctx, namespacedName, binding)
+	if err != nil {
+		log.Error(err, "failed to fetch binding", "binding", bindingID)
+		return err
+	}
+	rotationID := binding.GetAnnotations()[constants.RotateKey]
+
+	data, err := r.secretStore.Get(binding, secretstore.BindSecretName(bindingID))
+	if err != nil {
+		log.Error(err, "failed to fetch bind secret for rotation", "binding", bindingID)
+		return err
+	}
+	previousSecretName := secretstore.PreviousBindSecretName(bindingID)
+	err = r.secretStore.Put(binding, previousSecretName, data)
+	if err != nil {
+		log.Error(err, "failed to save previous credentials", "binding", bindingID)
+		return err
+	}
+
+	startedAt, deadline := timeouts.Start(r.getRotationTimeout(binding))
+	binding.Status.Rotation = &osbv1alpha1.BindingRotation{
+		ID:                rotationID,
+		State:             rotationInProgress,
+		PreviousSecretRef: r.secretStore.Ref(binding, previousSecretName),
+		StartedAt:         startedAt,
+		Deadline:          deadline,
+	}
+	binding.SetState("update")
+	err = r.Update(ctx, binding)
+	if err != nil {
+		if retryCount < constants.ErrorThreshold {
+			log.Info("Retrying", "function", "startRotation", "retryCount", retryCount+1, "bindingID", bindingID)
+			return r.startRotation(binding, retryCount+1)
+		}
+		log.Error(err, "failed to start rotation", "binding", bindingID)
+		return err
+	}
+	log.Info("Started credential rotation", "binding", bindingID, "rotationID", rotationID)
+	return nil
+}
+
+// failRotation records the failure of the rotation in progress only in
+// the rotation status. The binding stays succeeded with its current
+// credentials, so that the rotation can be retried with a new rotation id.
+func failRotation(status *osbv1alpha1.SFServiceBindingStatus, reason string) {
+	status.State = "succeeded"
+	status.Rotation.State = rotationFailed
+	status.Rotation.Error = reason
+	status.Rotation.PreviousResources = nil
+	status.Rotation.RevokeAfter = nil
+}
+
+// currentRotation returns the rotation of the binding as operation, nil if
+// it has none
+func currentRotation(binding *osbv1alpha1.SFServiceBinding) *timeouts.Operation {
+	rotation := binding.Status.Rotation
+	if rotation == nil {
+		return nil
+	}
+	return &timeouts.Operation{
+		ID:         rotation.ID,
+		Kind:       "SFServiceBinding",
+		Type:       rotateOperation,
+		PlanID:     binding.Spec.PlanID,
+		InProgress: rotation.State == rotationInProgress,
+		StartedAt:  rotation.StartedAt,
+		Deadline:   rotation.Deadline,
+	}
+}
+
+// rotationExpired is true if the rotation is still in progress after its
+// deadline
+func rotationExpired(binding *osbv1alpha1.SFServiceBinding) bool {
+	return currentRotation(binding).Expired()
+}
+
+// rotationResult requeues the binding at the deadline of its rotation
+func rotationResult(binding *osbv1alpha1.SFServiceBinding) ctrl.Result {
+	return currentRotation(binding).Result()
+}
+
+// failExpiredRotation fails the rotation once its deadline passed. Like
+// any failed rotation the binding keeps its current credentials.
+func (r *ReconcileSFServiceBinding) failExpiredRotation(binding *osbv1alpha1.SFServiceBinding, retryCount int) error {
+	return timeouts.Fail(r, r.recorder, binding, func() *timeouts.Operation {
+		return currentRotation(binding)
+	}, func(err error) {
+		failRotation(&binding.Status, err.Error())
+	}, retryCount)
+}
+
+// revokePrevious deletes the previous credentials from the secret store
+// and the resources which are no longer rendered since the rotation, like
+// unbind does for all of them
+func (r *ReconcileSFServiceBinding) revokePrevious(targetClient client.Client, binding *osbv1alpha1.SFServiceBinding, retryCount int) error {
+	ctx := context.Background()
+
+	bindingID := binding.GetName()
+	namespace := binding.GetNamespace()
+	log := r.Log.WithValues("sfservicebinding", bindingID)
+
+	namespacedName := types.NamespacedName{
+		Name:      bindingID,
+		Namespace: namespace,
+	}
+	err := r.Get(ctx, namespacedName, binding)
+	if err != nil {
+		log.Error(err, "failed to fetch binding", "binding", bindingID)
+		return err
+	}
+	rotation := binding.Status.Rotation
+	if rotation == nil || rotation.State != rotationOverlap {
+		return nil
+	}
+
+	err = r.secretStore.Delete(binding, secretstore.PreviousBindSecretName(bindingID))
+	if err != nil {
+		log.Error(err, "failed to delete previous credentials", "binding", bindingID)
+		return err
+	}
+	_, err = r.resourceManager.DeleteSubResources(targetClient, rotation.PreviousResources)
+	if err != nil {
+		log.Error(err, "failed to revoke previous credentials", "binding", bindingID)
+		return err
+	}
+
+	resources := []osbv1alpha1.Source{}
+	for _, resource := range binding.Status.Resources {
+		if !containsSource(rotation.PreviousResources, resource) {
+			resources = append(resources, resource)
+		}
+	}
+	binding.Status.Resources = resources
+	rotation.State = rotationSucceeded
+	rotation.PreviousSecretRef = ""
+	rotation.PreviousResources = nil
+	rotation.RevokeAfter = nil
+	err = r.Update(ctx, binding)
+	if err != nil {
+		if retryCount < constants.ErrorThreshold {
+			log.Info("Retrying", "function", "revokePrevious", "retryCount", retryCount+1, "bindingID", bindingID)
+			return r.revokePrevious(targetClient, binding, retryCount+1)
+		}
+		log.Error(err, "failed to update binding after revoking previous credentials", "binding", bindingID)
+		return err
+	}
+	log.Info("Revoked previous credentials", "binding", bindingID, "rotationID", rotation.ID)
+	return nil
+}
+
+func containsSource(list []osbv1alpha1.Source, item osbv1alpha1.Source) bool {
+	for _, source := range list {
+		if source == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,291 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sfservicebinding
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/config"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/resources/mock_resources"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/secretstore"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+
+	"github.com/golang/mock/gomock"
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_rotationRequested(t *testing.T) {
+	tests := []struct {
+		name     string
+		rotate   string
+		rotation *osbv1alpha1.BindingRotation
+		want     bool
+	}{
+		{
+			name: "not requested without annotation",
+		},
+		{
+			name:   "requested by the annotation",
+			rotate: "1",
+			want:   true,
+		},
+		{
+			name:     "not requested if already started",
+			rotate:   "1",
+			rotation: &osbv1alpha1.BindingRotation{ID: "1", State: rotationSucceeded},
+		},
+		{
+			name:     "requested again with another id",
+			rotate:   "2",
+			rotation: &osbv1alpha1.BindingRotation{ID: "1", State: rotationSucceeded},
+			want:     true,
+		},
+		{
+			name:     "not requested while the previous credentials are valid",
+			rotate:   "2",
+			rotation: &osbv1alpha1.BindingRotation{ID: "1", State: rotationOverlap},
+		},
+		{
+			name:     "requested again after a failed rotation",
+			rotate:   "2",
+			rotation: &osbv1alpha1.BindingRotation{ID: "1", State: rotationFailed},
+			want:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+			binding := &osbv1alpha1.SFServiceBinding{}
+			if tt.rotate != "" {
+				binding.SetAnnotations(map[string]string{constants.RotateKey: tt.rotate})
+			}
+			binding.Status.Rotation = tt.rotation
+			g.Expect(rotationRequested(binding)).To(gomega.Equal(tt.want))
+		})
+	}
+}
+
+func TestReconcileSFServiceBinding_rotation(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockResourceManager := mock_resources.NewMockResourceManager(ctrl)
+	secretStore, err := secretstore.NewKubernetesStore(k8sClient, scheme.Scheme)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	cfgManager, err := config.New(cfg, scheme.Scheme, nil)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	r := &ReconcileSFServiceBinding{
+		Client:          k8sClient,
+		Log:             testLog,
+		scheme:          scheme.Scheme,
+		resourceManager: mockResourceManager,
+		secretStore:     secretStore,
+		cfgManager:      cfgManager,
+	}
+
+	rotatedBinding := &osbv1alpha1.SFServiceBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "rotated-binding-id",
+			Namespace:   "default",
+			Annotations: map[string]string{constants.RotateKey: "1"},
+		},
+		Spec: osbv1alpha1.SFServiceBindingSpec{
+			ID:         "rotated-binding-id",
+			InstanceID: "instance-id",
+			PlanID:     "plan-id",
+			ServiceID:  "service-id",
+		},
+	}
+	g.Expect(k8sClient.Create(context.TODO(), rotatedBinding)).NotTo(gomega.HaveOccurred())
+	defer k8sClient.Delete(context.TODO(), rotatedBinding)
+	rotatedBinding.SetState("succeeded")
+	g.Expect(k8sClient.Update(context.TODO(), rotatedBinding)).NotTo(gomega.HaveOccurred())
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sf-rotated-binding-id",
+			Namespace: "default",
+		},
+		StringData: map[string]string{"response": "old"},
+	}
+	g.Expect(k8sClient.Create(context.TODO(), secret)).NotTo(gomega.HaveOccurred())
+	defer k8sClient.Delete(context.TODO(), secret)
+
+	// The current credentials are kept as previous credentials
+	g.Expect(r.startRotation(rotatedBinding, 0)).NotTo(gomega.HaveOccurred())
+	g.Expect(rotatedBinding.GetState()).To(gomega.Equal("update"))
+	g.Expect(rotatedBinding.Status.Rotation.ID).To(gomega.Equal("1"))
+	g.Expect(rotatedBinding.Status.Rotation.State).To(gomega.Equal(rotationInProgress))
+	g.Expect(rotatedBinding.Status.Rotation.PreviousSecretRef).To(gomega.Equal("sf-rotated-binding-id-previous"))
+	g.Expect(rotatedBinding.Status.Rotation.Deadline.Sub(rotatedBinding.Status.Rotation.StartedAt.Time)).To(gomega.Equal(time.Minute * 30))
+
+	previousSecret := &corev1.Secret{}
+	g.Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: "sf-rotated-binding-id-previous", Namespace: "default"}, previousSecret)).
+		NotTo(gomega.HaveOccurred())
+	g.Expect(previousSecret.Data).To(gomega.HaveKeyWithValue("response", []byte("old")))
+	defer k8sClient.Delete(context.TODO(), previousSecret)
+
+	// The previous credentials are revoked after the overlap
+	currentResource := osbv1alpha1.Source{APIVersion: "v1", Kind: "ConfigMap", Name: "user-2", Namespace: "default"}
+	previousResource := osbv1alpha1.Source{APIVersion: "v1", Kind: "ConfigMap", Name: "user-1", Namespace: "default"}
+	revokeAfter := metav1.NewTime(time.Now())
+	rotatedBinding.SetState("succeeded")
+	rotatedBinding.Status.Resources = []osbv1alpha1.Source{currentResource, previousResource}
+	rotatedBinding.Status.Rotation.State = rotationOverlap
+	rotatedBinding.Status.Rotation.PreviousResources = []osbv1alpha1.Source{previousResource}
+	rotatedBinding.Status.Rotation.RevokeAfter = &revokeAfter
+	g.Expect(k8sClient.Update(context.TODO(), rotatedBinding)).NotTo(gomega.HaveOccurred())
+
+	mockResourceManager.EXPECT().DeleteSubResources(r, []osbv1alpha1.Source{previousResource}).Return(nil, nil).Times(1)
+	g.Expect(r.revokePrevious(r, rotatedBinding, 0)).NotTo(gomega.HaveOccurred())
+	g.Expect(apiErrors.IsNotFound(k8sClient.Get(context.TODO(), types.NamespacedName{Name: "sf-rotated-binding-id-previous", Namespace: "default"}, previousSecret))).
+		To(gomega.BeTrue())
+	g.Expect(rotatedBinding.Status.Resources).To(gomega.Equal([]osbv1alpha1.Source{currentResource}))
+	g.Expect(rotatedBinding.Status.Rotation.State).To(gomega.Equal(rotationSucceeded))
+	g.Expect(rotatedBinding.Status.Rotation.PreviousSecretRef).To(gomega.BeEmpty())
+	g.Expect(rotationRequested(rotatedBinding)).To(gomega.BeFalse())
+}
+
+func TestReconcileSFServiceBinding_failRotation(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	testScheme := runtime.NewScheme()
+	g.Expect(osbv1alpha1.AddToScheme(testScheme)).To(gomega.Succeed())
+
+	revokeAfter := metav1.NewTime(time.Now().Add(time.Hour))
+	binding := &osbv1alpha1.SFServiceBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "rotated-binding-id",
+			Namespace: "default",
+			Labels: map[string]string{
+				constants.ErrorCountKey:    strconv.Itoa(constants.ErrorThreshold),
+				constants.LastOperationKey: "in_queue",
+			},
+		},
+		Status: osbv1alpha1.SFServiceBindingStatus{
+			State: "update",
+			Rotation: &osbv1alpha1.BindingRotation{
+				ID:                "1",
+				State:             rotationInProgress,
+				PreviousSecretRef: "sf-rotated-binding-id-previous",
+				PreviousResources: []osbv1alpha1.Source{
+					{APIVersion: "v1", Kind: "Secret", Namespace: "default", Name: "previous"},
+				},
+				RevokeAfter: &revokeAfter,
+			},
+		},
+	}
+	r := &ReconcileSFServiceBinding{
+		Client: fake.NewFakeClientWithScheme(testScheme, binding),
+		Log:    testLog,
+		scheme: testScheme,
+	}
+
+	// A failed re-render fails only the rotation once the retries are
+	// exhausted
+	result, err := r.handleError(binding, ctrl.Result{}, fmt.Errorf("render failed"), "update", 0)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(result).To(gomega.BeZero())
+
+	failedBinding := &osbv1alpha1.SFServiceBinding{}
+	namespacedName := types.NamespacedName{Name: "rotated-binding-id", Namespace: "default"}
+	g.Expect(r.Get(context.TODO(), namespacedName, failedBinding)).To(gomega.Succeed())
+	g.Expect(failedBinding.GetState()).To(gomega.Equal("succeeded"))
+	g.Expect(failedBinding.Status.Error).To(gomega.BeEmpty())
+	g.Expect(failedBinding.GetLabels()).NotTo(gomega.HaveKey(constants.ErrorCountKey))
+	g.Expect(failedBinding.GetLabels()).To(gomega.HaveKeyWithValue(constants.LastOperationKey, "in_queue"))
+	g.Expect(failedBinding.Status.Rotation).To(gomega.Equal(&osbv1alpha1.BindingRotation{
+		ID:                "1",
+		State:             rotationFailed,
+		Error:             "Retry threshold reached for rotated-binding-id.\nrender failed",
+		PreviousSecretRef: "sf-rotated-binding-id-previous",
+	}))
+
+	// The rotation can be retried with a new id
+	failedBinding.SetAnnotations(map[string]string{constants.RotateKey: "2"})
+	g.Expect(rotationRequested(failedBinding)).To(gomega.BeTrue())
+}
+
+func TestReconcileSFServiceBinding_failExpiredRotation(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	testScheme := runtime.NewScheme()
+	g.Expect(osbv1alpha1.AddToScheme(testScheme)).To(gomega.Succeed())
+
+	startedAt := metav1.NewTime(time.Now().Add(-time.Hour))
+	deadline := metav1.NewTime(time.Now().Add(time.Minute))
+	binding := &osbv1alpha1.SFServiceBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "rotated-binding-id",
+			Namespace: "default",
+		},
+		Status: osbv1alpha1.SFServiceBindingStatus{
+			State: "in progress",
+			Rotation: &osbv1alpha1.BindingRotation{
+				ID:                "1",
+				State:             rotationInProgress,
+				PreviousSecretRef: "sf-rotated-binding-id-previous",
+				StartedAt:         &startedAt,
+				Deadline:          &deadline,
+			},
+		},
+	}
+	recorder := record.NewFakeRecorder(10)
+	r := &ReconcileSFServiceBinding{
+		Client:   fake.NewFakeClientWithScheme(testScheme, binding),
+		Log:      testLog,
+		scheme:   testScheme,
+		recorder: recorder,
+	}
+
+	// The rotation is not failed before the deadline
+	g.Expect(rotationExpired(binding)).To(gomega.BeFalse())
+	g.Expect(rotationResult(binding).RequeueAfter).To(gomega.BeNumerically(">", 0))
+	g.Expect(r.failExpiredRotation(binding, 0)).To(gomega.Succeed())
+	g.Expect(binding.GetState()).To(gomega.Equal("in progress"))
+	g.Expect(recorder.Events).To(gomega.BeEmpty())
+
+	// The rotation is failed after the deadline, the binding keeps its
+	// current credentials
+	deadline = metav1.NewTime(time.Now().Add(-time.Minute))
+	binding.Status.Rotation.Deadline = &deadline
+	g.Expect(r.Update(context.TODO(), binding)).To(gomega.Succeed())
+	g.Expect(rotationExpired(binding)).To(gomega.BeTrue())
+	g.Expect(r.failExpiredRotation(binding, 0)).To(gomega.Succeed())
+
+	failedBinding := &osbv1alpha1.SFServiceBinding{}
+	namespacedName := types.NamespacedName{Name: "rotated-binding-id", Namespace: "default"}
+	g.Expect(r.Get(context.TODO(), namespacedName, failedBinding)).To(gomega.Succeed())
+	g.Expect(failedBinding.GetState()).To(gomega.Equal("succeeded"))
+	g.Expect(failedBinding.Status.Rotation.State).To(gomega.Equal(rotationFailed))
+	g.Expect(failedBinding.Status.Rotation.Error).To(gomega.Equal("rotate operation timed out after 59m0s"))
+	g.Expect(recorder.Events).To(gomega.Receive(gomega.Equal("Warning OperationTimeout rotate operation timed out after 59m0s")))
+	g.Expect(rotationExpired(failedBinding)).To(gomega.BeFalse())
+}
@@ -22,27 +22,30 @@ import (
 	"os"
 	"reflect"
 	"strconv"
+	"time"
 
 	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/config"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/encryption"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/properties"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/resources"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/secretstore"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/cluster/registry"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/utils"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/watches"
 
 	"github.com/go-logr/logr"
-	corev1 "k8s.io/api/core/v1"
 	apiErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
-	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 var ownClusterID string
@@ -54,12 +57,17 @@ type ReconcileSFServiceBinding struct {
 	scheme          *runtime.Scheme
 	clusterRegistry registry.ClusterRegistry
 	resourceManager resources.ResourceManager
+	secretStore     secretstore.SecretStore
+	encrypter       encryption.Encrypter
+	cfgManager      config.Config
+	recorder        record.EventRecorder
 }
 
 // Reconcile reads that state of the cluster for a SFServiceBinding object and makes changes based on the state read
 // and what is in the SFServiceBinding.Spec
 // Automatically generate RBAC rules to allow the Controller to read and write Deployments
 // +kubebuilder:rbac:groups=bind.servicefabrik.io,resources=*,verbs=*
+// +kubebuilder:rbac:groups=,resources=events,verbs=create;patch
 // TODO dynamically setup rbac rules and watches
 func (r *ReconcileSFServiceBinding) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	ctx := context.Background()
@@ -90,11 +98,14 @@ func (r *ReconcileSFServiceBinding) Reconcile(req ctrl.Request) (ctrl.Result, er
 		lastOperation = "in_queue"
 	}
 
-	if state == "succeeded" || state == "failed" {
+	// Succeeded bindings are only reconciled to rotate their credentials
+	rotate := state == "succeeded" && rotationRequested(binding)
+	revoke := state == "succeeded" && rotationState(binding) == rotationOverlap
+	if (state == "succeeded" && !rotate && !revoke) || state == "failed" {
 		return ctrl.Result{}, nil
 	}
 
-	if state == "in_queue" || state == "update" || state == "delete" || state == "in progress" {
+	if state == "in_queue" || state == "update" || state == "delete" || state == "in progress" || state == "succeeded" {
 		clusterID, err := binding.GetClusterID(r)
 		if err != nil {
 			if errors.SFServiceInstanceNotFound(err) || errors.ClusterIDNotSet(err) {
@@ -121,24 +132,37 @@ func (r *ReconcileSFServiceBinding) Reconcile(req ctrl.Request) (ctrl.Result, er
 
 	targetClient := r
 
+	if rotate {
+		err = r.startRotation(binding, 0)
+		return r.handleError(binding, ctrl.Result{}, err, "", 0)
+	}
+
+	if revoke {
+		revokeAfter := binding.Status.Rotation.RevokeAfter
+		if revokeAfter != nil && time.Until(revokeAfter.Time) > 0 {
+			return ctrl.Result{RequeueAfter: time.Until(revokeAfter.Time)}, nil
+		}
+		err = r.revokePrevious(targetClient, binding, 0)
+		return r.handleError(binding, ctrl.Result{}, err, "", 0)
+	}
+
 	if state == "delete" && !binding.GetDeletionTimestamp().IsZero() {
 		// The object is being deleted
 		// so lets handle our external dependency
-		// Explicitly delete BindSecret
-		secretName := "sf-" + bindingID
-		bindSecret := osbv1alpha1.Source{}
-		bindSecret.Kind = "Secret"
-		bindSecret.APIVersion = "v1"
-		bindSecret.Name = secretName
-		bindSecret.Namespace = binding.GetNamespace()
-		resourceRefs := append(binding.Status.Resources, bindSecret)
-		remainingResource, err := r.resourceManager.DeleteSubResources(targetClient, resourceRefs)
+		// Explicitly delete the credentials from the secret store
+		for _, secretName := range []string{secretstore.BindSecretName(bindingID), secretstore.PreviousBindSecretName(bindingID)} {
+			err = r.secretStore.Delete(binding, secretName)
+			if err != nil {
+				log.Error(err, "Delete bind secret failed", "binding", bindingID)
+				return r.handleError(binding, ctrl.Result{}, err, state, 0)
+			}
+		}
+		remainingResource, err := r.resourceManager.DeleteSubResources(targetClient, binding.Status.Resources)
 		if err != nil {
-			log.Error(err, "Delete sub resources failed", "binding", bindingID)
-			return r.handleError(binding, ctrl.Result{}, err, state, 0)
+			return r.handleResourcesError(binding, remainingResource, err, state)
 		}
 
-		err = r.setInProgress(req.NamespacedName, state, remainingResource, 0)
+		err = r.setInProgress(req.NamespacedName, state, remainingResource, nil, 0)
 		if err != nil {
 			return r.handleError(binding, ctrl.Result{}, err, state, 0)
 		}
@@ -153,12 +177,27 @@ func (r *ReconcileSFServiceBinding) Reconcile(req ctrl.Request) (ctrl.Result, er
 			return r.handleError(binding, ctrl.Result{}, err, state, 0)
 		}
 
-		resourceRefs, err := r.resourceManager.ReconcileResources(r, targetClient, expectedResources, binding.Status.Resources)
+		// The resources of the previous credentials are kept until they
+		// are revoked after a rotation
+		rotating := rotationState(binding) == rotationInProgress
+		lastResources := binding.Status.Resources
+		if rotating {
+			lastResources = nil
+		}
+		resourceRefs, _, err := r.resourceManager.ReconcileResources(r, targetClient, expectedResources, lastResources)
+		var previousResources []osbv1alpha1.Source
+		if rotating {
+			for _, resource := range binding.Status.Resources {
+				if !containsSource(resourceRefs, resource) {
+					previousResources = append(previousResources, resource)
+				}
+			}
+			resourceRefs = append(resourceRefs, previousResources...)
+		}
 		if err != nil {
-			log.Error(err, "ReconcileResources failed", "binding", bindingID)
-			return r.handleError(binding, ctrl.Result{}, err, state, 0)
+			return r.handleResourcesError(binding, resourceRefs, err, state)
 		}
-		err = r.setInProgress(req.NamespacedName, state, resourceRefs, 0)
+		err = r.setInProgress(req.NamespacedName, state, resourceRefs, previousResources, 0)
 		if err != nil {
 			return r.handleError(binding, ctrl.Result{}, err, state, 0)
 		}
@@ -177,6 +216,14 @@ func (r *ReconcileSFServiceBinding) Reconcile(req ctrl.Request) (ctrl.Result, er
 	}
 
 	if state == "in progress" {
+		if lastOperationExpired(binding) {
+			err = r.failLastOperation(binding, 0)
+			return r.handleError(binding, ctrl.Result{}, err, lastOperation, 0)
+		}
+		if rotationExpired(binding) {
+			err = r.failExpiredRotation(binding, 0)
+			return r.handleError(binding, ctrl.Result{}, err, lastOperation, 0)
+		}
 		if lastOperation == "delete" {
 			err = r.updateUnbindStatus(targetClient, binding, 0)
 			if err != nil {
@@ -189,7 +236,11 @@ func (r *ReconcileSFServiceBinding) Reconcile(req ctrl.Request) (ctrl.Result, er
 			}
 		}
 	}
-	return r.handleError(binding, ctrl.Result{}, nil, lastOperation, 0)
+	result := lastOperationResult(binding)
+	if rotationState(binding) == rotationInProgress {
+		result = rotationResult(binding)
+	}
+	return r.handleError(binding, result, nil, lastOperation, 0)
 }
 
 func (r *ReconcileSFServiceBinding) reconcileFinalizers(object *osbv1alpha1.SFServiceBinding, retryCount int) error {
@@ -231,7 +282,7 @@ func (r *ReconcileSFServiceBinding) reconcileFinalizers(object *osbv1alpha1.SFSe
 	return nil
 }
 
-func (r *ReconcileSFServiceBinding) setInProgress(namespacedName types.NamespacedName, state string, resources []osbv1alpha1.Source, retryCount int) error {
+func (r *ReconcileSFServiceBinding) setInProgress(namespacedName types.NamespacedName, state string, resources []osbv1alpha1.Source, previousResources []osbv1alpha1.Source, retryCount int) error {
 	ctx := context.Background()
 	log := r.Log.WithValues("sfservicebinding", namespacedName)
 
@@ -241,12 +292,17 @@ func (r *ReconcileSFServiceBinding) setInProgress(namespacedName types.Namespace
 		if err != nil {
 			if retryCount < constants.ErrorThreshold {
 				log.Info("Retrying", "function", "setInProgress", "retryCount", retryCount+1, "objectID", namespacedName.Name)
-				return r.setInProgress(namespacedName, state, resources, retryCount+1)
+				return r.setInProgress(namespacedName, state, resources, previousResources, retryCount+1)
 			}
 			log.Error(err, "Updating status to in progress failed", "binding", namespacedName.Name)
 			return err
 		}
 		binding.SetState("in progress")
+		// The last operation is kept for the broker to poll the bind, a
+		// rotation does not replace it
+		if rotationState(binding) != rotationInProgress {
+			r.startLastOperation(binding, state)
+		}
 		labels := binding.GetLabels()
 		if labels == nil {
 			labels = make(map[string]string)
@@ -254,11 +310,14 @@ func (r *ReconcileSFServiceBinding) setInProgress(namespacedName types.Namespace
 		labels[constants.LastOperationKey] = state
 		binding.SetLabels(labels)
 		binding.Status.Resources = resources
+		if previousResources != nil && binding.Status.Rotation != nil {
+			binding.Status.Rotation.PreviousResources = previousResources
+		}
 		err = r.Update(context.Background(), binding)
 		if err != nil {
 			if retryCount < constants.ErrorThreshold {
 				log.Info("Retrying", "function", "setInProgress", "retryCount", retryCount+1, "objectID", namespacedName.Name)
-				return r.setInProgress(namespacedName, state, resources, retryCount+1)
+				return r.setInProgress(namespacedName, state, resources, previousResources, retryCount+1)
 			}
 			log.Error(err, "Updating status to in progress failed", "binding", namespacedName.Name)
 			return err
@@ -336,6 +395,7 @@ func (r *ReconcileSFServiceBinding) updateUnbindStatus(targetClient client.Clien
 		binding.SetState("succeeded")
 		updateRequired = true
 	}
+	syncLastOperation(&binding.Status)
 
 	if updateRequired {
 		log.Info("Updating unbind status from template", "binding", namespacedName.Name)
@@ -384,41 +444,41 @@ func (r *ReconcileSFServiceBinding) updateBindStatus(targetClient client.Client,
 
 	computedBindingStatus := computedStatus.Bind
 
-	// Create secret if not exist
+	// Store the credentials if not stored yet, update them if the
+	// credentials are rotated. The credentials are encrypted if enabled
+	// and only decrypted for the bind response of the broker.
 	if computedBindingStatus.State == "succeeded" {
-		secretName := "sf-" + bindingID
-
-		data := make(map[string]string)
-		data["response"] = computedBindingStatus.Response
-		secret := &corev1.Secret{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      secretName,
-				Namespace: namespace,
-			},
-			StringData: data,
-		}
+		secretName := secretstore.BindSecretName(bindingID)
 
-		if err := controllerutil.SetControllerReference(binding, secret, r.scheme); err != nil {
-			log.Error(err, "failed to set owner reference for secret", "binding", bindingID)
-			return err
-		}
-		secretNamespacedName := types.NamespacedName{
-			Name:      secretName,
-			Namespace: namespace,
-		}
-		foundSecret := &corev1.Secret{}
-		err = r.Get(ctx, secretNamespacedName, foundSecret)
-		if err != nil && apiErrors.IsNotFound(err) {
-			err = r.Create(ctx, secret)
+		_, err = r.secretStore.Get(binding, secretName)
+		if errors.SecretNotFound(err) || (err == nil && rotationState(binding) == rotationInProgress) {
+			response, err := r.encrypter.Encrypt(computedBindingStatus.Response)
+			if err != nil {
+				log.Error(err, "failed to encrypt bind response", "binding", bindingID)
+				return err
+			}
+			data := make(map[string]string)
+			data["response"] = response
+			err = r.secretStore.Put(binding, secretName, data)
 			if err != nil {
-				log.Error(err, "failed to create secret", "binding", bindingID)
+				log.Error(err, "failed to store bind secret", "binding", bindingID)
 				return err
 			}
 		} else if err != nil {
 			return err
 		}
-		updatedStatus.Response.SecretRef = secretName
+		updatedStatus.Response.SecretRef = r.secretStore.Ref(binding, secretName)
+
+		if rotationState(binding) == rotationInProgress {
+			revokeAfter := metav1.NewTime(time.Now().Add(r.getRotationOverlap()))
+			updatedStatus.Rotation.State = rotationOverlap
+			updatedStatus.Rotation.RevokeAfter = &revokeAfter
+		}
+	} else if computedBindingStatus.State == "failed" && rotationState(binding) == rotationInProgress {
+		updatedStatus.Error = binding.Status.Error
+		failRotation(updatedStatus, computedBindingStatus.Error)
 	}
+	syncLastOperation(updatedStatus)
 
 	if !reflect.DeepEqual(&binding.Status, updatedStatus) {
 		updatedStatus.DeepCopyInto(&binding.Status)
@@ -436,6 +496,55 @@ func (r *ReconcileSFServiceBinding) updateBindStatus(targetClient client.Client,
 	return nil
 }
 
+// handleResourcesError records the resources to track when reconciling or
+// deleting the resources failed. The binding is requeued without counting an
+// error while resources are waited for.
+func (r *ReconcileSFServiceBinding) handleResourcesError(binding *osbv1alpha1.SFServiceBinding, resources []osbv1alpha1.Source, inputErr error, state string) (ctrl.Result, error) {
+	namespacedName := types.NamespacedName{
+		Name:      binding.GetName(),
+		Namespace: binding.GetNamespace(),
+	}
+	log := r.Log.WithValues("sfservicebinding", namespacedName)
+
+	if resources != nil {
+		err := r.setResources(namespacedName, resources, 0)
+		if err != nil {
+			return r.handleError(binding, ctrl.Result{}, err, state, 0)
+		}
+	}
+	if errors.ResourceNotReady(inputErr) {
+		log.Info("Waiting for resources", "reason", inputErr.Error())
+		return ctrl.Result{RequeueAfter: constants.ResourceWaitInterval}, nil
+	}
+	log.Error(inputErr, "Reconciling resources failed")
+	return r.handleError(binding, ctrl.Result{}, inputErr, state, 0)
+}
+
+// setResources updates the resources to track without changing the state
+func (r *ReconcileSFServiceBinding) setResources(namespacedName types.NamespacedName, resources []osbv1alpha1.Source, retryCount int) error {
+	ctx := context.Background()
+	log := r.Log.WithValues("sfservicebinding", namespacedName)
+
+	binding := &osbv1alpha1.SFServiceBinding{}
+	err := r.Get(ctx, namespacedName, binding)
+	if err == nil {
+		if reflect.DeepEqual(binding.Status.Resources, resources) {
+			return nil
+		}
+		binding.Status.Resources = resources
+		err = r.Update(ctx, binding)
+	}
+	if err != nil {
+		if retryCount < constants.ErrorThreshold {
+			log.Info("Retrying", "function", "setResources", "retryCount", retryCount+1, "objectID", namespacedName.Name)
+			return r.setResources(namespacedName, resources, retryCount+1)
+		}
+		log.Error(err, "Updating resources failed", "bindingId", namespacedName.Name)
+		return err
+	}
+	return nil
+}
+
 func (r *ReconcileSFServiceBinding) handleError(object *osbv1alpha1.SFServiceBinding, result ctrl.Result, inputErr error, lastOperation string, retryCount int) (ctrl.Result, error) {
 	ctx := context.Background()
 
@@ -508,10 +617,27 @@ func (r *ReconcileSFServiceBinding) handleError(object *osbv1alpha1.SFServiceBin
 		count++
 	}
 
+	if count > constants.ErrorThreshold && rotationState(object) == rotationInProgress {
+		log.Error(inputErr, "Retry threshold reached. Failing rotation", "objectID", objectID)
+		failRotation(&object.Status, fmt.Sprintf("Retry threshold reached for %s.\n%s", objectID, inputErr.Error()))
+		delete(labels, constants.ErrorCountKey)
+		object.SetLabels(labels)
+		err := r.Update(ctx, object)
+		if err != nil {
+			if retryCount < constants.ErrorThreshold {
+				log.Info("Retrying", "function", "handleError", "retryCount", retryCount+1, "lastOperation", lastOperation, "err", inputErr, "objectID", objectID)
+				return r.handleError(object, result, inputErr, lastOperation, retryCount+1)
+			}
+			log.Error(err, "Failed to fail rotation", "objectID", objectID)
+		}
+		return result, nil
+	}
+
 	if count > constants.ErrorThreshold {
 		log.Error(inputErr, "Retry threshold reached. Ignoring error", "objectID", objectID)
 		object.Status.State = "failed"
 		object.Status.Error = fmt.Sprintf("Retry threshold reached for %s.\n%s", objectID, inputErr.Error())
+		syncLastOperation(&object.Status)
 		if lastOperation != "" {
 			labels[constants.LastOperationKey] = lastOperation
 			object.SetLabels(labels)
@@ -549,23 +675,41 @@ func (r *ReconcileSFServiceBinding) SetupWithManager(mgr ctrl.Manager) error {
 		r.Log = ctrl.Log.WithName("provisioners").WithName("binding")
 	}
 	if r.clusterRegistry == nil {
-		clusterRegistry, err := registry.New(mgr.GetConfig(), mgr.GetScheme(), mgr.GetRESTMapper())
+		clusterRegistry, err := registry.ForManager(mgr)
 		if err != nil {
 			return err
 		}
 		r.clusterRegistry = clusterRegistry
 	}
 
-	if r.resourceManager == nil {
-		r.resourceManager = resources.New()
-	}
-
 	cfgManager, err := config.New(mgr.GetConfig(), mgr.GetScheme(), mgr.GetRESTMapper())
 	if err != nil {
 		return err
 	}
+	r.cfgManager = cfgManager
+	r.recorder = mgr.GetEventRecorderFor("binding")
 	interoperatorCfg := cfgManager.GetConfig()
 
+	if r.secretStore == nil {
+		secretStore, err := secretstore.New(r.Client, mgr.GetScheme(), interoperatorCfg.BindingSecretStore)
+		if err != nil {
+			return err
+		}
+		r.secretStore = secretStore
+	}
+
+	if r.encrypter == nil {
+		encrypter, err := encryption.New(interoperatorCfg.CredentialEncryption)
+		if err != nil {
+			return err
+		}
+		r.encrypter = encrypter
+	}
+
+	if r.resourceManager == nil {
+		r.resourceManager = resources.NewWithSecretStore(r.secretStore, r.encrypter)
+	}
+
 	ownClusterID = os.Getenv(constants.OwnClusterIDEnvKey)
 	if ownClusterID == "" {
 		ownClusterID = constants.DefaultMasterClusterID
@@ -578,18 +722,21 @@ func (r *ReconcileSFServiceBinding) SetupWithManager(mgr ctrl.Manager) error {
 		}).
 		For(&osbv1alpha1.SFServiceBinding{})
 
-	// TODO dynamically setup rbac rules and watches
-	subresources := make([]runtime.Object, len(interoperatorCfg.InstanceContollerWatchList))
-	for i, gvk := range interoperatorCfg.BindingContollerWatchList {
-		object := &unstructured.Unstructured{}
-		object.SetKind(gvk.GetKind())
-		object.SetAPIVersion(gvk.GetAPIVersion())
-		subresources[i] = object
+	c, err := builder.Build(r)
+	if err != nil {
+		return err
 	}
 
-	for _, subresource := range subresources {
-		builder = builder.Owns(subresource)
+	// Watches on sub resources are refreshed when the watch list changes
+	watcher, err := watches.NewSubResourceWatcher(c, &osbv1alpha1.SFServiceBinding{}, func() []osbv1alpha1.APIVersionKind {
+		return cfgManager.GetConfig().BindingContollerWatchList
+	})
+	if err != nil {
+		return err
 	}
-
-	return builder.Complete(r)
+	err = watcher.Update(interoperatorCfg.BindingContollerWatchList)
+	if err != nil {
+		return err
+	}
+	return mgr.Add(watcher)
 }
@@ -18,14 +18,23 @@ package sfservicebinding
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/config"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/encryption"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/properties"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/resources/mock_resources"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/secretstore"
 	mock_clusterRegistry "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/cluster/registry/mock_registry"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
@@ -36,10 +45,12 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/util/retry"
 	ctrlrun "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
@@ -223,8 +234,8 @@ func TestReconcile(t *testing.T) {
 	mockResourceManager.EXPECT().ComputeExpectedResources(gomock.Any(), "instance-id", "binding-id", "service-id", "plan-id", osbv1alpha1.BindAction, "default").Return(expectedResources, nil).AnyTimes()
 	mockResourceManager.EXPECT().SetOwnerReference(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 	mockClusterRegistry.EXPECT().GetClient("1").Return(controller, nil).AnyTimes()
-	mockResourceManager.EXPECT().ReconcileResources(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(appliedResources, err1).Times(1)
-	mockResourceManager.EXPECT().ReconcileResources(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(appliedResources, nil).AnyTimes()
+	mockResourceManager.EXPECT().ReconcileResources(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(appliedResources, nil, err1).Times(1)
+	mockResourceManager.EXPECT().ReconcileResources(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(appliedResources, nil, nil).AnyTimes()
 	mockResourceManager.EXPECT().ComputeStatus(gomock.Any(), gomock.Any(), "instance-id", "binding-id", "service-id", "plan-id", osbv1alpha1.BindAction, "default").Return(&properties.Status{
 		Bind: properties.GenericStatus{
 			State:    "succeeded",
@@ -282,7 +293,6 @@ func TestReconcile(t *testing.T) {
 		serviceBinding.SetState("delete")
 		return c.Update(context.TODO(), serviceBinding)
 	})
-	g.Expect(c.Delete(context.TODO(), secret)).NotTo(gomega.HaveOccurred())
 
 	// Binding should disappear from api server
 	g.Eventually(func() error {
@@ -295,6 +305,9 @@ func TestReconcile(t *testing.T) {
 		}
 		return fmt.Errorf("not deleted")
 	}, timeout).Should(gomega.Succeed())
+
+	// The bind secret is deleted from the secret store
+	g.Expect(apierrors.IsNotFound(c.Get(context.TODO(), secretKey, secret))).To(gomega.BeTrue())
 }
 
 func TestReconcileSFServiceBinding_handleError(t *testing.T) {
@@ -559,3 +572,153 @@ func TestReconcileSFServiceBinding_updateUnbindStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestReconcileSFServiceBinding_handleResourcesError(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	testScheme := runtime.NewScheme()
+	g.Expect(osbv1alpha1.AddToScheme(testScheme)).To(gomega.Succeed())
+
+	binding := &osbv1alpha1.SFServiceBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "binding-id",
+			Namespace: "default",
+		},
+		Spec: osbv1alpha1.SFServiceBindingSpec{
+			ID:         "binding-id",
+			InstanceID: "instance-id",
+			ServiceID:  "service-id",
+			PlanID:     "plan-id",
+		},
+		Status: osbv1alpha1.SFServiceBindingStatus{
+			State: "in_queue",
+		},
+	}
+	r := &ReconcileSFServiceBinding{
+		Client: fake.NewFakeClientWithScheme(testScheme, binding),
+		Log:    testLog,
+		scheme: testScheme,
+	}
+	namespacedName := types.NamespacedName{Name: "binding-id", Namespace: "default"}
+	resources := []osbv1alpha1.Source{
+		{APIVersion: "v1", Kind: "Secret", Namespace: "default", Name: "secret"},
+	}
+
+	// Waiting for resources is not an error and keeps the state
+	result, err := r.handleResourcesError(binding, resources, errors.NewResourceNotReady("Secret", "default/secret", "waiting", nil), "in_queue")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(result).To(gomega.Equal(ctrlrun.Result{RequeueAfter: constants.ResourceWaitInterval}))
+	g.Expect(r.Get(context.TODO(), namespacedName, binding)).To(gomega.Succeed())
+	g.Expect(binding.GetState()).To(gomega.Equal("in_queue"))
+	g.Expect(binding.Status.Resources).To(gomega.Equal(resources))
+	g.Expect(binding.GetLabels()).NotTo(gomega.HaveKey(constants.ErrorCountKey))
+
+	// Other errors are counted and the resources applied so far are kept
+	resources = append(resources, osbv1alpha1.Source{APIVersion: "apps/v1", Kind: "StatefulSet", Namespace: "default", Name: "statefulset"})
+	timeoutErr := errors.NewWaitTimeout("StatefulSet", "default/statefulset", time.Minute, nil)
+	_, err = r.handleResourcesError(binding, resources, timeoutErr, "in_queue")
+	g.Expect(err).To(gomega.Equal(timeoutErr))
+	g.Expect(r.Get(context.TODO(), namespacedName, binding)).To(gomega.Succeed())
+	g.Expect(binding.Status.Resources).To(gomega.Equal(resources))
+	g.Expect(binding.GetLabels()).To(gomega.HaveKeyWithValue(constants.ErrorCountKey, "1"))
+}
+
+func TestReconcileSFServiceBinding_updateBindStatus_vault(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	testScheme := runtime.NewScheme()
+	g.Expect(osbv1alpha1.AddToScheme(testScheme)).To(gomega.Succeed())
+
+	// Minimal stand-in for the KV version 2 secrets engine, the secrets are
+	// kept by their read path below /v1
+	secrets := make(map[string]json.RawMessage)
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("X-Vault-Token") != "test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		secretPath := strings.TrimPrefix(req.URL.Path, "/v1/")
+		switch req.Method {
+		case http.MethodGet:
+			secret, ok := secrets[secretPath]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			fmt.Fprintf(w, `{"data":%s}`, secret)
+		case http.MethodPost:
+			secret, _ := ioutil.ReadAll(req.Body)
+			secrets[secretPath] = secret
+			w.Write([]byte(`{"data":{"version":1}}`))
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer vault.Close()
+	defer os.Setenv(constants.VaultTokenEnvKey, os.Getenv(constants.VaultTokenEnvKey))
+	os.Setenv(constants.VaultTokenEnvKey, "test-token")
+	secretStore, err := secretstore.NewVaultStore(config.VaultConfig{Address: vault.URL})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	encrypter, err := encryption.New(config.CredentialEncryptionConfig{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	binding := &osbv1alpha1.SFServiceBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "binding-id",
+			Namespace: "default",
+		},
+		Spec: osbv1alpha1.SFServiceBindingSpec{
+			ID:         "binding-id",
+			InstanceID: "instance-id",
+			ServiceID:  "service-id",
+			PlanID:     "plan-id",
+		},
+		Status: osbv1alpha1.SFServiceBindingStatus{
+			State: "in progress",
+		},
+	}
+	c := fake.NewFakeClientWithScheme(testScheme, binding)
+	mockResourceManager := mock_resources.NewMockResourceManager(ctrl)
+	r := &ReconcileSFServiceBinding{
+		Client:          c,
+		Log:             testLog,
+		scheme:          testScheme,
+		resourceManager: mockResourceManager,
+		secretStore:     secretStore,
+		encrypter:       encrypter,
+	}
+	mockResourceManager.EXPECT().
+		ComputeStatus(r, c, "instance-id", "binding-id", "service-id", "plan-id", osbv1alpha1.BindAction, "default").
+		Return(&properties.Status{
+			Bind: properties.GenericStatus{
+				State:    "succeeded",
+				Response: `{"credentials":{"password":"secret"}}`,
+			},
+		}, nil)
+
+	g.Expect(r.updateBindStatus(c, binding, 0)).To(gomega.Succeed())
+
+	boundBinding := &osbv1alpha1.SFServiceBinding{}
+	g.Expect(c.Get(context.TODO(), bindingKey, boundBinding)).To(gomega.Succeed())
+	g.Expect(boundBinding.GetState()).To(gomega.Equal("succeeded"))
+	secretRef := boundBinding.Status.Response.SecretRef
+	g.Expect(secretRef).To(gomega.Equal("vault:secret/data/interoperator/default/sf-binding-id"))
+	_, err = r.secretStore.Get(boundBinding, "sf-binding-id")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	// The broker reads the credentials from the path of the reference
+	req, err := http.NewRequest(http.MethodGet, vault.URL+"/v1/"+strings.TrimPrefix(secretRef, "vault:"), nil)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	req.Header.Set("X-Vault-Token", "test-token")
+	resp, err := http.DefaultClient.Do(req)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	defer resp.Body.Close()
+	g.Expect(resp.StatusCode).To(gomega.Equal(http.StatusOK))
+	secret := struct {
+		Data struct {
+			Data map[string]string `json:"data"`
+		} `json:"data"`
+	}{}
+	g.Expect(json.NewDecoder(resp.Body).Decode(&secret)).To(gomega.Succeed())
+	g.Expect(secret.Data.Data).To(gomega.HaveKeyWithValue("response", `{"credentials":{"password":"secret"}}`))
+}
@@ -31,12 +31,15 @@ import (
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/utils"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/watches"
 
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
 	apiErrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -52,6 +55,7 @@ type ReconcileSFServiceInstance struct {
 	scheme          *runtime.Scheme
 	clusterRegistry registry.ClusterRegistry
 	resourceManager resources.ResourceManager
+	recorder        record.EventRecorder
 }
 
 // Reconcile reads that state of the cluster for a SFServiceInstance object and makes changes based on the state read
@@ -63,6 +67,7 @@ type ReconcileSFServiceInstance struct {
 // +kubebuilder:rbac:groups=kubedb.com,resources=Postgres,verbs=*
 // +kubebuilder:rbac:groups=,resources=configmap,verbs=*
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=*
+// +kubebuilder:rbac:groups=,resources=events,verbs=create;patch
 // TODO dynamically setup rbac rules
 func (r *ReconcileSFServiceInstance) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	ctx := context.Background()
@@ -135,16 +140,20 @@ func (r *ReconcileSFServiceInstance) Reconcile(req ctrl.Request) (ctrl.Result, e
 		// so lets handle our external dependency
 		remainingResource, err := r.resourceManager.DeleteSubResources(targetClient, instance.Status.Resources)
 		if err != nil {
-			log.Error(err, "Delete sub resources failed")
-			return r.handleError(instance, ctrl.Result{}, err, state, 0)
+			return r.handleResourcesError(instance, remainingResource, err, state)
 		}
-		err = r.setInProgress(req.NamespacedName, state, remainingResource, 0)
+		err = r.setInProgress(req.NamespacedName, state, remainingResource, nil, 0)
 		if err != nil {
 			return r.handleError(instance, ctrl.Result{}, err, state, 0)
 		}
 		lastOperation = state
 	} else if state == "in_queue" || state == "update" {
-		expectedResources, err := r.resourceManager.ComputeExpectedResources(r, instanceID, bindingID, serviceID, planID, osbv1alpha1.ProvisionAction, instance.GetNamespace())
+		expectedResources, err := r.resourceManager.ComputeExpectedResources(r, instanceID, bindingID, serviceID, planID, getAction(state), instance.GetNamespace())
+		if err != nil && state == "update" && errors.TemplateNotFound(err) {
+			// Plans without update template are updated using the provision template
+			log.Info("Plan does not have update template, using provision template", "planID", planID)
+			expectedResources, err = r.resourceManager.ComputeExpectedResources(r, instanceID, bindingID, serviceID, planID, osbv1alpha1.ProvisionAction, instance.GetNamespace())
+		}
 		if err != nil {
 			return r.handleError(instance, ctrl.Result{}, err, state, 0)
 		}
@@ -154,15 +163,18 @@ func (r *ReconcileSFServiceInstance) Reconcile(req ctrl.Request) (ctrl.Result, e
 			return r.handleError(instance, ctrl.Result{}, err, state, 0)
 		}
 
-		resourceRefs, err := r.resourceManager.ReconcileResources(r, targetClient, expectedResources, instance.Status.Resources)
+		resourceRefs, prunedRefs, err := r.resourceManager.ReconcileResources(r, targetClient, expectedResources, instance.Status.Resources)
 		if err != nil {
-			log.Error(err, "ReconcileResources failed")
-			return r.handleError(instance, ctrl.Result{}, err, state, 0)
+			return r.handleResourcesError(instance, resourceRefs, err, state)
 		}
-		err = r.setInProgress(req.NamespacedName, state, resourceRefs, 0)
+		err = r.setInProgress(req.NamespacedName, state, resourceRefs, prunedRefs, 0)
 		if err != nil {
 			return r.handleError(instance, ctrl.Result{}, err, state, 0)
 		}
+		for _, pruned := range prunedRefs {
+			r.recorder.Eventf(instance, corev1.EventTypeNormal, "Pruned", "Deleted %s %s/%s which is no longer rendered",
+				pruned.Kind, pruned.Namespace, pruned.Name)
+		}
 		lastOperation = state
 	}
 
@@ -177,7 +189,11 @@ func (r *ReconcileSFServiceInstance) Reconcile(req ctrl.Request) (ctrl.Result, e
 		lastOperation = "in_queue"
 	}
 
-	if state == "in progress" {
+	if state == "in progress" && operationExpired(instance) {
+		if err := r.failOperation(instance, 0); err != nil {
+			return r.handleError(instance, ctrl.Result{}, err, lastOperation, 0)
+		}
+	} else if state == "in progress" {
 		if lastOperation == "delete" {
 			if err := r.updateDeprovisionStatus(targetClient, instance, 0); err != nil {
 				return r.handleError(instance, ctrl.Result{}, err, lastOperation, 0)
@@ -189,7 +205,7 @@ func (r *ReconcileSFServiceInstance) Reconcile(req ctrl.Request) (ctrl.Result, e
 			}
 		}
 	}
-	return r.handleError(instance, ctrl.Result{}, nil, lastOperation, 0)
+	return r.handleError(instance, operationResult(instance), nil, lastOperation, 0)
 }
 
 func (r *ReconcileSFServiceInstance) reconcileFinalizers(object *osbv1alpha1.SFServiceInstance, retryCount int) error {
@@ -232,7 +248,7 @@ func (r *ReconcileSFServiceInstance) reconcileFinalizers(object *osbv1alpha1.SFS
 	return nil
 }
 
-func (r *ReconcileSFServiceInstance) setInProgress(namespacedName types.NamespacedName, state string, resources []osbv1alpha1.Source, retryCount int) error {
+func (r *ReconcileSFServiceInstance) setInProgress(namespacedName types.NamespacedName, state string, resources []osbv1alpha1.Source, pruned []osbv1alpha1.Source, retryCount int) error {
 	ctx := context.Background()
 	log := r.Log.WithValues("sfserviceinstance", namespacedName)
 
@@ -242,7 +258,7 @@ func (r *ReconcileSFServiceInstance) setInProgress(namespacedName types.Namespac
 		if err != nil {
 			if retryCount < constants.ErrorThreshold {
 				log.Info("Retrying", "function", "setInProgress", "retryCount", retryCount+1, "objectID", namespacedName.Name)
-				return r.setInProgress(namespacedName, state, resources, retryCount+1)
+				return r.setInProgress(namespacedName, state, resources, pruned, retryCount+1)
 			}
 			log.Error(err, "Updating status to in progress failed", "instanceId", namespacedName.Name)
 			return err
@@ -255,11 +271,13 @@ func (r *ReconcileSFServiceInstance) setInProgress(namespacedName types.Namespac
 		labels[constants.LastOperationKey] = state
 		instance.SetLabels(labels)
 		instance.Status.Resources = resources
+		instance.Status.Pruned = pruned
+		r.startOperation(instance, state)
 		err = r.Update(ctx, instance)
 		if err != nil {
 			if retryCount < constants.ErrorThreshold {
 				log.Info("Retrying", "function", "setInProgress", "retryCount", retryCount+1, "objectID", namespacedName.Name)
-				return r.setInProgress(namespacedName, state, resources, retryCount+1)
+				return r.setInProgress(namespacedName, state, resources, pruned, retryCount+1)
 			}
 			log.Error(err, "Updating status to in progress failed", "instanceId", namespacedName.Name)
 			return err
@@ -363,11 +381,21 @@ func (r *ReconcileSFServiceInstance) updateStatus(targetClient client.Client, in
 	ctx := context.Background()
 	log := r.Log.WithValues("sfserviceinstance", instanceID)
 
-	computedStatus, err := r.resourceManager.ComputeStatus(r, targetClient, instanceID, bindingID, serviceID, planID, osbv1alpha1.ProvisionAction, namespace)
+	lastOperation, ok := instance.GetLabels()[constants.LastOperationKey]
+	if !ok {
+		lastOperation = "in_queue"
+	}
+	action := getAction(lastOperation)
+
+	computedStatus, err := r.resourceManager.ComputeStatus(r, targetClient, instanceID, bindingID, serviceID, planID, action, namespace)
 	if err != nil {
 		log.Error(err, "Compute status failed", "instance", instanceID)
 		return err
 	}
+	operationStatus := computedStatus.Provision
+	if action == osbv1alpha1.UpdateAction && computedStatus.Update.State != "" {
+		operationStatus = computedStatus.Update
+	}
 
 	// Fetch object again before updating status
 	namespacedName := types.NamespacedName{
@@ -380,14 +408,14 @@ func (r *ReconcileSFServiceInstance) updateStatus(targetClient client.Client, in
 		return err
 	}
 	updatedStatus := instance.Status.DeepCopy()
-	updatedStatus.State = computedStatus.Provision.State
-	updatedStatus.Error = computedStatus.Provision.Error
-	updatedStatus.Description = computedStatus.Provision.Response
-	updatedStatus.DashboardURL = computedStatus.Provision.DashboardURL
+	updatedStatus.State = operationStatus.State
+	updatedStatus.Error = operationStatus.Error
+	updatedStatus.Description = operationStatus.Response
+	updatedStatus.DashboardURL = operationStatus.DashboardURL
 
 	if !reflect.DeepEqual(&instance.Status, updatedStatus) {
 		updatedStatus.DeepCopyInto(&instance.Status)
-		log.Info("Updating provision status from template", "instance", namespacedName.Name)
+		log.Info("Updating "+action+" status from template", "instance", namespacedName.Name)
 		err = r.Update(ctx, instance)
 		if err != nil {
 			if retryCount < constants.ErrorThreshold {
@@ -401,6 +429,63 @@ func (r *ReconcileSFServiceInstance) updateStatus(targetClient client.Client, in
 	return nil
 }
 
+// getAction returns the template action for the operation
+func getAction(operation string) string {
+	if operation == "update" {
+		return osbv1alpha1.UpdateAction
+	}
+	return osbv1alpha1.ProvisionAction
+}
+
+// handleResourcesError records the resources to track when reconciling or
+// deleting the resources failed. The instance is requeued without counting an
+// error while resources are waited for.
+func (r *ReconcileSFServiceInstance) handleResourcesError(instance *osbv1alpha1.SFServiceInstance, resources []osbv1alpha1.Source, inputErr error, state string) (ctrl.Result, error) {
+	namespacedName := types.NamespacedName{
+		Name:      instance.GetName(),
+		Namespace: instance.GetNamespace(),
+	}
+	log := r.Log.WithValues("sfserviceinstance", namespacedName)
+
+	if resources != nil {
+		err := r.setResources(namespacedName, resources, 0)
+		if err != nil {
+			return r.handleError(instance, ctrl.Result{}, err, state, 0)
+		}
+	}
+	if errors.ResourceNotReady(inputErr) {
+		log.Info("Waiting for resources", "reason", inputErr.Error())
+		return ctrl.Result{RequeueAfter: constants.ResourceWaitInterval}, nil
+	}
+	log.Error(inputErr, "Reconciling resources failed")
+	return r.handleError(instance, ctrl.Result{}, inputErr, state, 0)
+}
+
+// setResources updates the resources to track without changing the state
+func (r *ReconcileSFServiceInstance) setResources(namespacedName types.NamespacedName, resources []osbv1alpha1.Source, retryCount int) error {
+	ctx := context.Background()
+	log := r.Log.WithValues("sfserviceinstance", namespacedName)
+
+	instance := &osbv1alpha1.SFServiceInstance{}
+	err := r.Get(ctx, namespacedName, instance)
+	if err == nil {
+		if reflect.DeepEqual(instance.Status.Resources, resources) {
+			return nil
+		}
+		instance.Status.Resources = resources
+		err = r.Update(ctx, instance)
+	}
+	if err != nil {
+		if retryCount < constants.ErrorThreshold {
+			log.Info("Retrying", "function", "setResources", "retryCount", retryCount+1, "objectID", namespacedName.Name)
+			return r.setResources(namespacedName, resources, retryCount+1)
+		}
+		log.Error(err, "Updating resources failed", "instanceId", namespacedName.Name)
+		return err
+	}
+	return nil
+}
+
 func (r *ReconcileSFServiceInstance) handleError(object *osbv1alpha1.SFServiceInstance, result ctrl.Result, inputErr error, lastOperation string, retryCount int) (ctrl.Result, error) {
 	objectID := object.GetName()
 	namespace := object.GetNamespace()
@@ -497,7 +582,7 @@ func (r *ReconcileSFServiceInstance) SetupWithManager(mgr ctrl.Manager) error {
 		r.Log = ctrl.Log.WithName("provisioners").WithName("instance")
 	}
 	if r.clusterRegistry == nil {
-		clusterRegistry, err := registry.New(mgr.GetConfig(), mgr.GetScheme(), mgr.GetRESTMapper())
+		clusterRegistry, err := registry.ForManager(mgr)
 		if err != nil {
 			return err
 		}
@@ -508,6 +593,10 @@ func (r *ReconcileSFServiceInstance) SetupWithManager(mgr ctrl.Manager) error {
 		r.resourceManager = resources.New()
 	}
 
+	if r.recorder == nil {
+		r.recorder = mgr.GetEventRecorderFor("instance")
+	}
+
 	if r.uncachedClient == nil {
 		uncachedClient, err := client.New(mgr.GetConfig(), client.Options{
 			Scheme: mgr.GetScheme(),
@@ -537,18 +626,21 @@ func (r *ReconcileSFServiceInstance) SetupWithManager(mgr ctrl.Manager) error {
 		}).
 		For(&osbv1alpha1.SFServiceInstance{})
 
-	// TODO dynamically setup rbac rules and watches
-	subresources := make([]runtime.Object, len(interoperatorCfg.InstanceContollerWatchList))
-	for i, gvk := range interoperatorCfg.InstanceContollerWatchList {
-		object := &unstructured.Unstructured{}
-		object.SetKind(gvk.GetKind())
-		object.SetAPIVersion(gvk.GetAPIVersion())
-		subresources[i] = object
+	c, err := builder.Build(r)
+	if err != nil {
+		return err
 	}
 
-	for _, subresource := range subresources {
-		builder = builder.Owns(subresource)
+	// Watches on sub resources are refreshed when the watch list changes
+	watcher, err := watches.NewSubResourceWatcher(c, &osbv1alpha1.SFServiceInstance{}, func() []osbv1alpha1.APIVersionKind {
+		return cfgManager.GetConfig().InstanceContollerWatchList
+	})
+	if err != nil {
+		return err
 	}
-
-	return builder.Complete(r)
+	err = watcher.Update(interoperatorCfg.InstanceContollerWatchList)
+	if err != nil {
+		return err
+	}
+	return mgr.Add(watcher)
 }
@@ -36,10 +36,12 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/util/retry"
 	ctrlrun "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
@@ -205,8 +207,8 @@ func TestReconcile(t *testing.T) {
 	mockResourceManager.EXPECT().ComputeExpectedResources(gomock.Any(), "instance-id", "", "service-id", "plan-id", osbv1alpha1.ProvisionAction, "default").Return(expectedResources, nil).AnyTimes()
 	mockResourceManager.EXPECT().SetOwnerReference(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 	mockClusterRegistry.EXPECT().GetClient("1").Return(controller, nil).AnyTimes()
-	mockResourceManager.EXPECT().ReconcileResources(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(appliedResources, err1).Times(1)
-	mockResourceManager.EXPECT().ReconcileResources(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(appliedResources, nil).AnyTimes()
+	mockResourceManager.EXPECT().ReconcileResources(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(appliedResources, nil, err1).Times(1)
+	mockResourceManager.EXPECT().ReconcileResources(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(appliedResources, nil, nil).AnyTimes()
 	mockResourceManager.EXPECT().ComputeStatus(gomock.Any(), gomock.Any(), "instance-id", "", "service-id", "plan-id", osbv1alpha1.ProvisionAction, "default").Return(&properties.Status{
 		Provision: properties.InstanceStatus{
 			State: "succeeded",
@@ -215,6 +217,16 @@ func TestReconcile(t *testing.T) {
 			State: "succeeded",
 		},
 	}, nil).AnyTimes()
+	mockResourceManager.EXPECT().ComputeExpectedResources(gomock.Any(), "instance-id", "", "service-id", "plan-id", osbv1alpha1.UpdateAction, "default").Return(nil, errors.NewTemplateNotFound(osbv1alpha1.UpdateAction, "plan-id", nil)).AnyTimes()
+	mockResourceManager.EXPECT().ComputeStatus(gomock.Any(), gomock.Any(), "instance-id", "", "service-id", "plan-id", osbv1alpha1.UpdateAction, "default").Return(&properties.Status{
+		Provision: properties.InstanceStatus{
+			State: "succeeded",
+		},
+		Update: properties.InstanceStatus{
+			State:    "succeeded",
+			Response: "updated",
+		},
+	}, nil).AnyTimes()
 	mockResourceManager.EXPECT().DeleteSubResources(gomock.Any(), gomock.Any()).Return(appliedResources, nil).AnyTimes()
 
 	g.Expect(controller.SetupWithManager(mgr)).NotTo(gomega.HaveOccurred())
@@ -248,6 +260,30 @@ func TestReconcile(t *testing.T) {
 	}, timeout).Should(gomega.Succeed())
 	g.Expect(serviceInstance.Status.State).Should(gomega.Equal("succeeded"))
 
+	// Update the service instance, the plan does not have update template
+	// and the status is read from the update section
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		err = c.Get(context.TODO(), instanceKey, serviceInstance)
+		if err != nil {
+			return err
+		}
+		serviceInstance.SetState("update")
+		return c.Update(context.TODO(), serviceInstance)
+	})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	g.Eventually(func() error {
+		err := c.Get(context.TODO(), instanceKey, serviceInstance)
+		if err != nil {
+			return err
+		}
+		if serviceInstance.GetState() != "succeeded" || serviceInstance.Status.Description != "updated" {
+			return fmt.Errorf("update status not updated")
+		}
+		return nil
+	}, timeout).Should(gomega.Succeed())
+	g.Expect(serviceInstance.GetLabels()).To(gomega.HaveKeyWithValue(constants.LastOperationKey, "update"))
+
 	// Delete the service instance
 	g.Expect(c.Delete(context.TODO(), instance)).NotTo(gomega.HaveOccurred())
 	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
@@ -392,3 +428,50 @@ func TestReconcileSFServiceInstance_handleError(t *testing.T) {
 		})
 	}
 }
+
+func TestReconcileSFServiceInstance_handleResourcesError(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	testScheme := runtime.NewScheme()
+	g.Expect(osbv1alpha1.AddToScheme(testScheme)).To(gomega.Succeed())
+
+	instance := &osbv1alpha1.SFServiceInstance{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "instance-id",
+			Namespace: "default",
+		},
+		Spec: osbv1alpha1.SFServiceInstanceSpec{
+			ServiceID: "service-id",
+			PlanID:    "plan-id",
+		},
+		Status: osbv1alpha1.SFServiceInstanceStatus{
+			State: "in_queue",
+		},
+	}
+	r := &ReconcileSFServiceInstance{
+		Client: fake.NewFakeClientWithScheme(testScheme, instance),
+		Log:    testLog,
+		scheme: testScheme,
+	}
+	namespacedName := types.NamespacedName{Name: "instance-id", Namespace: "default"}
+	resources := []osbv1alpha1.Source{
+		{APIVersion: "v1", Kind: "Secret", Namespace: "default", Name: "secret"},
+	}
+
+	// Waiting for resources is not an error and keeps the state
+	result, err := r.handleResourcesError(instance, resources, errors.NewResourceNotReady("Secret", "default/secret", "waiting", nil), "in_queue")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(result).To(gomega.Equal(ctrlrun.Result{RequeueAfter: constants.ResourceWaitInterval}))
+	g.Expect(r.Get(context.TODO(), namespacedName, instance)).To(gomega.Succeed())
+	g.Expect(instance.GetState()).To(gomega.Equal("in_queue"))
+	g.Expect(instance.Status.Resources).To(gomega.Equal(resources))
+	g.Expect(instance.GetLabels()).NotTo(gomega.HaveKey(constants.ErrorCountKey))
+
+	// Other errors are counted and the resources applied so far are kept
+	resources = append(resources, osbv1alpha1.Source{APIVersion: "apps/v1", Kind: "StatefulSet", Namespace: "default", Name: "statefulset"})
+	timeoutErr := errors.NewWaitTimeout("StatefulSet", "default/statefulset", time.Minute, nil)
+	_, err = r.handleResourcesError(instance, resources, timeoutErr, "in_queue")
+	g.Expect(err).To(gomega.Equal(timeoutErr))
+	g.Expect(r.Get(context.TODO(), namespacedName, instance)).To(gomega.Succeed())
+	g.Expect(instance.Status.Resources).To(gomega.Equal(resources))
+	g.Expect(instance.GetLabels()).To(gomega.HaveKeyWithValue(constants.ErrorCountKey, "1"))
+}
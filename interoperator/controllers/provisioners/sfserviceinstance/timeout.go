@@ -0,0 +1,89 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sfserviceinstance
+
+import (
+	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/timeouts"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// getOperation returns the plan operation for the state requested by the broker
+func getOperation(state string) string {
+	switch state {
+	case "update":
+		return osbv1alpha1.UpdateOperation
+	case "delete":
+		return osbv1alpha1.DeprovisionOperation
+	}
+	return osbv1alpha1.ProvisionOperation
+}
+
+// startOperation records the start of the operation requested by state and
+// its deadline if the plan declares a timeout for the operation
+func (r *ReconcileSFServiceInstance) startOperation(instance *osbv1alpha1.SFServiceInstance, state string) {
+	operation := getOperation(state)
+	timeout, err := timeouts.ForOperation(r, instance.Spec.ServiceID, instance.Spec.PlanID, operation)
+	if err != nil {
+		r.Log.Error(err, "failed to get plan timeout. ignoring", "instance", instance.GetName(),
+			"operation", operation)
+		timeout = 0
+	}
+	instance.Status.OperationStartedAt, instance.Status.OperationDeadline = timeouts.Start(timeout)
+}
+
+// currentOperation returns the operation of the instance requested by the
+// last operation label
+func currentOperation(instance *osbv1alpha1.SFServiceInstance) *timeouts.Operation {
+	lastOperation, ok := instance.GetLabels()[constants.LastOperationKey]
+	if !ok {
+		lastOperation = "in_queue"
+	}
+	return &timeouts.Operation{
+		Kind:       "SFServiceInstance",
+		Type:       getOperation(lastOperation),
+		PlanID:     instance.Spec.PlanID,
+		InProgress: instance.GetState() == "in progress",
+		StartedAt:  instance.Status.OperationStartedAt,
+		Deadline:   instance.Status.OperationDeadline,
+	}
+}
+
+// operationExpired is true if the instance is still in progress after the
+// deadline of its operation
+func operationExpired(instance *osbv1alpha1.SFServiceInstance) bool {
+	return currentOperation(instance).Expired()
+}
+
+// operationResult requeues the instance at the deadline of its operation
+func operationResult(instance *osbv1alpha1.SFServiceInstance) ctrl.Result {
+	return currentOperation(instance).Result()
+}
+
+// failOperation marks the instance failed once the deadline of its
+// operation passed
+func (r *ReconcileSFServiceInstance) failOperation(instance *osbv1alpha1.SFServiceInstance, retryCount int) error {
+	return timeouts.Fail(r, r.recorder, instance, func() *timeouts.Operation {
+		return currentOperation(instance)
+	}, func(err error) {
+		instance.SetState("failed")
+		instance.Status.Error = err.Error()
+		instance.Status.Description = err.Error()
+	}, retryCount)
+}
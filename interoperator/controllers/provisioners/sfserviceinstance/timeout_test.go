@@ -0,0 +1,118 @@
+/*
+Copyright 2018 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sfserviceinstance
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcileSFServiceInstance_operationTimeout(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	testScheme := runtime.NewScheme()
+	g.Expect(osbv1alpha1.AddToScheme(testScheme)).To(gomega.Succeed())
+
+	labels := map[string]string{
+		"serviceId": "service-id",
+		"planId":    "plan-id",
+	}
+	service := &osbv1alpha1.SFService{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "service-id",
+			Namespace: "default",
+			Labels:    labels,
+		},
+		Spec: osbv1alpha1.SFServiceSpec{
+			ID: "service-id",
+		},
+	}
+	plan := &osbv1alpha1.SFPlan{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "plan-id",
+			Namespace: "default",
+			Labels:    labels,
+		},
+		Spec: osbv1alpha1.SFPlanSpec{
+			ID:        "plan-id",
+			ServiceID: "service-id",
+			Timeouts: &osbv1alpha1.OperationTimeouts{
+				Provision: "20m",
+			},
+		},
+	}
+	instance := &osbv1alpha1.SFServiceInstance{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "instance-id",
+			Namespace: "default",
+			Labels: map[string]string{
+				constants.LastOperationKey: "in_queue",
+			},
+		},
+		Spec: osbv1alpha1.SFServiceInstanceSpec{
+			ServiceID: "service-id",
+			PlanID:    "plan-id",
+		},
+		Status: osbv1alpha1.SFServiceInstanceStatus{
+			State: "in progress",
+		},
+	}
+	recorder := record.NewFakeRecorder(10)
+	r := &ReconcileSFServiceInstance{
+		Client:   fake.NewFakeClientWithScheme(testScheme, service, plan, instance),
+		Log:      testLog,
+		scheme:   testScheme,
+		recorder: recorder,
+	}
+
+	// The provision times out after the timeout of the plan
+	r.startOperation(instance, "in_queue")
+	g.Expect(instance.Status.OperationDeadline).NotTo(gomega.BeNil())
+	g.Expect(instance.Status.OperationDeadline.Sub(instance.Status.OperationStartedAt.Time)).To(gomega.Equal(time.Minute * 20))
+	g.Expect(operationExpired(instance)).To(gomega.BeFalse())
+	g.Expect(operationResult(instance).RequeueAfter).To(gomega.BeNumerically(">", 0))
+
+	// The update has no deadline without timeout of the plan
+	r.startOperation(instance, "update")
+	g.Expect(instance.Status.OperationStartedAt).NotTo(gomega.BeNil())
+	g.Expect(instance.Status.OperationDeadline).To(gomega.BeNil())
+	g.Expect(operationExpired(instance)).To(gomega.BeFalse())
+	g.Expect(operationResult(instance)).To(gomega.BeZero())
+
+	// The instance is failed after the deadline
+	startedAt := metav1.NewTime(time.Now().Add(-time.Minute * 21))
+	deadline := metav1.NewTime(startedAt.Add(time.Minute * 20))
+	instance.Status.OperationStartedAt = &startedAt
+	instance.Status.OperationDeadline = &deadline
+	g.Expect(r.Update(context.TODO(), instance)).NotTo(gomega.HaveOccurred())
+	g.Expect(operationExpired(instance)).To(gomega.BeTrue())
+	g.Expect(r.failOperation(instance, 0)).NotTo(gomega.HaveOccurred())
+	g.Expect(instance.GetState()).To(gomega.Equal("failed"))
+	g.Expect(instance.Status.Error).To(gomega.Equal("provision operation timed out after 20m0s"))
+	g.Expect(instance.Status.Description).To(gomega.Equal(instance.Status.Error))
+	g.Expect(recorder.Events).To(gomega.Receive(gomega.Equal("Warning OperationTimeout provision operation timed out after 20m0s")))
+	g.Expect(operationExpired(instance)).To(gomega.BeFalse())
+}
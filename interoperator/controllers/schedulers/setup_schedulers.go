@@ -20,8 +20,10 @@ package schedulers
 
 import (
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/controllers/schedulers/sfdefaultscheduler"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/controllers/schedulers/sfframeworkscheduler"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/controllers/schedulers/sflabelselectorscheduler"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/controllers/schedulers/sfleastutilizedscheduler"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/controllers/schedulers/sfresourceawarescheduler"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/controllers/schedulers/sfroundrobinscheduler"
 
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -64,5 +66,21 @@ func SetupWithManager(mgr ctrl.Manager) error {
 		return err
 	}
 
+	if err = (&sfresourceawarescheduler.SFResourceAwareScheduler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("schedulers").WithName("resourceaware"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create scheduler", "scheduler", "SFResourceAwareScheduler")
+		return err
+	}
+
+	if err = (&sfframeworkscheduler.SFFrameworkScheduler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("schedulers").WithName("framework"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create scheduler", "scheduler", "SFFrameworkScheduler")
+		return err
+	}
+
 	return nil
 }
@@ -18,14 +18,20 @@ package sfdefaultscheduler
 
 import (
 	"context"
+	"time"
 
 	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/config"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/scheduler"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/cluster/registry"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
 
 	"github.com/go-logr/logr"
-	"k8s.io/apimachinery/pkg/api/errors"
+	corev1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -33,12 +39,20 @@ import (
 // SFDefaultScheduler schedules an SFServiceInstance to the default cluster
 type SFDefaultScheduler struct {
 	client.Client
-	Log    logr.Logger
-	scheme *runtime.Scheme
+	Log             logr.Logger
+	scheme          *runtime.Scheme
+	clusterRegistry registry.ClusterRegistry
+	recorder        record.EventRecorder
 }
 
+// schedulingRetryInterval is the delay before an instance which could not be
+// scheduled to the default cluster is retried
+const schedulingRetryInterval = 30 * time.Second
+
 // Reconcile schedules the SFServiceInstance to the default SFCluster and sets the
-// ClusterID in SFServiceInstance.Spec.ClusterID.
+// ClusterID in SFServiceInstance.Spec.ClusterID. The instance is not scheduled
+// if the default SFCluster has a NoSchedule taint which it does not tolerate,
+// a warning event is recorded on it and scheduling is retried later.
 func (r *SFDefaultScheduler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	ctx := context.Background()
 	log := r.Log.WithValues("sfserviceinstance", req.NamespacedName)
@@ -47,7 +61,7 @@ func (r *SFDefaultScheduler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	instance := &osbv1alpha1.SFServiceInstance{}
 	err := r.Get(ctx, req.NamespacedName, instance)
 	if err != nil {
-		if errors.IsNotFound(err) {
+		if apiErrors.IsNotFound(err) {
 			// Object not found, return.  Created objects are automatically garbage collected.
 			// For additional cleanup logic use finalizers.
 			return ctrl.Result{}, nil
@@ -56,7 +70,18 @@ func (r *SFDefaultScheduler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return ctrl.Result{}, err
 	}
 	if instance.Spec.ClusterID == "" {
-		instance.Spec.ClusterID = constants.DefaultMasterClusterID
+		clusterID, err := r.schedule(instance)
+		if err != nil {
+			log.Error(err, "Failed to schedule", "planID", instance.Spec.PlanID)
+			if errors.SchedulerFailed(err) {
+				if r.recorder != nil {
+					r.recorder.Event(instance, corev1.EventTypeWarning, "SchedulingFailed", err.Error())
+				}
+				return ctrl.Result{RequeueAfter: schedulingRetryInterval}, nil
+			}
+			return ctrl.Result{}, err
+		}
+		instance.Spec.ClusterID = clusterID
 		if err := r.Update(context.Background(), instance); err != nil {
 			log.Error(err, "failed to set cluster id")
 			return ctrl.Result{}, err
@@ -65,20 +90,49 @@ func (r *SFDefaultScheduler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	return ctrl.Result{}, nil
 }
 
+func (r *SFDefaultScheduler) schedule(sfServiceInstance *osbv1alpha1.SFServiceInstance) (string, error) {
+	f, err := scheduler.NewForSchedulerType(r, nil, r.clusterRegistry, constants.DefaultSchedulerType)
+	if err != nil {
+		return "", err
+	}
+	clusterID, err := f.Schedule(sfServiceInstance)
+	if errors.ClusterRegistryError(err) {
+		// No SFCluster is registered for the default cluster in single
+		// cluster deployments, so there are no taints to honour
+		return constants.DefaultMasterClusterID, nil
+	}
+	if errors.SchedulerFailed(err) {
+		// Only taints on the default SFCluster can keep an instance from
+		// being scheduled to it. If it is not registered under that name
+		// there are no taints to honour either.
+		_, getErr := r.clusterRegistry.GetCluster(constants.DefaultMasterClusterID)
+		if errors.SFClusterNotFound(getErr) {
+			return constants.DefaultMasterClusterID, nil
+		}
+	}
+	return clusterID, err
+}
+
 // SetupWithManager registers the default scheduler with manager
 // add setups the watches.
 func (r *SFDefaultScheduler) SetupWithManager(mgr ctrl.Manager) error {
+	clusterRegistry, err := registry.ForManager(mgr)
+	if err != nil {
+		return err
+	}
+	r.clusterRegistry = clusterRegistry
+
 	cfgManager, err := config.New(mgr.GetConfig(), mgr.GetScheme(), mgr.GetRESTMapper())
 	if err != nil {
 		return err
 	}
 	interoperatorCfg := cfgManager.GetConfig()
-
 	if interoperatorCfg.SchedulerType != constants.DefaultSchedulerType {
 		return nil
 	}
 
 	r.scheme = mgr.GetScheme()
+	r.recorder = mgr.GetEventRecorderFor("scheduler_default")
 
 	return ctrl.NewControllerManagedBy(mgr).
 		Named("scheduler_default").
@@ -18,19 +18,28 @@ package sfdefaultscheduler
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
 	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
 	resourcev1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/resource/v1alpha1"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/scheduler/schedulertest"
+	mock_clusterRegistry "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/cluster/registry/mock_registry"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
 
+	"github.com/golang/mock/gomock"
 	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
@@ -106,3 +115,168 @@ func TestReconcile(t *testing.T) {
 	defer c.Delete(context.TODO(), instance)
 
 }
+
+func TestSFDefaultScheduler_schedule(t *testing.T) {
+	plan := schedulertest.NewSFPlan("plan-id")
+	tolerating := schedulertest.NewSFPlan("plan-tolerating")
+	tolerating.Spec.Tolerations = []corev1.Toleration{
+		{
+			Key:      "maintenance",
+			Operator: corev1.TolerationOpExists,
+		},
+	}
+
+	maintenance := schedulertest.NewSFCluster("1", corev1.ConditionTrue)
+	maintenance.Spec.Taints = []corev1.Taint{
+		{
+			Key:    "maintenance",
+			Effect: corev1.TaintEffectNoSchedule,
+		},
+	}
+
+	tests := []struct {
+		name     string
+		planID   string
+		clusters []resourcev1alpha1.SFCluster
+		want     string
+		wantErr  func(error) bool
+	}{
+		{
+			name: "choose the default cluster",
+			clusters: []resourcev1alpha1.SFCluster{
+				schedulertest.NewSFCluster("2", corev1.ConditionTrue),
+				schedulertest.NewSFCluster("1", corev1.ConditionTrue),
+			},
+			want: constants.DefaultMasterClusterID,
+		},
+		{
+			name:     "choose the default cluster if no cluster is registered",
+			clusters: nil,
+			want:     constants.DefaultMasterClusterID,
+		},
+		{
+			name: "choose the default cluster if it is not registered under its name",
+			clusters: []resourcev1alpha1.SFCluster{
+				schedulertest.NewSFCluster("2", corev1.ConditionTrue),
+			},
+			want: constants.DefaultMasterClusterID,
+		},
+		{
+			name: "fail if the default cluster has an untolerated NoSchedule taint",
+			clusters: []resourcev1alpha1.SFCluster{
+				maintenance,
+				schedulertest.NewSFCluster("2", corev1.ConditionTrue),
+			},
+			wantErr: errors.SchedulerFailed,
+		},
+		{
+			name:   "choose the default cluster if the plan tolerates its taints",
+			planID: "plan-tolerating",
+			clusters: []resourcev1alpha1.SFCluster{
+				maintenance,
+				schedulertest.NewSFCluster("2", corev1.ConditionTrue),
+			},
+			want: constants.DefaultMasterClusterID,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockClusterRegistry := mock_clusterRegistry.NewMockClusterRegistry(mockCtrl)
+			mockClusterRegistry.EXPECT().ListClusters(gomock.Any()).
+				Return(schedulertest.NewSFClusterList(tt.clusters...), nil).Times(1)
+			mockClusterRegistry.EXPECT().GetCluster(constants.DefaultMasterClusterID).
+				DoAndReturn(getCluster(tt.clusters)).AnyTimes()
+
+			r := &SFDefaultScheduler{
+				Client:          fake.NewFakeClientWithScheme(scheme.Scheme, plan.DeepCopy(), tolerating.DeepCopy()),
+				Log:             ctrl.Log.WithName("schedulers").WithName("default"),
+				clusterRegistry: mockClusterRegistry,
+			}
+			planID := tt.planID
+			if planID == "" {
+				planID = "plan-id"
+			}
+			got, err := r.schedule(schedulertest.NewSFServiceInstance("foo", planID))
+			if tt.wantErr != nil {
+				if !tt.wantErr(err) {
+					t.Errorf("SFDefaultScheduler.schedule() error = %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("SFDefaultScheduler.schedule() error = %v", err)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("SFDefaultScheduler.schedule() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func getCluster(clusters []resourcev1alpha1.SFCluster) func(string) (resourcev1alpha1.SFClusterInterface, error) {
+	return func(clusterID string) (resourcev1alpha1.SFClusterInterface, error) {
+		for i := range clusters {
+			if clusters[i].GetName() == clusterID {
+				return &clusters[i], nil
+			}
+		}
+		return nil, errors.NewSFClusterNotFound(clusterID, nil)
+	}
+}
+
+func TestSFDefaultScheduler_ReconcileTainted(t *testing.T) {
+	plan := schedulertest.NewSFPlan("plan-id")
+	instance := schedulertest.NewSFServiceInstance("foo", "plan-id")
+
+	maintenance := schedulertest.NewSFCluster("1", corev1.ConditionTrue)
+	maintenance.Spec.Taints = []corev1.Taint{
+		{
+			Key:    "maintenance",
+			Effect: corev1.TaintEffectNoSchedule,
+		},
+	}
+	clusters := []resourcev1alpha1.SFCluster{maintenance}
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockClusterRegistry := mock_clusterRegistry.NewMockClusterRegistry(mockCtrl)
+	mockClusterRegistry.EXPECT().ListClusters(gomock.Any()).
+		Return(schedulertest.NewSFClusterList(clusters...), nil).Times(1)
+	mockClusterRegistry.EXPECT().GetCluster(constants.DefaultMasterClusterID).
+		DoAndReturn(getCluster(clusters)).Times(1)
+
+	recorder := record.NewFakeRecorder(1)
+	r := &SFDefaultScheduler{
+		Client:          fake.NewFakeClientWithScheme(scheme.Scheme, plan, instance),
+		Log:             ctrl.Log.WithName("schedulers").WithName("default"),
+		clusterRegistry: mockClusterRegistry,
+		recorder:        recorder,
+	}
+	key := types.NamespacedName{Name: instance.GetName(), Namespace: instance.GetNamespace()}
+	result, err := r.Reconcile(reconcile.Request{NamespacedName: key})
+	if err != nil {
+		t.Fatalf("SFDefaultScheduler.Reconcile() error = %v", err)
+	}
+	if result.RequeueAfter != schedulingRetryInterval {
+		t.Errorf("SFDefaultScheduler.Reconcile() = %v, want requeue after %v", result, schedulingRetryInterval)
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.HasPrefix(event, "Warning SchedulingFailed") {
+			t.Errorf("SFDefaultScheduler.Reconcile() recorded event %q", event)
+		}
+	default:
+		t.Errorf("SFDefaultScheduler.Reconcile() did not record an event")
+	}
+
+	got := &osbv1alpha1.SFServiceInstance{}
+	if err := r.Get(context.TODO(), key, got); err != nil {
+		t.Fatalf("failed to get instance: %v", err)
+	}
+	if got.Spec.ClusterID != "" {
+		t.Errorf("SFDefaultScheduler.Reconcile() scheduled instance to %s", got.Spec.ClusterID)
+	}
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sfframeworkscheduler
+
+import (
+	"context"
+
+	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/config"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/scheduler"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/cluster/registry"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
+
+	"github.com/go-logr/logr"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SFFrameworkScheduler schedules SFServiceInstances using the filter
+// and score plugins configured in the interoperator config
+type SFFrameworkScheduler struct {
+	client.Client
+	Log             logr.Logger
+	scheme          *runtime.Scheme
+	clusterRegistry registry.ClusterRegistry
+	apiReader       client.Reader
+	plugins         config.SchedulerPluginsConfig
+}
+
+// Reconcile schedules the SFServiceInstance to one SFCluster and sets the ClusterID in
+// SFServiceInstance.Spec.ClusterID. The clusters are filtered and ranked by the plugins
+// listed in the schedulerPlugins section of the interoperator config.
+func (r *SFFrameworkScheduler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("sfserviceinstance", req.NamespacedName)
+
+	instance := &osbv1alpha1.SFServiceInstance{}
+	err := r.Get(ctx, req.NamespacedName, instance)
+	if err != nil {
+		if apiErrors.IsNotFound(err) {
+			// Object not found, return.  Created objects are automatically garbage collected.
+			// For additional cleanup logic use finalizers.
+			return ctrl.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		return ctrl.Result{}, err
+	}
+
+	if instance.Spec.ClusterID == "" {
+		clusterID, err := r.schedule(instance)
+		if err != nil {
+			log.Error(err, "Failed to schedule", "planID", instance.Spec.PlanID)
+			if errors.SchedulerFailed(err) {
+				return ctrl.Result{}, nil
+			}
+			return ctrl.Result{}, err
+		}
+
+		if clusterID != "" {
+			log.Info("Setting clusterID", "clusterID", clusterID)
+			err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+				err = r.Get(ctx, req.NamespacedName, instance)
+				if err != nil {
+					return err
+				}
+				instance.Spec.ClusterID = clusterID
+				return r.Update(ctx, instance)
+			})
+			if err != nil {
+				log.Error(err, "Failed to set cluster id", "clusterID", clusterID)
+				return ctrl.Result{}, err
+			}
+			err = r.reserve(instance, clusterID)
+			if err != nil {
+				log.Error(err, "Failed to reserve cluster", "clusterID", clusterID)
+			}
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func (r *SFFrameworkScheduler) schedule(sfServiceInstance *osbv1alpha1.SFServiceInstance) (string, error) {
+	f, err := scheduler.New(r, r.apiReader, r.clusterRegistry, constants.FrameworkSchedulerType, r.plugins)
+	if err != nil {
+		return "", err
+	}
+	return f.Schedule(sfServiceInstance)
+}
+
+func (r *SFFrameworkScheduler) reserve(sfServiceInstance *osbv1alpha1.SFServiceInstance, clusterID string) error {
+	f, err := scheduler.New(r, r.apiReader, r.clusterRegistry, constants.FrameworkSchedulerType, r.plugins)
+	if err != nil {
+		return err
+	}
+	return f.Reserve(sfServiceInstance, clusterID)
+}
+
+// SetupWithManager registers the framework scheduler with manager
+// and setups the watches.
+func (r *SFFrameworkScheduler) SetupWithManager(mgr ctrl.Manager) error {
+	clusterRegistry, err := registry.ForManager(mgr)
+	if err != nil {
+		return err
+	}
+	r.clusterRegistry = clusterRegistry
+	r.apiReader = mgr.GetAPIReader()
+
+	cfgManager, err := config.New(mgr.GetConfig(), mgr.GetScheme(), mgr.GetRESTMapper())
+	if err != nil {
+		return err
+	}
+	interoperatorCfg := cfgManager.GetConfig()
+	if interoperatorCfg.SchedulerType != constants.FrameworkSchedulerType {
+		return nil
+	}
+	r.plugins = interoperatorCfg.SchedulerPlugins
+
+	// Fail early if the plugins are not configured correctly
+	_, err = scheduler.New(r, r.apiReader, r.clusterRegistry, constants.FrameworkSchedulerType, r.plugins)
+	if err != nil {
+		r.Log.Error(err, "Invalid scheduler plugins config", "schedulerPlugins", r.plugins)
+		return err
+	}
+
+	r.scheme = mgr.GetScheme()
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("scheduler_framework").
+		For(&osbv1alpha1.SFServiceInstance{}).
+		Complete(r)
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sfframeworkscheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/scheduler/schedulertest"
+	mock_clusterRegistry "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/cluster/registry/mock_registry"
+
+	"github.com/golang/mock/gomock"
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	ctrlrun "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+var c client.Client
+
+const timeout = time.Second * 5
+
+const schedulerConfig = `schedulerType: framework
+schedulerPlugins:
+  filters:
+  - health
+  - capacity
+  scores:
+  - name: headroom
+    weight: 2
+  - name: utilization`
+
+func TestReconcile(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	configMap := schedulertest.NewConfigMap(schedulerConfig)
+
+	mgr, err := manager.New(cfg, manager.Options{
+		MetricsBindAddress: "0",
+	})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	c = mgr.GetClient()
+
+	mockClusterRegistry := mock_clusterRegistry.NewMockClusterRegistry(ctrl)
+
+	g.Expect(c.Create(context.TODO(), configMap)).NotTo(gomega.HaveOccurred())
+	defer c.Delete(context.TODO(), configMap)
+
+	plan := schedulertest.NewSFPlan("plan-id")
+	plan.Spec.ResourceRequests = schedulertest.ResourceList("2", "4Gi")
+	g.Expect(c.Create(context.TODO(), plan)).NotTo(gomega.HaveOccurred())
+	defer c.Delete(context.TODO(), plan)
+
+	scheduler := &SFFrameworkScheduler{
+		Client: mgr.GetClient(),
+		Log:    ctrlrun.Log.WithName("schedulers").WithName("framework"),
+	}
+	g.Expect(scheduler.SetupWithManager(mgr)).NotTo(gomega.HaveOccurred())
+	scheduler.clusterRegistry = mockClusterRegistry
+	stopMgr, mgrStopped := StartTestManager(mgr, g)
+
+	defer func() {
+		close(stopMgr)
+		mgrStopped.Wait()
+	}()
+
+	// Cluster 1 is not ready, cluster 2 does not have enough memory
+	// and cluster 3 is the only feasible cluster
+	sfcluster1 := schedulertest.NewSFClusterWithResources("1", "8", "16Gi", "0", "0")
+	sfcluster1.Status.Conditions[0].Status = corev1.ConditionFalse
+	sfcluster2 := schedulertest.NewSFClusterWithResources("2", "8", "16Gi", "2", "14Gi")
+	sfcluster3 := schedulertest.NewSFClusterWithResources("3", "8", "16Gi", "2", "4Gi")
+
+	mockClusterRegistry.EXPECT().ListClusters(&client.ListOptions{}).
+		Return(schedulertest.NewSFClusterList(sfcluster1, sfcluster2, sfcluster3), nil).Times(1)
+
+	instance1 := schedulertest.NewSFServiceInstance("foo1", "plan-id")
+	g.Expect(c.Create(context.TODO(), instance1)).NotTo(gomega.HaveOccurred())
+	defer c.Delete(context.TODO(), instance1)
+
+	g.Eventually(func() error {
+		err := c.Get(context.TODO(), schedulertest.Key(instance1), instance1)
+		if err != nil {
+			return err
+		}
+		_, err = instance1.GetClusterID()
+		if err != nil {
+			return err
+		}
+		return nil
+	}, timeout).Should(gomega.Succeed())
+	g.Expect(instance1.Spec.ClusterID).To(gomega.Equal(sfcluster3.GetName()))
+
+	// No cluster is feasible, instance is not scheduled
+	mockClusterRegistry.EXPECT().ListClusters(&client.ListOptions{}).
+		Return(schedulertest.NewSFClusterList(sfcluster1, sfcluster2), nil).Times(1)
+
+	instance2 := schedulertest.NewSFServiceInstance("foo2", "plan-id")
+	g.Expect(c.Create(context.TODO(), instance2)).NotTo(gomega.HaveOccurred())
+	defer c.Delete(context.TODO(), instance2)
+
+	g.Consistently(func() string {
+		err := c.Get(context.TODO(), schedulertest.Key(instance2), instance2)
+		if err != nil {
+			return err.Error()
+		}
+		return instance2.Spec.ClusterID
+	}, time.Second*2).Should(gomega.BeEmpty())
+}
+
+func TestSetupWithManagerInvalidPlugins(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	configMap := schedulertest.NewConfigMap(`schedulerType: framework
+schedulerPlugins:
+  filters:
+  - unknown`)
+
+	mgr, err := manager.New(cfg, manager.Options{
+		MetricsBindAddress: "0",
+	})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	c = mgr.GetClient()
+
+	g.Expect(k8sClient.Create(context.TODO(), configMap)).NotTo(gomega.HaveOccurred())
+	defer k8sClient.Delete(context.TODO(), configMap)
+
+	scheduler := &SFFrameworkScheduler{
+		Client: mgr.GetClient(),
+		Log:    ctrlrun.Log.WithName("schedulers").WithName("framework"),
+	}
+	g.Expect(scheduler.SetupWithManager(mgr)).To(gomega.HaveOccurred())
+}
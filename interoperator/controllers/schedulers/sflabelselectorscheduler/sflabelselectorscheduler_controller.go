@@ -18,15 +18,10 @@ package sflabelselectorscheduler
 
 import (
 	"context"
-	"math"
-	"os"
-	"strings"
 
 	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
-	resourcev1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/resource/v1alpha1"
-
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/config"
-	rendererFactory "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/renderer/factory"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/scheduler"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/cluster/registry"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
@@ -34,9 +29,7 @@ import (
 	"k8s.io/client-go/util/retry"
 
 	"github.com/go-logr/logr"
-	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -69,15 +62,9 @@ func (r *SFLabelSelectorScheduler) Reconcile(req ctrl.Request) (ctrl.Result, err
 	}
 
 	if instance.Spec.ClusterID == "" {
-		labelSelector, err := getLabelSelectorString(instance, r)
+		clusterID, err := r.schedule(instance)
 		if err != nil {
-			log.Info("Failed to get labelSelector string..", "error", err, "labelSelector", labelSelector)
-			return ctrl.Result{}, err
-		}
-
-		clusterID, err := r.schedule(instance, labelSelector)
-		if err != nil {
-			log.Error(err, "Failed to schedule ", "labelSelector", labelSelector, "clusterID", clusterID)
+			log.Error(err, "Failed to schedule ", "planID", instance.Spec.PlanID)
 			if errors.SchedulerFailed(err) {
 				return ctrl.Result{}, nil
 			}
@@ -104,135 +91,18 @@ func (r *SFLabelSelectorScheduler) Reconcile(req ctrl.Request) (ctrl.Result, err
 	return ctrl.Result{}, nil
 }
 
-func getLabelSelectorString(sfServiceInstance *osbv1alpha1.SFServiceInstance, r *SFLabelSelectorScheduler) (string, error) {
-	log := r.Log.WithValues("instance", sfServiceInstance.GetName())
-	ctx := context.Background()
-
-	sfNamespace := os.Getenv(constants.NamespaceEnvKey)
-	if sfNamespace == "" {
-		sfNamespace = constants.DefaultServiceFabrikNamespace
-	}
-	plan := &osbv1alpha1.SFPlan{}
-	namespacedName := types.NamespacedName{
-		Name:      sfServiceInstance.Spec.PlanID,
-		Namespace: sfNamespace,
-	}
-	err := r.Get(ctx, namespacedName, plan)
-	if err != nil {
-		return "", err
-	}
-	service := &osbv1alpha1.SFService{}
-	namespacedName = types.NamespacedName{
-		Name:      sfServiceInstance.Spec.ServiceID,
-		Namespace: sfNamespace,
-	}
-	err = r.Get(ctx, namespacedName, service)
-	if err != nil {
-		return "", err
-	}
-
-	labelSelectorTemplate, err := plan.GetTemplate(osbv1alpha1.ClusterLabelSelectorAction)
-	if err != nil {
-		if errors.TemplateNotFound(err) {
-			log.Info("Plan does not have clusterSelector template", "Plan", sfServiceInstance.Spec.PlanID)
-			// don't return error here. In cases when clusterSelector is not provided, scheduling should happen with least utilized cluster strategy
-			return "", nil
-		}
-		return "", err
-	}
-
-	if labelSelectorTemplate.Type != constants.GoTemplateType {
-		log.Info("Plan does not have clusterSelector gotemplate", "Plan", sfServiceInstance.Spec.PlanID)
-		// don't return error here. In cases when clusterSelector is not of gotemplate, scheduling should happen with least utilized cluster strategy
-		return "", nil
-	}
-
-	renderer, err := rendererFactory.GetRenderer(labelSelectorTemplate.Type, nil)
-	if err != nil {
-		return "", err
-	}
-	name := types.NamespacedName{
-		Namespace: sfServiceInstance.GetNamespace(),
-		Name:      sfServiceInstance.GetName(),
-	}
-	rendererInput, err := rendererFactory.GetRendererInput(labelSelectorTemplate, service, plan, sfServiceInstance, nil, name)
-	if err != nil {
-		return "", err
-	}
-	rendererOutput, err := renderer.Render(rendererInput)
-	if err != nil {
-		return "", err
-	}
-	labelSelector, err := rendererOutput.FileContent("main")
-	if err != nil {
-		return "", err
-	}
-	return strings.TrimSuffix(labelSelector, "\n"), nil
-}
-
-func (r *SFLabelSelectorScheduler) schedule(sfServiceInstance *osbv1alpha1.SFServiceInstance, labelSelector string) (string, error) {
-	ctx := context.Background()
-	log := r.Log.WithValues("instance", sfServiceInstance.GetName(), "labelSelector", labelSelector)
-	label, err := labels.Parse(labelSelector)
-	if err != nil {
-		return "", errors.NewSchedulerFailed(constants.LabelSelectorSchedulerType, "Parsing failed for labelSelector: "+labelSelector, err)
-	}
-	log.Info("Parsed Label is: ", "label", label)
-	clusters := &resourcev1alpha1.SFClusterList{}
-	if labelSelector == "" {
-		clusters, err = r.clusterRegistry.ListClusters(&client.ListOptions{})
-	} else {
-		clusters, err = r.clusterRegistry.ListClusters(&client.ListOptions{
-			LabelSelector: label,
-		})
-		if err != nil {
-			return "", err
-		}
-	}
-
-	log.Info("Cluster size is", "length", len(clusters.Items))
-	if len(clusters.Items) == 0 {
-		log.Info("No cluster matching the criteria, returning failure")
-		return "", errors.NewSchedulerFailed(constants.LabelSelectorSchedulerType, "No clusters found with matching criteria: "+labelSelector, nil)
-	}
-	if len(clusters.Items) == 1 {
-		log.Info("Only one cluster matching the criteria", "cluster name", clusters.Items[0].GetName())
-		return clusters.Items[0].GetName(), nil
-	}
-
-	sfserviceinstances := &osbv1alpha1.SFServiceInstanceList{}
-	err = r.List(ctx, sfserviceinstances, &client.ListOptions{})
+func (r *SFLabelSelectorScheduler) schedule(sfServiceInstance *osbv1alpha1.SFServiceInstance) (string, error) {
+	f, err := scheduler.NewForSchedulerType(r, nil, r.clusterRegistry, constants.LabelSelectorSchedulerType)
 	if err != nil {
-		log.Error(err, "Failed to list all sfserviceinstances")
 		return "", err
 	}
-
-	counts := make(map[string]int64)
-	for _, item := range sfserviceinstances.Items {
-		if item.Spec.ClusterID != "" {
-			counts[item.Spec.ClusterID] = counts[item.Spec.ClusterID] + 1
-		}
-	}
-
-	leastCount := int64(math.MaxInt64)
-	var clusterID string
-	for _, cluster := range clusters.Items {
-		count := counts[cluster.GetName()]
-		if count < leastCount {
-			leastCount = count
-			clusterID = cluster.GetName()
-			if count == 0 {
-				break
-			}
-		}
-	}
-	return clusterID, nil
+	return f.Schedule(sfServiceInstance)
 }
 
 // SetupWithManager registers the least utilized scheduler with manager
 // and setups the watches.
 func (r *SFLabelSelectorScheduler) SetupWithManager(mgr ctrl.Manager) error {
-	clusterRegistry, err := registry.New(mgr.GetConfig(), mgr.GetScheme(), mgr.GetRESTMapper())
+	clusterRegistry, err := registry.ForManager(mgr)
 	if err != nil {
 		return err
 	}
@@ -18,13 +18,12 @@ package sfleastutilizedscheduler
 
 import (
 	"context"
-	"math"
 
 	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/config"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/scheduler"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/cluster/registry"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
-	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
 
 	"github.com/go-logr/logr"
 	apiErrors "k8s.io/apimachinery/pkg/api/errors"
@@ -62,7 +61,7 @@ func (r *SFLeastUtilizedScheduler) Reconcile(req ctrl.Request) (ctrl.Result, err
 	}
 
 	if instance.Spec.ClusterID == "" {
-		clusterID, err := r.schedule()
+		clusterID, err := r.schedule(instance)
 		if err != nil {
 			return ctrl.Result{}, err
 		}
@@ -80,53 +79,18 @@ func (r *SFLeastUtilizedScheduler) Reconcile(req ctrl.Request) (ctrl.Result, err
 	return ctrl.Result{}, nil
 }
 
-func (r *SFLeastUtilizedScheduler) schedule() (string, error) {
-	log := r.Log
-
-	clusters, err := r.clusterRegistry.ListClusters(&client.ListOptions{})
-	if err != nil {
-		return "", err
-	}
-
-	if len(clusters.Items) == 0 {
-		return "", errors.NewClusterRegistryError("no sfcluster found", nil)
-	} else if len(clusters.Items) == 1 {
-		return clusters.Items[0].GetName(), nil
-	}
-
-	sfserviceinstances := &osbv1alpha1.SFServiceInstanceList{}
-	err = r.List(context.TODO(), sfserviceinstances, &client.ListOptions{})
+func (r *SFLeastUtilizedScheduler) schedule(sfServiceInstance *osbv1alpha1.SFServiceInstance) (string, error) {
+	f, err := scheduler.NewForSchedulerType(r, nil, r.clusterRegistry, constants.LeastUtilizedSchedulerType)
 	if err != nil {
-		log.Error(err, "failed to list all sfserviceinstances")
 		return "", err
 	}
-
-	counts := make(map[string]int64)
-	for _, item := range sfserviceinstances.Items {
-		if item.Spec.ClusterID != "" {
-			counts[item.Spec.ClusterID] = counts[item.Spec.ClusterID] + 1
-		}
-	}
-
-	leastCount := int64(math.MaxInt64)
-	var clusterID string
-	for _, cluster := range clusters.Items {
-		count := counts[cluster.GetName()]
-		if count < leastCount {
-			leastCount = count
-			clusterID = cluster.GetName()
-			if count == 0 {
-				break
-			}
-		}
-	}
-	return clusterID, nil
+	return f.Schedule(sfServiceInstance)
 }
 
 // SetupWithManager registers the least utilized scheduler with manager
 // and setups the watches.
 func (r *SFLeastUtilizedScheduler) SetupWithManager(mgr ctrl.Manager) error {
-	clusterRegistry, err := registry.New(mgr.GetConfig(), mgr.GetScheme(), mgr.GetRESTMapper())
+	clusterRegistry, err := registry.ForManager(mgr)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,185 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sfresourceawarescheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	resourcev1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/resource/v1alpha1"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/scheduler/schedulertest"
+	mock_clusterRegistry "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/cluster/registry/mock_registry"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
+
+	"github.com/golang/mock/gomock"
+	"github.com/onsi/gomega"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrlrun "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+var c client.Client
+
+const timeout = time.Second * 5
+
+func TestReconcile(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	configMap := schedulertest.NewConfigMap("schedulerType: resource-aware")
+
+	mgr, err := manager.New(cfg, manager.Options{
+		MetricsBindAddress: "0",
+	})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	c = mgr.GetClient()
+
+	mockClusterRegistry := mock_clusterRegistry.NewMockClusterRegistry(ctrl)
+
+	g.Expect(c.Create(context.TODO(), configMap)).NotTo(gomega.HaveOccurred())
+	defer c.Delete(context.TODO(), configMap)
+
+	plan := schedulertest.NewSFPlan("plan-id")
+	plan.Spec.ResourceRequests = schedulertest.ResourceList("2", "4Gi")
+	g.Expect(c.Create(context.TODO(), plan)).NotTo(gomega.HaveOccurred())
+	defer c.Delete(context.TODO(), plan)
+
+	scheduler := &SFResourceAwareScheduler{
+		Client: mgr.GetClient(),
+		Log:    ctrlrun.Log.WithName("schedulers").WithName("resourceaware"),
+	}
+	g.Expect(scheduler.SetupWithManager(mgr)).NotTo(gomega.HaveOccurred())
+	scheduler.clusterRegistry = mockClusterRegistry
+	stopMgr, mgrStopped := StartTestManager(mgr, g)
+
+	defer func() {
+		close(stopMgr)
+		mgrStopped.Wait()
+	}()
+
+	// Cluster 1 does not have enough memory, cluster 3 has more headroom than 2
+	sfcluster1 := schedulertest.NewSFClusterWithResources("1", "8", "16Gi", "2", "14Gi")
+	sfcluster2 := schedulertest.NewSFClusterWithResources("2", "8", "16Gi", "4", "8Gi")
+	sfcluster3 := schedulertest.NewSFClusterWithResources("3", "8", "16Gi", "2", "4Gi")
+
+	mockClusterRegistry.EXPECT().ListClusters(&client.ListOptions{}).
+		Return(schedulertest.NewSFClusterList(sfcluster1, sfcluster2, sfcluster3), nil).Times(1)
+
+	instance1 := schedulertest.NewSFServiceInstance("foo1", "plan-id")
+	g.Expect(c.Create(context.TODO(), instance1)).NotTo(gomega.HaveOccurred())
+	defer c.Delete(context.TODO(), instance1)
+
+	g.Eventually(func() error {
+		err := c.Get(context.TODO(), schedulertest.Key(instance1), instance1)
+		if err != nil {
+			return err
+		}
+		_, err = instance1.GetClusterID()
+		if err != nil {
+			return err
+		}
+		return nil
+	}, timeout).Should(gomega.Succeed())
+	g.Expect(instance1.Spec.ClusterID).To(gomega.Equal(sfcluster3.GetName()))
+}
+
+func TestSFResourceAwareScheduler_schedule(t *testing.T) {
+	plan := schedulertest.NewSFPlan("plan-id")
+	plan.Spec.ResourceRequests = schedulertest.ResourceList("2", "4Gi")
+	planWithoutRequests := schedulertest.NewSFPlan("plan-without-requests")
+
+	notReported := resourcev1alpha1.SFCluster{}
+	notReported.SetName("1")
+
+	tests := []struct {
+		name     string
+		planID   string
+		clusters []resourcev1alpha1.SFCluster
+		want     string
+		wantErr  func(error) bool
+	}{
+		{
+			name:     "refuse if allocatable is not reported",
+			planID:   "plan-id",
+			clusters: []resourcev1alpha1.SFCluster{notReported},
+			wantErr:  errors.SchedulerFailed,
+		},
+		{
+			name:     "refuse if cpu is not enough",
+			planID:   "plan-id",
+			clusters: []resourcev1alpha1.SFCluster{schedulertest.NewSFClusterWithResources("1", "4", "16Gi", "3", "0")},
+			wantErr:  errors.SchedulerFailed,
+		},
+		{
+			name:   "choose the cluster where the requests fit",
+			planID: "plan-id",
+			clusters: []resourcev1alpha1.SFCluster{
+				schedulertest.NewSFClusterWithResources("1", "4", "16Gi", "3", "0"),
+				schedulertest.NewSFClusterWithResources("2", "8", "16Gi", "6", "12Gi"),
+			},
+			want: "2",
+		},
+		{
+			name:   "choose the cluster with the most headroom",
+			planID: "plan-id",
+			clusters: []resourcev1alpha1.SFCluster{
+				schedulertest.NewSFClusterWithResources("1", "8", "16Gi", "4", "8Gi"),
+				schedulertest.NewSFClusterWithResources("2", "8", "16Gi", "2", "4Gi"),
+			},
+			want: "2",
+		},
+		{
+			name:     "schedule plans without requests on a full cluster",
+			planID:   "plan-without-requests",
+			clusters: []resourcev1alpha1.SFCluster{schedulertest.NewSFClusterWithResources("1", "8", "16Gi", "8", "16Gi")},
+			want:     "1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockClusterRegistry := mock_clusterRegistry.NewMockClusterRegistry(ctrl)
+			mockClusterRegistry.EXPECT().ListClusters(gomock.Any()).
+				Return(schedulertest.NewSFClusterList(tt.clusters...), nil).Times(1)
+
+			r := &SFResourceAwareScheduler{
+				Client:          fake.NewFakeClientWithScheme(scheme.Scheme, plan.DeepCopy(), planWithoutRequests.DeepCopy()),
+				Log:             ctrlrun.Log.WithName("schedulers").WithName("resourceaware"),
+				clusterRegistry: mockClusterRegistry,
+			}
+			got, err := r.schedule(schedulertest.NewSFServiceInstance("foo", tt.planID))
+			if tt.wantErr != nil {
+				if !tt.wantErr(err) {
+					t.Errorf("SFResourceAwareScheduler.schedule() error = %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("SFResourceAwareScheduler.schedule() error = %v", err)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("SFResourceAwareScheduler.schedule() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -18,12 +18,11 @@ package sfroundrobinscheduler
 
 import (
 	"context"
-	"sort"
-	"sync"
 
 	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
-	resourcev1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/resource/v1alpha1"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/config"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/scheduler"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/cluster/registry"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
 
 	"github.com/go-logr/logr"
@@ -33,16 +32,13 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-var (
-	l                           sync.Mutex
-	lastProvisionedClusterIndex = 0
-)
-
 // SFRoundRobinScheduler schedules an SFServiceInstance to a cluster
 type SFRoundRobinScheduler struct {
 	client.Client
-	Log    logr.Logger
-	scheme *runtime.Scheme
+	Log             logr.Logger
+	scheme          *runtime.Scheme
+	clusterRegistry registry.ClusterRegistry
+	apiReader       client.Reader
 }
 
 // Reconcile schedules the SFServiceInstance to one SFCluster and sets the ClusterID in
@@ -64,39 +60,49 @@ func (r *SFRoundRobinScheduler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 		return ctrl.Result{}, err
 	}
 	if instance.Spec.ClusterID == "" {
-		clusters := &resourcev1alpha1.SFClusterList{}
-		options := &client.ListOptions{}
-		err := r.List(ctx, clusters, options)
+		clusterID, err := r.schedule(instance)
 		if err != nil {
 			return ctrl.Result{}, err
 		}
-		items := clusters.Items
-		sort.Slice(items, func(i, j int) bool {
-			if items[i].GetCreationTimestamp().Time == items[j].GetCreationTimestamp().Time {
-				return items[i].Name < items[j].Name
-			}
-			return !items[i].GetCreationTimestamp().After(items[j].GetCreationTimestamp().Time)
-		})
-		l.Lock()
-		if len(items) <= lastProvisionedClusterIndex {
-			lastProvisionedClusterIndex = 0
-		}
-		currentlyProvisionedCluster := items[lastProvisionedClusterIndex]
-		lastProvisionedClusterIndex++
-		l.Unlock()
-		instance.Spec.ClusterID = currentlyProvisionedCluster.ObjectMeta.Name
+		instance.Spec.ClusterID = clusterID
 		if err := r.Update(ctx, instance); err != nil {
-			log.Error(err, "failed to update cluster id", "ClusterID",
-				currentlyProvisionedCluster.ObjectMeta.Name)
+			log.Error(err, "failed to update cluster id", "ClusterID", clusterID)
 			return ctrl.Result{}, err
 		}
+		// The turn moves on only once the instance is scheduled
+		if err := r.reserve(instance, clusterID); err != nil {
+			log.Error(err, "failed to reserve cluster", "ClusterID", clusterID)
+		}
 	}
 	return ctrl.Result{}, nil
 }
 
+func (r *SFRoundRobinScheduler) schedule(sfServiceInstance *osbv1alpha1.SFServiceInstance) (string, error) {
+	f, err := scheduler.NewForSchedulerType(r, r.apiReader, r.clusterRegistry, constants.RoundRobinSchedulerType)
+	if err != nil {
+		return "", err
+	}
+	return f.Schedule(sfServiceInstance)
+}
+
+func (r *SFRoundRobinScheduler) reserve(sfServiceInstance *osbv1alpha1.SFServiceInstance, clusterID string) error {
+	f, err := scheduler.NewForSchedulerType(r, r.apiReader, r.clusterRegistry, constants.RoundRobinSchedulerType)
+	if err != nil {
+		return err
+	}
+	return f.Reserve(sfServiceInstance, clusterID)
+}
+
 // SetupWithManager registers the round robin scheduler with manager
 // add setups the watches.
 func (r *SFRoundRobinScheduler) SetupWithManager(mgr ctrl.Manager) error {
+	clusterRegistry, err := registry.ForManager(mgr)
+	if err != nil {
+		return err
+	}
+	r.clusterRegistry = clusterRegistry
+	r.apiReader = mgr.GetAPIReader()
+
 	cfgManager, err := config.New(mgr.GetConfig(), mgr.GetScheme(), mgr.GetRESTMapper())
 	if err != nil {
 		return err
@@ -3,17 +3,20 @@ module github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator
 go 1.13
 
 require (
+	cuelang.org/go v0.2.2
 	github.com/Masterminds/goutils v1.1.0 // indirect
 	github.com/Masterminds/semver v1.5.0 // indirect
-	github.com/Masterminds/sprig v2.22.0+incompatible // indirect
+	github.com/Masterminds/sprig v2.22.0+incompatible
 	github.com/cyphar/filepath-securejoin v0.2.2 // indirect
 	github.com/go-logr/logr v0.1.0
 	github.com/gobwas/glob v0.2.3 // indirect
 	github.com/golang/mock v1.3.1
+	github.com/google/go-jsonnet v0.14.0
 	github.com/huandu/xstrings v1.2.0 // indirect
 	github.com/mitchellh/copystructure v1.0.0 // indirect
 	github.com/onsi/ginkgo v1.6.0
 	github.com/onsi/gomega v1.4.2
+	github.com/prometheus/client_golang v1.0.0
 	gopkg.in/yaml.v2 v2.2.2
 	k8s.io/api v0.0.0-20190918195907-bd6ac527cfd2
 	k8s.io/apiextensions-apiserver v0.0.0-20190918201827-3de75813f604
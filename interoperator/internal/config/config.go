@@ -30,10 +30,59 @@ type InteroperatorConfig struct {
 	ProvisionerWorkerCount int    `yaml:"provisionerWorkerCount,omitempty"`
 	SchedulerType          string `yaml:"schedulerType,omitempty"`
 
+	SchedulerPlugins SchedulerPluginsConfig `yaml:"schedulerPlugins,omitempty"`
+
+	ClusterReconcileInterval string `yaml:"clusterReconcileInterval,omitempty"`
+	BindingRotationOverlap   string `yaml:"bindingRotationOverlap,omitempty"`
+	BindingOperationTimeout  string `yaml:"bindingOperationTimeout,omitempty"`
+
+	BindingSecretStore   SecretStoreConfig          `yaml:"bindingSecretStore,omitempty"`
+	CredentialEncryption CredentialEncryptionConfig `yaml:"credentialEncryption,omitempty"`
+
 	InstanceContollerWatchList []osbv1alpha1.APIVersionKind `yaml:"instanceContollerWatchList,omitempty"`
 	BindingContollerWatchList  []osbv1alpha1.APIVersionKind `yaml:"bindingContollerWatchList,omitempty"`
 }
 
+// SchedulerPluginsConfig lists the plugins used by the framework scheduler.
+// Filters are applied in order, scores are combined using their weights.
+type SchedulerPluginsConfig struct {
+	Filters []string            `yaml:"filters,omitempty"`
+	Scores  []ScorePluginConfig `yaml:"scores,omitempty"`
+}
+
+// ScorePluginConfig configures a score plugin and its weight
+type ScorePluginConfig struct {
+	Name   string `yaml:"name"`
+	Weight int    `yaml:"weight,omitempty"`
+}
+
+// SecretStoreConfig selects where the binding credentials are stored. The
+// type is either kubernetes for a Secret in the namespace of the binding
+// or vault for a Vault KV version 2 secrets engine.
+type SecretStoreConfig struct {
+	Type  string      `yaml:"type,omitempty"`
+	Vault VaultConfig `yaml:"vault,omitempty"`
+}
+
+// VaultConfig configures the Vault secret store. The token is read from
+// TokenFile if set and from the VAULT_TOKEN environment variable otherwise.
+type VaultConfig struct {
+	Address    string `yaml:"address,omitempty"`
+	Mount      string `yaml:"mount,omitempty"`
+	PathPrefix string `yaml:"pathPrefix,omitempty"`
+	TokenFile  string `yaml:"tokenFile,omitempty"`
+}
+
+// CredentialEncryptionConfig enables the envelope encryption of binding
+// credentials. The key provider is file for a key encryption key mounted
+// at KeyFile or kms for a KMS plugin served at Endpoint. The credentials
+// are not encrypted if no key provider is set.
+type CredentialEncryptionConfig struct {
+	KeyProvider string `yaml:"keyProvider,omitempty"`
+	KeyFile     string `yaml:"keyFile,omitempty"`
+	Endpoint    string `yaml:"endpoint,omitempty"`
+}
+
 // setConfigDefaults assigns default values to config
 func setConfigDefaults(interoperatorConfig *InteroperatorConfig) *InteroperatorConfig {
 	if interoperatorConfig.BindingWorkerCount == 0 {
@@ -51,6 +100,18 @@ func setConfigDefaults(interoperatorConfig *InteroperatorConfig) *InteroperatorC
 	if interoperatorConfig.SchedulerType == "" {
 		interoperatorConfig.SchedulerType = constants.DefaultSchedulerType
 	}
+	if interoperatorConfig.ClusterReconcileInterval == "" {
+		interoperatorConfig.ClusterReconcileInterval = constants.DefaultClusterReconcileInterval
+	}
+	if interoperatorConfig.BindingRotationOverlap == "" {
+		interoperatorConfig.BindingRotationOverlap = constants.DefaultBindingRotationOverlap
+	}
+	if interoperatorConfig.BindingOperationTimeout == "" {
+		interoperatorConfig.BindingOperationTimeout = constants.DefaultBindingOperationTimeout
+	}
+	if interoperatorConfig.BindingSecretStore.Type == "" {
+		interoperatorConfig.BindingSecretStore.Type = constants.DefaultSecretStoreType
+	}
 
 	return interoperatorConfig
 }
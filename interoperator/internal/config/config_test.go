@@ -123,6 +123,12 @@ func Test_config_GetConfig(t *testing.T) {
 	data := make(map[string]string)
 	config := `
 instanceWorkerCount: 2
+schedulerPlugins:
+  filters:
+  - health
+  scores:
+  - name: utilization
+    weight: 2
 instanceContollerWatchList:
 - apiVersion: kubedb.com/v1alpha1
   kind: Postgres
@@ -148,6 +154,22 @@ instanceContollerWatchList:
 		SchedulerWorkerCount:   constants.DefaultSchedulerWorkerCount,
 		ProvisionerWorkerCount: constants.DefaultProvisionerWorkerCount,
 		SchedulerType:          constants.DefaultSchedulerType,
+		SchedulerPlugins: SchedulerPluginsConfig{
+			Filters: []string{"health"},
+			Scores: []ScorePluginConfig{
+				{
+					Name:   "utilization",
+					Weight: 2,
+				},
+			},
+		},
+
+		ClusterReconcileInterval: constants.DefaultClusterReconcileInterval,
+		BindingRotationOverlap:   constants.DefaultBindingRotationOverlap,
+		BindingOperationTimeout:  constants.DefaultBindingOperationTimeout,
+		BindingSecretStore: SecretStoreConfig{
+			Type: constants.DefaultSecretStoreType,
+		},
 		InstanceContollerWatchList: []osbv1alpha1.APIVersionKind{
 			osbv1alpha1.APIVersionKind{
 				APIVersion: "kubedb.com/v1alpha1",
@@ -199,6 +221,7 @@ instanceContollerWatchList:
 				data[constants.ConfigMapKey] = config
 				g.Expect(c.Update(context.TODO(), configMap)).NotTo(gomega.HaveOccurred())
 				interoperatorConfig.InstanceWorkerCount = constants.DefaultInstanceWorkerCount
+				interoperatorConfig.SchedulerPlugins = SchedulerPluginsConfig{}
 			},
 			want: interoperatorConfig,
 		},
@@ -0,0 +1,232 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/config"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("encryption")
+
+// Names of the key providers
+const (
+	FileKeyProviderName = "file"
+	KMSKeyProviderName  = "kms"
+)
+
+// EnvelopeVersion is the version of the envelope format
+const EnvelopeVersion = "v1"
+
+// keySize is the size of the data encryption keys for AES-256
+const keySize = 32
+
+// KeyProvider wraps the data encryption keys with a key encryption key,
+// e.g. a key mounted as file or a key managed by a KMS
+type KeyProvider interface {
+	// WrapKey encrypts the data encryption key and returns it with the
+	// id of the key encryption key
+	WrapKey(key []byte) ([]byte, string, error)
+	// UnwrapKey decrypts a data encryption key wrapped with the key
+	// encryption key keyID
+	UnwrapKey(wrappedKey []byte, keyID string) ([]byte, error)
+}
+
+// Encrypter encrypts credentials with a new data encryption key each,
+// which is stored wrapped by the key provider along with the credentials
+type Encrypter interface {
+	// Encrypt returns the envelope with the encrypted credentials as json,
+	// or the credentials if encryption is disabled
+	Encrypt(plaintext string) (string, error)
+	// Decrypt returns the credentials of an envelope. Credentials which
+	// are not encrypted are returned as they are.
+	Decrypt(ciphertext string) (string, error)
+}
+
+// Envelope holds credentials encrypted with AES-256-GCM and the data
+// encryption key wrapped by the key provider. The binary fields are
+// base64 encoded in json.
+type Envelope struct {
+	Version      string `json:"version"`
+	KeyProvider  string `json:"keyProvider"`
+	KeyID        string `json:"keyID"`
+	EncryptedKey []byte `json:"encryptedKey"`
+	Nonce        []byte `json:"nonce"`
+	Ciphertext   []byte `json:"ciphertext"`
+}
+
+// envelopeDocument is the json of encrypted credentials, i.e.
+// {"sfEnvelope": {...}}
+type envelopeDocument struct {
+	Envelope *Envelope `json:"sfEnvelope"`
+}
+
+type encrypter struct {
+	keyProviderName string
+	keyProvider     KeyProvider
+}
+
+// New returns an Encrypter which uses the configured key provider. The
+// returned Encrypter does not encrypt if no key provider is configured.
+func New(cfg config.CredentialEncryptionConfig) (Encrypter, error) {
+	var keyProvider KeyProvider
+	var err error
+	switch cfg.KeyProvider {
+	case "":
+	case FileKeyProviderName:
+		keyProvider, err = NewFileKeyProvider(cfg.KeyFile)
+	case KMSKeyProviderName:
+		keyProvider, err = NewKMSKeyProvider(cfg.Endpoint)
+	default:
+		err = errors.NewInputError("New encryption", "keyProvider", nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &encrypter{
+		keyProviderName: cfg.KeyProvider,
+		keyProvider:     keyProvider,
+	}, nil
+}
+
+// IsEncrypted is true if the credentials are an envelope
+func IsEncrypted(credentials string) bool {
+	_, ok := parseEnvelope(credentials)
+	return ok
+}
+
+func parseEnvelope(credentials string) (*Envelope, bool) {
+	document := &envelopeDocument{}
+	err := json.Unmarshal([]byte(credentials), document)
+	if err != nil || document.Envelope == nil {
+		return nil, false
+	}
+	return document.Envelope, true
+}
+
+func (e *encrypter) Encrypt(plaintext string) (string, error) {
+	if e.keyProvider == nil {
+		return plaintext, nil
+	}
+
+	key := make([]byte, keySize)
+	_, err := io.ReadFull(rand.Reader, key)
+	if err != nil {
+		return "", errors.NewEncryptionError("failed to generate data encryption key", err)
+	}
+	nonce, ciphertext, err := seal(key, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	encryptedKey, keyID, err := e.keyProvider.WrapKey(key)
+	if err != nil {
+		log.Error(err, "failed to wrap data encryption key", "keyProvider", e.keyProviderName)
+		return "", err
+	}
+
+	envelope, err := json.Marshal(&envelopeDocument{
+		Envelope: &Envelope{
+			Version:      EnvelopeVersion,
+			KeyProvider:  e.keyProviderName,
+			KeyID:        keyID,
+			EncryptedKey: encryptedKey,
+			Nonce:        nonce,
+			Ciphertext:   ciphertext,
+		},
+	})
+	if err != nil {
+		return "", errors.NewMarshalError("failed to marshal envelope", err)
+	}
+	return string(envelope), nil
+}
+
+func (e *encrypter) Decrypt(ciphertext string) (string, error) {
+	envelope, ok := parseEnvelope(ciphertext)
+	if !ok {
+		return ciphertext, nil
+	}
+	if e.keyProvider == nil || envelope.KeyProvider != e.keyProviderName {
+		return "", errors.NewEncryptionError("no key provider "+envelope.KeyProvider+" to decrypt credentials", nil)
+	}
+	if envelope.Version != EnvelopeVersion {
+		return "", errors.NewEncryptionError("unsupported envelope version "+envelope.Version, nil)
+	}
+
+	key, err := e.keyProvider.UnwrapKey(envelope.EncryptedKey, envelope.KeyID)
+	if err != nil {
+		log.Error(err, "failed to unwrap data encryption key", "keyProvider", e.keyProviderName, "keyID", envelope.KeyID)
+		return "", err
+	}
+	plaintext, err := open(key, envelope.Nonce, envelope.Ciphertext)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// seal encrypts the plaintext with AES-256-GCM and a random nonce
+func seal(key, plaintext []byte) ([]byte, []byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return nil, nil, errors.NewEncryptionError("failed to generate nonce", err)
+	}
+	return nonce, gcm.Seal(nil, nonce, plaintext, nil), nil
+}
+
+// open decrypts a ciphertext sealed with AES-256-GCM
+func open(key, nonce, ciphertext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, errors.NewEncryptionError("invalid nonce", nil)
+	}
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errors.NewEncryptionError("failed to decrypt", err)
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != keySize {
+		return nil, errors.NewEncryptionError("invalid key size", nil)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.NewEncryptionError("failed to create cipher", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.NewEncryptionError("failed to create cipher", err)
+	}
+	return gcm, nil
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/config"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
+
+	"github.com/onsi/gomega"
+)
+
+const credentials = `{"credentials":{"username":"user","password":"secret"}}`
+
+func writeKeyFile(g *gomega.GomegaWithT, dir, name string, content []byte) string {
+	keyFile := filepath.Join(dir, name)
+	g.Expect(ioutil.WriteFile(keyFile, content, 0600)).To(gomega.Succeed())
+	return keyFile
+}
+
+func TestNew(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	dir, err := ioutil.TempDir("", "encryption")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name    string
+		cfg     config.CredentialEncryptionConfig
+		wantErr bool
+	}{
+		{
+			name: "disabled without key provider",
+			cfg:  config.CredentialEncryptionConfig{},
+		},
+		{
+			name: "use raw key file",
+			cfg: config.CredentialEncryptionConfig{
+				KeyProvider: FileKeyProviderName,
+				KeyFile:     writeKeyFile(g, dir, "raw", bytes.Repeat([]byte("k"), 32)),
+			},
+		},
+		{
+			name: "use base64 encoded key file",
+			cfg: config.CredentialEncryptionConfig{
+				KeyProvider: FileKeyProviderName,
+				KeyFile:     writeKeyFile(g, dir, "base64", []byte(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("k"), 32))+"\n")),
+			},
+		},
+		{
+			name: "fail for key of wrong size",
+			cfg: config.CredentialEncryptionConfig{
+				KeyProvider: FileKeyProviderName,
+				KeyFile:     writeKeyFile(g, dir, "short", []byte("short")),
+			},
+			wantErr: true,
+		},
+		{
+			name: "fail for missing key file",
+			cfg: config.CredentialEncryptionConfig{
+				KeyProvider: FileKeyProviderName,
+				KeyFile:     filepath.Join(dir, "missing"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "fail for kms without endpoint",
+			cfg: config.CredentialEncryptionConfig{
+				KeyProvider: KMSKeyProviderName,
+			},
+			wantErr: true,
+		},
+		{
+			name: "fail for unknown key provider",
+			cfg: config.CredentialEncryptionConfig{
+				KeyProvider: "unknown",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+			_, err := New(tt.cfg)
+			if tt.wantErr {
+				g.Expect(err).To(gomega.HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(gomega.HaveOccurred())
+			}
+		})
+	}
+}
+
+func Test_encrypter_disabled(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	e, err := New(config.CredentialEncryptionConfig{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	encrypted, err := e.Encrypt(credentials)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(encrypted).To(gomega.Equal(credentials))
+	g.Expect(IsEncrypted(encrypted)).To(gomega.BeFalse())
+
+	decrypted, err := e.Decrypt(credentials)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(decrypted).To(gomega.Equal(credentials))
+}
+
+func Test_encrypter_file(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	dir, err := ioutil.TempDir("", "encryption")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	defer os.RemoveAll(dir)
+
+	e, err := New(config.CredentialEncryptionConfig{
+		KeyProvider: FileKeyProviderName,
+		KeyFile:     writeKeyFile(g, dir, "key", bytes.Repeat([]byte("a"), 32)),
+	})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	encrypted, err := e.Encrypt(credentials)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(IsEncrypted(encrypted)).To(gomega.BeTrue())
+	g.Expect(encrypted).NotTo(gomega.ContainSubstring("secret"))
+
+	document := &envelopeDocument{}
+	g.Expect(json.Unmarshal([]byte(encrypted), document)).To(gomega.Succeed())
+	g.Expect(document.Envelope.Version).To(gomega.Equal(EnvelopeVersion))
+	g.Expect(document.Envelope.KeyProvider).To(gomega.Equal(FileKeyProviderName))
+	g.Expect(document.Envelope.KeyID).To(gomega.HaveLen(16))
+
+	decrypted, err := e.Decrypt(encrypted)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(decrypted).To(gomega.Equal(credentials))
+
+	// Each encryption uses a new data encryption key
+	encryptedAgain, err := e.Encrypt(credentials)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(encryptedAgain).NotTo(gomega.Equal(encrypted))
+
+	// Tampered credentials are not decrypted
+	document.Envelope.Ciphertext[0] ^= 0xff
+	tampered, err := json.Marshal(document)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	_, err = e.Decrypt(string(tampered))
+	g.Expect(errors.EncryptionError(err)).To(gomega.BeTrue())
+
+	// Credentials of another key encryption key are not decrypted
+	other, err := New(config.CredentialEncryptionConfig{
+		KeyProvider: FileKeyProviderName,
+		KeyFile:     writeKeyFile(g, dir, "other", bytes.Repeat([]byte("b"), 32)),
+	})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	_, err = other.Decrypt(encrypted)
+	g.Expect(errors.EncryptionError(err)).To(gomega.BeTrue())
+
+	// Encrypted credentials are not decrypted without key provider
+	disabled, err := New(config.CredentialEncryptionConfig{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	_, err = disabled.Decrypt(encrypted)
+	g.Expect(errors.EncryptionError(err)).To(gomega.BeTrue())
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
+)
+
+// fileKeyProvider wraps the data encryption keys with AES-256-GCM using a
+// key encryption key read from a file. The wrapped key is the nonce
+// followed by the sealed key.
+type fileKeyProvider struct {
+	key   []byte
+	keyID string
+}
+
+// NewFileKeyProvider returns a KeyProvider using the 32 byte key
+// encryption key in keyFile, either raw or base64 encoded. The key id is
+// the hex encoded prefix of its SHA-256 hash.
+func NewFileKeyProvider(keyFile string) (KeyProvider, error) {
+	if keyFile == "" {
+		return nil, errors.NewInputError("NewFileKeyProvider", "keyFile", nil)
+	}
+	content, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, errors.NewEncryptionError("failed to read key encryption key", err)
+	}
+
+	key := content
+	if len(key) != keySize {
+		key, err = base64.StdEncoding.DecodeString(strings.TrimSpace(string(content)))
+		if err != nil || len(key) != keySize {
+			return nil, errors.NewEncryptionError(fmt.Sprintf("key encryption key in %s is not %d bytes", keyFile, keySize), err)
+		}
+	}
+	hash := sha256.Sum256(key)
+	return &fileKeyProvider{
+		key:   key,
+		keyID: hex.EncodeToString(hash[:8]),
+	}, nil
+}
+
+func (p *fileKeyProvider) WrapKey(key []byte) ([]byte, string, error) {
+	nonce, wrappedKey, err := seal(p.key, key)
+	if err != nil {
+		return nil, "", err
+	}
+	return append(nonce, wrappedKey...), p.keyID, nil
+}
+
+func (p *fileKeyProvider) UnwrapKey(wrappedKey []byte, keyID string) ([]byte, error) {
+	if keyID != p.keyID {
+		return nil, errors.NewEncryptionError("unknown key encryption key "+keyID, nil)
+	}
+	gcm, err := newGCM(p.key)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrappedKey) < gcm.NonceSize() {
+		return nil, errors.NewEncryptionError("invalid wrapped key", nil)
+	}
+	return open(p.key, wrappedKey[:gcm.NonceSize()], wrappedKey[gcm.NonceSize():])
+}
+
+// kmsKeyProvider delegates wrapping the data encryption keys to a KMS
+// plugin. POST /v1/wrap of {"plaintext": key} returns {"ciphertext":
+// wrappedKey, "keyID": id} and POST /v1/unwrap of {"ciphertext":
+// wrappedKey, "keyID": id} returns {"plaintext": key}, with the keys base64
+// encoded. Failed requests return {"error": message}.
+type kmsKeyProvider struct {
+	endpoint   string
+	httpClient *http.Client
+}
+
+type kmsRequest struct {
+	Plaintext  []byte `json:"plaintext,omitempty"`
+	Ciphertext []byte `json:"ciphertext,omitempty"`
+	KeyID      string `json:"keyID,omitempty"`
+}
+
+type kmsResponse struct {
+	Plaintext  []byte `json:"plaintext,omitempty"`
+	Ciphertext []byte `json:"ciphertext,omitempty"`
+	KeyID      string `json:"keyID,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// NewKMSKeyProvider returns a KeyProvider using the KMS plugin at endpoint
+func NewKMSKeyProvider(endpoint string) (KeyProvider, error) {
+	if endpoint == "" {
+		return nil, errors.NewInputError("NewKMSKeyProvider", "endpoint", nil)
+	}
+	return &kmsKeyProvider{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		httpClient: &http.Client{
+			Timeout: constants.KeyProviderTimeout,
+		},
+	}, nil
+}
+
+func (p *kmsKeyProvider) do(operation string, request *kmsRequest) (*kmsResponse, error) {
+	content, err := json.Marshal(request)
+	if err != nil {
+		return nil, errors.NewMarshalError("failed to marshal kms request", err)
+	}
+	resp, err := p.httpClient.Post(p.endpoint+"/v1/"+operation, "application/json", bytes.NewReader(content))
+	if err != nil {
+		return nil, errors.NewEncryptionError("kms "+operation+" failed", err)
+	}
+	defer resp.Body.Close()
+
+	response := &kmsResponse{}
+	err = json.NewDecoder(resp.Body).Decode(response)
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("status %d: %s", resp.StatusCode, response.Error)
+		return nil, errors.NewEncryptionError("kms "+operation+" failed", err)
+	}
+	if err != nil {
+		return nil, errors.NewUnmarshalError("failed to unmarshal kms response", err)
+	}
+	return response, nil
+}
+
+func (p *kmsKeyProvider) WrapKey(key []byte) ([]byte, string, error) {
+	response, err := p.do("wrap", &kmsRequest{Plaintext: key})
+	if err != nil {
+		return nil, "", err
+	}
+	return response.Ciphertext, response.KeyID, nil
+}
+
+func (p *kmsKeyProvider) UnwrapKey(wrappedKey []byte, keyID string) ([]byte, error) {
+	response, err := p.do("unwrap", &kmsRequest{Ciphertext: wrappedKey, KeyID: keyID})
+	if err != nil {
+		return nil, err
+	}
+	return response.Plaintext, nil
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/config"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
+
+	"github.com/onsi/gomega"
+)
+
+// kmsStandIn is a stand-in for a KMS plugin which wraps the keys with a
+// local key
+type kmsStandIn struct {
+	key      []byte
+	requests []string
+}
+
+func (k *kmsStandIn) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	k.requests = append(k.requests, req.URL.Path)
+	request := &kmsRequest{}
+	if err := json.NewDecoder(req.Body).Decode(request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&kmsResponse{Error: err.Error()})
+		return
+	}
+
+	switch req.URL.Path {
+	case "/v1/wrap":
+		nonce, ciphertext, err := seal(k.key, request.Plaintext)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(&kmsResponse{Ciphertext: append(nonce, ciphertext...), KeyID: "kms-key-1"})
+	case "/v1/unwrap":
+		if request.KeyID != "kms-key-1" {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(&kmsResponse{Error: "key not found"})
+			return
+		}
+		plaintext, err := open(k.key, request.Ciphertext[:12], request.Ciphertext[12:])
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(&kmsResponse{Error: err.Error()})
+			return
+		}
+		json.NewEncoder(w).Encode(&kmsResponse{Plaintext: plaintext})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func Test_encrypter_kms(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	kms := &kmsStandIn{key: bytes.Repeat([]byte("c"), 32)}
+	server := httptest.NewServer(kms)
+	defer server.Close()
+
+	e, err := New(config.CredentialEncryptionConfig{
+		KeyProvider: KMSKeyProviderName,
+		Endpoint:    server.URL + "/",
+	})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	encrypted, err := e.Encrypt(credentials)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(IsEncrypted(encrypted)).To(gomega.BeTrue())
+
+	decrypted, err := e.Decrypt(encrypted)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(decrypted).To(gomega.Equal(credentials))
+	g.Expect(kms.requests).To(gomega.Equal([]string{"/v1/wrap", "/v1/unwrap"}))
+
+	// Errors of the plugin are returned
+	document := &envelopeDocument{}
+	g.Expect(json.Unmarshal([]byte(encrypted), document)).To(gomega.Succeed())
+	document.Envelope.KeyID = "kms-key-2"
+	unknownKey, err := json.Marshal(document)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	_, err = e.Decrypt(string(unknownKey))
+	g.Expect(errors.EncryptionError(err)).To(gomega.BeTrue())
+	g.Expect(err.(*errors.InteroperatorError).Err.Error()).To(gomega.ContainSubstring("key not found"))
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package offline
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Print writes the rendered files as yaml documents, each preceded by
+// a comment naming the result and the file
+func Print(results []Result) string {
+	var buf bytes.Buffer
+	for _, result := range results {
+		for _, file := range result.Files {
+			fmt.Fprintf(&buf, "---\n# Source: %s/%s\n%s\n", result.Name, file.Name, strings.TrimSuffix(file.Content, "\n"))
+		}
+	}
+	return buf.String()
+}
+
+// Write writes the rendered files to dir/<result>/<file>
+func Write(results []Result, dir string) error {
+	for path, content := range files(results) {
+		path = filepath.Join(dir, filepath.FromSlash(path))
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			return err
+		}
+		err = ioutil.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Diff compares the rendered files with the files written to dir by
+// Write. It returns a line diff of the files which differ, are missing
+// or were not rendered; it is empty if all files match.
+func Diff(results []Result, dir string) (string, error) {
+	rendered := files(results)
+	expected := make(map[string]string)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		expected[filepath.ToSlash(rel)] = string(content)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	paths := make([]string, 0, len(rendered)+len(expected))
+	for path := range rendered {
+		paths = append(paths, path)
+	}
+	for path := range expected {
+		if _, ok := rendered[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var buf bytes.Buffer
+	for _, path := range paths {
+		want, wantOk := expected[path]
+		got, gotOk := rendered[path]
+		if wantOk && gotOk && want == got {
+			continue
+		}
+		from, to := "a/"+path, "b/"+path
+		if !wantOk {
+			from = "/dev/null"
+		}
+		if !gotOk {
+			to = "/dev/null"
+		}
+		fmt.Fprintf(&buf, "--- %s\n+++ %s\n", from, to)
+		for _, line := range diffLines(splitLines(want), splitLines(got)) {
+			buf.WriteString(line)
+			buf.WriteString("\n")
+		}
+	}
+	return buf.String(), nil
+}
+
+func files(results []Result) map[string]string {
+	files := make(map[string]string)
+	for _, result := range results {
+		for _, file := range result.Files {
+			files[result.Name+"/"+file.Name] = file.Content
+		}
+	}
+	return files
+}
+
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// diffLines returns the lines of a and b prefixed by "-" if only in a,
+// "+" if only in b and " " if in both, based on their longest common
+// subsequence
+func diffLines(a, b []string) []string {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	lines := make([]string, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, " "+a[i])
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, "-"+a[i])
+			i++
+		default:
+			lines = append(lines, "+"+b[j])
+			j++
+		}
+	}
+	return lines
+}
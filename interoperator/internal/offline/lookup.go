@@ -0,0 +1,82 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package offline
+
+import (
+	"context"
+	"strings"
+
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// sourcesReader answers the lookups of the templates from the source
+// objects instead of a cluster. Source objects without a namespace are
+// in the namespace the templates are rendered for.
+type sourcesReader struct {
+	sources   []*unstructured.Unstructured
+	namespace string
+}
+
+var _ client.Reader = sourcesReader{}
+
+func (r sourcesReader) matches(source *unstructured.Unstructured, apiVersion, kind, namespace string) bool {
+	sourceNamespace := source.GetNamespace()
+	if sourceNamespace == "" {
+		sourceNamespace = r.namespace
+	}
+	return source.GetAPIVersion() == apiVersion && source.GetKind() == kind && sourceNamespace == namespace
+}
+
+// Get copies the source object with the key and the kind of obj into obj.
+// A NotFound error is returned if there is none.
+func (r sourcesReader) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return apiErrors.NewBadRequest("only unstructured objects can be looked up offline")
+	}
+	for _, source := range r.sources {
+		if r.matches(source, u.GetAPIVersion(), u.GetKind(), key.Namespace) && source.GetName() == key.Name {
+			u.Object = source.DeepCopy().Object
+			return nil
+		}
+	}
+	gvk := u.GroupVersionKind()
+	return apiErrors.NewNotFound(schema.GroupResource{Group: gvk.Group, Resource: strings.ToLower(gvk.Kind)}, key.Name)
+}
+
+// List lists the source objects of the kind of the list in the namespace
+// of opts
+func (r sourcesReader) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	u, ok := list.(*unstructured.UnstructuredList)
+	if !ok {
+		return apiErrors.NewBadRequest("only unstructured objects can be looked up offline")
+	}
+	listOpts := &client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+	kind := strings.TrimSuffix(u.GetKind(), "List")
+	u.Items = nil
+	for _, source := range r.sources {
+		if r.matches(source, u.GetAPIVersion(), kind, listOpts.Namespace) {
+			u.Items = append(u.Items, *source.DeepCopy())
+		}
+	}
+	return nil
+}
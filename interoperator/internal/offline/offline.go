@@ -0,0 +1,289 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package offline renders the templates of a plan without a cluster.
+// The service, plan, instance, binding and the source objects read by
+// the status template are given as objects instead of being fetched.
+// The lookup function of gotemplate finds the source objects as well.
+package offline
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/properties"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/renderer"
+	rendererFactory "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/renderer/factory"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// BindingPrefix prefixes the results of the templates rendered for
+// the binding
+const BindingPrefix = "binding/"
+
+// Objects are the inputs of the templates
+type Objects struct {
+	Services  []*osbv1alpha1.SFService
+	Plans     []*osbv1alpha1.SFPlan
+	Instance  *osbv1alpha1.SFServiceInstance
+	Binding   *osbv1alpha1.SFServiceBinding
+	Sources   []*unstructured.Unstructured
+	Namespace string
+}
+
+// File is a file rendered by a template
+type File struct {
+	Name    string
+	Content string
+}
+
+// Result is the output of a template. Name is the action of the
+// template, prefixed by BindingPrefix if rendered for the binding.
+type Result struct {
+	Name  string
+	Files []File
+}
+
+// Read decodes the yaml or json documents of r into objs. Service
+// fabrik objects are decoded by kind, any other object is a source.
+func (objs *Objects) Read(r io.Reader) error {
+	decoder := yaml.NewYAMLOrJSONDecoder(r, 4096)
+	for {
+		obj := &unstructured.Unstructured{}
+		err := decoder.Decode(&obj.Object)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.NewUnmarshalError("unable to decode objects", err)
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		err = objs.add(obj)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (objs *Objects) add(obj *unstructured.Unstructured) error {
+	var typed runtime.Object
+	switch {
+	case obj.GroupVersionKind().GroupVersion() != osbv1alpha1.GroupVersion:
+		objs.Sources = append(objs.Sources, obj)
+		return nil
+	case obj.GetKind() == "SFService":
+		service := &osbv1alpha1.SFService{}
+		objs.Services = append(objs.Services, service)
+		typed = service
+	case obj.GetKind() == "SFPlan":
+		plan := &osbv1alpha1.SFPlan{}
+		objs.Plans = append(objs.Plans, plan)
+		typed = plan
+	case obj.GetKind() == "SFServiceInstance":
+		objs.Instance = &osbv1alpha1.SFServiceInstance{}
+		typed = objs.Instance
+	case obj.GetKind() == "SFServiceBinding":
+		objs.Binding = &osbv1alpha1.SFServiceBinding{}
+		typed = objs.Binding
+	default:
+		objs.Sources = append(objs.Sources, obj)
+		return nil
+	}
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, typed)
+	if err != nil {
+		return errors.NewConvertError(fmt.Sprintf("unable to convert %s %s", obj.GetKind(), obj.GetName()), err)
+	}
+	return nil
+}
+
+// Render renders every template of the plan of the instance. The
+// provision, update, sources, status and clusterSelector templates are
+// rendered for the instance. The bind, sources and status templates are
+// rendered again for the binding if it is given.
+func Render(objs *Objects) ([]Result, error) {
+	if objs.Instance == nil {
+		return nil, errors.NewInputError("Render", "instance", fmt.Errorf("SFServiceInstance is required"))
+	}
+	service, plan, err := objs.find(objs.Instance.Spec.ServiceID, objs.Instance.Spec.PlanID)
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := objs.Namespace
+	if namespace == "" {
+		namespace = objs.Instance.GetNamespace()
+	}
+	name := types.NamespacedName{
+		Namespace: namespace,
+		Name:      objs.Instance.GetName(),
+	}
+
+	results := make([]Result, 0, len(plan.Spec.Templates))
+	for _, template := range plan.Spec.Templates {
+		if template.Action == osbv1alpha1.BindAction {
+			continue
+		}
+		result, err := objs.render(&template, "", service, plan, nil, name)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	if objs.Binding == nil {
+		return results, nil
+	}
+	name.Name = objs.Binding.GetName()
+	for _, template := range plan.Spec.Templates {
+		switch template.Action {
+		case osbv1alpha1.BindAction, osbv1alpha1.SourcesAction, osbv1alpha1.StatusAction:
+			result, err := objs.render(&template, BindingPrefix, service, plan, objs.Binding, name)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+func (objs *Objects) find(serviceID, planID string) (*osbv1alpha1.SFService, *osbv1alpha1.SFPlan, error) {
+	var service *osbv1alpha1.SFService
+	for _, s := range objs.Services {
+		if s.Spec.ID == serviceID {
+			service = s
+		}
+	}
+	if service == nil {
+		return nil, nil, errors.NewSFServiceNotFound(serviceID, nil)
+	}
+	for _, plan := range objs.Plans {
+		if plan.Spec.ID == planID {
+			return service, plan, nil
+		}
+	}
+	return nil, nil, errors.NewSFPlanNotFound(planID, nil)
+}
+
+func (objs *Objects) render(template *osbv1alpha1.TemplateSpec, prefix string, service *osbv1alpha1.SFService, plan *osbv1alpha1.SFPlan, binding *osbv1alpha1.SFServiceBinding, name types.NamespacedName) (Result, error) {
+	result := Result{
+		Name: prefix + template.Action,
+	}
+
+	r, err := rendererFactory.GetOfflineRenderer(template.Type, sourcesReader{
+		sources:   objs.Sources,
+		namespace: name.Namespace,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	var input renderer.Input
+	if template.Action == osbv1alpha1.StatusAction {
+		sources, err := objs.sources(plan, service, binding, name)
+		if err != nil {
+			return result, err
+		}
+		input, err = rendererFactory.GetStatusRendererInput(template, name, sources)
+		if err != nil {
+			return result, err
+		}
+	} else {
+		input, err = rendererFactory.GetRendererInput(template, service, plan, objs.Instance, binding, name)
+		if err != nil {
+			return result, err
+		}
+	}
+	if input == nil {
+		return result, errors.NewInputError("Render", result.Name, fmt.Errorf("template has no content"))
+	}
+
+	output, err := r.Render(input)
+	if err != nil {
+		return result, errors.NewRendererError(template.Type, fmt.Sprintf("failed rendering %s", result.Name), err)
+	}
+	files, err := output.ListFiles()
+	if err != nil {
+		return result, err
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		content, err := output.FileContent(file)
+		if err != nil {
+			return result, err
+		}
+		result.Files = append(result.Files, File{
+			Name:    file,
+			Content: content,
+		})
+	}
+	return result, nil
+}
+
+// sources renders the sources template and returns the source objects
+// listed in it. Sources without an object are left out as they are
+// when not found in the cluster.
+func (objs *Objects) sources(plan *osbv1alpha1.SFPlan, service *osbv1alpha1.SFService, binding *osbv1alpha1.SFServiceBinding, name types.NamespacedName) (map[string]*unstructured.Unstructured, error) {
+	sourceObjects := make(map[string]*unstructured.Unstructured)
+	template, err := plan.GetTemplate(osbv1alpha1.SourcesAction)
+	if err != nil {
+		if errors.TemplateNotFound(err) {
+			return sourceObjects, nil
+		}
+		return nil, err
+	}
+
+	result, err := objs.render(template, "", service, plan, binding, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Files) == 0 {
+		return sourceObjects, nil
+	}
+	sourcesFile := result.Files[0]
+	for _, file := range result.Files {
+		if file.Name == "sources.yaml" {
+			sourcesFile = file
+			break
+		}
+	}
+	sources, err := properties.ParseSources(sourcesFile.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, val := range sources {
+		if val.Name == "" {
+			continue
+		}
+		for _, obj := range objs.Sources {
+			if obj.GetAPIVersion() == val.APIVersion && obj.GetKind() == val.Kind && obj.GetName() == val.Name {
+				sourceObjects[key] = obj
+				break
+			}
+		}
+	}
+	return sourceObjects, nil
+}
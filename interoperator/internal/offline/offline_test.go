@@ -0,0 +1,250 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package offline
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+const testObjects = `apiVersion: osb.servicefabrik.io/v1alpha1
+kind: SFService
+metadata:
+  name: service-id
+spec:
+  id: service-id
+  name: postgresql
+  bindable: true
+  planUpdatable: false
+  description: postgresql
+---
+apiVersion: osb.servicefabrik.io/v1alpha1
+kind: SFPlan
+metadata:
+  name: plan-id
+spec:
+  id: plan-id
+  serviceId: service-id
+  name: small
+  description: small
+  free: true
+  bindable: true
+  planUpdatable: false
+  manager:
+    async: true
+  context:
+    size: 10
+  templates:
+  - action: provision
+    type: helm
+    url: ../../config/samples/templates/helmtemplates/postgresql
+  - action: sources
+    type: gotemplate
+    content: |
+      secret:
+        apiVersion: v1
+        kind: Secret
+        name: {{ .instance.metadata.name }}-auth
+      service:
+        apiVersion: v1
+        kind: Service
+        name: {{ .instance.metadata.name }}
+  - action: status
+    type: gotemplate
+    content: |
+      provision:
+        state: {{ if .service }}succeeded{{ else }}in progress{{ end }}
+      bind:
+        state: succeeded
+        response: {{ .secret.data.password }}
+  - action: bind
+    type: gotemplate
+    content: |
+      apiVersion: v1
+      kind: ConfigMap
+      metadata:
+        name: {{ .binding.metadata.name }}
+---
+apiVersion: osb.servicefabrik.io/v1alpha1
+kind: SFServiceInstance
+metadata:
+  name: instance-id
+  namespace: sf-instance-id
+spec:
+  serviceId: service-id
+  planId: plan-id
+  context: {}
+  organizationGuid: org
+  spaceGuid: space
+---
+apiVersion: osb.servicefabrik.io/v1alpha1
+kind: SFServiceBinding
+metadata:
+  name: binding-id
+  namespace: sf-instance-id
+spec:
+  id: binding-id
+  instanceId: instance-id
+  serviceId: service-id
+  planId: plan-id
+`
+
+const testSources = `apiVersion: v1
+kind: Secret
+metadata:
+  name: instance-id-auth
+data:
+  password: c2VjcmV0
+`
+
+func readObjects(g *gomega.GomegaWithT, docs ...string) *Objects {
+	objs := &Objects{}
+	for _, doc := range docs {
+		g.Expect(objs.Read(strings.NewReader(doc))).NotTo(gomega.HaveOccurred())
+	}
+	return objs
+}
+
+func TestObjects_Read(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	objs := readObjects(g, testObjects, testSources)
+
+	g.Expect(objs.Services).To(gomega.HaveLen(1))
+	g.Expect(objs.Plans).To(gomega.HaveLen(1))
+	g.Expect(objs.Plans[0].Spec.Templates).To(gomega.HaveLen(4))
+	g.Expect(objs.Instance.Spec.PlanID).To(gomega.Equal("plan-id"))
+	g.Expect(objs.Binding.GetName()).To(gomega.Equal("binding-id"))
+	g.Expect(objs.Sources).To(gomega.HaveLen(1))
+	g.Expect(objs.Sources[0].GetName()).To(gomega.Equal("instance-id-auth"))
+
+	g.Expect(objs.Read(strings.NewReader("foo: [bar"))).To(gomega.HaveOccurred())
+}
+
+func TestRender(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	results, err := Render(readObjects(g, testObjects, testSources))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	names := make([]string, 0, len(results))
+	for _, result := range results {
+		names = append(names, result.Name)
+	}
+	g.Expect(names).To(gomega.Equal([]string{"provision", "sources", "status",
+		"binding/sources", "binding/status", "binding/bind"}))
+
+	// helm charts are rendered without a cluster
+	g.Expect(results[0].Files).To(gomega.HaveLen(1))
+	g.Expect(results[0].Files[0].Name).To(gomega.Equal("postgres.yaml"))
+
+	// The status is rendered from the given sources only
+	g.Expect(results[2].Files[0].Content).To(gomega.ContainSubstring("state: in progress"))
+	g.Expect(results[2].Files[0].Content).To(gomega.ContainSubstring("response: c2VjcmV0"))
+
+	g.Expect(results[5].Files[0].Content).To(gomega.ContainSubstring("name: binding-id"))
+
+	// The binding is optional
+	objs := readObjects(g, testObjects, testSources)
+	objs.Binding = nil
+	results, err = Render(objs)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(results).To(gomega.HaveLen(3))
+
+	// The instance and its service and plan are required
+	objs.Instance = nil
+	_, err = Render(objs)
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	objs = readObjects(g, testObjects)
+	objs.Plans = nil
+	_, err = Render(objs)
+	g.Expect(err).To(gomega.HaveOccurred())
+}
+
+func TestRenderLookup(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	objs := readObjects(g, testObjects, testSources)
+	bind := &objs.Plans[0].Spec.Templates[3]
+	bind.Content = `password: {{ (lookup "v1" "Secret" "sf-instance-id" "instance-id-auth").data.password }}
+secrets: {{ len (lookup "v1" "Secret" "sf-instance-id" "").items }}
+missing: {{ lookup "v1" "ConfigMap" "sf-instance-id" "instance-id-auth" }}`
+
+	// lookup answers from the source objects
+	results, err := Render(objs)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(results[5].Name).To(gomega.Equal("binding/bind"))
+	g.Expect(results[5].Files[0].Content).To(gomega.Equal("password: c2VjcmV0\nsecrets: 1\nmissing: map[]"))
+
+	// and is restricted to the namespace of the templates
+	bind.Content = `{{ lookup "v1" "Secret" "default" "instance-id-auth" }}`
+	_, err = Render(objs)
+	g.Expect(err).To(gomega.HaveOccurred())
+}
+
+func TestWriteDiff(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	dir, err := ioutil.TempDir("", "offline")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	defer os.RemoveAll(dir)
+
+	results := []Result{
+		{
+			Name: "provision",
+			Files: []File{
+				{Name: "main", Content: "a\nb\nc\n"},
+			},
+		},
+		{
+			Name: "binding/bind",
+			Files: []File{
+				{Name: "main", Content: "d\n"},
+			},
+		},
+	}
+	g.Expect(Write(results, dir)).NotTo(gomega.HaveOccurred())
+	content, err := ioutil.ReadFile(filepath.Join(dir, "binding", "bind", "main"))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(string(content)).To(gomega.Equal("d\n"))
+
+	diff, err := Diff(results, dir)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(diff).To(gomega.BeEmpty())
+
+	results[0].Files[0].Content = "a\nc\nd\n"
+	results[1].Name = "bind"
+	diff, err = Diff(results, dir)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(diff).To(gomega.Equal(`--- /dev/null
++++ b/bind/main
++d
+--- a/binding/bind/main
++++ /dev/null
+-d
+--- a/provision/main
++++ b/provision/main
+ a
+-b
+ c
++d
+`))
+
+	g.Expect(Print(results[:1])).To(gomega.Equal("---\n# Source: provision/main\na\nc\nd\n"))
+}
@@ -26,9 +26,12 @@ type InstanceStatus struct {
 }
 
 // Status is all the data to be read by interoperator from
-// services. status template is unmarshalled to this struct
+// services. status template is unmarshalled to this struct.
+// Update is optional, Provision is used for updates if the
+// state of Update is not set.
 type Status struct {
 	Provision   InstanceStatus `yaml:"provision" json:"provision"`
+	Update      InstanceStatus `yaml:"update,omitempty" json:"update,omitempty"`
 	Bind        GenericStatus  `yaml:"bind" json:"bind"`
 	Unbind      GenericStatus  `yaml:"unbind" json:"unbind"`
 	Deprovision GenericStatus  `yaml:"deprovision" json:"deprovision"`
@@ -80,6 +80,26 @@ func TestParseProperties(t *testing.T) {
 			want:    nil,
 			wantErr: true,
 		},
+		{
+			name: "parse string with update",
+			args: args{
+				propertiesString: `provision:
+  state: state
+update:
+  state: updated
+  response: response`,
+			},
+			want: &Status{
+				Provision: InstanceStatus{
+					State: "state",
+				},
+				Update: InstanceStatus{
+					State:    "updated",
+					Response: "response",
+				},
+			},
+			wantErr: false,
+		},
 		{
 			name: "parse string",
 			args: args{
@@ -0,0 +1,179 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cue
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/renderer"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/build"
+)
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+type cueRenderer struct{}
+
+type cueInput struct {
+	url     string
+	content string
+	name    string
+	values  map[string]interface{}
+}
+
+// NewInput creates a new cue Renderer input object. The cue
+// is either the embedded content or the file at url.
+func NewInput(url, content, name string, values map[string]interface{}) renderer.Input {
+	if content != "" || url != "" {
+		return cueInput{
+			url:     url,
+			content: content,
+			name:    name,
+			values:  values,
+		}
+	}
+
+	return nil
+}
+
+// New creates a new cue Renderer object.
+func New() (renderer.Renderer, error) {
+	return &cueRenderer{}, nil
+}
+
+// Render unifies the cue of the input with the values and the output
+// schema and returns the fields of output as renderer.Output object.
+// The values are top level fields named by their key, e.g. instance.
+//
+// A string field is used as it is, an array field is rendered as one
+// document per element. The cue can only import the builtin packages.
+func (r *cueRenderer) Render(rawInput renderer.Input) (renderer.Output, error) {
+	input, ok := rawInput.(cueInput)
+	if !ok {
+		return nil, errors.NewRendererError("cue", "invalid input", nil)
+	}
+
+	instance, err := newInstance(input)
+	if err != nil {
+		return nil, err
+	}
+	var runtime cue.Runtime
+	evaluated, err := runtime.Build(instance)
+	if err != nil {
+		return nil, errors.NewRendererError("cue", fmt.Sprintf("can't evaluate cue for %s", input.name), err)
+	}
+	result, err := evaluated.Lookup("output").MarshalJSON()
+	if err != nil {
+		return nil, errors.NewRendererError("cue", fmt.Sprintf("can't evaluate cue for %s", input.name), err)
+	}
+
+	files, err := splitFiles(result)
+	if err != nil {
+		return nil, errors.NewRendererError("cue", fmt.Sprintf("can't render from %s", input.name), err)
+	}
+	return &cueOutput{files: files}, nil
+}
+
+// newInstance returns the instance made of the cue of the input, the
+// output schema and the values
+func newInstance(input cueInput) (*build.Instance, error) {
+	var fileName string
+	var content []byte
+	switch {
+	case input.content != "":
+		fileName, content = input.name+".cue", []byte(input.content)
+	case strings.Contains(input.url, "://") && !strings.HasPrefix(input.url, "file://"):
+		return nil, errors.NewRendererError("cue", fmt.Sprintf("unsupported cue url %s", input.url), nil)
+	default:
+		fileName = strings.TrimPrefix(input.url, "file://")
+		var err error
+		content, err = ioutil.ReadFile(fileName)
+		if err != nil {
+			return nil, errors.NewRendererError("cue", fmt.Sprintf("failed to read cue %s", fileName), err)
+		}
+	}
+
+	values, err := valuesFile(input.values)
+	if err != nil {
+		return nil, err
+	}
+
+	instance := build.NewContext().NewInstance(filepath.Dir(fileName), nil)
+	for _, file := range []struct {
+		name    string
+		content []byte
+	}{
+		{name: fileName, content: content},
+		{name: "schema.cue", content: []byte(outputSchema)},
+		{name: "values.cue", content: values},
+	} {
+		err = instance.AddFile(file.name, file.content)
+		if err != nil {
+			return nil, errors.NewRendererError("cue", fmt.Sprintf("failed to parse %s", file.name), err)
+		}
+	}
+	return instance, nil
+}
+
+// valuesFile returns the cue declaring each value as a field named by
+// its key. Json is valid cue, so the values are declared as json. Only
+// identifier labels can be referenced from the other files, so keys
+// which are identifiers are not quoted.
+func valuesFile(values map[string]interface{}) ([]byte, error) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	buf := new(bytes.Buffer)
+	for _, key := range keys {
+		content, err := json.Marshal(values[key])
+		if err != nil {
+			return nil, errors.NewRendererError("cue", fmt.Sprintf("failed to marshal %s", key), err)
+		}
+		label := key
+		if !identifier.MatchString(key) {
+			label = strconv.Quote(key)
+		}
+		fmt.Fprintf(buf, "%s: %s\n", label, content)
+	}
+	return buf.Bytes(), nil
+}
+
+// splitFiles splits the exported output into the rendered files
+func splitFiles(result []byte) (map[string]string, error) {
+	value, err := renderer.DecodeJSON(result)
+	if err != nil {
+		return nil, err
+	}
+	output, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected an object, got %T", value)
+	}
+	return renderer.SplitFiles(output)
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cue
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/properties"
+	"github.com/onsi/gomega"
+)
+
+var testValues = map[string]interface{}{
+	"instance": map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name": "foo",
+		},
+	},
+}
+
+func Test_splitFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		result  string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:   "render the fields of output as files",
+			result: `{"main": [{"apiVersion": "v1", "kind": "ConfigMap"}, {"apiVersion": "v1", "kind": "Secret"}], "status.yaml": {"provision": {"state": "succeeded"}}, "clusterSelector": "foo=bar", "none": []}`,
+			want: map[string]string{
+				"main":            "{\"apiVersion\":\"v1\",\"kind\":\"ConfigMap\"}\n---\n{\"apiVersion\":\"v1\",\"kind\":\"Secret\"}",
+				"status.yaml":     `{"provision":{"state":"succeeded"}}`,
+				"clusterSelector": "foo=bar",
+			},
+		},
+		{
+			name:   "keep the numbers as evaluated",
+			result: `{"main": {"apiVersion": "v1", "kind": "ConfigMap", "data": {"size": 10000000}}}`,
+			want: map[string]string{
+				"main": `{"apiVersion":"v1","data":{"size":10000000},"kind":"ConfigMap"}`,
+			},
+		},
+		{
+			name:    "fail if output is not an object",
+			result:  `["foo"]`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+			got, err := splitFiles([]byte(tt.result))
+			if tt.wantErr {
+				g.Expect(err).To(gomega.HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+			g.Expect(got).To(gomega.Equal(tt.want))
+		})
+	}
+}
+
+func Test_valuesFile(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	values, err := valuesFile(testValues)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(string(values)).To(gomega.Equal(`instance: {"metadata":{"name":"foo"}}` + "\n"))
+
+	values, err = valuesFile(map[string]interface{}{"a-b": 1})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(string(values)).To(gomega.Equal(`"a-b": 1` + "\n"))
+
+	values, err = valuesFile(nil)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(values).To(gomega.BeEmpty())
+}
+
+func TestCueRenderer_Render(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	content := `import "strings"
+
+output: {
+	"status.yaml": provision: state: "succeeded"
+	main: [{
+		apiVersion: "v1"
+		kind:       "ConfigMap"
+		metadata: name: strings.ToUpper(instance.metadata.name)
+	}]
+}
+`
+	r, err := New()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	output, err := r.Render(NewInput("", content, "foo", testValues))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	files, err := output.ListFiles()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(files).To(gomega.Equal([]string{"main", "status.yaml"}))
+	main, err := output.FileContent("main")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(main).To(gomega.Equal(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"FOO"}}`))
+	status, err := output.FileContent("status.yaml")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	provisionStatus, err := properties.ParseStatus(status)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(provisionStatus.Provision.State).To(gomega.Equal("succeeded"))
+
+	// The cue is read from a file
+	dir, err := ioutil.TempDir("", "cue")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	defer os.RemoveAll(dir)
+	g.Expect(ioutil.WriteFile(filepath.Join(dir, "main.cue"), []byte(content), 0644)).NotTo(gomega.HaveOccurred())
+	output, err = r.Render(NewInput("file://"+filepath.Join(dir, "main.cue"), "", "foo", testValues))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(output.FileContent("main")).To(gomega.Equal(main))
+
+	// Incomplete values are renderer errors
+	_, err = r.Render(NewInput("", content, "foo", nil))
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	// Schema violations are renderer errors
+	_, err = r.Render(NewInput("", `output: main: {apiVersion: "v1"}`, "foo", testValues))
+	g.Expect(err).To(gomega.HaveOccurred())
+	_, err = r.Render(NewInput("", `output: "status.yaml": provision: state: 1`, "foo", testValues))
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	_, err = r.Render(NewInput("https://host/postgresql.cue", "", "foo", testValues))
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	_, err = r.Render(NewInput("file://"+filepath.Join(dir, "missing.cue"), "", "foo", testValues))
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	_, err = r.Render(nil)
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	g.Expect(NewInput("", "", "foo", testValues)).To(gomega.BeNil())
+}
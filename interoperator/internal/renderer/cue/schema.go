@@ -0,0 +1,67 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cue
+
+// outputSchema is unified with the template. The files of the template
+// are the fields of output. status.yaml and sources.yaml must match the
+// structs of the properties package, any other file is either a string
+// or one or more resources.
+const outputSchema = `
+#Resource: {
+	apiVersion: string & !=""
+	kind:       string & !=""
+	metadata: {
+		name: string & !=""
+		...
+	}
+	...
+}
+
+#InstanceStatus: {
+	state:         string
+	error?:        string
+	response?:     string
+	dashboardUrl?: string
+}
+
+#GenericStatus: {
+	state:     string
+	error?:    string
+	response?: string
+}
+
+#Status: {
+	provision?:   #InstanceStatus
+	update?:      #InstanceStatus
+	bind?:        #GenericStatus
+	unbind?:      #GenericStatus
+	deprovision?: #GenericStatus
+}
+
+#Source: {
+	apiVersion: string & !=""
+	kind:       string & !=""
+	name:       string & !=""
+	namespace?: string
+}
+
+output: {
+	"status.yaml"?: #Status
+	"sources.yaml"?: [string]: #Source
+	[!~"^(status|sources)\\.yaml$"]: string | #Resource | [...#Resource]
+}
+`
@@ -7,29 +7,74 @@ import (
 	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/dynamic"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/renderer"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/renderer/cue"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/renderer/gotemplate"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/renderer/helm"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/renderer/jsonnet"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/renderer/kustomize"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-// GetRenderer returns a renderer based on the type
-func GetRenderer(rendererType string, clientSet *kubernetes.Clientset) (renderer.Renderer, error) {
+// GetRenderer returns a renderer based on the type. The objects returned
+// by the lookup function of gotemplate are read with reader, lookup fails
+// if it is nil.
+func GetRenderer(rendererType string, clientSet *kubernetes.Clientset, reader client.Reader) (renderer.Renderer, error) {
 	switch rendererType {
 	case "helm", "Helm", "HELM":
 		return helm.New(clientSet)
 	case "gotemplate", "Gotemplate", "GoTemplate", "GOTEMPLATE":
-		return gotemplate.New()
+		return gotemplate.New(reader)
+	case "kustomize", "Kustomize", "KUSTOMIZE":
+		return kustomize.New()
+	case "jsonnet", "Jsonnet", "JSONNET":
+		return jsonnet.New()
+	case "cue", "Cue", "CUE":
+		return cue.New()
 	default:
 		return nil, fmt.Errorf("unable to create renderer for type %s. not implemented", rendererType)
 	}
 }
 
+// GetOfflineRenderer returns a renderer based on the type which does
+// not connect to a cluster. The lookup function of gotemplate reads the
+// objects with reader.
+func GetOfflineRenderer(rendererType string, reader client.Reader) (renderer.Renderer, error) {
+	switch rendererType {
+	case "helm", "Helm", "HELM":
+		return helm.NewOffline()
+	default:
+		return GetRenderer(rendererType, nil, reader)
+	}
+}
+
 // GetRendererInput contructs the input required for the renderer
 func GetRendererInput(template *osbv1alpha1.TemplateSpec, service *osbv1alpha1.SFService, plan *osbv1alpha1.SFPlan, instance *osbv1alpha1.SFServiceInstance, binding *osbv1alpha1.SFServiceBinding, name types.NamespacedName) (renderer.Input, error) {
-	rendererType := template.Type
+	values, err := rendererValues(service, plan, instance, binding)
+	if err != nil {
+		return nil, err
+	}
+	return newRendererInput(template, name, values)
+}
+
+// GetBindRendererInput contructs the input required for the renderer of
+// the bind template. The credentials of the binding before a rotation
+// are passed as previous, e.g. .previous.response of gotemplate.
+func GetBindRendererInput(template *osbv1alpha1.TemplateSpec, service *osbv1alpha1.SFService, plan *osbv1alpha1.SFPlan, instance *osbv1alpha1.SFServiceInstance, binding *osbv1alpha1.SFServiceBinding, name types.NamespacedName, previous map[string]interface{}) (renderer.Input, error) {
+	values, err := rendererValues(service, plan, instance, binding)
+	if err != nil {
+		return nil, err
+	}
+	if previous != nil {
+		values["previous"] = previous
+	}
+	return newRendererInput(template, name, values)
+}
+
+func rendererValues(service *osbv1alpha1.SFService, plan *osbv1alpha1.SFPlan, instance *osbv1alpha1.SFServiceInstance, binding *osbv1alpha1.SFServiceBinding) (map[string]interface{}, error) {
 	values := make(map[string]interface{})
 
 	if service != nil {
@@ -63,23 +108,42 @@ func GetRendererInput(template *osbv1alpha1.TemplateSpec, service *osbv1alpha1.S
 		}
 		values["binding"] = bindingObj
 	}
+	return values, nil
+}
 
+func newRendererInput(template *osbv1alpha1.TemplateSpec, name types.NamespacedName, values map[string]interface{}) (renderer.Input, error) {
+	rendererType := template.Type
 	switch rendererType {
 	case "helm", "Helm", "HELM":
 		input := helm.NewInput(template.URL, name.Name, name.Namespace, values)
 		return input, nil
 	case "gotemplate", "Gotemplate", "GoTemplate", "GOTEMPLATE":
-		var content string
-		if template.Content != "" {
-			content = template.Content
-		} else if template.ContentEncoded != "" {
-			decodedContent, err := base64.StdEncoding.DecodeString(template.ContentEncoded)
-			content = string(decodedContent)
-			if err != nil {
-				return nil, fmt.Errorf("unable to decode base64 content %v", err)
-			}
+		content, err := templateContent(template)
+		if err != nil {
+			return nil, err
+		}
+		input := gotemplate.NewInput(template.URL, content, name.Name, name.Namespace, values)
+		return input, nil
+	case "kustomize", "Kustomize", "KUSTOMIZE":
+		content, err := templateContent(template)
+		if err != nil {
+			return nil, err
+		}
+		input := kustomize.NewInput(template.URL, content, name.Name, values)
+		return input, nil
+	case "jsonnet", "Jsonnet", "JSONNET":
+		content, err := templateContent(template)
+		if err != nil {
+			return nil, err
+		}
+		input := jsonnet.NewInput(template.URL, content, name.Name, values)
+		return input, nil
+	case "cue", "Cue", "CUE":
+		content, err := templateContent(template)
+		if err != nil {
+			return nil, err
 		}
-		input := gotemplate.NewInput(template.URL, content, name.Name, values)
+		input := cue.NewInput(template.URL, content, name.Name, values)
 		return input, nil
 	default:
 		return nil, fmt.Errorf("unable to create renderer for type %s. not implemented", rendererType)
@@ -100,19 +164,43 @@ func GetStatusRendererInput(template *osbv1alpha1.TemplateSpec, name types.Names
 		input := helm.NewInput(template.URL, name.Name, name.Namespace, values)
 		return input, nil
 	case "gotemplate", "Gotemplate", "GoTemplate", "GOTEMPLATE":
-		var content string
-		if template.Content != "" {
-			content = template.Content
-		} else if template.ContentEncoded != "" {
-			decodedContent, err := base64.StdEncoding.DecodeString(template.ContentEncoded)
-			content = string(decodedContent)
-			if err != nil {
-				return nil, fmt.Errorf("unable to decode base64 content %v", err)
-			}
+		content, err := templateContent(template)
+		if err != nil {
+			return nil, err
 		}
-		input := gotemplate.NewInput(template.URL, content, name.Name, values)
+		input := gotemplate.NewInput(template.URL, content, name.Name, name.Namespace, values)
+		return input, nil
+	case "jsonnet", "Jsonnet", "JSONNET":
+		content, err := templateContent(template)
+		if err != nil {
+			return nil, err
+		}
+		input := jsonnet.NewInput(template.URL, content, name.Name, values)
+		return input, nil
+	case "cue", "Cue", "CUE":
+		content, err := templateContent(template)
+		if err != nil {
+			return nil, err
+		}
+		input := cue.NewInput(template.URL, content, name.Name, values)
 		return input, nil
 	default:
 		return nil, fmt.Errorf("unable to create renderer for type %s. not implemented", rendererType)
 	}
 }
+
+// templateContent returns the content of the template, decoding the
+// base64 encoded content if it is not set
+func templateContent(template *osbv1alpha1.TemplateSpec) (string, error) {
+	if template.Content != "" {
+		return template.Content, nil
+	}
+	if template.ContentEncoded != "" {
+		decodedContent, err := base64.StdEncoding.DecodeString(template.ContentEncoded)
+		if err != nil {
+			return "", fmt.Errorf("unable to decode base64 content %v", err)
+		}
+		return string(decodedContent), nil
+	}
+	return "", nil
+}
@@ -9,8 +9,11 @@ import (
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/dynamic"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/properties"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/renderer"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/renderer/cue"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/renderer/gotemplate"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/renderer/helm"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/renderer/jsonnet"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/renderer/kustomize"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
@@ -24,6 +27,9 @@ func TestGetRenderer(t *testing.T) {
 	}
 
 	helmRenderer, _ := helm.New(nil)
+	kustomizeRenderer, _ := kustomize.New()
+	jsonnetRenderer, _ := jsonnet.New()
+	cueRenderer, _ := cue.New()
 	tests := []struct {
 		name    string
 		args    args
@@ -39,6 +45,33 @@ func TestGetRenderer(t *testing.T) {
 			want:    helmRenderer,
 			wantErr: true,
 		},
+		{
+			name: "testValidInputKustomize",
+			args: args{
+				rendererType: "kustomize",
+				clientSet:    nil,
+			},
+			want:    kustomizeRenderer,
+			wantErr: false,
+		},
+		{
+			name: "testValidInputJsonnet",
+			args: args{
+				rendererType: "jsonnet",
+				clientSet:    nil,
+			},
+			want:    jsonnetRenderer,
+			wantErr: false,
+		},
+		{
+			name: "testValidInputCue",
+			args: args{
+				rendererType: "cue",
+				clientSet:    nil,
+			},
+			want:    cueRenderer,
+			wantErr: false,
+		},
 		{
 			name: "testInvalidInput",
 			args: args{
@@ -51,7 +84,7 @@ func TestGetRenderer(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := GetRenderer(tt.args.rendererType, tt.args.clientSet)
+			got, err := GetRenderer(tt.args.rendererType, tt.args.clientSet, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetRenderer() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -373,7 +406,7 @@ func TestGetPropertiesRendererInput(t *testing.T) {
 
 	template, _ := plan.GetTemplate(osbv1alpha1.SourcesAction)
 	clientSet, _ := kubernetes.NewForConfig(cfg)
-	rendererObj, _ := GetRenderer(template.Type, clientSet)
+	rendererObj, _ := GetRenderer(template.Type, clientSet, nil)
 	input, _ := GetRendererInput(template, &service, &plan, &instance, &binding, name)
 	output, _ := rendererObj.Render(input)
 	files, _ := output.ListFiles()
@@ -404,7 +437,7 @@ func TestGetPropertiesRendererInput(t *testing.T) {
 	helmInput := helm.NewInput(template.URL, name.Name, name.Namespace, values)
 
 	template2, _ := plan2.GetTemplate(osbv1alpha1.SourcesAction)
-	rendererObj2, _ := GetRenderer(template2.Type, nil)
+	rendererObj2, _ := GetRenderer(template2.Type, nil, nil)
 	input2, _ := GetRendererInput(template2, &service, &plan, &instance, &binding, name)
 	output2, _ := rendererObj2.Render(input2)
 	files2, _ := output2.ListFiles()
@@ -467,7 +500,7 @@ func TestGetPropertiesRendererInput(t *testing.T) {
 				name:     name,
 				sources:  sourceObjects2,
 			},
-			want:    gotemplate.NewInput(template.URL, "statuscontent", "foo", values2),
+			want:    gotemplate.NewInput(templateSpec2[2].URL, "statuscontent", "foo", name.Namespace, values2),
 			wantErr: false,
 		},
 	}
@@ -101,7 +101,7 @@ func TestGoTemplateRenderer(t *testing.T) {
 	}
 
 	template, _ := plan.GetTemplate("provision")
-	renderer, _ := GetRenderer(template.Type, nil)
+	renderer, _ := GetRenderer(template.Type, nil, nil)
 	input, _ := GetRendererInput(template, &service, &plan, &instance, &binding, name)
 	output, _ := renderer.Render(input)
 	files, _ := output.ListFiles()
@@ -126,21 +126,21 @@ func TestGoTemplateRenderer(t *testing.T) {
 
 	plan.Spec.Templates[0].ContentEncoded = "e3sgInByb3Zpc2lvbiIgfCB1bmtub3duX2Z1bmN0aW9uIH19" //{{ "provision" | unknown_function }}
 	template4, _ := plan.GetTemplate("provision")
-	renderer4, _ := GetRenderer(template4.Type, nil)
+	renderer4, _ := GetRenderer(template4.Type, nil, nil)
 	input4, _ := GetRendererInput(template4, &service, &plan, &instance, &binding, name)
 	output4, _ := renderer4.Render(input4)
 	g.Expect(output4).To(gomega.BeNil())
 
 	plan.Spec.Templates[0].ContentEncoded = "provision | unknown_function" //{{ "provision" | unknown_function }}
 	template5, _ := plan.GetTemplate("provision")
-	renderer5, _ := GetRenderer(template5.Type, nil)
+	renderer5, _ := GetRenderer(template5.Type, nil, nil)
 	input5, _ := GetRendererInput(template5, &service, &plan, &instance, &binding, name)
 	output5, _ := renderer5.Render(input5)
 	g.Expect(output5).To(gomega.BeNil())
 
 	plan.Spec.Templates[0].Content = "provisioncontent"
 	template6, _ := plan.GetTemplate("provision")
-	renderer6, _ := GetRenderer(template6.Type, nil)
+	renderer6, _ := GetRenderer(template6.Type, nil, nil)
 	input6, _ := GetRendererInput(template6, &service, &plan, &instance, &binding, name)
 	output6, _ := renderer6.Render(input6)
 	files6, _ := output6.ListFiles()
@@ -152,7 +152,7 @@ func TestGoTemplateRenderer(t *testing.T) {
 
 	plan.Spec.Templates[2].ContentEncoded = `e3sgJG5hbWUgOj0gIiIgfX0Ke3stIHdpdGggLnBvc3RncmVzcWxtdC5tZXRhZGF0YS5uYW1lIH19CiAge3stICRuYW1lID0gLiB9fQp7ey0gZW5kIH19Cnt7LSAkc3RhdGVTdHJpbmcgOj0gImluX3F1ZXVlIiB9fQp7ey0gJHJlc3BvbnNlIDo9ICIiIH19Cnt7LSAkZXJyb3IgOj0gIiIgfX0Ke3stIHdpdGggLnBvc3RncmVzcWxtdC5zdGF0dXMgfX0KICB7ey0gaWYgZXEgLnN0YXRlICJzdWNjZWVkZWQiIH19CiAgICB7ey0gJHN0YXRlU3RyaW5nID0gInN1Y2NlZWRlZCIgfX0KICAgIHt7LSAkcmVzcG9uc2UgPSAkcmVzcG9uc2UgfX0KICB7ey0gZWxzZSB9fQogICAge3stIGlmIGVxIC5zdGF0ZSAiZmFpbGVkIn19CiAgICAgIHt7LSAkc3RhdGVTdHJpbmcgPSAiZmFpbGVkIiB9fQogICAgICB7ey0gJGVycm9yID0gIC5lcnJvciB9fQogICAge3stIGVuZCB9fQogIHt7LSBlbmQgfX0Ke3stIGVuZCB9fQp7ey0gaWYgZXEgJHN0YXRlU3RyaW5nICJzdWNjZWVkZWQiIH19CiAge3stICRyZXNwb25zZSA9IChwcmludGYgIlNlcnZpY2UgSW5zdGFuY2UgJXMgY3JlYXRpb24gc3VjY2Vzc2Z1bGwiICRuYW1lKSB9fQp7ey0gZWxzZSB9fQogICAge3stICRyZXNwb25zZSA9IChwcmludGYgIlNlcnZpY2UgSW5zdGFuY2UgJXMgcHJvdmlzaW9uIGZhaWxlZCIgJG5hbWUpIH19Cnt7LSBlbmQgfX0Ke3stIGlmIGVxICRzdGF0ZVN0cmluZyAiaW5fcXVldWUiIH19CiAge3stICRyZXNwb25zZSA9ICIiIH19Cnt7LSBlbmQgfX0KcHJvdmlzaW9uOgogIHN0YXRlOiB7eyAkc3RhdGVTdHJpbmcgfX0KICByZXNwb25zZToge3sgJHJlc3BvbnNlIH19Cnt7LSBpZiBlcSAkc3RhdGVTdHJpbmcgImZhaWxlZCIgfX0KICBlcnJvcjoge3sgJGVycm9yIHwgcXVvdGV9fQp7ey0gZW5kIH19CiAgZGFzaGJvYXJkVXJsOiAiIgp7ey0gd2l0aCAucG9zdGdyZXNxbG10YmluZC5zdGF0dXMgfX0KICB7ey0gJHJlc3BvbnNlID0gKGI2NGRlYyAucmVzcG9uc2UgfCBxdW90ZSkgfX0Ke3stIGVuZCB9fQp7ey0gJHN0YXRlU3RyaW5nID0gImluX3F1ZXVlIiB9fSAKe3stIHdpdGggLnBvc3RncmVzcWxtdGJpbmQgfX0KICB7ey0gd2l0aCAuc3RhdHVzIH19CiAgICB7ey0gaWYgZXEgLnN0YXRlICJzdWNjZWVkZWQiIH19CiAgICAgIHt7LSAkc3RhdGVTdHJpbmcgPSAic3VjY2VlZGVkIiB9fQogICAge3stIGVsc2UgfX0KICAgICAge3stIGlmIGVxIC5zdGF0ZSAiZmFpbGVkIiB9fQogICAgICAgIHt7LSAkc3RhdGVTdHJpbmcgPSAiZmFpbGVkIiB9fQogICAgICAgIHt7LSAkZXJyb3IgPSAgLmVycm9yIH19CiAgICAgIHt7LSBlbmQgfX0KICAgIHt7LSBlbmQgfX0KICB7ey0gZW5kIH19Cnt7LSBlbmQgfX0KYmluZDoKICBzdGF0ZToge3sgJHN0YXRlU3RyaW5nIH19Cnt7LSBpZiBlcSAkc3RhdGVTdHJpbmcgImZhaWxlZCIgfX0KICBlcnJvcjoge3sgJGVycm9yIHwgcXVvdGV9fQp7ey0gZW5kIH19CiAgcmVzcG9uc2U6IHt7ICRyZXNwb25zZSB9fQp7ey0gd2l0aCAucG9zdGdyZXNxbG10YmluZC5zdGF0dXMgfX0KICB7ey0gJHJlc3BvbnNlID0gKGI2NGRlYyAucmVzcG9uc2UgfCBxdW90ZSkgfX0Ke3stIGVuZCB9fQp7ey0gJHN0YXRlU3RyaW5nID0gImRlbGV0ZSIgfX0gCnt7LSB3aXRoIC5wb3N0Z3Jlc3FsbXRiaW5kIH19CiAge3stIHdpdGggLnN0YXR1cyB9fQogICAge3stIGlmIGVxIC5zdGF0ZSAic3VjY2VlZGVkIiB9fQogICAgICB7ey0gJHN0YXRlU3RyaW5nID0gInN1Y2NlZWRlZCIgfX0KICAgIHt7LSBlbHNlIH19CiAgICAgIHt7LSBpZiBlcSAuc3RhdGUgImZhaWxlZCIgfX0KICAgICAgICB7ey0gJHN0YXRlU3RyaW5nID0gImZhaWxlZCIgfX0KICAgICAgICB7ey0gJGVycm9yID0gIC5lcnJvciB9fQogICAgICB7ey0gZW5kIH19CiAgICB7ey0gZW5kIH19CiAge3stIGVuZCB9fQp7ey0gZWxzZSB9fQogIHt7LSAkc3RhdGVTdHJpbmcgPSAic3VjY2VlZGVkIiB9fQp7ey0gZW5kIH19CnVuYmluZDoKICBzdGF0ZToge3sgJHN0YXRlU3RyaW5nIH19Cnt7LSBpZiBlcSAkc3RhdGVTdHJpbmcgImZhaWxlZCIgfX0KICBlcnJvcjoge3sgJGVycm9yIHwgcXVvdGV9fQp7ey0gZW5kIH19CiAgcmVzcG9uc2U6IHt7ICRyZXNwb25zZSB9fQp7ey0gJHJlc3BvbnNlIDo9ICIiIH19Cnt7LSAkc3RhdGVTdHJpbmcgPSAiZGVsZXRlIiB9fSAKe3stIHdpdGggLnBvc3RncmVzcWxtdCB9fQogIHt7LSB3aXRoIC5zdGF0dXMgfX0KICAgIHt7LSBpZiBlcSAuc3RhdGUgImRlbGV0ZSIgfX0KICAgICAge3stICRzdGF0ZVN0cmluZyA9ICJkZWxldGUiIH19CiAgICB7ey0gZWxzZSB9fQogICAgICB7ey0gaWYgZXEgLnN0YXRlICJmYWlsZWQiIH19CiAgICAgICAge3stICRzdGF0ZVN0cmluZyA9ICJmYWlsZWQiIH19CiAgICAgICAge3stICRlcnJvciA9ICAuZXJyb3IgfX0KICAgICAge3stIGVuZCB9fQogICAge3stIGVuZCB9fQogIHt7LSBlbmQgfX0Ke3stIGVsc2UgfX0KICB7ey0gJHN0YXRlU3RyaW5nID0gInN1Y2NlZWRlZCIgfX0Ke3stIGVuZCB9fQpkZXByb3Zpc2lvbjoKICBzdGF0ZToge3sgJHN0YXRlU3RyaW5nIH19Cnt7LSBpZiBlcSAkc3RhdGVTdHJpbmcgImZhaWxlZCIgfX0KICBlcnJvcjoge3sgJGVycm9yIHwgcXVvdGV9fQp7ey0gZW5kIH19CiAgcmVzcG9uc2U6IHt7ICRyZXNwb25zZSB9fQo=`
 	template7, _ := plan.GetTemplate("status")
-	renderer7, _ := GetRenderer(template7.Type, nil)
+	renderer7, _ := GetRenderer(template7.Type, nil, nil)
 	input7, err7 := GetRendererInput(template7, &service, &plan, &instance, &binding, name)
 	output7, err8 := renderer7.Render(input7)
 	files7, err9 := output7.ListFiles()
@@ -131,7 +131,7 @@ func TestHelmTemplateRenderer(t *testing.T) {
 
 	template, _ := plan.GetTemplate("provision")
 	clientSet, _ := kubernetes.NewForConfig(cfg)
-	renderer, _ := GetRenderer(template.Type, clientSet)
+	renderer, _ := GetRenderer(template.Type, clientSet, nil)
 	input, _ := GetRendererInput(template, &service, &plan, &instance, &binding, name)
 	output, _ := renderer.Render(input)
 	files, _ := output.ListFiles()
@@ -157,7 +157,7 @@ spec:
   terminationPolicy: WipeOut`))
 
 	clientSet2, _ := kubernetes.NewForConfig(cfg)
-	renderer2, _ := GetRenderer(template.Type, clientSet2)
+	renderer2, _ := GetRenderer(template.Type, clientSet2, nil)
 	input2, _ := GetRendererInput(template, &service, &plan, &instance, &binding, name)
 	output2, _ := renderer2.Render(input2)
 	files2, _ := output2.ListFiles()
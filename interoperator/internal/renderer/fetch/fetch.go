@@ -0,0 +1,97 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fetch has the helpers shared by the renderers which fetch
+// templates and charts from remote locations.
+package fetch
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+)
+
+// maxRedirects is the number of redirects followed, like the default
+// policy of http.Client
+const maxRedirects = 10
+
+var digestSuffix = regexp.MustCompile(`@sha256:([a-f0-9]{64})$`)
+
+// NewHTTPClient returns the client used to fetch templates and charts.
+// Requests time out after constants.RendererFetchTimeout and redirects
+// which would send credentials over plain http are refused.
+func NewHTTPClient() *http.Client {
+	return &http.Client{
+		Timeout: constants.RendererFetchTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return CheckCredentials(req.URL.String(), req.Header)
+		},
+	}
+}
+
+// CheckCredentials returns an error if the header carries credentials
+// and the url is not https
+func CheckCredentials(url string, header http.Header) error {
+	if header.Get("Authorization") == "" {
+		return nil
+	}
+	if !strings.HasPrefix(strings.ToLower(url), "https://") {
+		return fmt.Errorf("refusing to send credentials to %s over plain http", url)
+	}
+	return nil
+}
+
+// ReadAll reads the content, failing if it is larger than limit bytes
+func ReadAll(r io.Reader, limit int64) ([]byte, error) {
+	content, err := ioutil.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) > limit {
+		return nil, fmt.Errorf("content is larger than %d bytes", limit)
+	}
+	return content, nil
+}
+
+// Copy copies the content to dst, failing if it is larger than limit
+// bytes
+func Copy(dst io.Writer, src io.Reader, limit int64) (int64, error) {
+	written, err := io.Copy(dst, io.LimitReader(src, limit+1))
+	if err != nil {
+		return written, err
+	}
+	if written > limit {
+		return written, fmt.Errorf("content is larger than %d bytes", limit)
+	}
+	return written, nil
+}
+
+// SplitDigest splits the @sha256:<digest> suffix from the reference
+func SplitDigest(ref string) (string, string) {
+	match := digestSuffix.FindStringSubmatchIndex(ref)
+	if match == nil {
+		return ref, ""
+	}
+	return ref[:match[0]], ref[match[2]:match[3]]
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fetch
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+func TestCheckCredentials(t *testing.T) {
+	withAuth := make(http.Header)
+	withAuth.Set("Authorization", "Bearer token")
+
+	tests := []struct {
+		name    string
+		url     string
+		header  http.Header
+		wantErr bool
+	}{
+		{
+			name:    "allow plain http without credentials",
+			url:     "http://host/chart.tgz",
+			header:  make(http.Header),
+			wantErr: false,
+		},
+		{
+			name:    "allow https with credentials",
+			url:     "https://host/chart.tgz",
+			header:  withAuth,
+			wantErr: false,
+		},
+		{
+			name:    "refuse plain http with credentials",
+			url:     "http://host/chart.tgz",
+			header:  withAuth,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+			err := CheckCredentials(tt.url, tt.header)
+			if tt.wantErr {
+				g.Expect(err).To(gomega.HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(gomega.HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestNewHTTPClient_redirect(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("content"))
+	}))
+	defer target.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Redirect(w, req, target.URL, http.StatusFound)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient()
+	resp, err := client.Get(server.URL)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	resp.Body.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	req.SetBasicAuth("user", "password")
+	_, err = client.Do(req)
+	g.Expect(err).To(gomega.HaveOccurred())
+}
+
+func TestReadAll(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	content, err := ReadAll(strings.NewReader("content"), 7)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(string(content)).To(gomega.Equal("content"))
+
+	_, err = ReadAll(strings.NewReader("content"), 6)
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	buf := new(bytes.Buffer)
+	_, err = Copy(buf, strings.NewReader("content"), 7)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(buf.String()).To(gomega.Equal("content"))
+
+	_, err = Copy(new(bytes.Buffer), strings.NewReader("content"), 6)
+	g.Expect(err).To(gomega.HaveOccurred())
+}
+
+func TestSplitDigest(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	digest := strings.Repeat("a", 64)
+
+	ref, got := SplitDigest("https://host/chart.tgz@sha256:" + digest)
+	g.Expect(ref).To(gomega.Equal("https://host/chart.tgz"))
+	g.Expect(got).To(gomega.Equal(digest))
+
+	ref, got = SplitDigest("repo/chart@1.0.0")
+	g.Expect(ref).To(gomega.Equal("repo/chart@1.0.0"))
+	g.Expect(got).To(gomega.BeEmpty())
+}
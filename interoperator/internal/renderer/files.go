@@ -0,0 +1,74 @@
+/*
+Copyright 2018 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package renderer
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// DecodeJSON decodes the json output of a renderer. Numbers are kept as
+// json.Number, so that they are rendered as they were evaluated.
+func DecodeJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	err := decoder.Decode(&value)
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// SplitFiles renders the content of each file as yaml documents. Strings
+// are used as they are, each item of an array is a document and any other
+// value is a single document. Json is used as it is valid yaml. An empty
+// array renders no file.
+func SplitFiles(contents map[string]interface{}) (map[string]string, error) {
+	files := make(map[string]string)
+	for name, content := range contents {
+		doc, err := documents(content)
+		if err != nil {
+			return nil, err
+		}
+		if doc != "" {
+			files[name] = doc
+		}
+	}
+	return files, nil
+}
+
+func documents(value interface{}) (string, error) {
+	switch x := value.(type) {
+	case string:
+		return x, nil
+	case []interface{}:
+		docs := make([]string, 0, len(x))
+		for _, item := range x {
+			doc, err := json.Marshal(item)
+			if err != nil {
+				return "", err
+			}
+			docs = append(docs, string(doc))
+		}
+		return strings.Join(docs, "\n---\n"), nil
+	default:
+		doc, err := json.Marshal(x)
+		return string(doc), err
+	}
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2018 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package renderer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitFiles(t *testing.T) {
+	value, err := DecodeJSON([]byte(`{
+		"main": [{"kind": "ConfigMap", "size": 1.50}, {"kind": "Secret"}],
+		"status": "state: succeeded",
+		"object": {"replicas": 3},
+		"empty": []
+	}`))
+	if err != nil {
+		t.Fatalf("DecodeJSON() error = %v", err)
+	}
+	got, err := SplitFiles(value.(map[string]interface{}))
+	if err != nil {
+		t.Fatalf("SplitFiles() error = %v", err)
+	}
+	want := map[string]string{
+		"main":   "{\"kind\":\"ConfigMap\",\"size\":1.50}\n---\n{\"kind\":\"Secret\"}",
+		"status": "state: succeeded",
+		"object": "{\"replicas\":3}",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SplitFiles() = %v, want %v", got, want)
+	}
+}
@@ -1,11 +1,33 @@
 package gotemplate
 
 import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"os"
 	"strings"
 	"text/template"
+
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+
+	"github.com/Masterminds/sprig"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/helm/pkg/chartutil"
+	kubernetes "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Character sets of the rand functions
+const (
+	alphaCharset    = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	numericCharset  = "0123456789"
+	alphaNumCharset = alphaCharset + numericCharset
+	asciiCharset    = " !\"#$%&'()*+,-./" + numericCharset + ":;<=>?@ABCDEFGHIJKLMNOPQRSTUVWXYZ[\\]^_`abcdefghijklmnopqrstuvwxyz{|}~"
 )
 
 // encodeToString converts a string to base64 encoded string
@@ -63,14 +85,169 @@ func strval(v interface{}) string {
 	}
 }
 
+// required fails the rendering with the message if the value is
+// nil or an empty string
+func required(message string, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, fmt.Errorf("%s", message)
+	}
+	if str, ok := value.(string); ok && str == "" {
+		return nil, fmt.Errorf("%s", message)
+	}
+	return value, nil
+}
+
+// getFuncMap returns the sprig functions along with the helm and
+// interoperator specific functions. env and expandenv are removed as
+// in helm.
 func getFuncMap() template.FuncMap {
-	funcMap := template.FuncMap{
+	funcMap := sprig.TxtFuncMap()
+	delete(funcMap, "env")
+	delete(funcMap, "expandenv")
+
+	extra := template.FuncMap{
 		"b64enc":        encodeToString,
 		"b64dec":        decodeString,
 		"unmarshalJSON": unmarshalJSON,
 		"marshalJSON":   marshalJSON,
 		"quote":         quote,
 		"squote":        squote,
+		"required":      required,
+		"toToml":        chartutil.ToToml,
+		"toYaml":        chartutil.ToYaml,
+		"fromYaml":      chartutil.FromYaml,
+		"toJson":        chartutil.ToJson,
+		"fromJson":      chartutil.FromJson,
+	}
+	for k, v := range extra {
+		funcMap[k] = v
 	}
 	return funcMap
 }
+
+// getRenderFuncMap returns the functions bound to a single rendering.
+// include and tpl render using <engine> and <funcMap>. The rand functions
+// return the same values for the same seed and call sequence. They fail
+// if RAND_SEED is not set, as anyone knowing the seed could reproduce
+// the values.
+func getRenderFuncMap(engine *template.Template, funcMap template.FuncMap, seed string) template.FuncMap {
+	rand := newSeededRand(os.Getenv(constants.RandSeedEnvKey), seed)
+	renderFuncMap := template.FuncMap{
+		"include": func(name string, data interface{}) (string, error) {
+			buf := new(bytes.Buffer)
+			err := engine.ExecuteTemplate(buf, name, data)
+			if err != nil {
+				return "", err
+			}
+			return buf.String(), nil
+		},
+		"randAlphaNum": func(count int) (string, error) { return rand.randString(count, alphaNumCharset) },
+		"randAlpha":    func(count int) (string, error) { return rand.randString(count, alphaCharset) },
+		"randNumeric":  func(count int) (string, error) { return rand.randString(count, numericCharset) },
+		"randAscii":    func(count int) (string, error) { return rand.randString(count, asciiCharset) },
+	}
+	renderFuncMap["tpl"] = func(content string, data interface{}) (string, error) {
+		t, err := template.New("tpl").Funcs(funcMap).Funcs(renderFuncMap).Parse(content)
+		if err != nil {
+			return "", err
+		}
+		buf := new(bytes.Buffer)
+		err = t.Execute(buf, data)
+		if err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+	return renderFuncMap
+}
+
+// lookup fetches an object from the namespace the template is rendered
+// for. All objects of the kind in the namespace are listed if name is
+// empty. An empty map is returned if the object is not found. Objects in
+// other namespaces can not be looked up, as the templates are provided by
+// the service owners.
+func lookup(reader kubernetes.Reader, allowedNamespace string) func(apiVersion, kind, namespace, name string) (map[string]interface{}, error) {
+	return func(apiVersion, kind, namespace, name string) (map[string]interface{}, error) {
+		if reader == nil {
+			return nil, fmt.Errorf("lookup is not available in this template")
+		}
+		if namespace != allowedNamespace {
+			return nil, fmt.Errorf("lookup is restricted to the namespace %q", allowedNamespace)
+		}
+		if name == "" {
+			list := &unstructured.UnstructuredList{}
+			list.SetAPIVersion(apiVersion)
+			list.SetKind(kind + "List")
+			err := reader.List(context.TODO(), list, kubernetes.InNamespace(namespace))
+			if err != nil {
+				if apiErrors.IsNotFound(err) {
+					return map[string]interface{}{}, nil
+				}
+				return nil, err
+			}
+			return list.UnstructuredContent(), nil
+		}
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion(apiVersion)
+		obj.SetKind(kind)
+		err := reader.Get(context.TODO(), kubernetes.ObjectKey{Namespace: namespace, Name: name}, obj)
+		if err != nil {
+			if apiErrors.IsNotFound(err) {
+				return map[string]interface{}{}, nil
+			}
+			return nil, err
+		}
+		return obj.Object, nil
+	}
+}
+
+// seededRand generates a deterministic random stream from a secret and
+// a seed using HMAC-SHA256 in counter mode. Rotating the secret changes
+// every value generated afterwards, e.g. the passwords of bindings whose
+// credentials are rendered again.
+type seededRand struct {
+	secret  []byte
+	seed    []byte
+	counter uint64
+	buf     []byte
+}
+
+func newSeededRand(secret, seed string) *seededRand {
+	return &seededRand{
+		secret: []byte(secret),
+		seed:   []byte(seed),
+	}
+}
+
+func (r *seededRand) nextByte() byte {
+	if len(r.buf) == 0 {
+		mac := hmac.New(sha256.New, r.secret)
+		mac.Write(r.seed)
+		counter := make([]byte, 8)
+		binary.BigEndian.PutUint64(counter, r.counter)
+		mac.Write(counter)
+		r.buf = mac.Sum(nil)
+		r.counter++
+	}
+	b := r.buf[0]
+	r.buf = r.buf[1:]
+	return b
+}
+
+// randString returns count random characters from charset. Bytes
+// beyond the largest multiple of the charset length are rejected to
+// avoid a bias.
+func (r *seededRand) randString(count int, charset string) (string, error) {
+	if len(r.secret) == 0 {
+		return "", fmt.Errorf("%s is not set", constants.RandSeedEnvKey)
+	}
+	limit := 256 - 256%len(charset)
+	out := make([]byte, 0, count)
+	for len(out) < count {
+		b := int(r.nextByte())
+		if b < limit {
+			out = append(out, charset[b%len(charset)])
+		}
+	}
+	return string(out), nil
+}
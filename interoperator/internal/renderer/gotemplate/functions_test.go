@@ -1,9 +1,16 @@
 package gotemplate
 
 import (
+	"os"
+	"strings"
 	"testing"
 
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
 	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 func TestGoTemplateFunctions(t *testing.T) {
@@ -54,3 +61,185 @@ func TestGoTemplateFunctions(t *testing.T) {
 	g.Expect(intVal).To(gomega.Equal("10"))
 
 }
+
+func renderTemplate(content string, values map[string]interface{}) (string, error) {
+	r, err := New(nil)
+	if err != nil {
+		return "", err
+	}
+	output, err := r.Render(NewInput("", content, "instance-id", "default", values))
+	if err != nil {
+		return "", err
+	}
+	return output.FileContent("main")
+}
+
+func TestGoTemplateFuncMap(t *testing.T) {
+	values := map[string]interface{}{
+		"instance": map[string]interface{}{
+			"spec": map[string]interface{}{
+				"parameters": map[string]interface{}{
+					"size": "small",
+				},
+			},
+		},
+	}
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "use sprig functions",
+			content: `{{ .instance.spec.parameters.replicas | default 3 }} {{ list 1 2 | join "," }} {{ "foo" | upper }}`,
+			want:    "3 1,2 FOO",
+			wantErr: false,
+		},
+		{
+			name:    "keep the interoperator functions",
+			content: `{{ "Hello" | b64enc }} {{ b64dec "SGVsbG8=" }}`,
+			want:    "SGVsbG8= Hello",
+			wantErr: false,
+		},
+		{
+			name:    "not offer env functions",
+			content: `{{ env "HOME" }}`,
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name:    "convert to and from yaml",
+			content: `{{ .instance.spec.parameters | toYaml }} {{ (fromYaml "size: large").size }}`,
+			want:    "size: small\n large",
+			wantErr: false,
+		},
+		{
+			name:    "convert to and from json",
+			content: `{{ .instance.spec.parameters | toJson }} {{ (fromJson "{\"size\": \"large\"}").size }}`,
+			want:    `{"size":"small"} large`,
+			wantErr: false,
+		},
+		{
+			name:    "return the value if required is set",
+			content: `{{ required "size is required" .instance.spec.parameters.size }}`,
+			want:    "small",
+			wantErr: false,
+		},
+		{
+			name:    "fail if required is not set",
+			content: `{{ required "replicas is required" .instance.spec.parameters.replicas }}`,
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name:    "include a named template",
+			content: `{{ define "size" }}{{ .size | quote }}{{ end }}size: {{ include "size" .instance.spec.parameters | indent 2 }}`,
+			want:    `size:   "small"`,
+			wantErr: false,
+		},
+		{
+			name:    "render a string as a template",
+			content: `{{ tpl "{{ .size | upper }}" .instance.spec.parameters }}`,
+			want:    "SMALL",
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+			got, err := renderTemplate(tt.content, values)
+			if tt.wantErr {
+				g.Expect(err).To(gomega.HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+			g.Expect(got).To(gomega.Equal(tt.want))
+		})
+	}
+}
+
+func TestGoTemplateRandFunctions(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	content := `{{ randAlphaNum 16 }} {{ randAlphaNum 16 }} {{ randNumeric 4 }}`
+
+	// Fails closed without a secret
+	os.Unsetenv(constants.RandSeedEnvKey)
+	_, err := renderTemplate(content, nil)
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	os.Setenv(constants.RandSeedEnvKey, "secret")
+	defer os.Unsetenv(constants.RandSeedEnvKey)
+	first, err := renderTemplate(content, nil)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	second, err := renderTemplate(content, nil)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(first).To(gomega.Equal(second))
+	g.Expect(first).To(gomega.MatchRegexp(`^[a-zA-Z0-9]{16} [a-zA-Z0-9]{16} [0-9]{4}$`))
+
+	words := strings.Split(first, " ")
+	g.Expect(words[0]).NotTo(gomega.Equal(words[1]))
+
+	// Every binding gets its own values
+	binding, err := renderTemplate(content, map[string]interface{}{
+		"binding": map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"name": "binding-id",
+			},
+		},
+	})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(binding).NotTo(gomega.Equal(first))
+
+	// The secret changes the values
+	rand := newSeededRand("secret", "instance-id")
+	g.Expect(rand.randString(16, alphaNumCharset)).To(gomega.Equal(words[0]))
+	rand = newSeededRand("rotated", "instance-id")
+	g.Expect(rand.randString(16, alphaNumCharset)).NotTo(gomega.Equal(words[0]))
+}
+
+func TestGoTemplateLookup(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	c := fake.NewFakeClientWithScheme(scheme.Scheme, &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Data: map[string]string{
+			"key": "value",
+		},
+	})
+	lookupFn := lookup(c, "default")
+
+	obj, err := lookupFn("v1", "ConfigMap", "default", "foo")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(obj["data"]).To(gomega.Equal(map[string]interface{}{"key": "value"}))
+
+	obj, err = lookupFn("v1", "ConfigMap", "default", "bar")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(obj).To(gomega.BeEmpty())
+
+	// Objects outside of the namespace of the template can not be looked up
+	_, err = lookupFn("v1", "ConfigMap", "kube-system", "foo")
+	g.Expect(err).To(gomega.HaveOccurred())
+	_, err = lookup(c, "other")("v1", "ConfigMap", "default", "foo")
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	_, err = lookup(nil, "default")("v1", "ConfigMap", "default", "foo")
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	// The namespace of the input is used in templates
+	r, err := New(c)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	output, err := r.Render(NewInput("", `{{ (lookup "v1" "ConfigMap" "default" "foo").data.key }}`, "instance-id", "default", nil))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(output.FileContent("main")).To(gomega.Equal("value"))
+	_, err = r.Render(NewInput("", `{{ lookup "v1" "ConfigMap" "default" "foo" }}`, "instance-id", "other", nil))
+	g.Expect(err).To(gomega.HaveOccurred())
+}
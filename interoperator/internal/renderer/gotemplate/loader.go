@@ -0,0 +1,250 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gotemplate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/renderer/fetch"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// cacheTTL is the time a template fetched from a url without digest is
+// cached before it is fetched again
+const cacheTTL = 5 * time.Minute
+
+var (
+	sharedLoader     *templateLoader
+	sharedLoaderOnce sync.Once
+)
+
+// templateLoader fetches templates from a url. The following urls are supported
+//
+//	file:///path/to/template
+//	https://host/path/to/template
+//	configmap://namespace/name/key
+//
+// A url can be pinned by appending @sha256:<digest> of the template.
+// Pinned templates are cached by digest, others for cacheTTL.
+//
+// Files are only read below the template root and ConfigMaps only from
+// the namespace of interoperator. Templates are limited to
+// constants.MaxTemplateSize.
+type templateLoader struct {
+	httpClient   *http.Client
+	client       client.Reader
+	namespace    string
+	templateRoot string
+
+	mu    sync.Mutex
+	cache map[string]cachedTemplate
+}
+
+type cachedTemplate struct {
+	content string
+	expires time.Time
+}
+
+// getTemplateLoader returns the template loader shared by all
+// gotemplate renderers. The template root is read from TEMPLATE_ROOT
+// and the namespace of interoperator from POD_NAMESPACE.
+func getTemplateLoader() *templateLoader {
+	sharedLoaderOnce.Do(func() {
+		templateRoot := os.Getenv(constants.TemplateRootEnvKey)
+		if templateRoot == "" {
+			templateRoot = constants.DefaultTemplateRoot
+		}
+		namespace := os.Getenv(constants.NamespaceEnvKey)
+		if namespace == "" {
+			namespace = constants.DefaultServiceFabrikNamespace
+		}
+		sharedLoader = newTemplateLoader(fetch.NewHTTPClient(), nil, namespace, templateRoot)
+	})
+	return sharedLoader
+}
+
+// InitTemplateLoader sets the client used to read templates from
+// ConfigMaps. It is the client of the manager, configmap:// urls fail
+// until it is set.
+func InitTemplateLoader(c client.Reader) {
+	l := getTemplateLoader()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.client = c
+}
+
+func newTemplateLoader(httpClient *http.Client, c client.Reader, namespace, templateRoot string) *templateLoader {
+	return &templateLoader{
+		httpClient:   httpClient,
+		client:       c,
+		namespace:    namespace,
+		templateRoot: templateRoot,
+		cache:        make(map[string]cachedTemplate),
+	}
+}
+
+// load returns the content of the template at the url
+func (l *templateLoader) load(url string) (string, error) {
+	ref, digest := fetch.SplitDigest(url)
+	key := ref
+	if digest != "" {
+		key = "sha256:" + digest
+	}
+	if content, ok := l.getCached(key); ok {
+		return content, nil
+	}
+
+	var content []byte
+	var err error
+	switch {
+	case strings.HasPrefix(ref, "file://"):
+		content, err = l.readFile(ref)
+	case strings.HasPrefix(ref, "https://"), strings.HasPrefix(ref, "http://"):
+		content, err = l.fetchURL(ref)
+	case strings.HasPrefix(ref, "configmap://"):
+		content, err = l.fetchConfigMap(ref)
+	default:
+		err = errors.NewRendererError("gotemplate", fmt.Sprintf("unsupported template url %s", ref), nil)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if digest != "" {
+		sum := sha256.Sum256(content)
+		if got := hex.EncodeToString(sum[:]); got != digest {
+			return "", errors.NewRendererError("gotemplate",
+				fmt.Sprintf("digest mismatch for template %s: expected %s, got %s", ref, digest, got), nil)
+		}
+		l.setCached(key, string(content), time.Time{})
+	} else if !strings.HasPrefix(ref, "file://") {
+		l.setCached(key, string(content), time.Now().Add(cacheTTL))
+	}
+	return string(content), nil
+}
+
+// readFile reads the template from a file below the template root
+func (l *templateLoader) readFile(url string) ([]byte, error) {
+	path, err := filepath.EvalSymlinks(strings.TrimPrefix(url, "file://"))
+	if err != nil {
+		return nil, errors.NewRendererError("gotemplate", fmt.Sprintf("failed to read template %s", url), err)
+	}
+	root, err := filepath.EvalSymlinks(l.templateRoot)
+	if err != nil {
+		return nil, errors.NewRendererError("gotemplate", fmt.Sprintf("invalid template root %s", l.templateRoot), err)
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, errors.NewRendererError("gotemplate", fmt.Sprintf("template %s is not below the template root %s", url, l.templateRoot), nil)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.NewRendererError("gotemplate", fmt.Sprintf("failed to read template %s", url), err)
+	}
+	defer file.Close()
+	content, err := fetch.ReadAll(file, constants.MaxTemplateSize)
+	if err != nil {
+		return nil, errors.NewRendererError("gotemplate", fmt.Sprintf("failed to read template %s", url), err)
+	}
+	return content, nil
+}
+
+func (l *templateLoader) fetchURL(url string) ([]byte, error) {
+	resp, err := l.httpClient.Get(url)
+	if err != nil {
+		return nil, errors.NewRendererError("gotemplate", fmt.Sprintf("failed to fetch template %s", url), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.NewRendererError("gotemplate", fmt.Sprintf("failed to fetch template %s: %s", url, resp.Status), nil)
+	}
+	content, err := fetch.ReadAll(resp.Body, constants.MaxTemplateSize)
+	if err != nil {
+		return nil, errors.NewRendererError("gotemplate", fmt.Sprintf("failed to read template %s", url), err)
+	}
+	return content, nil
+}
+
+// fetchConfigMap reads the template from a key of a ConfigMap in the
+// namespace of interoperator. The url is of the form
+// configmap://namespace/name/key.
+func (l *templateLoader) fetchConfigMap(url string) ([]byte, error) {
+	parts := strings.Split(strings.TrimPrefix(url, "configmap://"), "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return nil, errors.NewRendererError("gotemplate",
+			fmt.Sprintf("invalid template url %s, expected configmap://namespace/name/key", url), nil)
+	}
+	namespace, name, key := parts[0], parts[1], parts[2]
+	if namespace != l.namespace {
+		return nil, errors.NewRendererError("gotemplate",
+			fmt.Sprintf("invalid template url %s, configmaps are only read from namespace %s", url, l.namespace), nil)
+	}
+
+	l.mu.Lock()
+	c := l.client
+	l.mu.Unlock()
+	if c == nil {
+		return nil, errors.NewRendererError("gotemplate", fmt.Sprintf("no client to read template %s", url), nil)
+	}
+	configMap := &corev1.ConfigMap{}
+	err := c.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, configMap)
+	if err != nil {
+		return nil, errors.NewRendererError("gotemplate", fmt.Sprintf("failed to get configmap %s/%s", namespace, name), err)
+	}
+	if content, ok := configMap.Data[key]; ok {
+		return []byte(content), nil
+	}
+	if content, ok := configMap.BinaryData[key]; ok {
+		return content, nil
+	}
+	return nil, errors.NewRendererError("gotemplate", fmt.Sprintf("key %s not found in configmap %s/%s", key, namespace, name), nil)
+}
+
+func (l *templateLoader) getCached(key string) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cached, ok := l.cache[key]
+	if !ok || (!cached.expires.IsZero() && time.Now().After(cached.expires)) {
+		return "", false
+	}
+	return cached.content, true
+}
+
+// setCached caches the content. The content never expires if expires
+// is zero.
+func (l *templateLoader) setCached(key, content string, expires time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cache[key] = cachedTemplate{
+		content: content,
+		expires: expires,
+	}
+}
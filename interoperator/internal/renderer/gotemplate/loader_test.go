@@ -0,0 +1,236 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gotemplate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/renderer/fetch"
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testTemplate = `{{ .instance.metadata.name }}`
+
+func TestTemplateLoader_load(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	sum := sha256.Sum256([]byte(testTemplate))
+	digest := hex.EncodeToString(sum[:])
+	wrongDigest := strings.Repeat("0", 64)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		if req.URL.Path != "/template" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(testTemplate))
+	}))
+	defer server.Close()
+
+	templateRoot, err := ioutil.TempDir("", "templates")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	defer os.RemoveAll(templateRoot)
+	templatePath := filepath.Join(templateRoot, "template")
+	g.Expect(ioutil.WriteFile(templatePath, []byte(testTemplate), 0644)).NotTo(gomega.HaveOccurred())
+
+	file, err := ioutil.TempFile("", "template")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(testTemplate)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(file.Close()).NotTo(gomega.HaveOccurred())
+
+	c := fake.NewFakeClientWithScheme(scheme.Scheme, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "templates",
+			Namespace: "default",
+		},
+		Data: map[string]string{
+			"provision": testTemplate,
+		},
+	}, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "templates",
+			Namespace: "other",
+		},
+		Data: map[string]string{
+			"provision": testTemplate,
+		},
+	})
+
+	tests := []struct {
+		name    string
+		url     string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "load a template from a file",
+			url:     "file://" + templatePath,
+			want:    testTemplate,
+			wantErr: false,
+		},
+		{
+			name:    "fail if the file is not below the template root",
+			url:     "file://" + file.Name(),
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name:    "fail if the path escapes the template root",
+			url:     "file://" + templateRoot + "/../" + filepath.Base(file.Name()),
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name:    "fail if the file does not exist",
+			url:     "file:///does/not/exist",
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name:    "fetch a template from a url",
+			url:     server.URL + "/template",
+			want:    testTemplate,
+			wantErr: false,
+		},
+		{
+			name:    "fetch a pinned template from a url",
+			url:     server.URL + "/template@sha256:" + digest,
+			want:    testTemplate,
+			wantErr: false,
+		},
+		{
+			name:    "fail if the template does not match the pinned digest",
+			url:     server.URL + "/template@sha256:" + wrongDigest,
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name:    "fail if the url is not found",
+			url:     server.URL + "/unknown",
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name:    "read a template from a configmap",
+			url:     "configmap://default/templates/provision",
+			want:    testTemplate,
+			wantErr: false,
+		},
+		{
+			name:    "fail if the key is not in the configmap",
+			url:     "configmap://default/templates/bind",
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name:    "fail if the configmap is not in the namespace of interoperator",
+			url:     "configmap://other/templates/provision",
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name:    "fail if the configmap url is invalid",
+			url:     "configmap://default/templates",
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name:    "fail if the scheme is not supported",
+			url:     "ftp://host/template",
+			want:    "",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+			l := newTemplateLoader(server.Client(), c, "default", templateRoot)
+			got, err := l.load(tt.url)
+			if tt.wantErr {
+				g.Expect(err).To(gomega.HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+			g.Expect(got).To(gomega.Equal(tt.want))
+		})
+	}
+
+	// Fetched templates are cached
+	l := newTemplateLoader(server.Client(), c, "default", templateRoot)
+	requests = 0
+	for i := 0; i < 2; i++ {
+		got, err := l.load(server.URL + "/template@sha256:" + digest)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(got).To(gomega.Equal(testTemplate))
+		got, err = l.load(server.URL + "/template")
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(got).To(gomega.Equal(testTemplate))
+	}
+	g.Expect(requests).To(gomega.Equal(2))
+}
+
+func TestGoTemplateRenderer_RenderURL(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	templateRoot, err := ioutil.TempDir("", "templates")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	defer os.RemoveAll(templateRoot)
+	templatePath := filepath.Join(templateRoot, "template")
+	g.Expect(ioutil.WriteFile(templatePath, []byte(testTemplate), 0644)).NotTo(gomega.HaveOccurred())
+
+	r, err := New(nil)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	r.(*gotemplateRenderer).loader = newTemplateLoader(fetch.NewHTTPClient(), nil, "default", templateRoot)
+	values := map[string]interface{}{
+		"instance": map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"name": "foo",
+			},
+		},
+	}
+
+	output, err := r.Render(NewInput("file://"+templatePath, "", "foo", "default", values))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	content, err := output.FileContent("main")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(content).To(gomega.Equal("foo"))
+
+	// Content takes precedence over the url
+	output, err = r.Render(NewInput("file:///does/not/exist", "bar", "foo", "default", values))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	content, err = output.FileContent("main")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(content).To(gomega.Equal("bar"))
+
+	g.Expect(NewInput("", "", "foo", "default", values)).To(gomega.BeNil())
+}
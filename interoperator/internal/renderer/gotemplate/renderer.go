@@ -23,34 +23,49 @@ import (
 
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/renderer"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
+
+	kubernetes "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type gotemplateRenderer struct {
 	funcMap template.FuncMap
+	loader  *templateLoader
+	reader  kubernetes.Reader
 }
 
 type gotemplateInput struct {
-	content string
-	name    string
-	values  map[string]interface{}
+	url       string
+	content   string
+	name      string
+	namespace string
+	values    map[string]interface{}
 }
 
 // NewInput creates a new gotemplate Renderer input object.
-func NewInput(url, content, name string, values map[string]interface{}) renderer.Input {
-	if content != "" {
+// The template is loaded from the url if content is empty. lookup is
+// restricted to namespace.
+func NewInput(url, content, name, namespace string, values map[string]interface{}) renderer.Input {
+	if content != "" || url != "" {
 		return gotemplateInput{
-			content: content,
-			name:    name,
-			values:  values,
+			url:       url,
+			content:   content,
+			name:      name,
+			namespace: namespace,
+			values:    values,
 		}
 	}
 
 	return nil
 }
 
-// New creates a new gotemplate Renderer object.
-func New() (renderer.Renderer, error) {
-	return &gotemplateRenderer{funcMap: getFuncMap()}, nil
+// New creates a new gotemplate Renderer object. The lookup function reads
+// the objects with reader. It fails if reader is nil.
+func New(reader kubernetes.Reader) (renderer.Renderer, error) {
+	return &gotemplateRenderer{
+		funcMap: getFuncMap(),
+		loader:  getTemplateLoader(),
+		reader:  reader,
+	}, nil
 }
 
 // Render loads the chart from the given location <chartPath> and calls the Render() function
@@ -61,7 +76,18 @@ func (r *gotemplateRenderer) Render(rawInput renderer.Input) (renderer.Output, e
 	if !ok {
 		return nil, errors.NewRendererError("gotemplate", "invalid input", nil)
 	}
-	engine, err := template.New(input.name).Funcs(r.funcMap).Parse(input.content)
+	content := input.content
+	if content == "" {
+		var err error
+		content, err = r.loader.load(input.url)
+		if err != nil {
+			return nil, err
+		}
+	}
+	engine := template.New(input.name)
+	renderFuncMap := getRenderFuncMap(engine, r.funcMap, input.seed())
+	renderFuncMap["lookup"] = lookup(r.reader, input.namespace)
+	_, err := engine.Funcs(r.funcMap).Funcs(renderFuncMap).Parse(content)
 	if err != nil {
 		return nil, errors.NewRendererError("gotemplate", fmt.Sprintf("can't create template from %s", input.name), err)
 	}
@@ -74,3 +100,18 @@ func (r *gotemplateRenderer) Render(rawInput renderer.Input) (renderer.Output, e
 
 	return &gotemplateOutput{content: *buf}, nil
 }
+
+// seed returns the seed of the rand functions. It is the name of the
+// instance, suffixed with the name of the binding if rendered for one.
+func (input gotemplateInput) seed() string {
+	binding, ok := input.values["binding"].(map[string]interface{})
+	if !ok {
+		return input.name
+	}
+	metadata, _ := binding["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+	if name == "" {
+		return input.name
+	}
+	return input.name + "/" + name
+}
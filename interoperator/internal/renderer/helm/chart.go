@@ -0,0 +1,553 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/renderer/fetch"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
+
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/helm/environment"
+	"k8s.io/helm/pkg/helm/helmpath"
+	chartapi "k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/repo"
+)
+
+// Media types of the helm chart layer in an OCI manifest
+const (
+	ociManifestMediaType      = "application/vnd.oci.image.manifest.v1+json"
+	helmChartContentMediaType = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+	helmChartLegacyMediaType  = "application/tar+gzip"
+)
+
+// resolveTTL is the time a repository index or a mutable reference (an
+// OCI tag or a tarball url without digest) is trusted before it is
+// resolved again
+const resolveTTL = 5 * time.Minute
+
+var (
+	repoReference = regexp.MustCompile(`^([a-zA-Z0-9_.-]+)/([a-zA-Z0-9_.-]+)(?:@(.+))?$`)
+
+	sharedLoader     *chartLoader
+	sharedLoaderOnce sync.Once
+)
+
+// chartLoader loads charts from the local file system or fetches them
+// into a content addressed cache. The following references are supported
+//
+//	/path/to/chart                      chart directory or archive in the image
+//	https://host/chart-1.0.0.tgz        chart archive
+//	repo/chart@version                  chart from a repository in repositories.yaml
+//	oci://host/path/chart:tag           chart from an OCI registry
+//
+// Archive urls and OCI references can be pinned by appending @sha256:<digest>.
+// Downloads are verified against the pinned digest or the digest in the
+// repository index. Credentials are only sent over https and downloads
+// are limited to constants.MaxChartSize.
+type chartLoader struct {
+	home       helmpath.Home
+	httpClient *http.Client
+
+	mu       sync.Mutex
+	resolved map[string]resolvedReference
+}
+
+type resolvedReference struct {
+	digest  string
+	expires time.Time
+}
+
+type ociManifest struct {
+	Layers []struct {
+		MediaType string `json:"mediaType"`
+		Digest    string `json:"digest"`
+	} `json:"layers"`
+}
+
+// getChartLoader returns the chart loader shared by all helm renderers.
+// The helm home is read from HELM_HOME.
+func getChartLoader() *chartLoader {
+	sharedLoaderOnce.Do(func() {
+		home := os.Getenv("HELM_HOME")
+		if home == "" {
+			home = environment.DefaultHelmHome
+		}
+		sharedLoader = newChartLoader(helmpath.Home(home), fetch.NewHTTPClient())
+	})
+	return sharedLoader
+}
+
+func newChartLoader(home helmpath.Home, httpClient *http.Client) *chartLoader {
+	return &chartLoader{
+		home:       home,
+		httpClient: httpClient,
+		resolved:   make(map[string]resolvedReference),
+	}
+}
+
+// load returns the chart for the reference
+func (l *chartLoader) load(ref string) (*chartapi.Chart, error) {
+	chartPath, err := l.locate(ref)
+	if err != nil {
+		return nil, err
+	}
+	return chartutil.Load(chartPath)
+}
+
+// locate returns the local path of the chart, fetching it if required
+func (l *chartLoader) locate(ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, "oci://"):
+		return l.fetchOCI(ref)
+	case strings.HasPrefix(ref, "https://"), strings.HasPrefix(ref, "http://"):
+		return l.fetchURL(ref)
+	}
+	if _, err := os.Stat(ref); err == nil {
+		return ref, nil
+	}
+	if match := repoReference.FindStringSubmatch(ref); match != nil {
+		return l.fetchFromRepository(match[1], match[2], match[3])
+	}
+	return ref, nil
+}
+
+// fetchURL fetches a chart archive
+func (l *chartLoader) fetchURL(ref string) (string, error) {
+	chartURL, pinnedDigest := fetch.SplitDigest(ref)
+	digest := pinnedDigest
+	if digest == "" {
+		digest = l.getResolved(ref)
+	}
+	if digest != "" && l.isCached(digest) {
+		return l.cachePath(digest), nil
+	}
+
+	header := make(http.Header)
+	if entry := l.findRepositoryEntry(chartURL); entry != nil {
+		setBasicAuth(header, entry)
+	}
+	fetched, err := l.download(chartURL, pinnedDigest, header)
+	if err != nil {
+		return "", err
+	}
+	if pinnedDigest == "" {
+		l.setResolved(ref, fetched, false)
+	}
+	return l.cachePath(fetched), nil
+}
+
+// fetchFromRepository fetches a chart from a helm chart repository. The
+// version can be a semver constraint, the latest version is fetched if
+// it is empty.
+func (l *chartLoader) fetchFromRepository(repoName, chartName, version string) (string, error) {
+	repoFile, err := repo.LoadRepositoriesFile(l.home.RepositoryFile())
+	if err != nil {
+		return "", errors.NewRendererError("helm", fmt.Sprintf("failed to load repositories file %s", l.home.RepositoryFile()), err)
+	}
+	entry, ok := repoFile.Get(repoName)
+	if !ok {
+		return "", errors.NewRendererError("helm", fmt.Sprintf("repository %s not found in %s", repoName, l.home.RepositoryFile()), nil)
+	}
+
+	index, err := l.loadIndex(entry)
+	if err != nil {
+		return "", err
+	}
+	chartVersion, err := index.Get(chartName, version)
+	if err != nil {
+		return "", errors.NewRendererError("helm", fmt.Sprintf("chart %s version %s not found in repository %s", chartName, version, repoName), err)
+	}
+	if len(chartVersion.URLs) == 0 {
+		return "", errors.NewRendererError("helm", fmt.Sprintf("chart %s version %s in repository %s has no url", chartName, chartVersion.Version, repoName), nil)
+	}
+	chartURL, err := repo.ResolveReferenceURL(entry.URL, chartVersion.URLs[0])
+	if err != nil {
+		return "", errors.NewRendererError("helm", fmt.Sprintf("invalid url for chart %s in repository %s", chartName, repoName), err)
+	}
+
+	indexDigest := strings.TrimPrefix(chartVersion.Digest, "sha256:")
+	digest := indexDigest
+	if digest == "" {
+		// Index without digests, the archive is cached like an unpinned url
+		digest = l.getResolved(chartURL)
+	}
+	if digest != "" && l.isCached(digest) {
+		return l.cachePath(digest), nil
+	}
+	header := make(http.Header)
+	setBasicAuth(header, entry)
+	fetched, err := l.download(chartURL, indexDigest, header)
+	if err != nil {
+		return "", err
+	}
+	if indexDigest == "" {
+		l.setResolved(chartURL, fetched, false)
+	}
+	return l.cachePath(fetched), nil
+}
+
+// loadIndex returns the index of the repository. The cached index is
+// downloaded again if it is older than resolveTTL.
+func (l *chartLoader) loadIndex(entry *repo.Entry) (*repo.IndexFile, error) {
+	indexPath := l.home.CacheIndex(entry.Name)
+	if info, err := os.Stat(indexPath); err != nil || time.Since(info.ModTime()) > resolveTTL {
+		indexURL := strings.TrimSuffix(entry.URL, "/") + "/index.yaml"
+		header := make(http.Header)
+		setBasicAuth(header, entry)
+		body, err := l.get(indexURL, header)
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		err = writeFileAtomic(indexPath, body)
+		if err != nil {
+			return nil, errors.NewRendererError("helm", fmt.Sprintf("failed to store index of repository %s", entry.Name), err)
+		}
+	}
+	index, err := repo.LoadIndexFile(indexPath)
+	if err != nil {
+		return nil, errors.NewRendererError("helm", fmt.Sprintf("failed to load index of repository %s", entry.Name), err)
+	}
+	return index, nil
+}
+
+// fetchOCI fetches a chart from an OCI registry. The reference is of the
+// form oci://host/path/chart:tag or oci://host/path/chart@sha256:<digest>
+// where the digest is the digest of the manifest.
+func (l *chartLoader) fetchOCI(ref string) (string, error) {
+	name, manifestDigest := fetch.SplitDigest(strings.TrimPrefix(ref, "oci://"))
+	slash := strings.Index(name, "/")
+	if slash <= 0 || slash == len(name)-1 {
+		return "", errors.NewRendererError("helm", fmt.Sprintf("invalid oci reference %s", ref), nil)
+	}
+	host, repository := name[:slash], name[slash+1:]
+	reference := "latest"
+	if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		repository, reference = repository[:i], repository[i+1:]
+	}
+	if manifestDigest != "" {
+		reference = "sha256:" + manifestDigest
+	}
+
+	if digest := l.getResolved(ref); digest != "" && l.isCached(digest) {
+		return l.cachePath(digest), nil
+	}
+
+	header := make(http.Header)
+	header.Set("Accept", ociManifestMediaType)
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", host, repository, reference)
+	body, err := l.registryGet(host, manifestURL, header)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	content, err := fetch.ReadAll(body, constants.MaxChartSize)
+	if err != nil {
+		return "", errors.NewRendererError("helm", fmt.Sprintf("failed to read manifest of %s", ref), err)
+	}
+	if manifestDigest != "" {
+		if got := sha256Hex(content); got != manifestDigest {
+			return "", errors.NewRendererError("helm", fmt.Sprintf("manifest digest mismatch for %s: expected %s, got %s", ref, manifestDigest, got), nil)
+		}
+	}
+
+	manifest := &ociManifest{}
+	err = json.Unmarshal(content, manifest)
+	if err != nil {
+		return "", errors.NewRendererError("helm", fmt.Sprintf("failed to parse manifest of %s", ref), err)
+	}
+	var layerDigest string
+	for _, layer := range manifest.Layers {
+		if layer.MediaType == helmChartContentMediaType || layer.MediaType == helmChartLegacyMediaType {
+			layerDigest = layer.Digest
+			break
+		}
+	}
+	if !strings.HasPrefix(layerDigest, "sha256:") {
+		return "", errors.NewRendererError("helm", fmt.Sprintf("no chart layer found in manifest of %s", ref), nil)
+	}
+	digest := strings.TrimPrefix(layerDigest, "sha256:")
+
+	if !l.isCached(digest) {
+		blobURL := fmt.Sprintf("https://%s/v2/%s/blobs/%s", host, repository, layerDigest)
+		blob, err := l.registryGet(host, blobURL, make(http.Header))
+		if err != nil {
+			return "", err
+		}
+		defer blob.Close()
+		_, err = l.store(blob, digest)
+		if err != nil {
+			return "", errors.NewRendererError("helm", fmt.Sprintf("failed to store chart %s", ref), err)
+		}
+	}
+	l.setResolved(ref, digest, manifestDigest != "")
+	return l.cachePath(digest), nil
+}
+
+// registryGet does a GET request to an OCI registry. Anonymous and
+// basic auth bearer tokens are requested if the registry asks for them.
+// Credentials are taken from the repository entry with url oci://<host>.
+func (l *chartLoader) registryGet(host, registryURL string, header http.Header) (io.ReadCloser, error) {
+	entry := l.findRepositoryEntry("oci://" + host + "/")
+	if entry != nil {
+		setBasicAuth(header, entry)
+	}
+	resp, err := l.do(registryURL, header)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		return checkResponse(registryURL, resp)
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		return nil, errors.NewRendererError("helm", fmt.Sprintf("unauthorized to fetch %s", registryURL), nil)
+	}
+
+	params := parseChallenge(challenge[len("bearer "):])
+	tokenURL, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return nil, errors.NewRendererError("helm", fmt.Sprintf("invalid auth challenge from %s", host), err)
+	}
+	query := tokenURL.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	tokenURL.RawQuery = query.Encode()
+	tokenHeader := make(http.Header)
+	if entry != nil {
+		setBasicAuth(tokenHeader, entry)
+	}
+	body, err := l.get(tokenURL.String(), tokenHeader)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	token := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	content, err := fetch.ReadAll(body, constants.MaxChartSize)
+	if err == nil {
+		err = json.Unmarshal(content, &token)
+	}
+	if err != nil {
+		return nil, errors.NewRendererError("helm", fmt.Sprintf("failed to decode token from %s", host), err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	header.Set("Authorization", "Bearer "+token.Token)
+	return l.get(registryURL, header)
+}
+
+// download fetches the url into the cache and returns the digest of the
+// content. The download fails if it does not match the expected digest.
+func (l *chartLoader) download(downloadURL, expectedDigest string, header http.Header) (string, error) {
+	body, err := l.get(downloadURL, header)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	digest, err := l.store(body, expectedDigest)
+	if err != nil {
+		return "", errors.NewRendererError("helm", fmt.Sprintf("failed to store chart from %s", downloadURL), err)
+	}
+	return digest, nil
+}
+
+// store writes the content into the cache and returns its digest
+func (l *chartLoader) store(content io.Reader, expectedDigest string) (string, error) {
+	dir := l.cacheDir()
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return "", err
+	}
+	tmp, err := ioutil.TempFile(dir, ".download-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	_, err = fetch.Copy(io.MultiWriter(tmp, hash), content, constants.MaxChartSize)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+	digest := hex.EncodeToString(hash.Sum(nil))
+	if expectedDigest != "" && digest != expectedDigest {
+		return "", fmt.Errorf("digest mismatch: expected %s, got %s", expectedDigest, digest)
+	}
+	err = os.Rename(tmp.Name(), l.cachePath(digest))
+	if err != nil {
+		return "", err
+	}
+	return digest, nil
+}
+
+func (l *chartLoader) get(getURL string, header http.Header) (io.ReadCloser, error) {
+	resp, err := l.do(getURL, header)
+	if err != nil {
+		return nil, err
+	}
+	return checkResponse(getURL, resp)
+}
+
+func (l *chartLoader) do(getURL string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, getURL, nil)
+	if err != nil {
+		return nil, errors.NewRendererError("helm", fmt.Sprintf("invalid url %s", getURL), err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	err = fetch.CheckCredentials(getURL, req.Header)
+	if err != nil {
+		return nil, errors.NewRendererError("helm", fmt.Sprintf("failed to fetch %s", getURL), err)
+	}
+	resp, err := l.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.NewRendererError("helm", fmt.Sprintf("failed to fetch %s", getURL), err)
+	}
+	return resp, nil
+}
+
+func checkResponse(getURL string, resp *http.Response) (io.ReadCloser, error) {
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, errors.NewRendererError("helm", fmt.Sprintf("failed to fetch %s: %s", getURL, resp.Status), nil)
+	}
+	return resp.Body, nil
+}
+
+// findRepositoryEntry returns the entry from repositories.yaml whose
+// url is a prefix of the given url
+func (l *chartLoader) findRepositoryEntry(entryURL string) *repo.Entry {
+	repoFile, err := repo.LoadRepositoriesFile(l.home.RepositoryFile())
+	if err != nil {
+		return nil
+	}
+	for _, entry := range repoFile.Repositories {
+		if entry.URL != "" && strings.HasPrefix(entryURL, strings.TrimSuffix(entry.URL, "/")+"/") {
+			return entry
+		}
+	}
+	return nil
+}
+
+func (l *chartLoader) cacheDir() string {
+	return filepath.Join(l.home.Cache(), "sha256")
+}
+
+func (l *chartLoader) cachePath(digest string) string {
+	return filepath.Join(l.cacheDir(), digest+".tgz")
+}
+
+func (l *chartLoader) isCached(digest string) bool {
+	_, err := os.Stat(l.cachePath(digest))
+	return err == nil
+}
+
+func (l *chartLoader) getResolved(ref string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	resolved, ok := l.resolved[ref]
+	if !ok || (!resolved.expires.IsZero() && time.Now().After(resolved.expires)) {
+		return ""
+	}
+	return resolved.digest
+}
+
+// setResolved remembers the digest of a reference. Pinned references
+// never expire.
+func (l *chartLoader) setResolved(ref, digest string, pinned bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	resolved := resolvedReference{digest: digest}
+	if !pinned {
+		resolved.expires = time.Now().Add(resolveTTL)
+	}
+	l.resolved[ref] = resolved
+}
+
+func setBasicAuth(header http.Header, entry *repo.Entry) {
+	if entry.Username == "" && entry.Password == "" {
+		return
+	}
+	req := &http.Request{Header: header}
+	req.SetBasicAuth(entry.Username, entry.Password)
+}
+
+// parseChallenge parses the parameters of a WWW-Authenticate challenge
+func parseChallenge(challenge string) map[string]string {
+	params := make(map[string]string)
+	for _, param := range strings.Split(challenge, ",") {
+		kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		params[strings.ToLower(kv[0])] = strings.Trim(kv[1], `"`)
+	}
+	return params
+}
+
+func sha256Hex(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func writeFileAtomic(path string, content io.Reader) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".download-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = fetch.Copy(tmp, content, constants.MaxChartSize)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
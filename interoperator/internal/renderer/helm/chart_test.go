@@ -0,0 +1,277 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/onsi/gomega"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/helm/helmpath"
+	"k8s.io/helm/pkg/repo"
+)
+
+const testChartPath = "../../../config/samples/templates/helmtemplates/postgresql"
+
+type testRegistry struct {
+	server   *httptest.Server
+	archive  []byte
+	digest   string
+	index    []byte
+	manifest []byte
+	requests map[string]int
+}
+
+func newTestRegistry(g *gomega.GomegaWithT, indexDigest string) *testRegistry {
+	chart, err := chartutil.Load(testChartPath)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	dir, err := ioutil.TempDir("", "chart")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	defer os.RemoveAll(dir)
+	archivePath, err := chartutil.Save(chart, dir)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	r := &testRegistry{
+		requests: make(map[string]int),
+	}
+	r.archive, err = ioutil.ReadFile(archivePath)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	r.digest = sha256Hex(r.archive)
+
+	if indexDigest == "" {
+		indexDigest = r.digest
+	}
+	index := repo.NewIndexFile()
+	index.Add(chart.GetMetadata(), "chart.tgz", "", "sha256:"+indexDigest)
+	g.Expect(index.WriteFile(filepath.Join(dir, "index.yaml"), 0644)).NotTo(gomega.HaveOccurred())
+	r.index, err = ioutil.ReadFile(filepath.Join(dir, "index.yaml"))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	r.manifest = []byte(fmt.Sprintf(`{"schemaVersion": 2, "layers": [{"mediaType": "%s", "digest": "sha256:%s"}]}`,
+		helmChartContentMediaType, r.digest))
+
+	r.server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.requests[req.URL.Path]++
+		if strings.HasPrefix(req.URL.Path, "/v2/") && req.Header.Get("Authorization") != "Bearer token" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="https://%s/token",service="registry"`, req.Host))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch req.URL.Path {
+		case "/token":
+			w.Write([]byte(`{"token": "token"}`))
+		case "/charts/index.yaml":
+			w.Write(r.index)
+		case "/charts/chart.tgz":
+			w.Write(r.archive)
+		case "/v2/charts/postgresql/manifests/1.0.0", "/v2/charts/postgresql/manifests/sha256:" + sha256Hex(r.manifest):
+			w.Write(r.manifest)
+		case "/v2/charts/postgresql/blobs/sha256:" + r.digest:
+			w.Write(r.archive)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return r
+}
+
+func newTestLoader(g *gomega.GomegaWithT, r *testRegistry) (*chartLoader, func()) {
+	home, err := ioutil.TempDir("", "helm")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	repoFile := repo.NewRepoFile()
+	repoFile.Add(&repo.Entry{
+		Name: "stable",
+		URL:  r.server.URL + "/charts",
+	})
+	g.Expect(os.MkdirAll(helmpath.Home(home).Repository(), 0755)).NotTo(gomega.HaveOccurred())
+	g.Expect(repoFile.WriteFile(helmpath.Home(home).RepositoryFile(), 0644)).NotTo(gomega.HaveOccurred())
+	return newChartLoader(helmpath.Home(home), r.server.Client()), func() {
+		os.RemoveAll(home)
+	}
+}
+
+func TestChartLoader_load(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	r := newTestRegistry(g, "")
+	defer r.server.Close()
+	ociHost := strings.TrimPrefix(r.server.URL, "https://")
+
+	tests := []struct {
+		name    string
+		ref     string
+		wantErr bool
+	}{
+		{
+			name:    "load a local chart",
+			ref:     testChartPath,
+			wantErr: false,
+		},
+		{
+			name:    "fetch a chart archive",
+			ref:     r.server.URL + "/charts/chart.tgz",
+			wantErr: false,
+		},
+		{
+			name:    "fetch a pinned chart archive",
+			ref:     r.server.URL + "/charts/chart.tgz@sha256:" + r.digest,
+			wantErr: false,
+		},
+		{
+			name:    "fail if the chart archive does not match the pinned digest",
+			ref:     r.server.URL + "/charts/chart.tgz@sha256:" + strings.Repeat("0", 64),
+			wantErr: true,
+		},
+		{
+			name:    "fail if the chart archive is not found",
+			ref:     r.server.URL + "/charts/unknown.tgz",
+			wantErr: true,
+		},
+		{
+			name:    "fetch a chart from a repository",
+			ref:     "stable/postgresql@0.1.0",
+			wantErr: false,
+		},
+		{
+			name:    "fetch the latest chart from a repository",
+			ref:     "stable/postgresql",
+			wantErr: false,
+		},
+		{
+			name:    "fail if the version is not in the repository",
+			ref:     "stable/postgresql@9.9.9",
+			wantErr: true,
+		},
+		{
+			name:    "fail if the repository is not configured",
+			ref:     "unknown/postgresql@0.1.0",
+			wantErr: true,
+		},
+		{
+			name:    "fetch a chart from an oci registry",
+			ref:     "oci://" + ociHost + "/charts/postgresql:1.0.0",
+			wantErr: false,
+		},
+		{
+			name:    "fetch a pinned chart from an oci registry",
+			ref:     "oci://" + ociHost + "/charts/postgresql@sha256:" + sha256Hex(r.manifest),
+			wantErr: false,
+		},
+		{
+			name:    "fail if the tag is not in the oci registry",
+			ref:     "oci://" + ociHost + "/charts/postgresql:2.0.0",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+			l, cleanup := newTestLoader(g, r)
+			defer cleanup()
+
+			chart, err := l.load(tt.ref)
+			if tt.wantErr {
+				g.Expect(err).To(gomega.HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+			g.Expect(chart.GetMetadata().GetName()).To(gomega.Equal("postgresql"))
+		})
+	}
+}
+
+func TestChartLoader_cache(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	r := newTestRegistry(g, "")
+	defer r.server.Close()
+	l, cleanup := newTestLoader(g, r)
+	defer cleanup()
+
+	// The pinned archive is fetched once
+	ref := r.server.URL + "/charts/chart.tgz@sha256:" + r.digest
+	for i := 0; i < 2; i++ {
+		chartPath, err := l.locate(ref)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(chartPath).To(gomega.Equal(filepath.Join(l.home.Cache(), "sha256", r.digest+".tgz")))
+	}
+	g.Expect(r.requests["/charts/chart.tgz"]).To(gomega.Equal(1))
+
+	// The content addressed archive is shared by the repository reference
+	_, err := l.locate("stable/postgresql@0.1.0")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(r.requests["/charts/index.yaml"]).To(gomega.Equal(1))
+	g.Expect(r.requests["/charts/chart.tgz"]).To(gomega.Equal(1))
+
+	// The resolved oci tag is not fetched again
+	ociRef := "oci://" + strings.TrimPrefix(r.server.URL, "https://") + "/charts/postgresql:1.0.0"
+	for i := 0; i < 2; i++ {
+		_, err = l.locate(ociRef)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+	}
+	g.Expect(r.requests["/v2/charts/postgresql/manifests/1.0.0"]).To(gomega.Equal(2))
+	g.Expect(r.requests["/v2/charts/postgresql/blobs/sha256:"+r.digest]).To(gomega.Equal(0))
+}
+
+func TestChartLoader_repositoryDigestMismatch(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	r := newTestRegistry(g, strings.Repeat("0", 64))
+	defer r.server.Close()
+	l, cleanup := newTestLoader(g, r)
+	defer cleanup()
+
+	_, err := l.locate("stable/postgresql@0.1.0")
+	g.Expect(err).To(gomega.HaveOccurred())
+	files, err := ioutil.ReadDir(filepath.Join(l.home.Cache(), "sha256"))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(files).To(gomega.BeEmpty())
+}
+
+func TestChartLoader_credentialsOverPlainHTTP(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	home, err := ioutil.TempDir("", "helm")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	defer os.RemoveAll(home)
+	repoFile := repo.NewRepoFile()
+	repoFile.Add(&repo.Entry{
+		Name:     "private",
+		URL:      server.URL + "/charts",
+		Username: "user",
+		Password: "password",
+	})
+	g.Expect(os.MkdirAll(helmpath.Home(home).Repository(), 0755)).NotTo(gomega.HaveOccurred())
+	g.Expect(repoFile.WriteFile(helmpath.Home(home).RepositoryFile(), 0644)).NotTo(gomega.HaveOccurred())
+	l := newChartLoader(helmpath.Home(home), server.Client())
+
+	_, err = l.locate(server.URL + "/charts/chart.tgz")
+	g.Expect(err).To(gomega.HaveOccurred())
+	_, err = l.locate("private/postgresql@0.1.0")
+	g.Expect(err).To(gomega.HaveOccurred())
+	g.Expect(requests).To(gomega.Equal(0))
+}
@@ -39,6 +39,7 @@ type helmRenderer struct {
 	renderer     *engine.Engine
 	capabilities *chartutil.Capabilities
 	clientSet    *kubernetes.Clientset
+	loader       *chartLoader
 }
 
 type helmInput struct {
@@ -80,18 +81,31 @@ func New(clientSet *kubernetes.Clientset) (renderer.Renderer, error) {
 		clientSet:    clientSet,
 		renderer:     engine.New(),
 		capabilities: &chartutil.Capabilities{KubeVersion: sv},
+		loader:       getChartLoader(),
+	}, nil
+}
+
+// NewOffline creates a new helm Renderer object which does not connect
+// to a cluster. The charts are rendered for the default kubernetes
+// version of helm.
+func NewOffline() (renderer.Renderer, error) {
+	return &helmRenderer{
+		renderer:     engine.New(),
+		capabilities: &chartutil.Capabilities{KubeVersion: chartutil.DefaultKubeVersion},
+		loader:       getChartLoader(),
 	}, nil
 }
 
 // Render loads the chart from the given location <chartPath> and calls the Render() function
-// to convert it into a renderer.Output object.
+// to convert it into a renderer.Output object. <chartPath> is either a local path, a
+// https url of a chart archive, a repo/chart@version reference or an oci:// reference.
 // TODO Consider using streams (io.Writer or io.Reader) in the API instead of buffers.
 func (r *helmRenderer) Render(rawInput renderer.Input) (renderer.Output, error) {
 	input, ok := rawInput.(helmInput)
 	if !ok {
 		return nil, errors.NewRendererError("helm", "invalid input to renderer", nil)
 	}
-	chart, err := chartutil.Load(input.chartPath)
+	chart, err := r.loader.load(input.chartPath)
 	if err != nil {
 		return nil, errors.NewRendererError("helm", fmt.Sprintf("can't create load chart from path %s", input.chartPath), err)
 	}
@@ -0,0 +1,28 @@
+package jsonnet
+
+import (
+	"fmt"
+	"sort"
+)
+
+type jsonnetOutput struct {
+	files map[string]string
+}
+
+// FileContent returns explicitly the content of the provided <filename>.
+func (c *jsonnetOutput) FileContent(filename string) (string, error) {
+	if content, ok := c.files[filename]; ok {
+		return content, nil
+	}
+	return "", fmt.Errorf("File not found")
+}
+
+// ListFiles returns list of file names rendered
+func (c *jsonnetOutput) ListFiles() ([]string, error) {
+	fileNames := make([]string, 0, len(c.files))
+	for name := range c.files {
+		fileNames = append(fileNames, name)
+	}
+	sort.Strings(fileNames)
+	return fileNames, nil
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jsonnet
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/renderer"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
+
+	"github.com/google/go-jsonnet"
+)
+
+type jsonnetRenderer struct{}
+
+type jsonnetInput struct {
+	url     string
+	content string
+	name    string
+	values  map[string]interface{}
+}
+
+// NewInput creates a new jsonnet Renderer input object. The
+// jsonnet is either the embedded content or the file at url.
+func NewInput(url, content, name string, values map[string]interface{}) renderer.Input {
+	if content != "" || url != "" {
+		return jsonnetInput{
+			url:     url,
+			content: content,
+			name:    name,
+			values:  values,
+		}
+	}
+
+	return nil
+}
+
+// New creates a new jsonnet Renderer object.
+func New() (renderer.Renderer, error) {
+	return &jsonnetRenderer{}, nil
+}
+
+// Render evaluates the jsonnet of the input and returns the result as
+// renderer.Output object. Each value is passed as external variable
+// named by its key, e.g. std.extVar('instance').
+//
+// A result with apiVersion and kind, or an array of such objects, is
+// rendered as the file main. Any other object is rendered as one file
+// per field, e.g. status.yaml. A string field is used as it is, an
+// array field is rendered as one document per element.
+func (r *jsonnetRenderer) Render(rawInput renderer.Input) (renderer.Output, error) {
+	input, ok := rawInput.(jsonnetInput)
+	if !ok {
+		return nil, errors.NewRendererError("jsonnet", "invalid input", nil)
+	}
+
+	vm, fileName, content, err := newVM(input)
+	if err != nil {
+		return nil, err
+	}
+	result, err := vm.EvaluateSnippet(fileName, content)
+	if err != nil {
+		return nil, errors.NewRendererError("jsonnet", fmt.Sprintf("can't evaluate jsonnet for %s", input.name), err)
+	}
+
+	files, err := splitFiles([]byte(result))
+	if err != nil {
+		return nil, errors.NewRendererError("jsonnet", fmt.Sprintf("can't render from %s", input.name), err)
+	}
+	return &jsonnetOutput{files: files}, nil
+}
+
+// newVM returns the vm evaluating the input along with the file name
+// and the content of the jsonnet. Imports of a jsonnet file are resolved
+// relative to the file, embedded jsonnet can't import.
+func newVM(input jsonnetInput) (*jsonnet.VM, string, string, error) {
+	vm := jsonnet.MakeVM()
+	for key, value := range input.values {
+		content, err := json.Marshal(value)
+		if err != nil {
+			return nil, "", "", errors.NewRendererError("jsonnet", fmt.Sprintf("failed to marshal %s", key), err)
+		}
+		vm.ExtCode(key, string(content))
+	}
+
+	if input.content != "" {
+		vm.Importer(&jsonnet.MemoryImporter{Data: map[string]jsonnet.Contents{}})
+		return vm, input.name + ".jsonnet", input.content, nil
+	}
+
+	var fileName string
+	switch {
+	case strings.HasPrefix(input.url, "file://"):
+		fileName = strings.TrimPrefix(input.url, "file://")
+	case strings.Contains(input.url, "://"):
+		return nil, "", "", errors.NewRendererError("jsonnet", fmt.Sprintf("unsupported jsonnet url %s", input.url), nil)
+	default:
+		fileName = input.url
+	}
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, "", "", errors.NewRendererError("jsonnet", fmt.Sprintf("failed to read jsonnet %s", fileName), err)
+	}
+	vm.Importer(&jsonnet.FileImporter{JPaths: []string{filepath.Dir(fileName)}})
+	return vm, fileName, string(content), nil
+}
+
+// splitFiles splits the evaluated json into the rendered files. An array
+// or a single resource is rendered as the main file.
+func splitFiles(result []byte) (map[string]string, error) {
+	value, err := renderer.DecodeJSON(result)
+	if err != nil {
+		return nil, err
+	}
+
+	contents := make(map[string]interface{})
+	switch x := value.(type) {
+	case []interface{}:
+		contents["main"] = x
+	case map[string]interface{}:
+		if isResource(x) {
+			contents["main"] = x
+		} else {
+			contents = x
+		}
+	default:
+		return nil, fmt.Errorf("expected an object or array, got %T", value)
+	}
+	return renderer.SplitFiles(contents)
+}
+
+func isResource(obj map[string]interface{}) bool {
+	_, hasAPIVersion := obj["apiVersion"]
+	_, hasKind := obj["kind"]
+	return hasAPIVersion && hasKind
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jsonnet
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/dynamic"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/properties"
+	"github.com/onsi/gomega"
+)
+
+var testValues = map[string]interface{}{
+	"instance": map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name": "foo",
+		},
+	},
+}
+
+func Test_splitFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		result  string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:   "render a resource as main",
+			result: `{"apiVersion": "v1", "kind": "ConfigMap", "data": {"replicas": 3}}`,
+			want: map[string]string{
+				"main": `{"apiVersion":"v1","data":{"replicas":3},"kind":"ConfigMap"}`,
+			},
+		},
+		{
+			name:   "render an array of resources as documents of main",
+			result: `[{"apiVersion": "v1", "kind": "ConfigMap"}, {"apiVersion": "v1", "kind": "Secret"}]`,
+			want: map[string]string{
+				"main": "{\"apiVersion\":\"v1\",\"kind\":\"ConfigMap\"}\n---\n{\"apiVersion\":\"v1\",\"kind\":\"Secret\"}",
+			},
+		},
+		{
+			name:   "render the fields of an object as files",
+			result: `{"status.yaml": {"provision": {"state": "succeeded"}}, "notes.txt": "ready", "resources": []}`,
+			want: map[string]string{
+				"status.yaml": `{"provision":{"state":"succeeded"}}`,
+				"notes.txt":   "ready",
+			},
+		},
+		{
+			name:    "fail if the result is not an object",
+			result:  `"foo"`,
+			wantErr: true,
+		},
+		{
+			name:    "fail if the result is not json",
+			result:  `foo`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+			got, err := splitFiles([]byte(tt.result))
+			if tt.wantErr {
+				g.Expect(err).To(gomega.HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+			g.Expect(got).To(gomega.Equal(tt.want))
+		})
+	}
+}
+
+func TestJsonnetRenderer_Render(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	content := `{
+  main: {
+    apiVersion: "v1",
+    kind: "ConfigMap",
+    metadata: { name: std.extVar("instance").metadata.name },
+  },
+  "status.yaml": { provision: { state: "succeeded" } },
+}`
+	r, err := New()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	output, err := r.Render(NewInput("", content, "foo", testValues))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	files, err := output.ListFiles()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(files).To(gomega.Equal([]string{"main", "status.yaml"}))
+
+	main, err := output.FileContent("main")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	resources, err := dynamic.StringToUnstructured(main)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(resources).To(gomega.HaveLen(1))
+	g.Expect(resources[0].GetKind()).To(gomega.Equal("ConfigMap"))
+	g.Expect(resources[0].GetName()).To(gomega.Equal("foo"))
+
+	status, err := output.FileContent("status.yaml")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	provisionStatus, err := properties.ParseStatus(status)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(provisionStatus.Provision.State).To(gomega.Equal("succeeded"))
+
+	_, err = output.FileContent("sources.yaml")
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	// Evaluation failures are renderer errors
+	_, err = r.Render(NewInput("", content, "foo", nil))
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	// Embedded jsonnet can't import
+	_, err = r.Render(NewInput("", `import "/etc/passwd"`, "foo", testValues))
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	_, err = r.Render(NewInput("https://host/postgresql.jsonnet", "", "foo", testValues))
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	_, err = r.Render(nil)
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	g.Expect(NewInput("", "", "foo", testValues)).To(gomega.BeNil())
+}
+
+func TestJsonnetRenderer_RenderFile(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	dir, err := ioutil.TempDir("", "jsonnet")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	defer os.RemoveAll(dir)
+
+	// Imports are resolved relative to the jsonnet file
+	lib := `{ configMap(name):: { apiVersion: "v1", kind: "ConfigMap", metadata: { name: name } } }`
+	g.Expect(ioutil.WriteFile(filepath.Join(dir, "lib.libsonnet"), []byte(lib), 0644)).NotTo(gomega.HaveOccurred())
+	main := `local lib = import "lib.libsonnet";
+[lib.configMap(std.extVar("instance").metadata.name)]`
+	g.Expect(ioutil.WriteFile(filepath.Join(dir, "main.jsonnet"), []byte(main), 0644)).NotTo(gomega.HaveOccurred())
+
+	r, err := New()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	for _, url := range []string{"file://" + filepath.Join(dir, "main.jsonnet"), filepath.Join(dir, "main.jsonnet")} {
+		output, err := r.Render(NewInput(url, "", "foo", testValues))
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		content, err := output.FileContent("main")
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(content).To(gomega.Equal(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"foo"}}`))
+	}
+
+	_, err = r.Render(NewInput("file://"+filepath.Join(dir, "missing.jsonnet"), "", "foo", testValues))
+	g.Expect(err).To(gomega.HaveOccurred())
+}
@@ -0,0 +1,22 @@
+package kustomize
+
+import (
+	"fmt"
+)
+
+type kustomizeOutput struct {
+	content string
+}
+
+// FileContent returns explicitly the content of the provided <filename>.
+func (c *kustomizeOutput) FileContent(filename string) (string, error) {
+	if filename == "main" {
+		return c.content, nil
+	}
+	return "", fmt.Errorf("File not found")
+}
+
+// ListFiles returns list of file names rendered
+func (c *kustomizeOutput) ListFiles() ([]string, error) {
+	return []string{"main"}, nil
+}
@@ -0,0 +1,262 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/renderer"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+type kustomizeRenderer struct {
+	binary string
+}
+
+type kustomizeInput struct {
+	url     string
+	content string
+	name    string
+	values  map[string]interface{}
+}
+
+// kustomization is the subset of the kustomization file written
+// around the kustomization of the template
+type kustomization struct {
+	APIVersion         string               `yaml:"apiVersion"`
+	Kind               string               `yaml:"kind"`
+	Resources          []string             `yaml:"resources"`
+	GeneratorOptions   generatorOptions     `yaml:"generatorOptions"`
+	ConfigMapGenerator []configMapGenerator `yaml:"configMapGenerator,omitempty"`
+	Vars               []variable           `yaml:"vars,omitempty"`
+}
+
+type generatorOptions struct {
+	DisableNameSuffixHash bool `yaml:"disableNameSuffixHash"`
+}
+
+type configMapGenerator struct {
+	Name     string   `yaml:"name"`
+	Literals []string `yaml:"literals,omitempty"`
+}
+
+type variable struct {
+	Name     string   `yaml:"name"`
+	ObjRef   objRef   `yaml:"objref"`
+	FieldRef fieldRef `yaml:"fieldref"`
+}
+
+type objRef struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Name       string `yaml:"name"`
+}
+
+type fieldRef struct {
+	FieldPath string `yaml:"fieldpath"`
+}
+
+// NewInput creates a new kustomize Renderer input object. The
+// kustomization is either the embedded content or the one at url.
+func NewInput(url, content, name string, values map[string]interface{}) renderer.Input {
+	if content != "" || url != "" {
+		return kustomizeInput{
+			url:     url,
+			content: content,
+			name:    name,
+			values:  values,
+		}
+	}
+
+	return nil
+}
+
+// New creates a new kustomize Renderer object. The kustomization is
+// built by the kustomize binary found at KUSTOMIZE_BINARY or in PATH.
+func New() (renderer.Renderer, error) {
+	binary := os.Getenv(constants.KustomizeBinaryEnvKey)
+	if binary == "" {
+		binary = constants.DefaultKustomizeBinary
+	}
+	return &kustomizeRenderer{
+		binary: binary,
+	}, nil
+}
+
+// Render builds the kustomization of the input with the values injected
+// and returns the built resources as renderer.Output object.
+//
+// The names of the values are available as vars $(<KEY>_NAME) in the
+// resources. They are read from a ConfigMap <name>-values generated for
+// the build, which is removed from the built resources.
+func (r *kustomizeRenderer) Render(rawInput renderer.Input) (renderer.Output, error) {
+	input, ok := rawInput.(kustomizeInput)
+	if !ok {
+		return nil, errors.NewRendererError("kustomize", "invalid input", nil)
+	}
+
+	dir, err := ioutil.TempDir("", "kustomize")
+	if err != nil {
+		return nil, errors.NewRendererError("kustomize", "failed to create build directory", err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = writeKustomization(dir, input)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), constants.RendererCommandTimeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, r.binary, "build", dir)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if err != nil {
+		return nil, errors.NewRendererError("kustomize",
+			fmt.Sprintf("can't build kustomization for %s: %s", input.name, strings.TrimSpace(stderr.String())), err)
+	}
+
+	content, err := removeResource(stdout.String(), "ConfigMap", valuesName(input.name))
+	if err != nil {
+		return nil, errors.NewRendererError("kustomize", fmt.Sprintf("can't parse kustomization built for %s", input.name), err)
+	}
+	return &kustomizeOutput{content: content}, nil
+}
+
+// writeKustomization writes the kustomization of the input into dir.
+// Embedded content is written to the base directory, any other url
+// (absolute path, file:// or remote base) is passed to kustomize.
+func writeKustomization(dir string, input kustomizeInput) error {
+	var base string
+	switch {
+	case input.content != "":
+		base = "base"
+		err := os.Mkdir(filepath.Join(dir, base), 0755)
+		if err == nil {
+			err = ioutil.WriteFile(filepath.Join(dir, base, "kustomization.yaml"), []byte(input.content), 0644)
+		}
+		if err != nil {
+			return errors.NewRendererError("kustomize", "failed to write kustomization", err)
+		}
+	case strings.HasPrefix(input.url, "file://"):
+		base = strings.TrimPrefix(input.url, "file://")
+	default:
+		base = input.url
+	}
+
+	k := kustomization{
+		APIVersion: "kustomize.config.k8s.io/v1beta1",
+		Kind:       "Kustomization",
+		Resources:  []string{base},
+		GeneratorOptions: generatorOptions{
+			DisableNameSuffixHash: true,
+		},
+	}
+	generator := configMapGenerator{
+		Name: valuesName(input.name),
+	}
+
+	keys := make([]string, 0, len(input.values))
+	for key := range input.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		name := valueName(input.values[key])
+		if name == "" {
+			continue
+		}
+		generator.Literals = append(generator.Literals, fmt.Sprintf("%sName=%s", key, name))
+		k.Vars = append(k.Vars, variable{
+			Name: strings.ToUpper(key) + "_NAME",
+			ObjRef: objRef{
+				APIVersion: "v1",
+				Kind:       "ConfigMap",
+				Name:       generator.Name,
+			},
+			FieldRef: fieldRef{
+				FieldPath: fmt.Sprintf("data.%sName", key),
+			},
+		})
+	}
+	if len(generator.Literals) > 0 {
+		k.ConfigMapGenerator = []configMapGenerator{generator}
+	}
+
+	content, err := yaml.Marshal(k)
+	if err != nil {
+		return errors.NewRendererError("kustomize", "failed to marshal kustomization", err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "kustomization.yaml"), content, 0644)
+	if err != nil {
+		return errors.NewRendererError("kustomize", "failed to write kustomization", err)
+	}
+	return nil
+}
+
+// valuesName returns the name of the ConfigMap holding the values
+func valuesName(name string) string {
+	return name + "-values"
+}
+
+// removeResource removes the resource of the kind and name from the
+// built resources
+func removeResource(content, kind, name string) (string, error) {
+	documents := strings.Split(content, "\n---\n")
+	kept := make([]string, 0, len(documents))
+	for _, document := range documents {
+		resource := struct {
+			Kind     string `yaml:"kind"`
+			Metadata struct {
+				Name string `yaml:"name"`
+			} `yaml:"metadata"`
+		}{}
+		err := yaml.Unmarshal([]byte(document), &resource)
+		if err != nil {
+			return "", err
+		}
+		if resource.Kind == kind && resource.Metadata.Name == name {
+			continue
+		}
+		kept = append(kept, document)
+	}
+	return strings.Join(kept, "\n---\n"), nil
+}
+
+// valueName returns metadata.name of the value if it is an object
+func valueName(value interface{}) string {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+	return name
+}
@@ -0,0 +1,215 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+	"github.com/onsi/gomega"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const testKustomization = `resources:
+- deployment.yaml
+`
+
+var testValues = map[string]interface{}{
+	"instance": map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name": "foo",
+		},
+	},
+	"plan": map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name": "bar",
+		},
+	},
+	"previous": map[string]interface{}{
+		"password": "previous-password",
+	},
+}
+
+func TestWriteKustomization(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    kustomizeInput
+		wantBase string
+	}{
+		{
+			name: "write embedded kustomization",
+			input: kustomizeInput{
+				content: testKustomization,
+				name:    "foo",
+				values:  testValues,
+			},
+			wantBase: "base",
+		},
+		{
+			name: "refer a local kustomization",
+			input: kustomizeInput{
+				url:    "file:///templates/postgresql",
+				name:   "foo",
+				values: testValues,
+			},
+			wantBase: "/templates/postgresql",
+		},
+		{
+			name: "refer a remote kustomization",
+			input: kustomizeInput{
+				url:    "github.com/org/repo//postgresql?ref=v1.0.0",
+				name:   "foo",
+				values: testValues,
+			},
+			wantBase: "github.com/org/repo//postgresql?ref=v1.0.0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+			dir, err := ioutil.TempDir("", "kustomize")
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			g.Expect(writeKustomization(dir, tt.input)).NotTo(gomega.HaveOccurred())
+			content, err := ioutil.ReadFile(filepath.Join(dir, "kustomization.yaml"))
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+			k := kustomization{}
+			g.Expect(yaml.Unmarshal(content, &k)).NotTo(gomega.HaveOccurred())
+
+			g.Expect(k.Resources).To(gomega.Equal([]string{tt.wantBase}))
+			g.Expect(k.ConfigMapGenerator).To(gomega.Equal([]configMapGenerator{
+				{
+					Name:     "foo-values",
+					Literals: []string{"instanceName=foo", "planName=bar"},
+				},
+			}))
+			g.Expect(k.Vars).To(gomega.HaveLen(2))
+			g.Expect(k.Vars[0].Name).To(gomega.Equal("INSTANCE_NAME"))
+			g.Expect(k.Vars[0].FieldRef.FieldPath).To(gomega.Equal("data.instanceName"))
+
+			// Only the names are written into the build directory
+			g.Expect(string(content)).NotTo(gomega.ContainSubstring("previous-password"))
+			_, err = os.Stat(filepath.Join(dir, "instance.json"))
+			g.Expect(os.IsNotExist(err)).To(gomega.BeTrue())
+
+			if tt.input.content != "" {
+				base, err := ioutil.ReadFile(filepath.Join(dir, "base", "kustomization.yaml"))
+				g.Expect(err).NotTo(gomega.HaveOccurred())
+				g.Expect(string(base)).To(gomega.Equal(testKustomization))
+			}
+		})
+	}
+}
+
+func TestKustomizeRenderer_Render(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	// The fake kustomize prints the kustomization it is asked to build
+	dir, err := ioutil.TempDir("", "bin")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	defer os.RemoveAll(dir)
+	binary := filepath.Join(dir, "kustomize")
+	script := "#!/bin/sh\n[ \"$1\" = build ] || exit 1\ncat \"$2/base/kustomization.yaml\"\n"
+	g.Expect(ioutil.WriteFile(binary, []byte(script), 0755)).NotTo(gomega.HaveOccurred())
+
+	r := &kustomizeRenderer{binary: binary}
+	output, err := r.Render(NewInput("", testKustomization, "foo", testValues))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	files, err := output.ListFiles()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(files).To(gomega.Equal([]string{"main"}))
+	content, err := output.FileContent("main")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(content).To(gomega.Equal(testKustomization))
+
+	// The values are removed from the built resources
+	script = "#!/bin/sh\ncat <<EOF\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: foo-values\n---\napiVersion: v1\nkind: Service\nmetadata:\n  name: foo\nEOF\n"
+	valuesBinary := filepath.Join(dir, "kustomize-values")
+	g.Expect(ioutil.WriteFile(valuesBinary, []byte(script), 0755)).NotTo(gomega.HaveOccurred())
+	output, err = (&kustomizeRenderer{binary: valuesBinary}).Render(NewInput("", testKustomization, "foo", testValues))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	content, err = output.FileContent("main")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(content).To(gomega.Equal("apiVersion: v1\nkind: Service\nmetadata:\n  name: foo\n"))
+
+	// Build failures are renderer errors
+	_, err = r.Render(NewInput("file:///does/not/exist", "", "foo", testValues))
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	r = &kustomizeRenderer{binary: filepath.Join(dir, "missing")}
+	_, err = r.Render(NewInput("", testKustomization, "foo", testValues))
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	_, err = r.Render(nil)
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	g.Expect(NewInput("", "", "foo", testValues)).To(gomega.BeNil())
+}
+
+// TestKustomizeRenderer_RenderBinary builds a kustomization with the
+// kustomize binary at KUSTOMIZE_BINARY or in PATH
+func TestKustomizeRenderer_RenderBinary(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	binary := os.Getenv(constants.KustomizeBinaryEnvKey)
+	if binary == "" {
+		binary = constants.DefaultKustomizeBinary
+	}
+	binary, err := exec.LookPath(binary)
+	if err != nil {
+		t.Skipf("kustomize binary not found: %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "kustomization")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	defer os.RemoveAll(dir)
+	service := `apiVersion: v1
+kind: Service
+metadata:
+  name: $(INSTANCE_NAME)
+spec:
+  selector:
+    plan: $(PLAN_NAME)
+`
+	g.Expect(ioutil.WriteFile(filepath.Join(dir, "service.yaml"), []byte(service), 0644)).NotTo(gomega.HaveOccurred())
+	g.Expect(ioutil.WriteFile(filepath.Join(dir, "kustomization.yaml"), []byte("resources:\n- service.yaml\n"), 0644)).NotTo(gomega.HaveOccurred())
+
+	r := &kustomizeRenderer{binary: binary}
+	output, err := r.Render(NewInput("file://"+dir, "", "foo", testValues))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	content, err := output.FileContent("main")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	resources := []map[string]interface{}{}
+	for _, document := range strings.Split(content, "\n---\n") {
+		resource := map[string]interface{}{}
+		g.Expect(yaml.Unmarshal([]byte(document), &resource)).NotTo(gomega.HaveOccurred())
+		resources = append(resources, resource)
+	}
+	g.Expect(resources).To(gomega.HaveLen(1))
+	g.Expect(resources[0]["kind"]).To(gomega.Equal("Service"))
+	g.Expect(content).To(gomega.ContainSubstring("name: foo"))
+	g.Expect(content).To(gomega.ContainSubstring("plan: bar"))
+	g.Expect(content).NotTo(gomega.ContainSubstring("foo-values"))
+	g.Expect(content).NotTo(gomega.ContainSubstring("previous-password"))
+}
@@ -0,0 +1,227 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
+
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	kubernetes "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// readyConditions are the condition types which tell whether a resource
+// is ready, in order of precedence
+var readyConditions = []string{"Ready", "Available", "Established", "Complete"}
+
+// groupPhases groups the resources by their weight in ascending order. The
+// weight is the interoperator.servicefabrik.io/order annotation, or the
+// helm.sh/hook-weight annotation, and 0 if neither is set. Resources of
+// the same weight keep the order in which they were rendered.
+func groupPhases(resources []*unstructured.Unstructured) [][]*unstructured.Unstructured {
+	byWeight := make(map[int][]*unstructured.Unstructured)
+	weights := make([]int, 0)
+	for _, resource := range resources {
+		w := weight(resource)
+		if _, ok := byWeight[w]; !ok {
+			weights = append(weights, w)
+		}
+		byWeight[w] = append(byWeight[w], resource)
+	}
+	sort.Ints(weights)
+
+	phases := make([][]*unstructured.Unstructured, 0, len(weights))
+	for _, w := range weights {
+		phases = append(phases, byWeight[w])
+	}
+	return phases
+}
+
+func weight(resource *unstructured.Unstructured) int {
+	annotations := resource.GetAnnotations()
+	for _, key := range []string{constants.OrderKey, constants.HelmHookWeightKey} {
+		value, ok := annotations[key]
+		if !ok {
+			continue
+		}
+		w, err := strconv.Atoi(value)
+		if err != nil {
+			log.Info("ignoring invalid order annotation", "kind", resource.GetKind(), "name", resource.GetName(), "annotation", key, "value", value)
+			continue
+		}
+		return w
+	}
+	return 0
+}
+
+// waitTimeout returns how long to wait for the resource before the next
+// phase. The interoperator.servicefabrik.io/wait annotation is either
+// "true" to wait for constants.ResourceWaitTimeout or a duration.
+func waitTimeout(resource *unstructured.Unstructured) (time.Duration, bool) {
+	value, ok := resource.GetAnnotations()[constants.WaitKey]
+	if !ok || value == "false" {
+		return 0, false
+	}
+	if value == "true" {
+		return constants.ResourceWaitTimeout, true
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		log.Info("invalid wait annotation, using default timeout", "kind", resource.GetKind(), "name", resource.GetName(), "value", value)
+		return constants.ResourceWaitTimeout, true
+	}
+	return timeout, true
+}
+
+// ready tells whether the resource is ready based on its status. The
+// observed generation, replicas, the ready conditions and the state of
+// service fabrik operators are checked in this order. Resources without
+// any of them are ready once they exist.
+func ready(resource *unstructured.Unstructured) bool {
+	if resource.GetDeletionTimestamp() != nil {
+		return false
+	}
+
+	observedGeneration, found, _ := unstructured.NestedInt64(resource.Object, "status", "observedGeneration")
+	if found && observedGeneration < resource.GetGeneration() {
+		return false
+	}
+
+	replicas, found, _ := unstructured.NestedInt64(resource.Object, "spec", "replicas")
+	if found {
+		readyReplicas, _, _ := unstructured.NestedInt64(resource.Object, "status", "readyReplicas")
+		return readyReplicas >= replicas
+	}
+
+	conditions, _, _ := unstructured.NestedSlice(resource.Object, "status", "conditions")
+	for _, conditionType := range readyConditions {
+		for _, c := range conditions {
+			condition, ok := c.(map[string]interface{})
+			if ok && condition["type"] == conditionType {
+				return condition["status"] == "True"
+			}
+		}
+	}
+
+	state, found, _ := unstructured.NestedString(resource.Object, "status", "state")
+	if found {
+		return state == "succeeded"
+	}
+	return true
+}
+
+// waitReady checks whether the resources of a phase annotated with
+// interoperator.servicefabrik.io/wait are ready. It does not block, the
+// caller requeues on a ResourceNotReady error. The wait times out once the
+// resource is not ready for the timeout after interoperator applied it.
+func (r resourceManager) waitReady(client kubernetes.Client, phase []*unstructured.Unstructured) error {
+	for _, resource := range phase {
+		timeout, ok := waitTimeout(resource)
+		if !ok {
+			continue
+		}
+		object, err := r.getLive(client, resource)
+		if err != nil && !apiErrors.IsNotFound(err) {
+			return err
+		}
+		if err == nil && ready(object) {
+			continue
+		}
+
+		name := types.NamespacedName{Name: resource.GetName(), Namespace: resource.GetNamespace()}.String()
+		if at := appliedAt(object); err == nil && !at.IsZero() && time.Since(at) > timeout {
+			return errors.NewWaitTimeout(resource.GetKind(), name, timeout, nil)
+		}
+		log.Info("reconcile - waiting for resource to become ready", "kind", resource.GetKind(), "name", name, "timeout", timeout)
+		return errors.NewResourceNotReady(resource.GetKind(), name, "waiting for the resource to become ready", nil)
+	}
+	return nil
+}
+
+// waitDeleted checks whether the resources of a phase annotated with
+// interoperator.servicefabrik.io/wait are gone, e.g. once their finalizers
+// are done. Like waitReady it does not block. The wait times out once the
+// resource is not gone for the timeout after its deletion was requested.
+func (r resourceManager) waitDeleted(client kubernetes.Client, phase []*unstructured.Unstructured) error {
+	for _, resource := range phase {
+		timeout, ok := waitTimeout(resource)
+		if !ok {
+			continue
+		}
+		object, err := r.getLive(client, resource)
+		if err != nil {
+			if apiErrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+
+		name := types.NamespacedName{Name: resource.GetName(), Namespace: resource.GetNamespace()}.String()
+		if at := deleteRequestedAt(object); !at.IsZero() && time.Since(at) > timeout {
+			return errors.NewWaitTimeout(resource.GetKind(), name, timeout, nil)
+		}
+		log.Info("waiting for resource to be deleted", "kind", resource.GetKind(), "name", name, "timeout", timeout)
+		return errors.NewResourceNotReady(resource.GetKind(), name, "waiting for the resource to be deleted", nil)
+	}
+	return nil
+}
+
+// getLive gets the live object of the resource
+func (r resourceManager) getLive(client kubernetes.Client, resource *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	namespacedName := types.NamespacedName{
+		Name:      resource.GetName(),
+		Namespace: resource.GetNamespace(),
+	}
+	object := &unstructured.Unstructured{}
+	object.SetGroupVersionKind(resource.GroupVersionKind())
+	err := client.Get(context.TODO(), namespacedName, object)
+	return object, err
+}
+
+// appliedAt returns when interoperator last changed the object, or its
+// creation if that is not known. It is zero if neither is known.
+func appliedAt(object *unstructured.Unstructured) time.Time {
+	for _, entry := range object.GetManagedFields() {
+		if entry.Manager == constants.FieldManager && entry.Operation == metav1.ManagedFieldsOperationApply && entry.Time != nil {
+			return entry.Time.Time
+		}
+	}
+	return object.GetCreationTimestamp().Time
+}
+
+// deleteRequestedAt returns when the deletion of the object was requested.
+// The sf operator resources are deleted by setting their state, they have
+// no deletionTimestamp but the interoperator.servicefabrik.io/deleterequested
+// annotation. It is zero if the deletion was not requested.
+func deleteRequestedAt(object *unstructured.Unstructured) time.Time {
+	if deletedAt := object.GetDeletionTimestamp(); deletedAt != nil {
+		return deletedAt.Time
+	}
+	requestedAt, err := time.Parse(time.RFC3339, object.GetAnnotations()[constants.DeleteRequestKey])
+	if err != nil {
+		return time.Time{}
+	}
+	return requestedAt
+}
@@ -0,0 +1,392 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
+
+	"github.com/onsi/gomega"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kubernetes "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// orderClient keeps the objects by name and records the applies and
+// deletes. Deleted objects are gone at once unless they have finalizers.
+type orderClient struct {
+	kubernetes.Client
+	objects map[string]*unstructured.Unstructured
+	ops     []string
+}
+
+func (c *orderClient) Get(ctx context.Context, key kubernetes.ObjectKey, obj runtime.Object) error {
+	object, ok := c.objects[key.Name]
+	if !ok {
+		return apiErrors.NewNotFound(schema.GroupResource{}, key.Name)
+	}
+	object.DeepCopyInto(obj.(*unstructured.Unstructured))
+	return nil
+}
+
+func (c *orderClient) Patch(ctx context.Context, obj runtime.Object, patch kubernetes.Patch, opts ...kubernetes.PatchOption) error {
+	object := obj.(*unstructured.Unstructured)
+	c.ops = append(c.ops, "apply "+object.GetName())
+	c.objects[object.GetName()] = object.DeepCopy()
+	return nil
+}
+
+func (c *orderClient) Update(ctx context.Context, obj runtime.Object, opts ...kubernetes.UpdateOption) error {
+	object := obj.(*unstructured.Unstructured)
+	c.ops = append(c.ops, "update "+object.GetName())
+	c.objects[object.GetName()] = object.DeepCopy()
+	return nil
+}
+
+func (c *orderClient) Delete(ctx context.Context, obj runtime.Object, opts ...kubernetes.DeleteOption) error {
+	object := obj.(*unstructured.Unstructured)
+	c.ops = append(c.ops, "delete "+object.GetName())
+	if len(c.objects[object.GetName()].GetFinalizers()) == 0 {
+		delete(c.objects, object.GetName())
+	}
+	return nil
+}
+
+func newOrderedResource(name string, annotations map[string]string) *unstructured.Unstructured {
+	resource := &unstructured.Unstructured{}
+	resource.SetAPIVersion("v1")
+	resource.SetKind("ConfigMap")
+	resource.SetNamespace("default")
+	resource.SetName(name)
+	resource.SetAnnotations(annotations)
+	return resource
+}
+
+func Test_groupPhases(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	resources := []*unstructured.Unstructured{
+		newOrderedResource("statefulset", map[string]string{constants.OrderKey: "10"}),
+		newOrderedResource("service", nil),
+		newOrderedResource("secret", map[string]string{constants.HelmHookWeightKey: "-5"}),
+		newOrderedResource("crd", map[string]string{constants.OrderKey: "-10", constants.HelmHookWeightKey: "5"}),
+		newOrderedResource("configmap", map[string]string{constants.OrderKey: "invalid"}),
+	}
+
+	names := [][]string{}
+	for _, phase := range groupPhases(resources) {
+		phaseNames := []string{}
+		for _, resource := range phase {
+			phaseNames = append(phaseNames, resource.GetName())
+		}
+		names = append(names, phaseNames)
+	}
+	g.Expect(names).To(gomega.Equal([][]string{
+		{"crd"},
+		{"secret"},
+		{"service", "configmap"},
+		{"statefulset"},
+	}))
+	g.Expect(groupPhases(nil)).To(gomega.BeEmpty())
+}
+
+func Test_waitTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        time.Duration
+		wantOk      bool
+	}{
+		{
+			name: "do not wait by default",
+		},
+		{
+			name:        "do not wait if disabled",
+			annotations: map[string]string{constants.WaitKey: "false"},
+		},
+		{
+			name:        "wait for the default timeout",
+			annotations: map[string]string{constants.WaitKey: "true"},
+			want:        constants.ResourceWaitTimeout,
+			wantOk:      true,
+		},
+		{
+			name:        "wait for the given timeout",
+			annotations: map[string]string{constants.WaitKey: "30s"},
+			want:        30 * time.Second,
+			wantOk:      true,
+		},
+		{
+			name:        "wait for the default timeout if invalid",
+			annotations: map[string]string{constants.WaitKey: "soon"},
+			want:        constants.ResourceWaitTimeout,
+			wantOk:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+			got, ok := waitTimeout(newOrderedResource("configmap", tt.annotations))
+			g.Expect(got).To(gomega.Equal(tt.want))
+			g.Expect(ok).To(gomega.Equal(tt.wantOk))
+		})
+	}
+}
+
+func Test_ready(t *testing.T) {
+	tests := []struct {
+		name   string
+		object map[string]interface{}
+		want   bool
+	}{
+		{
+			name:   "ready if there is no status",
+			object: map[string]interface{}{},
+			want:   true,
+		},
+		{
+			name: "not ready if the generation is not observed",
+			object: map[string]interface{}{
+				"metadata": map[string]interface{}{"generation": int64(2)},
+				"status":   map[string]interface{}{"observedGeneration": int64(1)},
+			},
+			want: false,
+		},
+		{
+			name: "not ready until all replicas are ready",
+			object: map[string]interface{}{
+				"spec":   map[string]interface{}{"replicas": int64(3)},
+				"status": map[string]interface{}{"readyReplicas": int64(2)},
+			},
+			want: false,
+		},
+		{
+			name: "ready if all replicas are ready",
+			object: map[string]interface{}{
+				"spec":   map[string]interface{}{"replicas": int64(3)},
+				"status": map[string]interface{}{"readyReplicas": int64(3)},
+			},
+			want: true,
+		},
+		{
+			name: "not ready if the ready condition is false",
+			object: map[string]interface{}{
+				"status": map[string]interface{}{
+					"conditions": []interface{}{
+						map[string]interface{}{"type": "Available", "status": "True"},
+						map[string]interface{}{"type": "Ready", "status": "False"},
+					},
+				},
+			},
+			want: false,
+		},
+		{
+			name: "ready if established",
+			object: map[string]interface{}{
+				"status": map[string]interface{}{
+					"conditions": []interface{}{
+						map[string]interface{}{"type": "NamesAccepted", "status": "True"},
+						map[string]interface{}{"type": "Established", "status": "True"},
+					},
+				},
+			},
+			want: true,
+		},
+		{
+			name: "not ready until the operator succeeded",
+			object: map[string]interface{}{
+				"status": map[string]interface{}{"state": "in_progress"},
+			},
+			want: false,
+		},
+		{
+			name: "ready if the operator succeeded",
+			object: map[string]interface{}{
+				"status": map[string]interface{}{"state": "succeeded"},
+			},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+			g.Expect(ready(&unstructured.Unstructured{Object: tt.object})).To(gomega.Equal(tt.want))
+		})
+	}
+}
+
+func Test_resourceManager_ReconcileResources_Order(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	secret := newOrderedResource("secret", map[string]string{
+		constants.OrderKey: "-1",
+		constants.WaitKey:  "true",
+	})
+	statefulset := newOrderedResource("statefulset", nil)
+	client := &orderClient{objects: make(map[string]*unstructured.Unstructured)}
+
+	got, _, err := resourceManager{}.ReconcileResources(client, client, []*unstructured.Unstructured{statefulset, secret}, nil)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(client.ops).To(gomega.Equal([]string{"apply secret", "apply statefulset"}))
+	g.Expect(got).To(gomega.HaveLen(2))
+
+	// The next phase is not applied until the resource is ready. The
+	// applied and the last resources are still tracked.
+	secret = newOrderedResource("secret", map[string]string{
+		constants.OrderKey: "-1",
+		constants.WaitKey:  "1m",
+	})
+	secret.Object["status"] = map[string]interface{}{"state": "in_progress"}
+	client = &orderClient{objects: make(map[string]*unstructured.Unstructured)}
+	last := osbv1alpha1.Source{APIVersion: "v1", Kind: "ConfigMap", Namespace: "default", Name: "last"}
+
+	got, pruned, err := resourceManager{}.ReconcileResources(client, client, []*unstructured.Unstructured{statefulset, secret}, []osbv1alpha1.Source{last})
+	g.Expect(errors.ResourceNotReady(err)).To(gomega.BeTrue())
+	g.Expect(client.ops).To(gomega.Equal([]string{"apply secret"}))
+	g.Expect(got).To(gomega.Equal([]osbv1alpha1.Source{resourceManager{}.unstructuredToSource(secret), last}))
+	g.Expect(pruned).To(gomega.BeEmpty())
+
+	// The wait times out once the resource is not ready for the timeout
+	// after it was applied
+	applied := metav1.NewTime(time.Now().Add(-2 * time.Minute))
+	client.objects["secret"].SetManagedFields([]metav1.ManagedFieldsEntry{
+		{Manager: "kubectl", Operation: metav1.ManagedFieldsOperationUpdate},
+		{Manager: "interoperator", Operation: metav1.ManagedFieldsOperationApply, Time: &applied},
+	})
+	err = resourceManager{}.waitReady(client, []*unstructured.Unstructured{secret})
+	g.Expect(errors.WaitTimeout(err)).To(gomega.BeTrue())
+}
+
+func Test_resourceManager_DeleteSubResources_Order(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	secret := newOrderedResource("secret", map[string]string{constants.OrderKey: "-1"})
+	statefulset := newOrderedResource("statefulset", map[string]string{constants.WaitKey: "true"})
+	client := &orderClient{objects: map[string]*unstructured.Unstructured{
+		"secret":      secret,
+		"statefulset": statefulset,
+	}}
+	subResources := []osbv1alpha1.Source{
+		resourceManager{}.unstructuredToSource(secret),
+		resourceManager{}.unstructuredToSource(statefulset),
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: "default", Name: "gone"},
+	}
+
+	remaining, err := resourceManager{}.DeleteSubResources(client, subResources)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(client.ops).To(gomega.Equal([]string{"delete statefulset", "delete secret"}))
+	g.Expect(remaining).To(gomega.ConsistOf(subResources[0], subResources[1]))
+
+	// The next phase is not deleted until the resource is gone
+	statefulset = newOrderedResource("statefulset", map[string]string{constants.WaitKey: "1m"})
+	statefulset.SetFinalizers([]string{"kubernetes"})
+	client = &orderClient{objects: map[string]*unstructured.Unstructured{
+		"secret":      secret,
+		"statefulset": statefulset,
+	}}
+
+	remaining, err = resourceManager{}.DeleteSubResources(client, subResources)
+	g.Expect(errors.ResourceNotReady(err)).To(gomega.BeTrue())
+	g.Expect(client.ops).To(gomega.Equal([]string{"delete statefulset"}))
+	g.Expect(remaining).To(gomega.ConsistOf(subResources[0], subResources[1]))
+
+	// The wait times out once the resource is not gone for the timeout
+	// after its deletion
+	deletedAt := metav1.NewTime(time.Now().Add(-2 * time.Minute))
+	client.objects["statefulset"].SetDeletionTimestamp(&deletedAt)
+	remaining, err = resourceManager{}.DeleteSubResources(client, subResources)
+	g.Expect(errors.WaitTimeout(err)).To(gomega.BeTrue())
+	g.Expect(remaining).To(gomega.ConsistOf(subResources[0], subResources[1]))
+}
+
+func Test_resourceManager_DeleteSubResources_StateDelete(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	secret := newOrderedResource("secret", map[string]string{constants.OrderKey: "-1"})
+	director := newOrderedResource("director", map[string]string{constants.WaitKey: "1m"})
+	director.SetAPIVersion("deployment.servicefabrik.io/v1alpha1")
+	director.SetKind("Director")
+	client := &orderClient{objects: map[string]*unstructured.Unstructured{
+		"secret":   secret,
+		"director": director,
+	}}
+	subResources := []osbv1alpha1.Source{
+		resourceManager{}.unstructuredToSource(secret),
+		resourceManager{}.unstructuredToSource(director),
+	}
+
+	// The sf operator resources are deleted by setting their state
+	remaining, err := resourceManager{}.DeleteSubResources(client, subResources)
+	g.Expect(errors.ResourceNotReady(err)).To(gomega.BeTrue())
+	g.Expect(client.ops).To(gomega.Equal([]string{"update director"}))
+	g.Expect(remaining).To(gomega.ConsistOf(subResources[0], subResources[1]))
+	object := client.objects["director"]
+	state, _, _ := unstructured.NestedString(object.Object, "status", "state")
+	g.Expect(state).To(gomega.Equal("delete"))
+	requestedAt := object.GetAnnotations()[constants.DeleteRequestKey]
+	g.Expect(requestedAt).NotTo(gomega.BeEmpty())
+
+	// Requesting the delete again keeps the time of the first request
+	remaining, err = resourceManager{}.DeleteSubResources(client, subResources)
+	g.Expect(errors.ResourceNotReady(err)).To(gomega.BeTrue())
+	g.Expect(client.objects["director"].GetAnnotations()[constants.DeleteRequestKey]).To(gomega.Equal(requestedAt))
+
+	// The wait times out once the resource is not gone for the timeout
+	// after its deletion was requested
+	client.objects["director"].SetAnnotations(map[string]string{
+		constants.WaitKey:          "1m",
+		constants.DeleteRequestKey: time.Now().Add(-2 * time.Minute).UTC().Format(time.RFC3339),
+	})
+	remaining, err = resourceManager{}.DeleteSubResources(client, subResources)
+	g.Expect(errors.WaitTimeout(err)).To(gomega.BeTrue())
+	g.Expect(remaining).To(gomega.ConsistOf(subResources[0], subResources[1]))
+}
+
+func Test_deleteRequestedAt(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	object := newOrderedResource("director", nil)
+	g.Expect(deleteRequestedAt(object).IsZero()).To(gomega.BeTrue())
+
+	requestedAt := time.Now().Add(-time.Hour).Truncate(time.Second).UTC()
+	object.SetAnnotations(map[string]string{constants.DeleteRequestKey: requestedAt.Format(time.RFC3339)})
+	g.Expect(deleteRequestedAt(object)).To(gomega.Equal(requestedAt))
+
+	deletedAt := metav1.NewTime(time.Now().Add(-time.Minute).Truncate(time.Second))
+	object.SetDeletionTimestamp(&deletedAt)
+	g.Expect(deleteRequestedAt(object)).To(gomega.Equal(deletedAt.Time))
+}
+
+func Test_appliedAt(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	object := newOrderedResource("secret", nil)
+	g.Expect(appliedAt(object).IsZero()).To(gomega.BeTrue())
+
+	createdAt := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	object.SetCreationTimestamp(createdAt)
+	g.Expect(appliedAt(object)).To(gomega.Equal(createdAt.Time))
+
+	updatedAt := metav1.NewTime(time.Now().Add(-time.Minute).Truncate(time.Second))
+	object.SetManagedFields([]metav1.ManagedFieldsEntry{
+		{Manager: "interoperator", Operation: metav1.ManagedFieldsOperationUpdate, Time: &createdAt},
+		{Manager: "interoperator", Operation: metav1.ManagedFieldsOperationApply, Time: &updatedAt},
+	})
+	g.Expect(appliedAt(object)).To(gomega.Equal(updatedAt.Time))
+}
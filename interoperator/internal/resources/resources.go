@@ -4,11 +4,17 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/dynamic"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/encryption"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/properties"
 	rendererFactory "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/renderer/factory"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/secretstore"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/services"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
@@ -18,6 +24,7 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/util/retry"
 	kubernetes "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -26,17 +33,28 @@ import (
 
 var log = logf.Log.WithName("resources.internal")
 
+var (
+	// legacyFieldManager manages the fields written by updates of
+	// interoperator before it used server side apply. The api server
+	// derives it from the user agent, i.e. the name of the binary.
+	legacyFieldManager = filepath.Base(os.Args[0])
+
+	conflictManager = regexp.MustCompile(`conflict with "([^"]*)"`)
+)
+
 // ResourceManager defines the interface implemented by resources
 //go:generate mockgen -source resources.go -destination ./mock_resources/mock_resources.go
 type ResourceManager interface {
 	ComputeExpectedResources(client kubernetes.Client, instanceID, bindingID, serviceID, planID, action, namespace string) ([]*unstructured.Unstructured, error)
 	SetOwnerReference(owner metav1.Object, resources []*unstructured.Unstructured, scheme *runtime.Scheme) error
-	ReconcileResources(sourceClient kubernetes.Client, targetClient kubernetes.Client, expectedResources []*unstructured.Unstructured, lastResources []osbv1alpha1.Source) ([]osbv1alpha1.Source, error)
+	ReconcileResources(sourceClient kubernetes.Client, targetClient kubernetes.Client, expectedResources []*unstructured.Unstructured, lastResources []osbv1alpha1.Source) ([]osbv1alpha1.Source, []osbv1alpha1.Source, error)
 	ComputeStatus(sourceClient kubernetes.Client, targetClient kubernetes.Client, instanceID, bindingID, serviceID, planID, action, namespace string) (*properties.Status, error)
 	DeleteSubResources(client kubernetes.Client, subResources []osbv1alpha1.Source) ([]osbv1alpha1.Source, error)
 }
 
 type resourceManager struct {
+	secretStore secretstore.SecretStore
+	encrypter   encryption.Encrypter
 }
 
 // New creates a new ResourceManager object.
@@ -44,6 +62,16 @@ func New() ResourceManager {
 	return resourceManager{}
 }
 
+// NewWithSecretStore creates a new ResourceManager object which reads the
+// previous credentials of rotated bindings from the secret store and
+// decrypts them with the encrypter.
+func NewWithSecretStore(secretStore secretstore.SecretStore, encrypter encryption.Encrypter) ResourceManager {
+	return resourceManager{
+		secretStore: secretStore,
+		encrypter:   encrypter,
+	}
+}
+
 func (r resourceManager) fetchResources(client kubernetes.Client, instanceID, bindingID, serviceID, planID, namespace string) (*osbv1alpha1.SFServiceInstance, *osbv1alpha1.SFServiceBinding, *osbv1alpha1.SFService, *osbv1alpha1.SFPlan, error) {
 	var instance *osbv1alpha1.SFServiceInstance
 	var binding *osbv1alpha1.SFServiceBinding
@@ -129,13 +157,22 @@ func (r resourceManager) ComputeExpectedResources(client kubernetes.Client, inst
 		return nil, err
 	}
 
-	renderer, err := rendererFactory.GetRenderer(template.Type, nil)
+	renderer, err := rendererFactory.GetRenderer(template.Type, nil, client)
 	if err != nil {
 		log.Error(err, "failed to get renderer", "type", template.Type)
 		return nil, err
 	}
 
-	input, err := rendererFactory.GetRendererInput(template, service, plan, instance, binding, name)
+	var previous map[string]interface{}
+	if action == osbv1alpha1.BindAction {
+		previous, err = r.previousCredentials(client, binding)
+		if err != nil {
+			log.Error(err, "failed fetching previous credentials of rotation")
+			return nil, err
+		}
+	}
+
+	input, err := rendererFactory.GetBindRendererInput(template, service, plan, instance, binding, name, previous)
 	if err != nil {
 		log.Error(err, "failed creating renderer input", "type", template.Type)
 		return nil, err
@@ -179,6 +216,44 @@ func (r resourceManager) ComputeExpectedResources(client kubernetes.Client, inst
 	return resources, nil
 }
 
+// previousCredentials returns the credentials of the binding before the
+// rotation in progress, nil if the credentials are not rotated. Encrypted
+// credentials are decrypted, the bind template gets the plaintext.
+func (r resourceManager) previousCredentials(client kubernetes.Client, binding *osbv1alpha1.SFServiceBinding) (map[string]interface{}, error) {
+	if binding == nil || binding.Status.Rotation == nil || binding.Status.Rotation.PreviousSecretRef == "" {
+		return nil, nil
+	}
+	store := r.secretStore
+	if store == nil {
+		var err error
+		store, err = secretstore.NewKubernetesStore(client, clientgoscheme.Scheme)
+		if err != nil {
+			return nil, err
+		}
+	}
+	data, err := store.Get(binding, secretstore.PreviousBindSecretName(binding.GetName()))
+	if err != nil {
+		if errors.SecretNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	previous := make(map[string]interface{})
+	for key, value := range data {
+		if r.encrypter != nil {
+			value, err = r.encrypter.Decrypt(value)
+			if err != nil {
+				log.Error(err, "failed to decrypt previous credentials", "binding", binding.GetName())
+				return nil, err
+			}
+		} else if encryption.IsEncrypted(value) {
+			return nil, errors.NewEncryptionError("no encrypter to decrypt previous credentials", nil)
+		}
+		previous[key] = value
+	}
+	return previous, nil
+}
+
 // SetOwnerReference updates the owner reference for all the resources
 func (r resourceManager) SetOwnerReference(owner metav1.Object, resources []*unstructured.Unstructured, scheme *runtime.Scheme) error {
 	for _, obj := range resources {
@@ -190,54 +265,46 @@ func (r resourceManager) SetOwnerReference(owner metav1.Object, resources []*uns
 	return nil
 }
 
-// ReconcileResources setups all resources according to expectation
-func (r resourceManager) ReconcileResources(sourceClient kubernetes.Client, targetClient kubernetes.Client, expectedResources []*unstructured.Unstructured, lastResources []osbv1alpha1.Source) ([]osbv1alpha1.Source, error) {
+// ReconcileResources setups all resources according to expectation.
+// The resources are applied in phases ordered by their weight. The next
+// phase is applied once the resources annotated with
+// interoperator.servicefabrik.io/wait are ready, otherwise a
+// ResourceNotReady error is returned and the caller requeues. The last
+// resources which are no longer expected are pruned, unless they are
+// annotated with interoperator.servicefabrik.io/prune: "false".
+// It returns the resources to track and the pruned resources. On errors
+// the resources to track are the ones applied so far and the last ones.
+func (r resourceManager) ReconcileResources(sourceClient kubernetes.Client, targetClient kubernetes.Client, expectedResources []*unstructured.Unstructured, lastResources []osbv1alpha1.Source) ([]osbv1alpha1.Source, []osbv1alpha1.Source, error) {
 	foundResources := make([]*unstructured.Unstructured, 0, len(expectedResources))
-	for _, expectedResource := range expectedResources {
-		foundResource := &unstructured.Unstructured{}
-
-		kind := expectedResource.GetKind()
-		apiVersion := expectedResource.GetAPIVersion()
-		foundResource.SetKind(kind)
-		foundResource.SetAPIVersion(apiVersion)
-		namespacedName := types.NamespacedName{
-			Name:      expectedResource.GetName(),
-			Namespace: expectedResource.GetNamespace(),
-		}
-		foundResource.SetName(namespacedName.Name)
-		foundResource.SetNamespace(namespacedName.Namespace)
+	phases := groupPhases(expectedResources)
+	for i, phase := range phases {
+		for _, expectedResource := range phase {
+			kind := expectedResource.GetKind()
+			namespacedName := types.NamespacedName{
+				Name:      expectedResource.GetName(),
+				Namespace: expectedResource.GetNamespace(),
+			}
 
-		err := targetClient.Get(context.TODO(), namespacedName, foundResource)
-		if err != nil && apiErrors.IsNotFound(err) {
-			log.Info("reconcile - creating resource", "kind", kind, "namespacedName", namespacedName)
-			err = targetClient.Create(context.TODO(), expectedResource)
+			log.Info("reconcile - applying resource", "kind", kind, "namespacedName", namespacedName)
+			err := r.applyResource(targetClient, expectedResource)
 			if err != nil {
-				log.Error(err, "reconcile - failed to create resource", "kind", kind, "namespacedName", namespacedName)
-				return nil, err
+				log.Error(err, "reconcile - failed to apply resource", "kind", kind, "namespacedName", namespacedName)
+				return r.trackedResources(foundResources, lastResources), nil, err
 			}
-			foundResources = append(foundResources, foundResource)
-			continue
-		} else if err != nil {
-			log.Error(err, "reconcile - failed fetching resource", "kind", kind, "namespacedName", namespacedName)
-			return nil, err
+			foundResources = append(foundResources, expectedResource)
 		}
-
-		toBeUpdated := false
-		updatedResource, toBeUpdated := dynamic.DeepUpdate(foundResource.Object, expectedResource.Object)
-		if toBeUpdated {
-			log.Info("reconcile - updating resource", "kind", kind, "namespacedName", namespacedName)
-			foundResource.Object = updatedResource.(map[string]interface{})
-			err = targetClient.Update(context.TODO(), foundResource)
+		if i < len(phases)-1 {
+			err := r.waitReady(targetClient, phase)
 			if err != nil {
-				log.Error(err, "reconcile- failed to update resource", "kind", kind, "namespacedName", namespacedName)
-				return nil, err
+				if !errors.ResourceNotReady(err) {
+					log.Error(err, "reconcile - resources not ready for next phase")
+				}
+				return r.trackedResources(foundResources, lastResources), nil, err
 			}
-		} else {
-			log.Info("reconcile - resource already up todate", "kind", kind, "namespacedName", namespacedName)
 		}
-		foundResources = append(foundResources, foundResource)
 	}
 
+	prunedResources := []osbv1alpha1.Source{}
 	for _, lastResource := range lastResources {
 		oldResource := &unstructured.Unstructured{}
 		oldResource.SetKind(lastResource.Kind)
@@ -245,7 +312,7 @@ func (r resourceManager) ReconcileResources(sourceClient kubernetes.Client, targ
 		oldResource.SetName(lastResource.Name)
 		oldResource.SetNamespace(lastResource.Namespace)
 		if ok := r.findUnstructuredObject(foundResources, oldResource); !ok {
-			err := targetClient.Delete(context.TODO(), oldResource)
+			pruned, err := r.pruneResource(targetClient, oldResource)
 			if err != nil {
 				// Not failing here. Add the outdated resource to foundResource
 				// Delete will be retried on next reconcile
@@ -253,14 +320,167 @@ func (r resourceManager) ReconcileResources(sourceClient kubernetes.Client, targ
 				foundResources = append(foundResources, oldResource)
 				continue
 			}
-			log.Info("reconcile - delete triggered for outdated resource", "resource", lastResource)
+			if pruned {
+				log.Info("reconcile - delete triggered for outdated resource", "resource", lastResource)
+				prunedResources = append(prunedResources, lastResource)
+			}
 		}
 	}
 	resourceRefs := []osbv1alpha1.Source{}
 	for _, object := range foundResources {
 		resourceRefs = append(resourceRefs, r.unstructuredToSource(object))
 	}
-	return resourceRefs, nil
+	return resourceRefs, prunedResources, nil
+}
+
+// trackedResources returns the applied resources and the last resources
+// which are not applied
+func (r resourceManager) trackedResources(appliedResources []*unstructured.Unstructured, lastResources []osbv1alpha1.Source) []osbv1alpha1.Source {
+	resourceRefs := make([]osbv1alpha1.Source, 0, len(appliedResources)+len(lastResources))
+	for _, object := range appliedResources {
+		resourceRefs = append(resourceRefs, r.unstructuredToSource(object))
+	}
+	for _, lastResource := range lastResources {
+		found := false
+		for _, resourceRef := range resourceRefs {
+			if resourceRef == lastResource {
+				found = true
+				break
+			}
+		}
+		if !found {
+			resourceRefs = append(resourceRefs, lastResource)
+		}
+	}
+	return resourceRefs
+}
+
+// pruneResource deletes an outdated resource. It returns false if the
+// resource is already gone or has opted out of pruning.
+func (r resourceManager) pruneResource(client kubernetes.Client, resource *unstructured.Unstructured) (bool, error) {
+	namespacedName := types.NamespacedName{
+		Name:      resource.GetName(),
+		Namespace: resource.GetNamespace(),
+	}
+	err := client.Get(context.TODO(), namespacedName, resource)
+	if err != nil {
+		if apiErrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if resource.GetAnnotations()[constants.PruneKey] == "false" {
+		log.Info("reconcile - outdated resource opted out of pruning", "kind", resource.GetKind(), "namespacedName", namespacedName)
+		return false, nil
+	}
+	err = client.Delete(context.TODO(), resource)
+	if err != nil {
+		if apiErrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// applyResource applies the resource with server side apply. Fields
+// removed from the resource are removed from the live object if they
+// are not managed by other field managers. The fields written by updates
+// of interoperator before it used server side apply are migrated to the
+// interoperator apply manager first. The apply fails with an ApplyConflict
+// error if a field is managed by another controller, unless it was only
+// written by those updates.
+func (r resourceManager) applyResource(client kubernetes.Client, resource *unstructured.Unstructured) error {
+	err := r.migrateManagedFields(client, resource)
+	if err != nil {
+		return err
+	}
+
+	resource.SetResourceVersion("")
+	resource.SetManagedFields(nil)
+	err = client.Patch(context.TODO(), resource, kubernetes.Apply, kubernetes.FieldOwner(constants.FieldManager))
+	if err == nil || !apiErrors.IsConflict(err) {
+		return err
+	}
+
+	managers, fields := conflicts(err)
+	legacy := len(managers) > 0
+	for _, manager := range managers {
+		legacy = legacy && manager == legacyFieldManager
+	}
+	if !legacy {
+		name := types.NamespacedName{Name: resource.GetName(), Namespace: resource.GetNamespace()}.String()
+		message := fmt.Sprintf("fields %s managed by %s", strings.Join(fields, ", "), strings.Join(managers, ", "))
+		return errors.NewApplyConflict(resource.GetKind(), name, message, err)
+	}
+	log.Info("reconcile - taking over fields from updates", "kind", resource.GetKind(), "name", resource.GetName(), "fields", fields)
+	return client.Patch(context.TODO(), resource, kubernetes.Apply, kubernetes.FieldOwner(constants.FieldManager), kubernetes.ForceOwnership)
+}
+
+// migrateManagedFields hands the fields written by updates of interoperator
+// before it used server side apply over to the interoperator apply manager.
+// Otherwise the update manager keeps owning them and fields removed from the
+// resource are never removed from the live object. It is a no-op once
+// interoperator has applied the object.
+func (r resourceManager) migrateManagedFields(client kubernetes.Client, resource *unstructured.Unstructured) error {
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(resource.GroupVersionKind())
+	namespacedName := types.NamespacedName{
+		Name:      resource.GetName(),
+		Namespace: resource.GetNamespace(),
+	}
+	err := client.Get(context.TODO(), namespacedName, live)
+	if err != nil {
+		if apiErrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	managedFields := live.GetManagedFields()
+	legacy := -1
+	for i, entry := range managedFields {
+		switch {
+		case entry.Manager == constants.FieldManager && entry.Operation == metav1.ManagedFieldsOperationApply:
+			return nil
+		case legacy < 0 && entry.Manager == legacyFieldManager && entry.Operation == metav1.ManagedFieldsOperationUpdate &&
+			entry.APIVersion == resource.GetAPIVersion():
+			legacy = i
+		}
+	}
+	if legacy < 0 {
+		return nil
+	}
+
+	// Entries of other api versions are left to the forced apply
+	managedFields[legacy].Manager = constants.FieldManager
+	managedFields[legacy].Operation = metav1.ManagedFieldsOperationApply
+	live.SetManagedFields(managedFields)
+	log.Info("reconcile - migrating fields from updates to apply", "kind", resource.GetKind(), "namespacedName", namespacedName)
+	return client.Update(context.TODO(), live)
+}
+
+// conflicts returns the field managers and fields of an apply conflict
+func conflicts(err error) ([]string, []string) {
+	managers := make([]string, 0)
+	fields := make([]string, 0)
+	status, ok := err.(apiErrors.APIStatus)
+	if !ok || status.Status().Details == nil {
+		return managers, fields
+	}
+	seen := make(map[string]bool)
+	for _, cause := range status.Status().Details.Causes {
+		if cause.Type != metav1.CauseTypeFieldManagerConflict {
+			continue
+		}
+		fields = append(fields, cause.Field)
+		match := conflictManager.FindStringSubmatch(cause.Message)
+		if match != nil && !seen[match[1]] {
+			seen[match[1]] = true
+			managers = append(managers, match[1])
+		}
+	}
+	return managers, fields
 }
 
 func (r resourceManager) unstructuredToSource(object *unstructured.Unstructured) osbv1alpha1.Source {
@@ -314,7 +534,7 @@ func (r resourceManager) ComputeStatus(sourceClient kubernetes.Client, targetCli
 		return nil, err
 	}
 
-	renderer, err := rendererFactory.GetRenderer(template.Type, nil)
+	renderer, err := rendererFactory.GetRenderer(template.Type, nil, targetClient)
 	if err != nil {
 		log.Error(err, "failed to get sources renderer", "type", template.Type)
 		return nil, err
@@ -393,7 +613,7 @@ func (r resourceManager) ComputeStatus(sourceClient kubernetes.Client, targetCli
 		return nil, err
 	}
 
-	renderer, err = rendererFactory.GetRenderer(template.Type, nil)
+	renderer, err = rendererFactory.GetRenderer(template.Type, nil, targetClient)
 	if err != nil {
 		log.Error(err, "failed to get status renderer", "type", template.Type)
 		return nil, err
@@ -449,7 +669,11 @@ func (r resourceManager) ComputeStatus(sourceClient kubernetes.Client, targetCli
 	return status, nil
 }
 
-// DeleteSubResources setups all resources according to expectation
+// DeleteSubResources deletes the resources in the reverse order of their
+// weight. The next phase is deleted once the resources annotated with
+// interoperator.servicefabrik.io/wait are gone, otherwise a
+// ResourceNotReady error is returned with the remaining resources and the
+// caller requeues.
 func (r resourceManager) DeleteSubResources(client kubernetes.Client, subResources []osbv1alpha1.Source) ([]osbv1alpha1.Source, error) {
 	//
 	// delete the external dependency here
@@ -460,25 +684,63 @@ func (r resourceManager) DeleteSubResources(client kubernetes.Client, subResourc
 	var remainingResource []osbv1alpha1.Source
 	var lastError error
 
+	resources := make([]*unstructured.Unstructured, 0, len(subResources))
 	for _, subResource := range subResources {
 		resource := &unstructured.Unstructured{}
 		resource.SetKind(subResource.Kind)
 		resource.SetAPIVersion(subResource.APIVersion)
 		resource.SetName(subResource.Name)
 		resource.SetNamespace(subResource.Namespace)
-		err := r.deleteSubResource(client, resource)
+		namespacedName := types.NamespacedName{
+			Name:      subResource.Name,
+			Namespace: subResource.Namespace,
+		}
+		err := client.Get(context.TODO(), namespacedName, resource)
 		if err != nil {
 			if apiErrors.IsNotFound(err) {
 				log.Info("deleted completed for subResource", "subResource", subResource)
 				continue
 			}
-			log.Error(err, "failed to delete subResource", "subResource", subResource)
+			log.Error(err, "failed to get subResource", "subResource", subResource)
 			remainingResource = append(remainingResource, subResource)
 			lastError = err
 			continue
 		}
-		log.Info("deleted triggered for subResource", "subResource", subResource)
-		remainingResource = append(remainingResource, subResource)
+		resources = append(resources, resource)
+	}
+
+	phases := groupPhases(resources)
+	for i := len(phases) - 1; i >= 0; i-- {
+		for _, resource := range phases[i] {
+			subResource := r.unstructuredToSource(resource)
+			err := r.deleteSubResource(client, resource)
+			if err != nil {
+				if apiErrors.IsNotFound(err) {
+					log.Info("deleted completed for subResource", "subResource", subResource)
+					continue
+				}
+				log.Error(err, "failed to delete subResource", "subResource", subResource)
+				remainingResource = append(remainingResource, subResource)
+				lastError = err
+				continue
+			}
+			log.Info("deleted triggered for subResource", "subResource", subResource)
+			remainingResource = append(remainingResource, subResource)
+		}
+		if i > 0 {
+			err := r.waitDeleted(client, phases[i])
+			if err != nil {
+				if !errors.ResourceNotReady(err) {
+					log.Error(err, "subResources not deleted for next phase")
+				}
+				for _, phase := range phases[:i] {
+					for _, resource := range phase {
+						remainingResource = append(remainingResource, r.unstructuredToSource(resource))
+					}
+				}
+				return remainingResource, err
+			}
+		}
 	}
 	return remainingResource, lastError
 }
@@ -515,6 +777,17 @@ func (r resourceManager) deleteSubResource(client kubernetes.Client, resource *u
 				status["state"] = "delete"
 				content["status"] = status
 				resource.SetUnstructuredContent(content)
+
+				// These resources get no deletionTimestamp, remember when
+				// the delete was requested for waitDeleted
+				annotations := resource.GetAnnotations()
+				if annotations == nil {
+					annotations = make(map[string]string)
+				}
+				if _, ok := annotations[constants.DeleteRequestKey]; !ok {
+					annotations[constants.DeleteRequestKey] = time.Now().UTC().Format(time.RFC3339)
+					resource.SetAnnotations(annotations)
+				}
 				err = client.Update(context.TODO(), resource)
 				if err != nil {
 					return err
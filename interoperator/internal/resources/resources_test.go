@@ -2,6 +2,7 @@ package resources
 
 import (
 	"context"
+	"io/ioutil"
 	stdlog "log"
 	"os"
 	"path/filepath"
@@ -11,18 +12,26 @@ import (
 
 	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
 	resourcev1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/resource/v1alpha1"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/config"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/dynamic"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/encryption"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/properties"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/secretstore"
+
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
 
 	"github.com/onsi/gomega"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	kubernetes "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 )
 
@@ -676,11 +685,14 @@ func Test_resourceManager_ReconcileResources_ResourceNotFound(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			r := resourceManager{}
-			got, err := r.ReconcileResources(tt.args.sourceClient, tt.args.targetClient, tt.args.expectedResources, tt.args.lastResources)
+			got, pruned, err := r.ReconcileResources(tt.args.sourceClient, tt.args.targetClient, tt.args.expectedResources, tt.args.lastResources)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("resourceManager.ReconcileResources() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if len(pruned) != 0 {
+				t.Errorf("resourceManager.ReconcileResources() pruned = %v, want none", pruned)
+			}
 			if !reflect.DeepEqual(got[0].GetAPIVersion(), tt.want[0].GetAPIVersion()) {
 				t.Errorf("resourceManager.ReconcileResources() = %v, want %v", got, tt.want)
 			}
@@ -796,11 +808,14 @@ status:
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			r := resourceManager{}
-			got, err := r.ReconcileResources(tt.args.sourceClient, tt.args.targetClient, tt.args.expectedResources, tt.args.lastResources)
+			got, pruned, err := r.ReconcileResources(tt.args.sourceClient, tt.args.targetClient, tt.args.expectedResources, tt.args.lastResources)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("resourceManager.ReconcileResources() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if len(pruned) != 0 {
+				t.Errorf("resourceManager.ReconcileResources() pruned = %v, want none", pruned)
+			}
 			if !reflect.DeepEqual(got[0].GetAPIVersion(), tt.want[0].GetAPIVersion()) {
 				t.Errorf("resourceManager.ReconcileResources() = %v, want %v", got, tt.want)
 			}
@@ -817,6 +832,302 @@ status:
 	}
 }
 
+func Test_resourceManager_ReconcileResources_Prune(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	expectedResources, err := dynamic.StringToUnstructured(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: instance-id
+  namespace: default
+data:
+  foo: bar`)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	outdatedResources, err := dynamic.StringToUnstructured(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: instance-id-outdated
+  namespace: default
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: instance-id-retained
+  namespace: default
+  annotations:
+    interoperator.servicefabrik.io/prune: "false"`)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	lastResources := []osbv1alpha1.Source{}
+	for _, resource := range outdatedResources {
+		g.Expect(c.Create(context.TODO(), resource)).NotTo(gomega.HaveOccurred())
+		defer c.Delete(context.TODO(), resource)
+		lastResources = append(lastResources, resourceManager{}.unstructuredToSource(resource))
+	}
+	gone := resourceManager{}.unstructuredToSource(outdatedResources[0])
+	gone.Name = "instance-id-gone"
+	lastResources = append(lastResources, gone)
+	defer c.Delete(context.TODO(), expectedResources[0])
+
+	got, pruned, err := resourceManager{}.ReconcileResources(c, c, expectedResources, lastResources)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(got).To(gomega.Equal([]osbv1alpha1.Source{resourceManager{}.unstructuredToSource(expectedResources[0])}))
+	g.Expect(pruned).To(gomega.Equal(lastResources[:1]))
+
+	retained := &unstructured.Unstructured{}
+	retained.SetAPIVersion("v1")
+	retained.SetKind("ConfigMap")
+	g.Expect(c.Get(context.TODO(), types.NamespacedName{Name: "instance-id-retained", Namespace: "default"}, retained)).
+		NotTo(gomega.HaveOccurred())
+	g.Eventually(func() bool {
+		err := c.Get(context.TODO(), types.NamespacedName{Name: "instance-id-outdated", Namespace: "default"}, retained)
+		return apiErrors.IsNotFound(err)
+	}, timeout).Should(gomega.BeTrue())
+}
+
+// applyClient fails the apply patches with the errors in order and
+// records whether they are forced. The live object has the managed
+// fields, updates record them.
+type applyClient struct {
+	kubernetes.Client
+	errs          []error
+	forced        []bool
+	managedFields []metav1.ManagedFieldsEntry
+	getErr        error
+	updated       []metav1.ManagedFieldsEntry
+}
+
+func (c *applyClient) Get(ctx context.Context, key kubernetes.ObjectKey, obj runtime.Object) error {
+	if c.getErr != nil {
+		return c.getErr
+	}
+	obj.(*unstructured.Unstructured).SetManagedFields(c.managedFields)
+	return nil
+}
+
+func (c *applyClient) Update(ctx context.Context, obj runtime.Object, opts ...kubernetes.UpdateOption) error {
+	c.updated = obj.(*unstructured.Unstructured).GetManagedFields()
+	return nil
+}
+
+func (c *applyClient) Patch(ctx context.Context, obj runtime.Object, patch kubernetes.Patch, opts ...kubernetes.PatchOption) error {
+	options := (&kubernetes.PatchOptions{}).ApplyOptions(opts)
+	if patch.Type() != types.ApplyPatchType || options.FieldManager != "interoperator" {
+		return apiErrors.NewBadRequest("expected apply patch of interoperator")
+	}
+	c.forced = append(c.forced, options.Force != nil && *options.Force)
+	if len(c.errs) == 0 {
+		return nil
+	}
+	err := c.errs[0]
+	c.errs = c.errs[1:]
+	return err
+}
+
+func newApplyConflict(managers ...string) error {
+	causes := make([]metav1.StatusCause, 0, len(managers))
+	for _, manager := range managers {
+		causes = append(causes, metav1.StatusCause{
+			Type:    metav1.CauseTypeFieldManagerConflict,
+			Message: `conflict with "` + manager + `" using apps/v1 at 2019-11-25T10:00:00Z`,
+			Field:   ".spec.replicas",
+		})
+	}
+	return &apiErrors.StatusError{ErrStatus: metav1.Status{
+		Status: metav1.StatusFailure,
+		Code:   409,
+		Reason: metav1.StatusReasonConflict,
+		Details: &metav1.StatusDetails{
+			Causes: causes,
+		},
+	}}
+}
+
+func Test_resourceManager_applyResource(t *testing.T) {
+	tests := []struct {
+		name          string
+		errs          []error
+		managedFields []metav1.ManagedFieldsEntry
+		getErr        error
+		wantForced    []bool
+		wantUpdated   []metav1.ManagedFieldsEntry
+		wantErr       bool
+		wantConflict  bool
+	}{
+		{
+			name:       "apply the resource",
+			errs:       nil,
+			wantForced: []bool{false},
+		},
+		{
+			name:         "report conflicts with other controllers",
+			errs:         []error{newApplyConflict("kubectl", legacyFieldManager)},
+			wantForced:   []bool{false},
+			wantErr:      true,
+			wantConflict: true,
+		},
+		{
+			name:       "take over fields written by updates of interoperator",
+			errs:       []error{newApplyConflict(legacyFieldManager, legacyFieldManager)},
+			wantForced: []bool{false, true},
+		},
+		{
+			name:         "report conflicts without field managers",
+			errs:         []error{newApplyConflict()},
+			wantForced:   []bool{false},
+			wantErr:      true,
+			wantConflict: true,
+		},
+		{
+			name:       "fail if apply fails",
+			errs:       []error{apiErrors.NewBadRequest("bad request")},
+			wantForced: []bool{false},
+			wantErr:    true,
+		},
+		{
+			name:    "fail if the live object can not be read",
+			getErr:  apiErrors.NewBadRequest("bad request"),
+			wantErr: true,
+		},
+		{
+			name:       "apply a new resource",
+			getErr:     apiErrors.NewNotFound(schema.GroupResource{Group: "apps", Resource: "deployments"}, "instance-id"),
+			wantForced: []bool{false},
+		},
+		{
+			name: "migrate the fields written by updates of interoperator",
+			managedFields: []metav1.ManagedFieldsEntry{
+				{Manager: "kubectl", Operation: metav1.ManagedFieldsOperationUpdate, APIVersion: "apps/v1"},
+				{Manager: legacyFieldManager, Operation: metav1.ManagedFieldsOperationUpdate, APIVersion: "apps/v1beta1"},
+				{Manager: legacyFieldManager, Operation: metav1.ManagedFieldsOperationUpdate, APIVersion: "apps/v1"},
+			},
+			wantForced: []bool{false},
+			wantUpdated: []metav1.ManagedFieldsEntry{
+				{Manager: "kubectl", Operation: metav1.ManagedFieldsOperationUpdate, APIVersion: "apps/v1"},
+				{Manager: legacyFieldManager, Operation: metav1.ManagedFieldsOperationUpdate, APIVersion: "apps/v1beta1"},
+				{Manager: "interoperator", Operation: metav1.ManagedFieldsOperationApply, APIVersion: "apps/v1"},
+			},
+		},
+		{
+			name: "not migrate once interoperator applied the resource",
+			managedFields: []metav1.ManagedFieldsEntry{
+				{Manager: "interoperator", Operation: metav1.ManagedFieldsOperationApply, APIVersion: "apps/v1"},
+				{Manager: legacyFieldManager, Operation: metav1.ManagedFieldsOperationUpdate, APIVersion: "apps/v1"},
+			},
+			wantForced: []bool{false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+			resource := &unstructured.Unstructured{}
+			resource.SetAPIVersion("apps/v1")
+			resource.SetKind("Deployment")
+			resource.SetNamespace("default")
+			resource.SetName("instance-id")
+			resource.SetResourceVersion("1")
+
+			c := &applyClient{errs: tt.errs, managedFields: tt.managedFields, getErr: tt.getErr}
+			err := resourceManager{}.applyResource(c, resource)
+			if tt.wantErr {
+				g.Expect(err).To(gomega.HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(gomega.HaveOccurred())
+			}
+			g.Expect(errors.ApplyConflict(err)).To(gomega.Equal(tt.wantConflict))
+			g.Expect(c.forced).To(gomega.Equal(tt.wantForced))
+			g.Expect(c.updated).To(gomega.Equal(tt.wantUpdated))
+			if tt.getErr == nil || apiErrors.IsNotFound(tt.getErr) {
+				g.Expect(resource.GetResourceVersion()).To(gomega.BeEmpty())
+			}
+		})
+	}
+}
+
+// pruneClient returns its annotations for the resource and fails the
+// get and delete with the given errors
+type pruneClient struct {
+	kubernetes.Client
+	annotations map[string]string
+	getErr      error
+	deleteErr   error
+	deleted     bool
+}
+
+func (c *pruneClient) Get(ctx context.Context, key kubernetes.ObjectKey, obj runtime.Object) error {
+	if c.getErr != nil {
+		return c.getErr
+	}
+	obj.(*unstructured.Unstructured).SetAnnotations(c.annotations)
+	return nil
+}
+
+func (c *pruneClient) Delete(ctx context.Context, obj runtime.Object, opts ...kubernetes.DeleteOption) error {
+	c.deleted = c.deleteErr == nil
+	return c.deleteErr
+}
+
+func Test_resourceManager_pruneResource(t *testing.T) {
+	notFound := apiErrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, "instance-id")
+	tests := []struct {
+		name        string
+		client      *pruneClient
+		wantPruned  bool
+		wantDeleted bool
+		wantErr     bool
+	}{
+		{
+			name:        "delete the resource",
+			client:      &pruneClient{},
+			wantPruned:  true,
+			wantDeleted: true,
+		},
+		{
+			name: "keep the resource if it opted out",
+			client: &pruneClient{annotations: map[string]string{
+				"interoperator.servicefabrik.io/prune": "false",
+			}},
+		},
+		{
+			name:   "ignore resources already deleted",
+			client: &pruneClient{getErr: notFound},
+		},
+		{
+			name:   "ignore resources deleted meanwhile",
+			client: &pruneClient{deleteErr: notFound},
+		},
+		{
+			name:    "fail if get fails",
+			client:  &pruneClient{getErr: apiErrors.NewBadRequest("bad request")},
+			wantErr: true,
+		},
+		{
+			name:    "fail if delete fails",
+			client:  &pruneClient{deleteErr: apiErrors.NewBadRequest("bad request")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+			resource := &unstructured.Unstructured{}
+			resource.SetAPIVersion("v1")
+			resource.SetKind("ConfigMap")
+			resource.SetNamespace("default")
+			resource.SetName("instance-id")
+
+			pruned, err := resourceManager{}.pruneResource(tt.client, resource)
+			if tt.wantErr {
+				g.Expect(err).To(gomega.HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(gomega.HaveOccurred())
+			}
+			g.Expect(pruned).To(gomega.Equal(tt.wantPruned))
+			g.Expect(tt.client.deleted).To(gomega.Equal(tt.wantDeleted))
+		})
+	}
+}
+
 func Test_resourceManager_findUnstructuredObject(t *testing.T) {
 	type args struct {
 		list []*unstructured.Unstructured
@@ -1454,3 +1765,66 @@ func Test_resourceManager_DeleteSubResources(t *testing.T) {
 		})
 	}
 }
+
+func Test_resourceManager_previousCredentials(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	dir, err := ioutil.TempDir("", "previous-credentials")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	defer os.RemoveAll(dir)
+	keyFile := filepath.Join(dir, "key")
+	g.Expect(ioutil.WriteFile(keyFile, []byte("0123456789abcdef0123456789abcdef"), 0600)).To(gomega.Succeed())
+	encrypter, err := encryption.New(config.CredentialEncryptionConfig{
+		KeyProvider: encryption.FileKeyProviderName,
+		KeyFile:     keyFile,
+	})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	binding := &osbv1alpha1.SFServiceBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "binding-id",
+			Namespace: "default",
+		},
+		Status: osbv1alpha1.SFServiceBindingStatus{
+			Rotation: &osbv1alpha1.BindingRotation{
+				ID:                "1",
+				State:             "in_progress",
+				PreviousSecretRef: "sf-binding-id-previous",
+			},
+		},
+	}
+	sourceClient := fake.NewFakeClientWithScheme(scheme.Scheme)
+	store, err := secretstore.NewKubernetesStore(sourceClient, scheme.Scheme)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	r := NewWithSecretStore(store, encrypter).(resourceManager)
+
+	// Not rotated, or the previous credentials are gone
+	previous, err := r.previousCredentials(sourceClient, &osbv1alpha1.SFServiceBinding{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(previous).To(gomega.BeNil())
+	previous, err = r.previousCredentials(sourceClient, binding)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(previous).To(gomega.BeNil())
+
+	// The encrypted credentials of the rotation are given as plaintext
+	response := `{"credentials":{"password":"previous-password"}}`
+	ciphertext, err := encrypter.Encrypt(response)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(encryption.IsEncrypted(ciphertext)).To(gomega.BeTrue())
+	g.Expect(store.Put(binding, secretstore.PreviousBindSecretName("binding-id"),
+		map[string]string{"response": ciphertext})).To(gomega.Succeed())
+	previous, err = r.previousCredentials(sourceClient, binding)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(previous).To(gomega.Equal(map[string]interface{}{"response": response}))
+
+	// Encrypted credentials are not given to templates without encrypter
+	_, err = NewWithSecretStore(store, nil).(resourceManager).previousCredentials(sourceClient, binding)
+	g.Expect(errors.EncryptionError(err)).To(gomega.BeTrue())
+
+	// Credentials stored before encryption was enabled are given as they are
+	g.Expect(store.Put(binding, secretstore.PreviousBindSecretName("binding-id"),
+		map[string]string{"response": response})).To(gomega.Succeed())
+	previous, err = r.previousCredentials(sourceClient, binding)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(previous).To(gomega.Equal(map[string]interface{}{"response": response}))
+}
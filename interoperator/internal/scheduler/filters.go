@@ -0,0 +1,227 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"strings"
+
+	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
+	resourcev1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/resource/v1alpha1"
+	rendererFactory "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/renderer/factory"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// labelSelector restricts the clusters to the ones matching the label
+// selector rendered from the clusterSelector template of the plan
+type labelSelector struct{}
+
+func (p *labelSelector) Name() string {
+	return LabelSelectorPluginName
+}
+
+func (p *labelSelector) PreFilter(state *State, options *client.ListOptions) error {
+	selector, err := getLabelSelectorString(state)
+	if err != nil {
+		return err
+	}
+	label, err := labels.Parse(selector)
+	if err != nil {
+		return errors.NewSchedulerFailed(constants.LabelSelectorSchedulerType, "Parsing failed for labelSelector: "+selector, err)
+	}
+	if selector != "" {
+		options.LabelSelector = label
+	}
+	return nil
+}
+
+func getLabelSelectorString(state *State) (string, error) {
+	instance := state.Instance
+	plan, err := state.GetPlan()
+	if err != nil {
+		return "", err
+	}
+	service, err := state.GetService()
+	if err != nil {
+		return "", err
+	}
+
+	labelSelectorTemplate, err := plan.GetTemplate(osbv1alpha1.ClusterLabelSelectorAction)
+	if err != nil {
+		if errors.TemplateNotFound(err) {
+			log.Info("Plan does not have clusterSelector template", "Plan", instance.Spec.PlanID)
+			// don't return error here. In cases when clusterSelector is not provided, all clusters are considered
+			return "", nil
+		}
+		return "", err
+	}
+
+	if labelSelectorTemplate.Type != constants.GoTemplateType {
+		log.Info("Plan does not have clusterSelector gotemplate", "Plan", instance.Spec.PlanID)
+		// don't return error here. In cases when clusterSelector is not of gotemplate, all clusters are considered
+		return "", nil
+	}
+
+	renderer, err := rendererFactory.GetRenderer(labelSelectorTemplate.Type, nil, state.c)
+	if err != nil {
+		return "", err
+	}
+	name := types.NamespacedName{
+		Namespace: instance.GetNamespace(),
+		Name:      instance.GetName(),
+	}
+	rendererInput, err := rendererFactory.GetRendererInput(labelSelectorTemplate, service, plan, instance, nil, name)
+	if err != nil {
+		return "", err
+	}
+	rendererOutput, err := renderer.Render(rendererInput)
+	if err != nil {
+		return "", err
+	}
+	selector, err := rendererOutput.FileContent("main")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(selector, "\n"), nil
+}
+
+// defaultCluster removes all the clusters except the default cluster,
+// which is the cluster the interoperator is running in
+type defaultCluster struct{}
+
+func (p *defaultCluster) Name() string {
+	return DefaultClusterPluginName
+}
+
+func (p *defaultCluster) Filter(state *State, cluster *resourcev1alpha1.SFCluster) (bool, string, error) {
+	if cluster.GetName() != constants.DefaultMasterClusterID {
+		return false, "cluster is not the default cluster", nil
+	}
+	return true, "", nil
+}
+
+// health removes the clusters reporting that they are not ready.
+// Clusters which have not reported their status yet are considered healthy.
+type health struct{}
+
+func (p *health) Name() string {
+	return HealthPluginName
+}
+
+func (p *health) Filter(state *State, cluster *resourcev1alpha1.SFCluster) (bool, string, error) {
+	condition := cluster.Status.GetCondition(resourcev1alpha1.SFClusterReady)
+	if condition != nil && condition.Status == corev1.ConditionFalse {
+		return false, "cluster is not ready: " + condition.Message, nil
+	}
+	return true, "", nil
+}
+
+// capacity removes the clusters which do not have enough resources
+// left for the resource requests of the plan. Plans without resource
+// requests fit in every cluster. The requests of the instances not yet
+// provisioned on a cluster are counted as requested.
+type capacity struct{}
+
+func (p *capacity) Name() string {
+	return CapacityPluginName
+}
+
+func (p *capacity) Filter(state *State, cluster *resourcev1alpha1.SFCluster) (bool, string, error) {
+	plan, err := state.GetPlan()
+	if err != nil {
+		return false, "", err
+	}
+	if len(plan.Spec.ResourceRequests) == 0 {
+		return true, "", nil
+	}
+	pendingRequests, err := state.GetPendingRequests()
+	if err != nil {
+		return false, "", err
+	}
+	if _, fits := getHeadroom(cluster, plan.Spec.ResourceRequests, pendingRequests[cluster.GetName()]); !fits {
+		return false, "not enough resources for plan " + plan.GetName(), nil
+	}
+	return true, "", nil
+}
+
+// taints removes the clusters having NoSchedule or NoExecute taints which
+// are not tolerated by the instance, and prefers the clusters with the least
+// number of untolerated PreferNoSchedule taints. The tolerations are read
+// from the plan and the parameters of the instance.
+type taints struct{}
+
+func (p *taints) Name() string {
+	return TaintsPluginName
+}
+
+func (p *taints) Filter(state *State, cluster *resourcev1alpha1.SFCluster) (bool, string, error) {
+	if len(cluster.Spec.Taints) == 0 {
+		return true, "", nil
+	}
+	tolerations, err := state.GetTolerations()
+	if err != nil {
+		return false, "", err
+	}
+	for i := range cluster.Spec.Taints {
+		taint := &cluster.Spec.Taints[i]
+		if taint.Effect == corev1.TaintEffectPreferNoSchedule {
+			continue
+		}
+		if !toleratesTaint(tolerations, taint) {
+			return false, "untolerated taint " + taint.ToString(), nil
+		}
+	}
+	return true, "", nil
+}
+
+func (p *taints) Score(state *State, clusters []resourcev1alpha1.SFCluster) ([]float64, error) {
+	scores := make([]float64, len(clusters))
+	tainted := false
+	for _, cluster := range clusters {
+		tainted = tainted || len(cluster.Spec.Taints) > 0
+	}
+	if !tainted {
+		return scores, nil
+	}
+	tolerations, err := state.GetTolerations()
+	if err != nil {
+		return nil, err
+	}
+	for i, cluster := range clusters {
+		for j := range cluster.Spec.Taints {
+			taint := &cluster.Spec.Taints[j]
+			if taint.Effect == corev1.TaintEffectPreferNoSchedule && !toleratesTaint(tolerations, taint) {
+				scores[i]--
+			}
+		}
+	}
+	return scores, nil
+}
+
+func toleratesTaint(tolerations []corev1.Toleration, taint *corev1.Taint) bool {
+	for i := range tolerations {
+		if tolerations[i].ToleratesTaint(taint) {
+			return true
+		}
+	}
+	return false
+}
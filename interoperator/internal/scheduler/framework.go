@@ -0,0 +1,514 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
+	resourcev1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/resource/v1alpha1"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/config"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/cluster/registry"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("scheduler.framework")
+
+// Plugin is the parent type of all the scheduler plugins
+type Plugin interface {
+	Name() string
+}
+
+// PreFilterPlugin narrows down the clusters fetched from the cluster registry
+// by updating the list options used to list them
+type PreFilterPlugin interface {
+	Plugin
+	PreFilter(state *State, options *client.ListOptions) error
+}
+
+// FilterPlugin decides whether an instance can be scheduled on a cluster.
+// It returns false along with the reason when the cluster is not feasible.
+type FilterPlugin interface {
+	Plugin
+	Filter(state *State, cluster *resourcev1alpha1.SFCluster) (bool, string, error)
+}
+
+// ScorePlugin ranks the feasible clusters. It returns one score per cluster,
+// a higher score is preferred. Scores are normalized by the framework before
+// they are weighted, so plugins can use any scale.
+type ScorePlugin interface {
+	Plugin
+	Score(state *State, clusters []resourcev1alpha1.SFCluster) ([]float64, error)
+}
+
+// ReservePlugin is notified of the cluster an instance is scheduled on,
+// once the ClusterID of the instance is set
+type ReservePlugin interface {
+	Plugin
+	Reserve(state *State, clusterID string) error
+}
+
+// State holds the data of one scheduling cycle. The plan, service and
+// the list of instances are fetched lazily and shared by the plugins.
+type State struct {
+	Instance *osbv1alpha1.SFServiceInstance
+
+	c         client.Client
+	apiReader client.Reader
+	plans     map[string]*osbv1alpha1.SFPlan
+	service   *osbv1alpha1.SFService
+	instances *osbv1alpha1.SFServiceInstanceList
+	clusters  []resourcev1alpha1.SFCluster
+
+	pendingRequests map[string]corev1.ResourceList
+
+	tolerations       []corev1.Toleration
+	tolerationsLoaded bool
+}
+
+// NewState returns the State for scheduling instance. apiReader reads the
+// objects which must not be served from the cache of c. c is used if it is
+// nil.
+func NewState(c client.Client, apiReader client.Reader, instance *osbv1alpha1.SFServiceInstance) *State {
+	if apiReader == nil {
+		apiReader = c
+	}
+	return &State{
+		Instance:  instance,
+		c:         c,
+		apiReader: apiReader,
+		plans:     make(map[string]*osbv1alpha1.SFPlan),
+	}
+}
+
+func getNamespace() string {
+	sfNamespace := os.Getenv(constants.NamespaceEnvKey)
+	if sfNamespace == "" {
+		sfNamespace = constants.DefaultServiceFabrikNamespace
+	}
+	return sfNamespace
+}
+
+// GetPlan returns the SFPlan of the instance
+func (s *State) GetPlan() (*osbv1alpha1.SFPlan, error) {
+	return s.getPlan(s.Instance.Spec.PlanID)
+}
+
+func (s *State) getPlan(planID string) (*osbv1alpha1.SFPlan, error) {
+	if plan, ok := s.plans[planID]; ok {
+		return plan, nil
+	}
+	plan := &osbv1alpha1.SFPlan{}
+	err := s.c.Get(context.TODO(), types.NamespacedName{
+		Name:      planID,
+		Namespace: getNamespace(),
+	}, plan)
+	if err != nil {
+		return nil, err
+	}
+	s.plans[planID] = plan
+	return plan, nil
+}
+
+// GetService returns the SFService of the instance
+func (s *State) GetService() (*osbv1alpha1.SFService, error) {
+	if s.service != nil {
+		return s.service, nil
+	}
+	service := &osbv1alpha1.SFService{}
+	err := s.c.Get(context.TODO(), types.NamespacedName{
+		Name:      s.Instance.Spec.ServiceID,
+		Namespace: getNamespace(),
+	}, service)
+	if err != nil {
+		return nil, err
+	}
+	s.service = service
+	return service, nil
+}
+
+// ListInstances returns all the SFServiceInstances
+func (s *State) ListInstances() ([]osbv1alpha1.SFServiceInstance, error) {
+	if s.instances != nil {
+		return s.instances.Items, nil
+	}
+	instances := &osbv1alpha1.SFServiceInstanceList{}
+	err := s.c.List(context.TODO(), instances, &client.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	s.instances = instances
+	return instances.Items, nil
+}
+
+// GetPendingRequests returns the sum of the resource requests of the plans
+// of the instances scheduled on each cluster which are not provisioned yet.
+// Their pods might not be running yet, so their requests are not part of
+// the requested resources probed into the SFCluster status.
+func (s *State) GetPendingRequests() (map[string]corev1.ResourceList, error) {
+	if s.pendingRequests != nil {
+		return s.pendingRequests, nil
+	}
+	instances, err := s.ListInstances()
+	if err != nil {
+		return nil, err
+	}
+	pendingRequests := make(map[string]corev1.ResourceList)
+	for i := range instances {
+		instance := &instances[i]
+		if instance.Spec.ClusterID == "" || !provisionPending(instance) {
+			continue
+		}
+		plan, err := s.getPlan(instance.Spec.PlanID)
+		if err != nil {
+			if apiErrors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		if len(plan.Spec.ResourceRequests) == 0 {
+			continue
+		}
+		requests, ok := pendingRequests[instance.Spec.ClusterID]
+		if !ok {
+			requests = make(corev1.ResourceList)
+			pendingRequests[instance.Spec.ClusterID] = requests
+		}
+		for name, quantity := range plan.Spec.ResourceRequests {
+			total := requests[name]
+			total.Add(quantity)
+			requests[name] = total
+		}
+	}
+	s.pendingRequests = pendingRequests
+	return pendingRequests, nil
+}
+
+// provisionPending is true if the instance is not provisioned yet
+func provisionPending(instance *osbv1alpha1.SFServiceInstance) bool {
+	switch instance.GetState() {
+	case "", "in_queue":
+		return true
+	case "in progress":
+		lastOperation, ok := instance.GetLabels()[constants.LastOperationKey]
+		return !ok || lastOperation == "in_queue"
+	}
+	return false
+}
+
+// GetTolerations returns the tolerations of the plan followed by the
+// tolerations passed in the parameters of the instance
+func (s *State) GetTolerations() ([]corev1.Toleration, error) {
+	if s.tolerationsLoaded {
+		return s.tolerations, nil
+	}
+	plan, err := s.GetPlan()
+	if err != nil {
+		return nil, err
+	}
+	instanceTolerations, err := s.Instance.GetTolerations()
+	if err != nil {
+		return nil, err
+	}
+	tolerations := make([]corev1.Toleration, 0, len(plan.Spec.Tolerations)+len(instanceTolerations))
+	tolerations = append(tolerations, plan.Spec.Tolerations...)
+	tolerations = append(tolerations, instanceTolerations...)
+	s.tolerations = tolerations
+	s.tolerationsLoaded = true
+	return tolerations, nil
+}
+
+// Framework schedules SFServiceInstances using a set of plugins. Reserve
+// must be called once the ClusterID returned by Schedule is set on the
+// instance, so that a failed update does not count as a scheduling decision.
+type Framework interface {
+	Schedule(instance *osbv1alpha1.SFServiceInstance) (string, error)
+	Reserve(instance *osbv1alpha1.SFServiceInstance, clusterID string) error
+}
+
+type weightedScorePlugin struct {
+	ScorePlugin
+	weight float64
+}
+
+type framework struct {
+	c               client.Client
+	apiReader       client.Reader
+	clusterRegistry registry.ClusterRegistry
+	schedulerType   string
+	preFilters      []PreFilterPlugin
+	filters         []FilterPlugin
+	scores          []weightedScorePlugin
+	reserves        []ReservePlugin
+}
+
+// New returns a Framework running the plugins in profile. schedulerType
+// is used to report scheduling failures. The taints of the clusters are
+// always honoured, the taints plugin is added if profile does not list it.
+// apiReader is used by the plugins to read the state they keep outside of
+// the watched resources, like the round robin cursor, without caching it.
+// c is used if it is nil.
+func New(c client.Client, apiReader client.Reader, clusterRegistry registry.ClusterRegistry, schedulerType string, profile config.SchedulerPluginsConfig) (Framework, error) {
+	if c == nil {
+		return nil, errors.NewInputError("New scheduler framework", "c", nil)
+	}
+	if clusterRegistry == nil {
+		return nil, errors.NewInputError("New scheduler framework", "clusterRegistry", nil)
+	}
+
+	f := &framework{
+		c:               c,
+		apiReader:       apiReader,
+		clusterRegistry: clusterRegistry,
+		schedulerType:   schedulerType,
+	}
+	reserves := make(map[string]bool)
+	addReserve := func(p Plugin) {
+		if reserve, ok := p.(ReservePlugin); ok && !reserves[p.Name()] {
+			reserves[p.Name()] = true
+			f.reserves = append(f.reserves, reserve)
+		}
+	}
+
+	for _, name := range profile.Filters {
+		p, err := getPlugin(name)
+		if err != nil {
+			return nil, err
+		}
+		preFilter, isPreFilter := p.(PreFilterPlugin)
+		if isPreFilter {
+			f.preFilters = append(f.preFilters, preFilter)
+		}
+		filter, isFilter := p.(FilterPlugin)
+		if isFilter {
+			f.filters = append(f.filters, filter)
+		}
+		if !isPreFilter && !isFilter {
+			return nil, errors.NewInputError("New scheduler framework", "filter plugin "+name, nil)
+		}
+		addReserve(p)
+	}
+
+	for _, scoreConfig := range profile.Scores {
+		p, err := getPlugin(scoreConfig.Name)
+		if err != nil {
+			return nil, err
+		}
+		score, ok := p.(ScorePlugin)
+		if !ok {
+			return nil, errors.NewInputError("New scheduler framework", "score plugin "+scoreConfig.Name, nil)
+		}
+		if scoreConfig.Weight < 0 {
+			return nil, errors.NewInputError("New scheduler framework", "weight of score plugin "+scoreConfig.Name, nil)
+		}
+		weight := scoreConfig.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		f.scores = append(f.scores, weightedScorePlugin{
+			ScorePlugin: score,
+			weight:      float64(weight),
+		})
+		addReserve(p)
+	}
+
+	if !containsFilter(profile, TaintsPluginName) {
+		f.filters = append([]FilterPlugin{&taints{}}, f.filters...)
+	}
+	if !containsScore(profile, TaintsPluginName) {
+		f.scores = append(f.scores, weightedScorePlugin{
+			ScorePlugin: &taints{},
+			weight:      defaultTaintsWeight,
+		})
+	}
+	return f, nil
+}
+
+func containsFilter(profile config.SchedulerPluginsConfig, name string) bool {
+	for _, filter := range profile.Filters {
+		if filter == name {
+			return true
+		}
+	}
+	return false
+}
+
+func containsScore(profile config.SchedulerPluginsConfig, name string) bool {
+	for _, score := range profile.Scores {
+		if score.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Schedule returns the clusterID of the cluster chosen for instance. The
+// clusters are listed with the options set by the pre filter plugins, the
+// infeasible ones are removed by the filter plugins and the one with the
+// highest weighted score is chosen. Ties go to the cluster listed first.
+func (f *framework) Schedule(instance *osbv1alpha1.SFServiceInstance) (string, error) {
+	if instance == nil {
+		return "", errors.NewInputError("Schedule", "instance", nil)
+	}
+	return f.schedule(instance)
+}
+
+// Reserve notifies the reserve plugins that instance is scheduled on the
+// cluster clusterID
+func (f *framework) Reserve(instance *osbv1alpha1.SFServiceInstance, clusterID string) error {
+	if instance == nil {
+		return errors.NewInputError("Reserve", "instance", nil)
+	}
+	state := NewState(f.c, f.apiReader, instance)
+	for _, p := range f.reserves {
+		err := p.Reserve(state, clusterID)
+		if err != nil {
+			log.Info("Failed to reserve cluster", "plugin", p.Name(), "instance", instance.GetName(),
+				"clusterID", clusterID, "error", err.Error())
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *framework) schedule(instance *osbv1alpha1.SFServiceInstance) (string, error) {
+	state := NewState(f.c, f.apiReader, instance)
+
+	options := &client.ListOptions{}
+	for _, p := range f.preFilters {
+		err := p.PreFilter(state, options)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	clusters, err := f.clusterRegistry.ListClusters(options)
+	if err != nil {
+		return "", err
+	}
+	state.clusters = clusters.Items
+	if len(clusters.Items) == 0 {
+		if len(f.preFilters) == 0 {
+			return "", errors.NewClusterRegistryError("no sfcluster found", nil)
+		}
+		return "", errors.NewSchedulerFailed(f.schedulerType, "No clusters found with matching criteria", nil)
+	}
+
+	feasible := make([]resourcev1alpha1.SFCluster, 0, len(clusters.Items))
+	reasons := make([]string, 0)
+	for _, cluster := range clusters.Items {
+		fits, reason, err := f.filter(state, &cluster)
+		if err != nil {
+			return "", err
+		}
+		if !fits {
+			log.V(1).Info("Cluster filtered out", "instance", instance.GetName(), "clusterID", cluster.GetName(),
+				"reason", reason)
+			reasons = append(reasons, fmt.Sprintf("%s: %s", cluster.GetName(), reason))
+			continue
+		}
+		feasible = append(feasible, cluster)
+	}
+
+	if len(feasible) == 0 {
+		return "", errors.NewSchedulerFailed(f.schedulerType,
+			"No feasible cluster for instance "+instance.GetName()+": "+strings.Join(reasons, ", "), nil)
+	}
+
+	chosen := 0
+	if len(feasible) > 1 {
+		chosen, err = f.score(state, feasible)
+		if err != nil {
+			return "", err
+		}
+	}
+	return feasible[chosen].GetName(), nil
+}
+
+func (f *framework) filter(state *State, cluster *resourcev1alpha1.SFCluster) (bool, string, error) {
+	for _, p := range f.filters {
+		fits, reason, err := p.Filter(state, cluster)
+		if err != nil {
+			return false, "", err
+		}
+		if !fits {
+			return false, p.Name() + " " + reason, nil
+		}
+	}
+	return true, "", nil
+}
+
+// score returns the index of the cluster with the highest weighted score
+func (f *framework) score(state *State, clusters []resourcev1alpha1.SFCluster) (int, error) {
+	total := make([]float64, len(clusters))
+	for _, p := range f.scores {
+		scores, err := p.Score(state, clusters)
+		if err != nil {
+			return 0, err
+		}
+		if len(scores) != len(clusters) {
+			return 0, errors.NewSchedulerFailed(f.schedulerType,
+				fmt.Sprintf("score plugin %s returned %d scores for %d clusters", p.Name(), len(scores), len(clusters)), nil)
+		}
+		for i, score := range normalize(scores) {
+			total[i] += p.weight * score
+		}
+	}
+
+	best := 0
+	for i := range total {
+		if total[i] > total[best] {
+			best = i
+		}
+	}
+	return best, nil
+}
+
+// normalize scales scores to the range [0, 1]. If all the scores
+// are equal, they are all set to zero.
+func normalize(scores []float64) []float64 {
+	normalized := make([]float64, len(scores))
+	if len(scores) == 0 {
+		return normalized
+	}
+	min, max := scores[0], scores[0]
+	for _, score := range scores {
+		if score < min {
+			min = score
+		}
+		if score > max {
+			max = score
+		}
+	}
+	if max == min {
+		return normalized
+	}
+	for i, score := range scores {
+		normalized[i] = (score - min) / (max - min)
+	}
+	return normalized
+}
@@ -0,0 +1,701 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
+	resourcev1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/resource/v1alpha1"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/config"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/scheduler/schedulertest"
+	mock_clusterRegistry "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/cluster/registry/mock_registry"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
+
+	"github.com/golang/mock/gomock"
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestNew(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClusterRegistry := mock_clusterRegistry.NewMockClusterRegistry(ctrl)
+
+	tests := []struct {
+		name    string
+		profile config.SchedulerPluginsConfig
+		wantErr bool
+	}{
+		{
+			name: "fail if plugin is unknown",
+			profile: config.SchedulerPluginsConfig{
+				Filters: []string{"unknown"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "fail if score plugin is used as filter",
+			profile: config.SchedulerPluginsConfig{
+				Filters: []string{UtilizationPluginName},
+			},
+			wantErr: true,
+		},
+		{
+			name: "fail if filter plugin is used as score",
+			profile: config.SchedulerPluginsConfig{
+				Scores: []config.ScorePluginConfig{
+					{Name: HealthPluginName},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "fail if weight is negative",
+			profile: config.SchedulerPluginsConfig{
+				Scores: []config.ScorePluginConfig{
+					{Name: UtilizationPluginName, Weight: -1},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "return framework",
+			profile: config.SchedulerPluginsConfig{
+				Filters: []string{LabelSelectorPluginName, HealthPluginName, CapacityPluginName},
+				Scores: []config.ScorePluginConfig{
+					{Name: UtilizationPluginName, Weight: 2},
+					{Name: HeadroomPluginName},
+					{Name: SpreadPluginName},
+					{Name: AffinityPluginName},
+					{Name: RoundRobinPluginName},
+				},
+			},
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(c, nil, mockClusterRegistry, constants.FrameworkSchedulerType, tt.profile)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr && !errors.InputError(err) {
+				t.Errorf("New() error = %v, want InputError", err)
+			}
+			if !tt.wantErr && got == nil {
+				t.Errorf("New() returned nil framework")
+			}
+		})
+	}
+
+	_, err := New(nil, nil, mockClusterRegistry, constants.FrameworkSchedulerType, config.SchedulerPluginsConfig{})
+	if !errors.InputError(err) {
+		t.Errorf("New() error = %v, want InputError", err)
+	}
+}
+
+func TestNewForSchedulerType(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClusterRegistry := mock_clusterRegistry.NewMockClusterRegistry(ctrl)
+
+	for _, schedulerType := range []string{
+		constants.DefaultSchedulerType,
+		constants.LeastUtilizedSchedulerType,
+		constants.RoundRobinSchedulerType,
+		constants.LabelSelectorSchedulerType,
+		constants.ResourceAwareSchedulerType,
+	} {
+		if _, err := NewForSchedulerType(c, nil, mockClusterRegistry, schedulerType); err != nil {
+			t.Errorf("NewForSchedulerType(%s) error = %v", schedulerType, err)
+		}
+	}
+	if _, err := NewForSchedulerType(c, nil, mockClusterRegistry, "unknown"); err == nil {
+		t.Errorf("NewForSchedulerType(unknown) expected error")
+	}
+}
+
+func Test_framework_Schedule(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	instances := []*osbv1alpha1.SFServiceInstance{
+		_getDummySFServiceInstance("instance-1", "plan-a", "org-1", "space-1", "1"),
+		_getDummySFServiceInstance("instance-2", "plan-b", "org-2", "", "1"),
+		_getDummySFServiceInstance("instance-3", "plan-a", "org-2", "", "2"),
+	}
+	for _, instance := range instances {
+		g.Expect(c.Create(context.TODO(), instance)).NotTo(gomega.HaveOccurred())
+		defer c.Delete(context.TODO(), instance)
+	}
+
+	sfcluster1 := schedulertest.NewSFCluster("1", corev1.ConditionTrue)
+	sfcluster2 := schedulertest.NewSFCluster("2", corev1.ConditionTrue)
+	sfcluster3 := schedulertest.NewSFCluster("3", corev1.ConditionTrue)
+	notReady2 := schedulertest.NewSFCluster("2", corev1.ConditionFalse)
+	notReady3 := schedulertest.NewSFCluster("3", corev1.ConditionFalse)
+
+	tests := []struct {
+		name     string
+		profile  config.SchedulerPluginsConfig
+		clusters []resourcev1alpha1.SFCluster
+		instance *osbv1alpha1.SFServiceInstance
+		want     string
+		wantErr  func(error) bool
+	}{
+		{
+			name: "fail if no cluster is registered",
+			profile: config.SchedulerPluginsConfig{
+				Scores: []config.ScorePluginConfig{{Name: UtilizationPluginName}},
+			},
+			clusters: nil,
+			instance: _getDummySFServiceInstance("foo", "plan-a", "", "", ""),
+			wantErr:  errors.ClusterRegistryError,
+		},
+		{
+			name: "return the only cluster",
+			profile: config.SchedulerPluginsConfig{
+				Scores: []config.ScorePluginConfig{{Name: UtilizationPluginName}},
+			},
+			clusters: []resourcev1alpha1.SFCluster{sfcluster1},
+			instance: _getDummySFServiceInstance("foo", "plan-a", "", "", ""),
+			want:     "1",
+		},
+		{
+			name: "choose least utilized cluster",
+			profile: config.SchedulerPluginsConfig{
+				Scores: []config.ScorePluginConfig{{Name: UtilizationPluginName}},
+			},
+			clusters: []resourcev1alpha1.SFCluster{sfcluster1, sfcluster2, sfcluster3},
+			instance: _getDummySFServiceInstance("foo", "plan-a", "", "", ""),
+			want:     "3",
+		},
+		{
+			name: "choose first cluster on ties",
+			profile: config.SchedulerPluginsConfig{
+				Scores: []config.ScorePluginConfig{{Name: SpreadPluginName}},
+			},
+			clusters: []resourcev1alpha1.SFCluster{sfcluster1, sfcluster2},
+			instance: _getDummySFServiceInstance("foo", "plan-a", "", "", ""),
+			want:     "1",
+		},
+		{
+			name: "choose cluster with instances of same space and organization",
+			profile: config.SchedulerPluginsConfig{
+				Scores: []config.ScorePluginConfig{{Name: AffinityPluginName}},
+			},
+			clusters: []resourcev1alpha1.SFCluster{sfcluster2, sfcluster1, sfcluster3},
+			instance: _getDummySFServiceInstance("foo", "plan-a", "org-1", "space-1", ""),
+			want:     "1",
+		},
+		{
+			name: "combine scores using weights",
+			profile: config.SchedulerPluginsConfig{
+				Scores: []config.ScorePluginConfig{
+					{Name: UtilizationPluginName, Weight: 3},
+					{Name: AffinityPluginName, Weight: 1},
+				},
+			},
+			clusters: []resourcev1alpha1.SFCluster{sfcluster1, sfcluster2, sfcluster3},
+			instance: _getDummySFServiceInstance("foo", "plan-a", "org-1", "space-1", ""),
+			want:     "3",
+		},
+		{
+			name: "skip clusters which are not ready",
+			profile: config.SchedulerPluginsConfig{
+				Filters: []string{HealthPluginName},
+				Scores:  []config.ScorePluginConfig{{Name: UtilizationPluginName}},
+			},
+			clusters: []resourcev1alpha1.SFCluster{sfcluster1, notReady2},
+			instance: _getDummySFServiceInstance("foo", "plan-a", "", "", ""),
+			want:     "1",
+		},
+		{
+			name: "choose the default cluster",
+			profile: config.SchedulerPluginsConfig{
+				Filters: []string{DefaultClusterPluginName},
+			},
+			clusters: []resourcev1alpha1.SFCluster{sfcluster2, sfcluster1, sfcluster3},
+			instance: _getDummySFServiceInstance("foo", "plan-a", "", "", ""),
+			want:     "1",
+		},
+		{
+			name: "fail if no cluster is feasible",
+			profile: config.SchedulerPluginsConfig{
+				Filters: []string{HealthPluginName},
+			},
+			clusters: []resourcev1alpha1.SFCluster{notReady2, notReady3},
+			instance: _getDummySFServiceInstance("foo", "plan-a", "", "", ""),
+			wantErr:  errors.SchedulerFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockClusterRegistry := mock_clusterRegistry.NewMockClusterRegistry(ctrl)
+			mockClusterRegistry.EXPECT().ListClusters(gomock.Any()).
+				Return(&resourcev1alpha1.SFClusterList{Items: tt.clusters}, nil).Times(1)
+
+			f, err := New(c, nil, mockClusterRegistry, constants.FrameworkSchedulerType, tt.profile)
+			if err != nil {
+				t.Errorf("New() error = %v", err)
+				return
+			}
+			got, err := f.Schedule(tt.instance)
+			if tt.wantErr != nil {
+				if !tt.wantErr(err) {
+					t.Errorf("framework.Schedule() error = %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("framework.Schedule() error = %v", err)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("framework.Schedule() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_framework_ScheduleTaints(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	plan := schedulertest.NewSFPlan("plan-a")
+	g.Expect(c.Create(context.TODO(), plan)).NotTo(gomega.HaveOccurred())
+	defer c.Delete(context.TODO(), plan)
+	tolerating := schedulertest.NewSFPlan("plan-tolerating")
+	tolerating.Spec.Tolerations = []corev1.Toleration{
+		{
+			Key:      "dedicated",
+			Operator: corev1.TolerationOpEqual,
+			Value:    "tenant-a",
+			Effect:   corev1.TaintEffectNoSchedule,
+		},
+	}
+	g.Expect(c.Create(context.TODO(), tolerating)).NotTo(gomega.HaveOccurred())
+	defer c.Delete(context.TODO(), tolerating)
+
+	maintenance := schedulertest.NewSFCluster("1", corev1.ConditionTrue)
+	maintenance.Spec.Taints = []corev1.Taint{
+		{
+			Key:    "maintenance",
+			Effect: corev1.TaintEffectNoSchedule,
+		},
+	}
+	dedicated := schedulertest.NewSFCluster("2", corev1.ConditionTrue)
+	dedicated.Spec.Taints = []corev1.Taint{
+		{
+			Key:    "dedicated",
+			Value:  "tenant-a",
+			Effect: corev1.TaintEffectNoSchedule,
+		},
+	}
+	busy := schedulertest.NewSFCluster("3", corev1.ConditionTrue)
+	busy.Spec.Taints = []corev1.Taint{
+		{
+			Key:    "busy",
+			Effect: corev1.TaintEffectPreferNoSchedule,
+		},
+	}
+	untainted := schedulertest.NewSFCluster("4", corev1.ConditionTrue)
+
+	toleratingInstance := _getDummySFServiceInstance("foo", "plan-a", "", "", "")
+	toleratingInstance.Spec.RawParameters = &runtime.RawExtension{
+		Raw: []byte(`{"tolerations": [{"key": "maintenance", "operator": "Exists"}]}`),
+	}
+
+	tests := []struct {
+		name     string
+		clusters []resourcev1alpha1.SFCluster
+		instance *osbv1alpha1.SFServiceInstance
+		want     string
+		wantErr  func(error) bool
+	}{
+		{
+			name:     "avoid clusters with untolerated taints",
+			clusters: []resourcev1alpha1.SFCluster{maintenance, busy, untainted},
+			instance: _getDummySFServiceInstance("foo", "plan-a", "", "", ""),
+			want:     "4",
+		},
+		{
+			name:     "choose cluster with PreferNoSchedule taint if no other cluster is feasible",
+			clusters: []resourcev1alpha1.SFCluster{maintenance, busy},
+			instance: _getDummySFServiceInstance("foo", "plan-a", "", "", ""),
+			want:     "3",
+		},
+		{
+			name:     "fail if all clusters have untolerated NoSchedule taints",
+			clusters: []resourcev1alpha1.SFCluster{maintenance, dedicated},
+			instance: _getDummySFServiceInstance("foo", "plan-a", "", "", ""),
+			wantErr:  errors.SchedulerFailed,
+		},
+		{
+			name:     "honour tolerations of the plan",
+			clusters: []resourcev1alpha1.SFCluster{maintenance, dedicated},
+			instance: _getDummySFServiceInstance("foo", "plan-tolerating", "", "", ""),
+			want:     "2",
+		},
+		{
+			name:     "honour tolerations in instance parameters",
+			clusters: []resourcev1alpha1.SFCluster{dedicated, maintenance},
+			instance: toleratingInstance,
+			want:     "1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockClusterRegistry := mock_clusterRegistry.NewMockClusterRegistry(ctrl)
+			mockClusterRegistry.EXPECT().ListClusters(gomock.Any()).
+				Return(&resourcev1alpha1.SFClusterList{Items: tt.clusters}, nil).Times(1)
+
+			f, err := NewForSchedulerType(c, nil, mockClusterRegistry, constants.LeastUtilizedSchedulerType)
+			if err != nil {
+				t.Errorf("NewForSchedulerType() error = %v", err)
+				return
+			}
+			got, err := f.Schedule(tt.instance)
+			if tt.wantErr != nil {
+				if !tt.wantErr(err) {
+					t.Errorf("framework.Schedule() error = %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("framework.Schedule() error = %v", err)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("framework.Schedule() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_framework_ScheduleRoundRobin(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClusterRegistry := mock_clusterRegistry.NewMockClusterRegistry(ctrl)
+
+	now := time.Now()
+	sfcluster1 := schedulertest.NewSFCluster("1", corev1.ConditionTrue)
+	sfcluster1.SetCreationTimestamp(metav1.NewTime(now.Add(-time.Minute)))
+	sfcluster2 := schedulertest.NewSFCluster("2", corev1.ConditionTrue)
+	sfcluster2.SetCreationTimestamp(metav1.NewTime(now))
+	sfcluster3 := schedulertest.NewSFCluster("3", corev1.ConditionTrue)
+	sfcluster3.SetCreationTimestamp(metav1.NewTime(now))
+	clusters := schedulertest.NewSFClusterList(sfcluster3, sfcluster2, sfcluster1)
+
+	sequencesKey := types.NamespacedName{
+		Name:      constants.RoundRobinConfigMapName,
+		Namespace: constants.DefaultServiceFabrikNamespace,
+	}
+	sequences := &corev1.ConfigMap{}
+	defer func() {
+		if c.Get(context.TODO(), sequencesKey, sequences) == nil {
+			c.Delete(context.TODO(), sequences)
+		}
+	}()
+
+	mockClusterRegistry.EXPECT().ListClusters(gomock.Any()).Return(clusters, nil).AnyTimes()
+
+	f, err := NewForSchedulerType(c, nil, mockClusterRegistry, constants.RoundRobinSchedulerType)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	instance := _getDummySFServiceInstance("foo", "plan-a", "", "", "")
+	for _, want := range []string{"1", "2", "3", "1"} {
+		got, err := f.Schedule(instance)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(got).To(gomega.Equal(want))
+		g.Expect(f.Reserve(instance, got)).NotTo(gomega.HaveOccurred())
+	}
+
+	// The turn does not move on until the cluster is reserved
+	got, err := f.Schedule(instance)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(got).To(gomega.Equal("2"))
+	got, err = f.Schedule(instance)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(got).To(gomega.Equal("2"))
+
+	// The turn is stored in a ConfigMap and survives a new framework
+	f, err = NewForSchedulerType(c, nil, mockClusterRegistry, constants.RoundRobinSchedulerType)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	got, err = f.Schedule(instance)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(got).To(gomega.Equal("2"))
+
+	// Another replica reserved cluster 2 meanwhile
+	g.Expect(c.Get(context.TODO(), sequencesKey, sequences)).NotTo(gomega.HaveOccurred())
+	sequences.Data["2"] = "10"
+	g.Expect(c.Update(context.TODO(), sequences)).NotTo(gomega.HaveOccurred())
+	got, err = f.Schedule(instance)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(got).To(gomega.Equal("3"))
+	g.Expect(f.Reserve(instance, got)).NotTo(gomega.HaveOccurred())
+
+	g.Expect(c.Get(context.TODO(), sequencesKey, sequences)).NotTo(gomega.HaveOccurred())
+	g.Expect(sequences.Data).To(gomega.Equal(map[string]string{
+		"1": "3",
+		"2": "10",
+		"3": "11",
+	}))
+}
+
+func Test_framework_ScheduleRoundRobinAPIReader(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClusterRegistry := mock_clusterRegistry.NewMockClusterRegistry(ctrl)
+
+	clusters := schedulertest.NewSFClusterList(
+		schedulertest.NewSFCluster("1", corev1.ConditionTrue),
+		schedulertest.NewSFCluster("2", corev1.ConditionTrue),
+	)
+	mockClusterRegistry.EXPECT().ListClusters(gomock.Any()).Return(clusters, nil).AnyTimes()
+
+	sequences := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      constants.RoundRobinConfigMapName,
+			Namespace: constants.DefaultServiceFabrikNamespace,
+		},
+		Data: map[string]string{
+			"1": "1",
+			"2": "0",
+		},
+	}
+	// The cached client has not seen the cursor yet
+	cached := fake.NewFakeClientWithScheme(scheme.Scheme)
+	apiReader := fake.NewFakeClientWithScheme(scheme.Scheme, sequences)
+
+	f, err := NewForSchedulerType(cached, apiReader, mockClusterRegistry, constants.RoundRobinSchedulerType)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	got, err := f.Schedule(_getDummySFServiceInstance("foo", "plan-a", "", "", ""))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(got).To(gomega.Equal("2"))
+}
+
+func Test_framework_ScheduleResourceAware(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	plan := schedulertest.NewSFPlan("plan-requests")
+	plan.Spec.ResourceRequests = schedulertest.ResourceList("2", "4Gi")
+	g.Expect(c.Create(context.TODO(), plan)).NotTo(gomega.HaveOccurred())
+	defer c.Delete(context.TODO(), plan)
+
+	// instances scheduled but not provisioned yet on cluster 1
+	pending := _getDummySFServiceInstance("pending-1", "plan-requests", "", "", "1")
+	pending.SetState("in_queue")
+	inProgress := _getDummySFServiceInstance("pending-2", "plan-requests", "", "", "1")
+	inProgress.SetState("in progress")
+	inProgress.SetLabels(map[string]string{constants.LastOperationKey: "in_queue"})
+	// instance already running on cluster 2, counted in the probed requests
+	provisioned := _getDummySFServiceInstance("provisioned", "plan-requests", "", "", "2")
+	provisioned.SetState("succeeded")
+	for _, instance := range []*osbv1alpha1.SFServiceInstance{pending, inProgress, provisioned} {
+		g.Expect(c.Create(context.TODO(), instance)).NotTo(gomega.HaveOccurred())
+		defer c.Delete(context.TODO(), instance)
+	}
+
+	sfcluster1 := schedulertest.NewSFClusterWithResources("1", "8", "16Gi", "0", "0")
+	sfcluster2 := schedulertest.NewSFClusterWithResources("2", "6", "12Gi", "2", "4Gi")
+	sfcluster3 := schedulertest.NewSFClusterWithResources("3", "4", "8Gi", "0", "0")
+
+	tests := []struct {
+		name     string
+		clusters []resourcev1alpha1.SFCluster
+		want     string
+		wantErr  func(error) bool
+	}{
+		{
+			name:     "count requests of instances not yet provisioned",
+			clusters: []resourcev1alpha1.SFCluster{sfcluster1, sfcluster3},
+			want:     "3",
+		},
+		{
+			name:     "not count requests of provisioned instances twice",
+			clusters: []resourcev1alpha1.SFCluster{sfcluster1, sfcluster2},
+			want:     "2",
+		},
+		{
+			name:     "fail if pending requests use up the clusters",
+			clusters: []resourcev1alpha1.SFCluster{schedulertest.NewSFClusterWithResources("1", "5", "10Gi", "0", "0")},
+			wantErr:  errors.SchedulerFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockClusterRegistry := mock_clusterRegistry.NewMockClusterRegistry(ctrl)
+			mockClusterRegistry.EXPECT().ListClusters(gomock.Any()).
+				Return(&resourcev1alpha1.SFClusterList{Items: tt.clusters}, nil).Times(1)
+
+			f, err := NewForSchedulerType(c, nil, mockClusterRegistry, constants.ResourceAwareSchedulerType)
+			if err != nil {
+				t.Errorf("NewForSchedulerType() error = %v", err)
+				return
+			}
+			got, err := f.Schedule(_getDummySFServiceInstance("foo", "plan-requests", "", "", ""))
+			if tt.wantErr != nil {
+				if !tt.wantErr(err) {
+					t.Errorf("framework.Schedule() error = %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("framework.Schedule() error = %v", err)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("framework.Schedule() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_normalize(t *testing.T) {
+	tests := []struct {
+		name   string
+		scores []float64
+		want   []float64
+	}{
+		{
+			name:   "return empty for no scores",
+			scores: []float64{},
+			want:   []float64{},
+		},
+		{
+			name:   "return zeros if all scores are equal",
+			scores: []float64{-2, -2},
+			want:   []float64{0, 0},
+		},
+		{
+			name:   "scale scores between zero and one",
+			scores: []float64{-4, 0, -2},
+			want:   []float64{0, 1, 0.5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := normalize(tt.scores)
+			if len(got) != len(tt.want) {
+				t.Errorf("normalize() = %v, want %v", got, tt.want)
+				return
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("normalize() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func Test_getHeadroom(t *testing.T) {
+	requests := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("2"),
+		corev1.ResourceMemory: resource.MustParse("4Gi"),
+	}
+	tests := []struct {
+		name     string
+		cluster  resourcev1alpha1.SFCluster
+		requests corev1.ResourceList
+		pending  corev1.ResourceList
+		want     float64
+		wantFits bool
+	}{
+		{
+			name:     "not fit if allocatable is not reported",
+			cluster:  resourcev1alpha1.SFCluster{},
+			requests: requests,
+			want:     0,
+			wantFits: false,
+		},
+		{
+			name:     "not fit if cpu is not enough",
+			cluster:  schedulertest.NewSFClusterWithResources("1", "4", "16Gi", "3", "0"),
+			requests: requests,
+			want:     0,
+			wantFits: false,
+		},
+		{
+			name:     "return headroom if requests fit",
+			cluster:  schedulertest.NewSFClusterWithResources("1", "8", "16Gi", "2", "4Gi"),
+			requests: requests,
+			want:     0.5,
+			wantFits: true,
+		},
+		{
+			name:     "not fit if pending requests use up the cluster",
+			cluster:  schedulertest.NewSFClusterWithResources("1", "8", "16Gi", "2", "4Gi"),
+			requests: requests,
+			pending: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("6"),
+				corev1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+			want:     0,
+			wantFits: false,
+		},
+		{
+			name:     "count pending requests as requested",
+			cluster:  schedulertest.NewSFClusterWithResources("1", "8", "16Gi", "2", "4Gi"),
+			requests: requests,
+			pending:  requests,
+			want:     0.25,
+			wantFits: true,
+		},
+		{
+			name:     "return headroom if plan has no requests",
+			cluster:  schedulertest.NewSFClusterWithResources("1", "8", "16Gi", "4", "8Gi"),
+			requests: nil,
+			want:     0.5,
+			wantFits: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, fits := getHeadroom(&tt.cluster, tt.requests, tt.pending)
+			if got != tt.want || fits != tt.wantFits {
+				t.Errorf("getHeadroom() = %v, %v, want %v, %v", got, fits, tt.want, tt.wantFits)
+			}
+		})
+	}
+}
+
+func _getDummySFServiceInstance(name, planID, organizationGUID, spaceGUID, clusterID string) *osbv1alpha1.SFServiceInstance {
+	instance := schedulertest.NewSFServiceInstance(name, planID)
+	instance.Spec.OrganizationGUID = organizationGUID
+	instance.Spec.SpaceGUID = spaceGUID
+	instance.Spec.ClusterID = clusterID
+	return instance
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/config"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/cluster/registry"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Names of the scheduler plugins
+const (
+	DefaultClusterPluginName = "default-cluster"
+	LabelSelectorPluginName  = "label-selector"
+	HealthPluginName         = "health"
+	CapacityPluginName       = "capacity"
+	UtilizationPluginName    = "utilization"
+	HeadroomPluginName       = "headroom"
+	SpreadPluginName         = "spread"
+	AffinityPluginName       = "affinity"
+	RoundRobinPluginName     = "round-robin"
+	TaintsPluginName         = "taints"
+)
+
+// Weight of the taints score plugin when it is not configured. It is high
+// enough for untolerated PreferNoSchedule taints to outweigh other scores.
+const defaultTaintsWeight = 10
+
+var plugins = map[string]func() Plugin{
+	DefaultClusterPluginName: func() Plugin { return &defaultCluster{} },
+	LabelSelectorPluginName:  func() Plugin { return &labelSelector{} },
+	HealthPluginName:         func() Plugin { return &health{} },
+	CapacityPluginName:       func() Plugin { return &capacity{} },
+	UtilizationPluginName:    func() Plugin { return &utilization{} },
+	HeadroomPluginName:       func() Plugin { return &headroom{} },
+	SpreadPluginName:         func() Plugin { return &spread{} },
+	AffinityPluginName:       func() Plugin { return &affinity{} },
+	RoundRobinPluginName:     func() Plugin { return &roundRobin{} },
+	TaintsPluginName:         func() Plugin { return &taints{} },
+}
+
+func getPlugin(name string) (Plugin, error) {
+	newPlugin, ok := plugins[name]
+	if !ok {
+		return nil, errors.NewInputError("New scheduler framework", "unknown plugin "+name, nil)
+	}
+	return newPlugin(), nil
+}
+
+// profiles express the built in scheduler types as a set of plugins
+var profiles = map[string]config.SchedulerPluginsConfig{
+	constants.DefaultSchedulerType: {
+		Filters: []string{DefaultClusterPluginName},
+	},
+	constants.LeastUtilizedSchedulerType: {
+		Scores: []config.ScorePluginConfig{
+			{Name: UtilizationPluginName},
+		},
+	},
+	constants.RoundRobinSchedulerType: {
+		Scores: []config.ScorePluginConfig{
+			{Name: RoundRobinPluginName},
+		},
+	},
+	constants.LabelSelectorSchedulerType: {
+		Filters: []string{LabelSelectorPluginName},
+		Scores: []config.ScorePluginConfig{
+			{Name: UtilizationPluginName},
+		},
+	},
+	constants.ResourceAwareSchedulerType: {
+		Filters: []string{CapacityPluginName},
+		Scores: []config.ScorePluginConfig{
+			{Name: HeadroomPluginName},
+		},
+	},
+}
+
+// NewForSchedulerType returns a Framework with the plugins of a built in
+// scheduler type. See New for apiReader.
+func NewForSchedulerType(c client.Client, apiReader client.Reader, clusterRegistry registry.ClusterRegistry, schedulerType string) (Framework, error) {
+	profile, ok := profiles[schedulerType]
+	if !ok {
+		return nil, errors.NewInputError("NewForSchedulerType", "schedulerType "+schedulerType, nil)
+	}
+	return New(c, apiReader, clusterRegistry, schedulerType, profile)
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package schedulertest provides the fixtures shared by the tests of the
+// scheduler framework and of the scheduler controllers.
+package schedulertest
+
+import (
+	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
+	resourcev1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/resource/v1alpha1"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// NewSFServiceInstance returns an SFServiceInstance of planID in the
+// default namespace which is not scheduled yet
+func NewSFServiceInstance(name, planID string) *osbv1alpha1.SFServiceInstance {
+	return &osbv1alpha1.SFServiceInstance{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: osbv1alpha1.SFServiceInstanceSpec{
+			ServiceID: "service-id",
+			PlanID:    planID,
+		},
+	}
+}
+
+// NewSFPlan returns an SFPlan without templates
+func NewSFPlan(name string) *osbv1alpha1.SFPlan {
+	return &osbv1alpha1.SFPlan{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: constants.DefaultServiceFabrikNamespace,
+		},
+		Spec: osbv1alpha1.SFPlanSpec{
+			Name:        "plan-name",
+			ID:          name,
+			Description: "description",
+			ServiceID:   "service-id",
+			Templates:   []osbv1alpha1.TemplateSpec{},
+		},
+	}
+}
+
+// NewSFCluster returns an SFCluster with the given Ready condition
+func NewSFCluster(name string, ready corev1.ConditionStatus) resourcev1alpha1.SFCluster {
+	return resourcev1alpha1.SFCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: constants.DefaultServiceFabrikNamespace,
+		},
+		Status: resourcev1alpha1.SFClusterStatus{
+			Conditions: []resourcev1alpha1.SFClusterCondition{
+				{
+					Type:   resourcev1alpha1.SFClusterReady,
+					Status: ready,
+				},
+			},
+		},
+	}
+}
+
+// NewSFClusterWithResources returns a ready SFCluster reporting the given
+// allocatable and requested cpu and memory
+func NewSFClusterWithResources(name, allocatableCPU, allocatableMemory, requestedCPU, requestedMemory string) resourcev1alpha1.SFCluster {
+	cluster := NewSFCluster(name, corev1.ConditionTrue)
+	cluster.Status.Allocatable = ResourceList(allocatableCPU, allocatableMemory)
+	cluster.Status.Requested = ResourceList(requestedCPU, requestedMemory)
+	return cluster
+}
+
+// NewSFClusterList returns an SFClusterList of clusters
+func NewSFClusterList(clusters ...resourcev1alpha1.SFCluster) *resourcev1alpha1.SFClusterList {
+	return &resourcev1alpha1.SFClusterList{
+		Items: clusters,
+	}
+}
+
+// NewConfigMap returns the interoperator ConfigMap holding config
+func NewConfigMap(config string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      constants.ConfigMapName,
+			Namespace: constants.DefaultServiceFabrikNamespace,
+		},
+		Data: map[string]string{
+			constants.ConfigMapKey: config,
+		},
+	}
+}
+
+// ResourceList returns a ResourceList of cpu and memory
+func ResourceList(cpu, memory string) corev1.ResourceList {
+	return corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse(cpu),
+		corev1.ResourceMemory: resource.MustParse(memory),
+	}
+}
+
+// Key returns the NamespacedName of obj
+func Key(obj metav1.Object) types.NamespacedName {
+	return types.NamespacedName{
+		Name:      obj.GetName(),
+		Namespace: obj.GetNamespace(),
+	}
+}
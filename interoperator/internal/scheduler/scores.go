@@ -0,0 +1,321 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
+	resourcev1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/resource/v1alpha1"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+
+	corev1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Resources considered for computing the headroom of a cluster
+var scoredResources = []corev1.ResourceName{
+	corev1.ResourceCPU,
+	corev1.ResourceMemory,
+}
+
+// countInstances returns the number of instances accepted by match
+// deployed on each cluster
+func countInstances(state *State, match func(instance *osbv1alpha1.SFServiceInstance) bool) (map[string]int64, error) {
+	instances, err := state.ListInstances()
+	if err != nil {
+		log.Error(err, "Failed to list all sfserviceinstances")
+		return nil, err
+	}
+	counts := make(map[string]int64)
+	for _, item := range instances {
+		if item.Spec.ClusterID != "" && match(&item) {
+			counts[item.Spec.ClusterID] = counts[item.Spec.ClusterID] + 1
+		}
+	}
+	return counts, nil
+}
+
+// utilization prefers the clusters with the least number of instances
+type utilization struct{}
+
+func (p *utilization) Name() string {
+	return UtilizationPluginName
+}
+
+func (p *utilization) Score(state *State, clusters []resourcev1alpha1.SFCluster) ([]float64, error) {
+	counts, err := countInstances(state, func(*osbv1alpha1.SFServiceInstance) bool {
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	scores := make([]float64, len(clusters))
+	for i, cluster := range clusters {
+		scores[i] = -float64(counts[cluster.GetName()])
+	}
+	return scores, nil
+}
+
+// spread prefers the clusters with the least number of instances
+// of the same plan
+type spread struct{}
+
+func (p *spread) Name() string {
+	return SpreadPluginName
+}
+
+func (p *spread) Score(state *State, clusters []resourcev1alpha1.SFCluster) ([]float64, error) {
+	planID := state.Instance.Spec.PlanID
+	counts, err := countInstances(state, func(instance *osbv1alpha1.SFServiceInstance) bool {
+		return instance.Spec.PlanID == planID
+	})
+	if err != nil {
+		return nil, err
+	}
+	scores := make([]float64, len(clusters))
+	for i, cluster := range clusters {
+		scores[i] = -float64(counts[cluster.GetName()])
+	}
+	return scores, nil
+}
+
+// affinity prefers the clusters already hosting instances of the same
+// organization and space. An instance in the same space counts twice.
+type affinity struct{}
+
+func (p *affinity) Name() string {
+	return AffinityPluginName
+}
+
+func (p *affinity) Score(state *State, clusters []resourcev1alpha1.SFCluster) ([]float64, error) {
+	spec := state.Instance.Spec
+	sameOrg, err := countInstances(state, func(instance *osbv1alpha1.SFServiceInstance) bool {
+		return spec.OrganizationGUID != "" && instance.Spec.OrganizationGUID == spec.OrganizationGUID
+	})
+	if err != nil {
+		return nil, err
+	}
+	sameSpace, err := countInstances(state, func(instance *osbv1alpha1.SFServiceInstance) bool {
+		return spec.SpaceGUID != "" && instance.Spec.SpaceGUID == spec.SpaceGUID
+	})
+	if err != nil {
+		return nil, err
+	}
+	scores := make([]float64, len(clusters))
+	for i, cluster := range clusters {
+		scores[i] = float64(sameOrg[cluster.GetName()] + sameSpace[cluster.GetName()])
+	}
+	return scores, nil
+}
+
+// headroom prefers the clusters with the most cpu and memory left after
+// placing the resource requests of the plan. The allocatable and requested
+// resources of the clusters are read from the SFCluster status, and the
+// requests of the instances not yet provisioned are added to the requested.
+type headroom struct{}
+
+func (p *headroom) Name() string {
+	return HeadroomPluginName
+}
+
+func (p *headroom) Score(state *State, clusters []resourcev1alpha1.SFCluster) ([]float64, error) {
+	plan, err := state.GetPlan()
+	if err != nil {
+		return nil, err
+	}
+	pendingRequests, err := state.GetPendingRequests()
+	if err != nil {
+		return nil, err
+	}
+	scores := make([]float64, len(clusters))
+	for i := range clusters {
+		value, fits := getHeadroom(&clusters[i], plan.Spec.ResourceRequests, pendingRequests[clusters[i].GetName()])
+		if !fits {
+			value = -1
+		}
+		scores[i] = value
+	}
+	return scores, nil
+}
+
+// getHeadroom returns the average fraction of cpu and memory of the cluster
+// left free after placing requests on top of the pending requests. Returns
+// false if the requests do not fit in the cluster or the cluster has not
+// reported its allocatable resources.
+func getHeadroom(cluster *resourcev1alpha1.SFCluster, requests corev1.ResourceList, pending corev1.ResourceList) (float64, bool) {
+	allocatable := cluster.Status.Allocatable
+	requested := cluster.Status.Requested
+
+	free := func(name corev1.ResourceName) (int64, int64, bool) {
+		alloc, ok := allocatable[name]
+		if !ok || alloc.IsZero() {
+			return 0, 0, false
+		}
+		available := alloc.DeepCopy()
+		if used, ok := requested[name]; ok {
+			available.Sub(used)
+		}
+		if used, ok := pending[name]; ok {
+			available.Sub(used)
+		}
+		if request, ok := requests[name]; ok {
+			available.Sub(request)
+		}
+		if available.Sign() < 0 {
+			return 0, 0, false
+		}
+		return available.MilliValue(), alloc.MilliValue(), true
+	}
+
+	headroom := 0.0
+	for _, name := range scoredResources {
+		available, alloc, ok := free(name)
+		if !ok {
+			return 0, false
+		}
+		headroom += float64(available) / float64(alloc)
+	}
+
+	// Other requested resources must fit, but are not scored
+	for name := range requests {
+		if _, ok := allocatable[name]; !ok {
+			continue
+		}
+		if _, _, ok := free(name); !ok {
+			return 0, false
+		}
+	}
+	return headroom / float64(len(scoredResources)), true
+}
+
+// roundRobin prefers the cluster chosen least recently. Every time an
+// instance is scheduled, its cluster is given a sequence number higher than
+// the one of all the other clusters. The sequence numbers are kept in a
+// dedicated ConfigMap in the interoperator namespace and updated with
+// optimistic concurrency, so the turn is kept across restarts and shared by
+// all the replicas without writing to the watched SFClusters. Clusters never
+// chosen come first, ordered by creation timestamp and name.
+type roundRobin struct{}
+
+func (p *roundRobin) Name() string {
+	return RoundRobinPluginName
+}
+
+// getRoundRobinSequences returns the ConfigMap holding the round robin
+// sequence number of each cluster. An empty ConfigMap is returned along
+// with false if it does not exist yet. The ConfigMap is read through the
+// api reader of the state, as a stale cursor would schedule back to back
+// instances to the same cluster and caching it would start an informer on
+// all the ConfigMaps.
+func getRoundRobinSequences(c client.Reader) (*corev1.ConfigMap, bool, error) {
+	sequences := &corev1.ConfigMap{}
+	err := c.Get(context.TODO(), types.NamespacedName{
+		Name:      constants.RoundRobinConfigMapName,
+		Namespace: getNamespace(),
+	}, sequences)
+	if err != nil {
+		if !apiErrors.IsNotFound(err) {
+			return nil, false, err
+		}
+		sequences = &corev1.ConfigMap{}
+		sequences.SetName(constants.RoundRobinConfigMapName)
+		sequences.SetNamespace(getNamespace())
+		return sequences, false, nil
+	}
+	return sequences, true, nil
+}
+
+// getSequence returns the round robin sequence number of the cluster.
+// Returns -1 if the cluster was never chosen.
+func getSequence(sequences *corev1.ConfigMap, clusterID string) int64 {
+	value, ok := sequences.Data[clusterID]
+	if !ok {
+		return -1
+	}
+	sequence, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Info("Ignoring invalid round robin sequence", "clusterID", clusterID, "sequence", value)
+		return -1
+	}
+	return sequence
+}
+
+func (p *roundRobin) Score(state *State, clusters []resourcev1alpha1.SFCluster) ([]float64, error) {
+	sequences, _, err := getRoundRobinSequences(state.apiReader)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*resourcev1alpha1.SFCluster, len(clusters))
+	for i := range clusters {
+		items[i] = &clusters[i]
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		si, sj := getSequence(sequences, items[i].GetName()), getSequence(sequences, items[j].GetName())
+		if si != sj {
+			return si < sj
+		}
+		if items[i].GetCreationTimestamp().Time == items[j].GetCreationTimestamp().Time {
+			return items[i].Name < items[j].Name
+		}
+		return !items[i].GetCreationTimestamp().After(items[j].GetCreationTimestamp().Time)
+	})
+
+	positions := make(map[string]int)
+	for i, item := range items {
+		positions[item.GetName()] = i
+	}
+	scores := make([]float64, len(clusters))
+	for i, cluster := range clusters {
+		scores[i] = -float64(positions[cluster.GetName()])
+	}
+	return scores, nil
+}
+
+func (p *roundRobin) Reserve(state *State, clusterID string) error {
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		sequences, found, err := getRoundRobinSequences(state.apiReader)
+		if err != nil {
+			return err
+		}
+		var sequence int64 = -1
+		for id := range sequences.Data {
+			if s := getSequence(sequences, id); s > sequence {
+				sequence = s
+			}
+		}
+		if sequences.Data == nil {
+			sequences.Data = make(map[string]string)
+		}
+		sequences.Data[clusterID] = strconv.FormatInt(sequence+1, 10)
+
+		if !found {
+			err = state.c.Create(context.TODO(), sequences)
+			if apiErrors.IsAlreadyExists(err) {
+				// Created by another replica meanwhile
+				return apiErrors.NewConflict(corev1.Resource("configmaps"), sequences.GetName(), err)
+			}
+			return err
+		}
+		return state.c.Update(context.TODO(), sequences)
+	})
+}
@@ -0,0 +1,533 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package schema validates service parameters against the JSON schemas
+// of a SFPlan. A subset of JSON schema draft-04 to draft-07 which covers
+// the schemas used by the OSB service catalogs is supported. Schemas using
+// an assertion keyword outside of that subset, like dependencies, contains,
+// propertyNames or if/then/else, are rejected instead of being partially
+// validated. Annotation keywords like title, description, default or format
+// are ignored, as JSON schema allows validators to treat format as an
+// annotation only.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// unsupportedKeywords are the assertion keywords of JSON schema which are
+// not implemented by the validator
+var unsupportedKeywords = []string{
+	"dependencies",
+	"dependentRequired",
+	"dependentSchemas",
+	"if",
+	"then",
+	"else",
+	"contains",
+	"minContains",
+	"maxContains",
+	"propertyNames",
+	"unevaluatedItems",
+	"unevaluatedProperties",
+}
+
+// maxDepth bounds the nesting of the schemas applied to a value, so that
+// recursive references like {"$ref": "#"} do not exhaust the stack
+const maxDepth = 64
+
+// Validate validates params against the JSON schema. Both schema and params
+// are JSON documents. Empty params are validated as an empty object. The
+// returned field errors are relative to fldPath. An error is returned if
+// schema or params can not be parsed.
+func Validate(schema, params []byte, fldPath *field.Path) (field.ErrorList, error) {
+	if len(schema) == 0 {
+		return nil, nil
+	}
+	var root interface{}
+	if err := json.Unmarshal(schema, &root); err != nil {
+		return nil, errors.NewUnmarshalError("failed to unmarshal schema", err)
+	}
+	if err := checkKeywords(root, field.NewPath("schema"), 0); err != nil {
+		return nil, err
+	}
+	var value interface{} = map[string]interface{}{}
+	if len(params) != 0 {
+		if err := json.Unmarshal(params, &value); err != nil {
+			return nil, errors.NewUnmarshalError("failed to unmarshal parameters", err)
+		}
+	}
+	v := &validator{
+		root:     root,
+		patterns: make(map[string]*regexp.Regexp),
+	}
+	errs := v.validate(root, value, fldPath)
+	if v.err != nil {
+		return nil, v.err
+	}
+	return errs, nil
+}
+
+// checkKeywords returns an error if schema or one of its sub schemas uses
+// an unsupported keyword
+func checkKeywords(schema interface{}, fldPath *field.Path, depth int) error {
+	s, ok := schema.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if depth > maxDepth {
+		return errors.NewInputError("schema.Validate", fldPath.String(), fmt.Errorf("schema is nested too deeply"))
+	}
+	for _, keyword := range unsupportedKeywords {
+		if _, ok := s[keyword]; ok {
+			return errors.NewInputError("schema.Validate", fldPath.Child(keyword).String(),
+				fmt.Errorf("keyword %s is not supported", keyword))
+		}
+	}
+	for _, keyword := range []string{"properties", "patternProperties", "definitions", "$defs"} {
+		subSchemas, _ := s[keyword].(map[string]interface{})
+		for _, name := range sortedKeys(subSchemas) {
+			if err := checkKeywords(subSchemas[name], fldPath.Child(keyword, name), depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	for _, keyword := range []string{"items", "allOf", "anyOf", "oneOf"} {
+		if subSchemas, ok := s[keyword].([]interface{}); ok {
+			for i, sub := range subSchemas {
+				if err := checkKeywords(sub, fldPath.Child(keyword).Index(i), depth+1); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for _, keyword := range []string{"items", "additionalItems", "additionalProperties", "not"} {
+		if err := checkKeywords(s[keyword], fldPath.Child(keyword), depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type validator struct {
+	root     interface{}
+	patterns map[string]*regexp.Regexp
+	// depth is the number of schemas currently applied to the value
+	depth int
+	// err is the first error found in the schema itself
+	err error
+}
+
+func (v *validator) setErr(err error) {
+	if v.err == nil {
+		v.err = err
+	}
+}
+
+func (v *validator) validate(schema, value interface{}, fldPath *field.Path) field.ErrorList {
+	if v.depth >= maxDepth {
+		v.setErr(errors.NewInputError("schema.Validate", fldPath.String(), fmt.Errorf("schema references are nested too deeply")))
+		return nil
+	}
+	v.depth++
+	defer func() { v.depth-- }()
+
+	switch s := schema.(type) {
+	case bool:
+		if !s {
+			return field.ErrorList{field.Forbidden(fldPath, "not allowed by schema")}
+		}
+		return nil
+	case map[string]interface{}:
+		return v.validateObjectSchema(s, value, fldPath)
+	default:
+		v.setErr(errors.NewInputError("schema.Validate", fldPath.String(), fmt.Errorf("schema must be an object or a boolean")))
+		return nil
+	}
+}
+
+func (v *validator) validateObjectSchema(s map[string]interface{}, value interface{}, fldPath *field.Path) field.ErrorList {
+	if ref, ok := s["$ref"].(string); ok {
+		resolved, err := v.resolve(ref)
+		if err != nil {
+			v.setErr(err)
+			return nil
+		}
+		return v.validate(resolved, value, fldPath)
+	}
+
+	allErrs := field.ErrorList{}
+	if t, ok := s["type"]; ok {
+		if errs := validateType(t, value, fldPath); len(errs) != 0 {
+			// Other keywords are meaningless for a value of wrong type
+			return errs
+		}
+	}
+	if enum, ok := s["enum"].([]interface{}); ok {
+		allErrs = append(allErrs, validateEnum(enum, value, fldPath)...)
+	}
+	if c, ok := s["const"]; ok && !equal(c, value) {
+		allErrs = append(allErrs, field.Invalid(fldPath, value, fmt.Sprintf("must be equal to %s", toJSON(c))))
+	}
+
+	switch val := value.(type) {
+	case map[string]interface{}:
+		allErrs = append(allErrs, v.validateObject(s, val, fldPath)...)
+	case []interface{}:
+		allErrs = append(allErrs, v.validateArray(s, val, fldPath)...)
+	case string:
+		allErrs = append(allErrs, v.validateString(s, val, fldPath)...)
+	case float64:
+		allErrs = append(allErrs, validateNumber(s, val, fldPath)...)
+	}
+
+	if allOf, ok := s["allOf"].([]interface{}); ok {
+		for _, sub := range allOf {
+			allErrs = append(allErrs, v.validate(sub, value, fldPath)...)
+		}
+	}
+	if anyOf, ok := s["anyOf"].([]interface{}); ok {
+		matched := false
+		for _, sub := range anyOf {
+			if len(v.validate(sub, value, fldPath)) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			allErrs = append(allErrs, field.Invalid(fldPath, value, "must match at least one of the schemas in anyOf"))
+		}
+	}
+	if oneOf, ok := s["oneOf"].([]interface{}); ok {
+		count := 0
+		for _, sub := range oneOf {
+			if len(v.validate(sub, value, fldPath)) == 0 {
+				count++
+			}
+		}
+		if count != 1 {
+			allErrs = append(allErrs, field.Invalid(fldPath, value,
+				fmt.Sprintf("must match exactly one of the schemas in oneOf, matched %d", count)))
+		}
+	}
+	if not, ok := s["not"]; ok {
+		if len(v.validate(not, value, fldPath)) == 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath, value, "must not match the schema in not"))
+		}
+	}
+	return allErrs
+}
+
+func (v *validator) validateObject(s map[string]interface{}, value map[string]interface{}, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if required, ok := s["required"].([]interface{}); ok {
+		for _, r := range required {
+			name, ok := r.(string)
+			if !ok {
+				continue
+			}
+			if _, found := value[name]; !found {
+				allErrs = append(allErrs, field.Required(fldPath.Child(name), ""))
+			}
+		}
+	}
+	if min, ok := toInt(s["minProperties"]); ok && len(value) < min {
+		allErrs = append(allErrs, field.Invalid(fldPath, value, fmt.Sprintf("must have at least %d properties", min)))
+	}
+	if max, ok := toInt(s["maxProperties"]); ok && len(value) > max {
+		allErrs = append(allErrs, field.Invalid(fldPath, value, fmt.Sprintf("must have at most %d properties", max)))
+	}
+
+	properties, _ := s["properties"].(map[string]interface{})
+	patternProperties, _ := s["patternProperties"].(map[string]interface{})
+	additional, hasAdditional := s["additionalProperties"]
+
+	for _, key := range sortedKeys(value) {
+		childPath := fldPath.Child(key)
+		matched := false
+		if sub, ok := properties[key]; ok {
+			matched = true
+			allErrs = append(allErrs, v.validate(sub, value[key], childPath)...)
+		}
+		for _, pattern := range sortedKeys(patternProperties) {
+			re := v.compile(pattern)
+			if re == nil || !re.MatchString(key) {
+				continue
+			}
+			matched = true
+			allErrs = append(allErrs, v.validate(patternProperties[pattern], value[key], childPath)...)
+		}
+		if matched || !hasAdditional {
+			continue
+		}
+		if allowed, ok := additional.(bool); ok && !allowed {
+			allErrs = append(allErrs, field.Forbidden(childPath, "additional properties are not allowed"))
+			continue
+		}
+		allErrs = append(allErrs, v.validate(additional, value[key], childPath)...)
+	}
+	return allErrs
+}
+
+func (v *validator) validateArray(s map[string]interface{}, value []interface{}, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if min, ok := toInt(s["minItems"]); ok && len(value) < min {
+		allErrs = append(allErrs, field.Invalid(fldPath, value, fmt.Sprintf("must have at least %d items", min)))
+	}
+	if max, ok := toInt(s["maxItems"]); ok && len(value) > max {
+		allErrs = append(allErrs, field.Invalid(fldPath, value, fmt.Sprintf("must have at most %d items", max)))
+	}
+	if unique, ok := s["uniqueItems"].(bool); ok && unique {
+		for i := range value {
+			for j := 0; j < i; j++ {
+				if equal(value[i], value[j]) {
+					allErrs = append(allErrs, field.Duplicate(fldPath.Index(i), value[i]))
+					break
+				}
+			}
+		}
+	}
+	switch items := s["items"].(type) {
+	case []interface{}:
+		// Tuple validation
+		for i, item := range value {
+			childPath := fldPath.Index(i)
+			if i < len(items) {
+				allErrs = append(allErrs, v.validate(items[i], item, childPath)...)
+				continue
+			}
+			if additional, ok := s["additionalItems"]; ok {
+				if allowed, ok := additional.(bool); ok && !allowed {
+					allErrs = append(allErrs, field.Forbidden(childPath, "additional items are not allowed"))
+					continue
+				}
+				allErrs = append(allErrs, v.validate(additional, item, childPath)...)
+			}
+		}
+	case nil:
+	default:
+		for i, item := range value {
+			allErrs = append(allErrs, v.validate(items, item, fldPath.Index(i))...)
+		}
+	}
+	return allErrs
+}
+
+func (v *validator) validateString(s map[string]interface{}, value string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	length := utf8.RuneCountInString(value)
+	if min, ok := toInt(s["minLength"]); ok && length < min {
+		allErrs = append(allErrs, field.Invalid(fldPath, value, fmt.Sprintf("must be at least %d characters long", min)))
+	}
+	if max, ok := toInt(s["maxLength"]); ok && length > max {
+		allErrs = append(allErrs, field.TooLong(fldPath, value, max))
+	}
+	if pattern, ok := s["pattern"].(string); ok {
+		if re := v.compile(pattern); re != nil && !re.MatchString(value) {
+			allErrs = append(allErrs, field.Invalid(fldPath, value, fmt.Sprintf("must match the pattern %s", pattern)))
+		}
+	}
+	return allErrs
+}
+
+func validateNumber(s map[string]interface{}, value float64, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if min, ok := s["minimum"].(float64); ok {
+		// draft-04 has boolean exclusiveMinimum
+		if exclusive, _ := s["exclusiveMinimum"].(bool); exclusive {
+			if value <= min {
+				allErrs = append(allErrs, field.Invalid(fldPath, value, fmt.Sprintf("must be greater than %v", min)))
+			}
+		} else if value < min {
+			allErrs = append(allErrs, field.Invalid(fldPath, value, fmt.Sprintf("must be greater than or equal to %v", min)))
+		}
+	}
+	if max, ok := s["maximum"].(float64); ok {
+		if exclusive, _ := s["exclusiveMaximum"].(bool); exclusive {
+			if value >= max {
+				allErrs = append(allErrs, field.Invalid(fldPath, value, fmt.Sprintf("must be less than %v", max)))
+			}
+		} else if value > max {
+			allErrs = append(allErrs, field.Invalid(fldPath, value, fmt.Sprintf("must be less than or equal to %v", max)))
+		}
+	}
+	if min, ok := s["exclusiveMinimum"].(float64); ok && value <= min {
+		allErrs = append(allErrs, field.Invalid(fldPath, value, fmt.Sprintf("must be greater than %v", min)))
+	}
+	if max, ok := s["exclusiveMaximum"].(float64); ok && value >= max {
+		allErrs = append(allErrs, field.Invalid(fldPath, value, fmt.Sprintf("must be less than %v", max)))
+	}
+	if multipleOf, ok := s["multipleOf"].(float64); ok && multipleOf > 0 {
+		quotient := value / multipleOf
+		if math.Abs(quotient-math.Round(quotient)) > 1e-9 {
+			allErrs = append(allErrs, field.Invalid(fldPath, value, fmt.Sprintf("must be a multiple of %v", multipleOf)))
+		}
+	}
+	return allErrs
+}
+
+func validateType(t interface{}, value interface{}, fldPath *field.Path) field.ErrorList {
+	var types []string
+	switch tt := t.(type) {
+	case string:
+		types = []string{tt}
+	case []interface{}:
+		for _, item := range tt {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+	default:
+		return nil
+	}
+	for _, expected := range types {
+		if isType(expected, value) {
+			return nil
+		}
+	}
+	return field.ErrorList{field.Invalid(fldPath, value,
+		fmt.Sprintf("must be of type %s, found %s", strings.Join(types, " or "), typeOf(value)))}
+}
+
+func validateEnum(enum []interface{}, value interface{}, fldPath *field.Path) field.ErrorList {
+	for _, e := range enum {
+		if equal(e, value) {
+			return nil
+		}
+	}
+	validValues := make([]string, len(enum))
+	for i, e := range enum {
+		validValues[i] = toJSON(e)
+	}
+	return field.ErrorList{field.NotSupported(fldPath, value, validValues)}
+}
+
+// resolve resolves a JSON pointer reference local to the root schema
+func (v *validator) resolve(ref string) (interface{}, error) {
+	if ref != "#" && !strings.HasPrefix(ref, "#/") {
+		return nil, errors.NewInputError("schema.Validate", ref, fmt.Errorf("only local references are supported"))
+	}
+	current := v.root
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#"), "/")[1:] {
+		token = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, errors.NewInputError("schema.Validate", ref, fmt.Errorf("reference can not be resolved"))
+		}
+		current, ok = obj[token]
+		if !ok {
+			return nil, errors.NewInputError("schema.Validate", ref, fmt.Errorf("reference can not be resolved"))
+		}
+	}
+	return current, nil
+}
+
+func (v *validator) compile(pattern string) *regexp.Regexp {
+	if re, ok := v.patterns[pattern]; ok {
+		return re
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		v.setErr(errors.NewInputError("schema.Validate", pattern, err))
+		re = nil
+	}
+	v.patterns[pattern] = re
+	return re
+}
+
+func isType(t string, value interface{}) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return false
+}
+
+func typeOf(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return reflect.TypeOf(value).String()
+}
+
+func equal(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+func toInt(value interface{}) (int, bool) {
+	n, ok := value.(float64)
+	if !ok {
+		return 0, false
+	}
+	return int(n), true
+}
+
+func toJSON(value interface{}) string {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(b)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
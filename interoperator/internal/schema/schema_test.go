@@ -0,0 +1,271 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const testSchema = `{
+  "$schema": "http://json-schema.org/draft-06/schema#",
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["size"],
+  "definitions": {
+    "port": {"type": "integer", "minimum": 1, "maximum": 65535}
+  },
+  "properties": {
+    "size": {"type": "string", "enum": ["small", "large"]},
+    "replicas": {"type": "integer", "exclusiveMinimum": 0, "multipleOf": 1},
+    "name": {"type": "string", "minLength": 3, "maxLength": 8, "pattern": "^[a-z]+$"},
+    "port": {"$ref": "#/definitions/port"},
+    "tags": {
+      "type": "array",
+      "maxItems": 2,
+      "uniqueItems": true,
+      "items": {"type": "string"}
+    },
+    "backup": {
+      "type": "object",
+      "required": ["enabled"],
+      "properties": {
+        "enabled": {"type": "boolean"},
+        "schedule": {"type": ["string", "null"]}
+      }
+    }
+  }
+}`
+
+func TestValidate(t *testing.T) {
+	fldPath := field.NewPath("spec", "parameters")
+	type args struct {
+		schema string
+		params string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "allow everything if schema is empty",
+			args: args{
+				schema: "",
+				params: `{"foo": "bar"}`,
+			},
+			want:    nil,
+			wantErr: false,
+		},
+		{
+			name: "fail if schema is not valid json",
+			args: args{
+				schema: "{",
+				params: `{}`,
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "fail if schema has an invalid pattern",
+			args: args{
+				schema: `{"properties": {"foo": {"pattern": "("}}}`,
+				params: `{"foo": "bar"}`,
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "fail if schema has an unresolvable reference",
+			args: args{
+				schema: `{"properties": {"foo": {"$ref": "#/definitions/foo"}}}`,
+				params: `{"foo": "bar"}`,
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "fail if schema uses an unsupported keyword",
+			args: args{
+				schema: `{"properties": {"url": {"type": "string"}}, "dependencies": {"url": ["port"]}}`,
+				params: `{"url": "foo"}`,
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "ignore the format annotation",
+			args: args{
+				schema: `{"properties": {"url": {"type": "string", "format": "uri", "title": "URL"}}}`,
+				params: `{"url": "foo"}`,
+			},
+			want:    []string{},
+			wantErr: false,
+		},
+		{
+			name: "fail if a sub schema not applied to the parameters uses an unsupported keyword",
+			args: args{
+				schema: `{"definitions": {"tags": {"contains": {"const": "foo"}}},
+					"anyOf": [{"required": ["size"]}, {"if": {"required": ["name"]}}]}`,
+				params: `{"size": "small"}`,
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "allow properties named like unsupported keywords",
+			args: args{
+				schema: `{"properties": {"format": {"type": "string"}}, "required": ["format"]}`,
+				params: `{"format": "json"}`,
+			},
+			want:    []string{},
+			wantErr: false,
+		},
+		{
+			name: "fail if schema references itself recursively",
+			args: args{
+				schema: `{"$ref": "#"}`,
+				params: `{}`,
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "validate recursive schemas bounded by the parameters",
+			args: args{
+				schema: `{"type": "object", "properties": {"child": {"$ref": "#"}, "name": {"type": "string"}}}`,
+				params: `{"child": {"child": {"name": 1}}}`,
+			},
+			want: []string{
+				`spec.parameters.child.child.name: Invalid value: 1: must be of type string, found number`,
+			},
+			wantErr: false,
+		},
+		{
+			name: "validate valid parameters",
+			args: args{
+				schema: testSchema,
+				params: `{"size": "small", "replicas": 3, "name": "foo", "port": 8080,
+					"tags": ["a", "b"], "backup": {"enabled": true, "schedule": null}}`,
+			},
+			want:    []string{},
+			wantErr: false,
+		},
+		{
+			name: "validate empty parameters as an empty object",
+			args: args{
+				schema: testSchema,
+				params: "",
+			},
+			want:    []string{"spec.parameters.size: Required value"},
+			wantErr: false,
+		},
+		{
+			name: "report the field path of every invalid parameter",
+			args: args{
+				schema: testSchema,
+				params: `{"size": "medium", "replicas": 0, "name": "Foo", "port": 70000,
+					"tags": ["a", "a", "b"], "backup": {"schedule": 1}, "foo": "bar"}`,
+			},
+			want: []string{
+				`spec.parameters.backup.enabled: Required value`,
+				`spec.parameters.backup.schedule: Invalid value: 1: must be of type string or null, found number`,
+				`spec.parameters.foo: Forbidden: additional properties are not allowed`,
+				`spec.parameters.name: Invalid value: "Foo": must match the pattern ^[a-z]+$`,
+				`spec.parameters.port: Invalid value: 70000: must be less than or equal to 65535`,
+				`spec.parameters.replicas: Invalid value: 0: must be greater than 0`,
+				`spec.parameters.size: Unsupported value: "medium": supported values: "\"small\"", "\"large\""`,
+				`spec.parameters.tags: Invalid value: []interface {}{"a", "a", "b"}: must have at most 2 items`,
+				`spec.parameters.tags[1]: Duplicate value: "a"`,
+			},
+			wantErr: false,
+		},
+		{
+			name: "report a type mismatch of the root",
+			args: args{
+				schema: testSchema,
+				params: `["small"]`,
+			},
+			want: []string{
+				`spec.parameters: Invalid value: []interface {}{"small"}: must be of type object, found array`,
+			},
+			wantErr: false,
+		},
+		{
+			name: "validate string length in characters",
+			args: args{
+				schema: `{"properties": {"name": {"minLength": 2, "maxLength": 3}}}`,
+				params: `{"name": "äöüß"}`,
+			},
+			want: []string{
+				`spec.parameters.name: Too long: must have at most 3 characters`,
+			},
+			wantErr: false,
+		},
+		{
+			name: "validate draft-04 exclusive limits and integers",
+			args: args{
+				schema: `{"properties": {"cpu": {"type": "integer", "minimum": 1, "exclusiveMinimum": true}}}`,
+				params: `{"cpu": 1.5}`,
+			},
+			want: []string{
+				`spec.parameters.cpu: Invalid value: 1.5: must be of type integer, found number`,
+			},
+			wantErr: false,
+		},
+		{
+			name: "validate combinators",
+			args: args{
+				schema: `{"properties": {
+					"a": {"anyOf": [{"type": "string"}, {"type": "integer"}]},
+					"b": {"oneOf": [{"minimum": 1}, {"maximum": 5}]},
+					"c": {"not": {"type": "null"}},
+					"d": {"allOf": [{"type": "string"}, {"minLength": 2}]}}}`,
+				params: `{"a": true, "b": 3, "c": null, "d": "x"}`,
+			},
+			want: []string{
+				`spec.parameters.a: Invalid value: true: must match at least one of the schemas in anyOf`,
+				`spec.parameters.b: Invalid value: 3: must match exactly one of the schemas in oneOf, matched 2`,
+				`spec.parameters.c: Invalid value: "null": must not match the schema in not`,
+				`spec.parameters.d: Invalid value: "x": must be at least 2 characters long`,
+			},
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Validate([]byte(tt.args.schema), []byte(tt.args.params), fldPath)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			var gotErrs []string
+			if got != nil {
+				gotErrs = []string{}
+				for _, e := range got {
+					gotErrs = append(gotErrs, e.Error())
+				}
+			}
+			if !reflect.DeepEqual(gotErrs, tt.want) {
+				t.Errorf("Validate() = %q, want %q", gotErrs, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretstore
+
+import (
+	"context"
+
+	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/config"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("secretstore")
+
+// SecretStore stores the credentials of bindings. The secrets are
+// identified by the binding and a name, e.g. sf-<bindingID>.
+type SecretStore interface {
+	// Get returns the data of the secret or an errors.SecretNotFound
+	// error if it does not exist
+	Get(binding *osbv1alpha1.SFServiceBinding, name string) (map[string]string, error)
+	// Put creates or replaces the secret
+	Put(binding *osbv1alpha1.SFServiceBinding, name string, data map[string]string) error
+	// Delete deletes the secret. Deleting a secret which does not exist
+	// is not an error.
+	Delete(binding *osbv1alpha1.SFServiceBinding, name string) error
+	// Ref returns the reference to the secret which is set as secretRef
+	// of the binding response
+	Ref(binding *osbv1alpha1.SFServiceBinding, name string) string
+}
+
+// BindSecretName returns the name of the secret with the credentials of
+// the binding
+func BindSecretName(bindingID string) string {
+	return "sf-" + bindingID
+}
+
+// PreviousBindSecretName returns the name of the secret with the previous
+// credentials of the binding during a rotation
+func PreviousBindSecretName(bindingID string) string {
+	return "sf-" + bindingID + "-previous"
+}
+
+// New returns the SecretStore of the configured type
+func New(c client.Client, scheme *runtime.Scheme, cfg config.SecretStoreConfig) (SecretStore, error) {
+	switch cfg.Type {
+	case "", constants.KubernetesSecretStoreType:
+		return NewKubernetesStore(c, scheme)
+	case constants.VaultSecretStoreType:
+		return NewVaultStore(cfg.Vault)
+	default:
+		return nil, errors.NewInputError("New secretstore", "type", nil)
+	}
+}
+
+type kubernetesStore struct {
+	c      client.Client
+	scheme *runtime.Scheme
+}
+
+// NewKubernetesStore returns a SecretStore which keeps the secrets as
+// Secrets in the namespace of the binding, owned by the binding
+func NewKubernetesStore(c client.Client, scheme *runtime.Scheme) (SecretStore, error) {
+	if c == nil {
+		return nil, errors.NewInputError("NewKubernetesStore", "client", nil)
+	}
+	if scheme == nil {
+		return nil, errors.NewInputError("NewKubernetesStore", "scheme", nil)
+	}
+	return &kubernetesStore{
+		c:      c,
+		scheme: scheme,
+	}, nil
+}
+
+func (s *kubernetesStore) Get(binding *osbv1alpha1.SFServiceBinding, name string) (map[string]string, error) {
+	secret := &corev1.Secret{}
+	namespacedName := types.NamespacedName{
+		Name:      name,
+		Namespace: binding.GetNamespace(),
+	}
+	err := s.c.Get(context.TODO(), namespacedName, secret)
+	if err != nil {
+		if apiErrors.IsNotFound(err) {
+			return nil, errors.NewSecretNotFound(namespacedName.String(), err)
+		}
+		return nil, err
+	}
+
+	data := make(map[string]string)
+	for key, value := range secret.Data {
+		data[key] = string(value)
+	}
+	for key, value := range secret.StringData {
+		data[key] = value
+	}
+	return data, nil
+}
+
+func (s *kubernetesStore) Put(binding *osbv1alpha1.SFServiceBinding, name string, data map[string]string) error {
+	ctx := context.TODO()
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: binding.GetNamespace(),
+		},
+		StringData: data,
+	}
+	if err := controllerutil.SetControllerReference(binding, secret, s.scheme); err != nil {
+		return err
+	}
+
+	err := s.c.Create(ctx, secret)
+	if !apiErrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	foundSecret := &corev1.Secret{}
+	err = s.c.Get(ctx, types.NamespacedName{Name: name, Namespace: binding.GetNamespace()}, foundSecret)
+	if err != nil {
+		return err
+	}
+	foundSecret.Data = nil
+	foundSecret.StringData = data
+	return s.c.Update(ctx, foundSecret)
+}
+
+func (s *kubernetesStore) Delete(binding *osbv1alpha1.SFServiceBinding, name string) error {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: binding.GetNamespace(),
+		},
+	}
+	err := s.c.Delete(context.TODO(), secret)
+	if err != nil && !apiErrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *kubernetesStore) Ref(binding *osbv1alpha1.SFServiceBinding, name string) string {
+	return name
+}
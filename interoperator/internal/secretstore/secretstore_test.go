@@ -0,0 +1,129 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretstore
+
+import (
+	"context"
+	"testing"
+
+	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/config"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newBinding() *osbv1alpha1.SFServiceBinding {
+	return &osbv1alpha1.SFServiceBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "binding-id",
+			Namespace: "default",
+			UID:       "binding-uid",
+		},
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.SecretStoreConfig
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name: "return kubernetes store by default",
+			cfg:  config.SecretStoreConfig{},
+			want: &kubernetesStore{},
+		},
+		{
+			name: "return vault store",
+			cfg: config.SecretStoreConfig{
+				Type: constants.VaultSecretStoreType,
+				Vault: config.VaultConfig{
+					Address: "http://localhost:8200",
+				},
+			},
+			want: &vaultStore{},
+		},
+		{
+			name: "fail if vault address is missing",
+			cfg: config.SecretStoreConfig{
+				Type: constants.VaultSecretStoreType,
+			},
+			wantErr: true,
+		},
+		{
+			name: "fail for unknown type",
+			cfg: config.SecretStoreConfig{
+				Type: "unknown",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+			got, err := New(fake.NewFakeClientWithScheme(scheme.Scheme), scheme.Scheme, tt.cfg)
+			if tt.wantErr {
+				g.Expect(err).To(gomega.HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+			g.Expect(got).To(gomega.BeAssignableToTypeOf(tt.want))
+		})
+	}
+}
+
+func Test_kubernetesStore(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	testScheme := runtime.NewScheme()
+	g.Expect(scheme.AddToScheme(testScheme)).To(gomega.Succeed())
+	g.Expect(osbv1alpha1.AddToScheme(testScheme)).To(gomega.Succeed())
+	c := fake.NewFakeClientWithScheme(testScheme)
+	store, err := NewKubernetesStore(c, testScheme)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	binding := newBinding()
+
+	_, err = store.Get(binding, "sf-binding-id")
+	g.Expect(errors.SecretNotFound(err)).To(gomega.BeTrue())
+
+	g.Expect(store.Put(binding, "sf-binding-id", map[string]string{"response": "old"})).To(gomega.Succeed())
+	ref := store.Ref(binding, "sf-binding-id")
+	g.Expect(ref).To(gomega.Equal("sf-binding-id"))
+
+	secret := &corev1.Secret{}
+	g.Expect(c.Get(context.TODO(), types.NamespacedName{Name: ref, Namespace: "default"}, secret)).To(gomega.Succeed())
+	g.Expect(secret.GetOwnerReferences()).To(gomega.HaveLen(1))
+	g.Expect(secret.GetOwnerReferences()[0].Name).To(gomega.Equal("binding-id"))
+
+	g.Expect(store.Put(binding, "sf-binding-id", map[string]string{"response": "new"})).To(gomega.Succeed())
+	data, err := store.Get(binding, "sf-binding-id")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(data).To(gomega.Equal(map[string]string{"response": "new"}))
+
+	g.Expect(store.Delete(binding, "sf-binding-id")).To(gomega.Succeed())
+	_, err = store.Get(binding, "sf-binding-id")
+	g.Expect(errors.SecretNotFound(err)).To(gomega.BeTrue())
+	g.Expect(store.Delete(binding, "sf-binding-id")).To(gomega.Succeed())
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package secretstoretest provides the fixtures shared by the tests of the
+// secret stores and of the controllers using them.
+package secretstoretest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// VaultToken is the only token accepted by the VaultStandIn
+const VaultToken = "test-token"
+
+// VaultStandIn is a minimal stand-in for the KV version 2 secrets engine
+// of vault mounted at secret. Secrets holds the data of the secrets by
+// their path below the mount.
+type VaultStandIn struct {
+	mu      sync.Mutex
+	Secrets map[string]map[string]string
+}
+
+// NewVaultStandIn returns a VaultStandIn without any secrets
+func NewVaultStandIn() *VaultStandIn {
+	return &VaultStandIn{
+		Secrets: make(map[string]map[string]string),
+	}
+}
+
+type vaultSecret struct {
+	Data map[string]string `json:"data"`
+}
+
+type vaultReadResponse struct {
+	Data vaultSecret `json:"data"`
+}
+
+type vaultErrorResponse struct {
+	Errors []string `json:"errors"`
+}
+
+func (v *VaultStandIn) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if req.Header.Get("X-Vault-Token") != VaultToken {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(&vaultErrorResponse{Errors: []string{"permission denied"}})
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(req.URL.Path, "/v1/secret/data/"):
+		secretPath := strings.TrimPrefix(req.URL.Path, "/v1/secret/data/")
+		switch req.Method {
+		case http.MethodGet:
+			data, ok := v.Secrets[secretPath]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(&vaultErrorResponse{Errors: []string{}})
+				return
+			}
+			json.NewEncoder(w).Encode(&vaultReadResponse{Data: vaultSecret{Data: data}})
+		case http.MethodPost, http.MethodPut:
+			secret := &vaultSecret{}
+			if err := json.NewDecoder(req.Body).Decode(secret); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			v.Secrets[secretPath] = secret.Data
+			w.Write([]byte(`{"data":{"version":1}}`))
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	case strings.HasPrefix(req.URL.Path, "/v1/secret/metadata/") && req.Method == http.MethodDelete:
+		delete(v.Secrets, strings.TrimPrefix(req.URL.Path, "/v1/secret/metadata/"))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretstore
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/config"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
+)
+
+// vaultStore keeps the secrets in a Vault KV version 2 secrets engine at
+// <mount>/data/<pathPrefix>/<namespace>/<name>
+type vaultStore struct {
+	address    string
+	mount      string
+	pathPrefix string
+	tokenFile  string
+	httpClient *http.Client
+}
+
+// vaultSecret is the body of the KV version 2 read and write requests
+type vaultSecret struct {
+	Data map[string]string `json:"data"`
+}
+
+// vaultReadResponse is the body of the KV version 2 read response
+type vaultReadResponse struct {
+	Data vaultSecret `json:"data"`
+}
+
+// vaultErrorResponse is the body of vault error responses
+type vaultErrorResponse struct {
+	Errors []string `json:"errors"`
+}
+
+// NewVaultStore returns a SecretStore which keeps the secrets in a Vault
+// KV version 2 secrets engine. Only the reference
+// vault:<mount>/data/<pathPrefix>/<namespace>/<name> is kept in the binding,
+// which is the read path of the secret below /v1 of the vault api.
+func NewVaultStore(cfg config.VaultConfig) (SecretStore, error) {
+	if cfg.Address == "" {
+		return nil, errors.NewInputError("NewVaultStore", "address", nil)
+	}
+	s := &vaultStore{
+		address:    strings.TrimSuffix(cfg.Address, "/"),
+		mount:      cfg.Mount,
+		pathPrefix: cfg.PathPrefix,
+		tokenFile:  cfg.TokenFile,
+		httpClient: &http.Client{
+			Timeout: constants.SecretStoreTimeout,
+		},
+	}
+	if s.mount == "" {
+		s.mount = constants.DefaultVaultMount
+	}
+	if s.pathPrefix == "" {
+		s.pathPrefix = constants.DefaultVaultPathPrefix
+	}
+	return s, nil
+}
+
+func (s *vaultStore) secretPath(binding *osbv1alpha1.SFServiceBinding, name string) string {
+	return path.Join(s.pathPrefix, binding.GetNamespace(), name)
+}
+
+// token reads the token on each request as it may be renewed in the file
+func (s *vaultStore) token() (string, error) {
+	if s.tokenFile == "" {
+		return os.Getenv(constants.VaultTokenEnvKey), nil
+	}
+	token, err := ioutil.ReadFile(s.tokenFile)
+	if err != nil {
+		return "", errors.NewSecretStoreError("failed to read vault token", err)
+	}
+	return strings.TrimSpace(string(token)), nil
+}
+
+// do sends a request to the KV version 2 endpoint, e.g. data or
+// metadata, of the secret. It returns the status code and the body of
+// successful responses.
+func (s *vaultStore) do(method, endpoint, secretPath string, body interface{}) (int, []byte, error) {
+	var reader io.Reader
+	if body != nil {
+		content, err := json.Marshal(body)
+		if err != nil {
+			return 0, nil, errors.NewMarshalError("failed to marshal vault request", err)
+		}
+		reader = bytes.NewReader(content)
+	}
+
+	url := fmt.Sprintf("%s/v1/%s/%s/%s", s.address, s.mount, endpoint, secretPath)
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return 0, nil, errors.NewSecretStoreError("failed to create vault request", err)
+	}
+	token, err := s.token()
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, errors.NewSecretStoreError(fmt.Sprintf("vault %s %s failed", method, secretPath), err)
+	}
+	defer resp.Body.Close()
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, errors.NewSecretStoreError(fmt.Sprintf("failed to read vault response for %s", secretPath), err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode < 300 {
+		return resp.StatusCode, content, nil
+	}
+	vaultErr := &vaultErrorResponse{}
+	_ = json.Unmarshal(content, vaultErr)
+	err = fmt.Errorf("status %d: %s", resp.StatusCode, strings.Join(vaultErr.Errors, ", "))
+	return resp.StatusCode, nil, errors.NewSecretStoreError(fmt.Sprintf("vault %s %s failed", method, secretPath), err)
+}
+
+func (s *vaultStore) Get(binding *osbv1alpha1.SFServiceBinding, name string) (map[string]string, error) {
+	secretPath := s.secretPath(binding, name)
+	status, content, err := s.do(http.MethodGet, "data", secretPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusNotFound {
+		return nil, errors.NewSecretNotFound(secretPath, nil)
+	}
+
+	secret := &vaultReadResponse{}
+	err = json.Unmarshal(content, secret)
+	if err != nil {
+		return nil, errors.NewUnmarshalError("failed to unmarshal vault secret", err)
+	}
+	if secret.Data.Data == nil {
+		// The latest version of the secret is deleted
+		return nil, errors.NewSecretNotFound(secretPath, nil)
+	}
+	return secret.Data.Data, nil
+}
+
+func (s *vaultStore) Put(binding *osbv1alpha1.SFServiceBinding, name string, data map[string]string) error {
+	secretPath := s.secretPath(binding, name)
+	_, _, err := s.do(http.MethodPost, "data", secretPath, &vaultSecret{Data: data})
+	if err != nil {
+		log.Error(err, "failed to write secret to vault", "path", secretPath)
+		return err
+	}
+	return nil
+}
+
+func (s *vaultStore) Delete(binding *osbv1alpha1.SFServiceBinding, name string) error {
+	// Deleting the metadata deletes all versions of the secret
+	secretPath := s.secretPath(binding, name)
+	_, _, err := s.do(http.MethodDelete, "metadata", secretPath, nil)
+	if err != nil {
+		log.Error(err, "failed to delete secret from vault", "path", secretPath)
+		return err
+	}
+	return nil
+}
+
+func (s *vaultStore) Ref(binding *osbv1alpha1.SFServiceBinding, name string) string {
+	return fmt.Sprintf("%s:%s/data/%s", constants.VaultSecretStoreType, s.mount, s.secretPath(binding, name))
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretstore
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/config"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/secretstore/secretstoretest"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
+
+	"github.com/onsi/gomega"
+)
+
+func Test_vaultStore(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	vault := secretstoretest.NewVaultStandIn()
+	server := httptest.NewServer(vault)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "vault")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	g.Expect(ioutil.WriteFile(tokenFile, []byte(secretstoretest.VaultToken+"\n"), 0600)).To(gomega.Succeed())
+
+	store, err := NewVaultStore(config.VaultConfig{
+		Address:   server.URL + "/",
+		TokenFile: tokenFile,
+	})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	binding := newBinding()
+
+	_, err = store.Get(binding, "sf-binding-id")
+	g.Expect(errors.SecretNotFound(err)).To(gomega.BeTrue())
+
+	g.Expect(store.Put(binding, "sf-binding-id", map[string]string{"response": "old"})).To(gomega.Succeed())
+	ref := store.Ref(binding, "sf-binding-id")
+	g.Expect(ref).To(gomega.Equal("vault:secret/data/interoperator/default/sf-binding-id"))
+	g.Expect(vault.Secrets).To(gomega.HaveKeyWithValue("interoperator/default/sf-binding-id",
+		map[string]string{"response": "old"}))
+
+	g.Expect(store.Put(binding, "sf-binding-id", map[string]string{"response": "new"})).To(gomega.Succeed())
+	data, err := store.Get(binding, "sf-binding-id")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(data).To(gomega.Equal(map[string]string{"response": "new"}))
+
+	g.Expect(store.Delete(binding, "sf-binding-id")).To(gomega.Succeed())
+	g.Expect(vault.Secrets).To(gomega.BeEmpty())
+	_, err = store.Get(binding, "sf-binding-id")
+	g.Expect(errors.SecretNotFound(err)).To(gomega.BeTrue())
+
+	// Requests with an invalid token fail
+	g.Expect(ioutil.WriteFile(tokenFile, []byte("invalid"), 0600)).To(gomega.Succeed())
+	err = store.Put(binding, "sf-binding-id", map[string]string{"response": "new"})
+	g.Expect(errors.SecretStoreError(err)).To(gomega.BeTrue())
+	g.Expect(err.(*errors.InteroperatorError).Err.Error()).To(gomega.ContainSubstring("permission denied"))
+}
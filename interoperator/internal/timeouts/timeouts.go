@@ -0,0 +1,175 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timeouts
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/services"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	kubernetes "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("timeouts")
+
+var operationTimeouts = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "interoperator_operation_timeouts_total",
+	Help: "Number of operations on instances and bindings which failed after their timeout",
+}, []string{"kind", "operation", "plan"})
+
+func init() {
+	metrics.Registry.MustRegister(operationTimeouts)
+}
+
+// ForOperation fetches the timeout which the plan declares for the
+// operation. It is 0 if the plan declares no timeout for the operation.
+func ForOperation(client kubernetes.Client, serviceID, planID, operation string) (time.Duration, error) {
+	serviceNamespace := os.Getenv(constants.NamespaceEnvKey)
+	if serviceNamespace == "" {
+		serviceNamespace = constants.DefaultServiceFabrikNamespace
+	}
+	_, plan, err := services.FindServiceInfo(client, serviceID, planID, serviceNamespace)
+	if err != nil {
+		return 0, err
+	}
+	return plan.GetOperationTimeout(operation)
+}
+
+// Expired is true if the deadline of an operation passed
+func Expired(deadline time.Time) bool {
+	return !time.Now().Before(deadline)
+}
+
+// Object is an instance or binding whose operations may time out
+type Object interface {
+	runtime.Object
+	metav1.Object
+}
+
+// Operation is an operation of an instance or binding which fails if it is
+// still in progress after its deadline. It has no deadline if the plan
+// declares no timeout for it.
+type Operation struct {
+	ID         string
+	Kind       string
+	Type       string
+	PlanID     string
+	InProgress bool
+	StartedAt  *metav1.Time
+	Deadline   *metav1.Time
+}
+
+// Start returns the start of an operation starting now and its deadline,
+// which is nil without timeout
+func Start(timeout time.Duration) (*metav1.Time, *metav1.Time) {
+	startedAt := metav1.Now()
+	if timeout <= 0 {
+		return &startedAt, nil
+	}
+	deadline := metav1.NewTime(startedAt.Add(timeout))
+	return &startedAt, &deadline
+}
+
+// Expired is true if the operation is still in progress after its deadline
+func (o *Operation) Expired() bool {
+	if o == nil || !o.InProgress || o.Deadline == nil {
+		return false
+	}
+	return Expired(o.Deadline.Time)
+}
+
+// Result requeues the object at the deadline of its operation, so that
+// it fails even if its resources never change
+func (o *Operation) Result() ctrl.Result {
+	if o == nil || !o.InProgress || o.Deadline == nil {
+		return ctrl.Result{}
+	}
+	if remaining := time.Until(o.Deadline.Time); remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}
+	}
+	return ctrl.Result{Requeue: true}
+}
+
+// Timeout returns how long the operation may stay in progress, 0 if its
+// start is not known
+func (o *Operation) Timeout() time.Duration {
+	if o == nil || o.StartedAt == nil || o.Deadline == nil {
+		return 0
+	}
+	return o.Deadline.Sub(o.StartedAt.Time)
+}
+
+// Fail marks the object failed once the deadline of its operation passed.
+// The object is fetched again before, operation returns its operation and
+// fail sets the timeout error in its status. A Warning event with reason
+// OperationTimeout is recorded for the object and the counter of timed out
+// operations is incremented.
+func Fail(client kubernetes.Client, recorder record.EventRecorder, object Object, operation func() *Operation, fail func(error), retryCount int) error {
+	ctx := context.Background()
+
+	namespacedName := types.NamespacedName{
+		Name:      object.GetName(),
+		Namespace: object.GetNamespace(),
+	}
+	err := client.Get(ctx, namespacedName, object)
+	if err != nil {
+		log.Error(err, "failed to fetch object", "name", namespacedName)
+		return err
+	}
+	op := operation()
+	if !op.Expired() {
+		return nil
+	}
+
+	timeoutErr := errors.NewOperationTimeout(op.Type, op.Timeout(), nil)
+	fail(timeoutErr)
+	err = client.Update(ctx, object)
+	if err != nil {
+		if retryCount < constants.ErrorThreshold {
+			log.Info("Retrying", "function", "Fail", "retryCount", retryCount+1, "kind", op.Kind, "name", namespacedName)
+			return Fail(client, recorder, object, operation, fail, retryCount+1)
+		}
+		log.Error(err, "failed to fail operation", "kind", op.Kind, "name", namespacedName)
+		return err
+	}
+	log.Info("Operation timed out", "kind", op.Kind, "name", namespacedName, "operationID", op.ID,
+		"operation", op.Type, "timeout", op.Timeout())
+	if recorder != nil {
+		recorder.Event(object, corev1.EventTypeWarning, "OperationTimeout", timeoutErr.Error())
+	}
+	Record(op.Kind, op.Type, op.PlanID)
+	return nil
+}
+
+// Record counts an operation on an object of kind which failed after its
+// timeout
+func Record(kind, operation, planID string) {
+	operationTimeouts.WithLabelValues(kind, operation, planID).Inc()
+}
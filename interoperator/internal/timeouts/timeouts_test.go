@@ -0,0 +1,188 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timeouts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
+
+	"github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestForOperation(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	scheme := runtime.NewScheme()
+	g.Expect(osbv1alpha1.AddToScheme(scheme)).To(gomega.Succeed())
+
+	labels := map[string]string{
+		"serviceId": "service-id",
+		"planId":    "plan-id",
+	}
+	service := &osbv1alpha1.SFService{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "service-id",
+			Namespace: "default",
+			Labels:    labels,
+		},
+		Spec: osbv1alpha1.SFServiceSpec{
+			ID: "service-id",
+		},
+	}
+	plan := &osbv1alpha1.SFPlan{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "plan-id",
+			Namespace: "default",
+			Labels:    labels,
+		},
+		Spec: osbv1alpha1.SFPlanSpec{
+			ID:        "plan-id",
+			ServiceID: "service-id",
+			Timeouts: &osbv1alpha1.OperationTimeouts{
+				Default: "1h",
+				Bind:    "5m",
+			},
+		},
+	}
+	c := fake.NewFakeClientWithScheme(scheme, service, plan)
+
+	timeout, err := ForOperation(c, "service-id", "plan-id", osbv1alpha1.BindOperation)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(timeout).To(gomega.Equal(time.Minute * 5))
+
+	timeout, err = ForOperation(c, "service-id", "plan-id", osbv1alpha1.ProvisionOperation)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(timeout).To(gomega.Equal(time.Hour))
+
+	_, err = ForOperation(c, "service-id", "other-plan-id", osbv1alpha1.ProvisionOperation)
+	g.Expect(errors.SFPlanNotFound(err)).To(gomega.BeTrue())
+}
+
+func TestRecord(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	Record("SFServiceInstance", osbv1alpha1.ProvisionOperation, "plan-id")
+	Record("SFServiceInstance", osbv1alpha1.ProvisionOperation, "plan-id")
+	g.Expect(testutil.ToFloat64(operationTimeouts.WithLabelValues("SFServiceInstance", osbv1alpha1.ProvisionOperation, "plan-id"))).
+		To(gomega.Equal(float64(2)))
+}
+
+func TestOperation(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	// Without timeout the operation has no deadline
+	startedAt, deadline := Start(0)
+	g.Expect(startedAt).NotTo(gomega.BeNil())
+	g.Expect(deadline).To(gomega.BeNil())
+	op := &Operation{InProgress: true, StartedAt: startedAt, Deadline: deadline}
+	g.Expect(op.Expired()).To(gomega.BeFalse())
+	g.Expect(op.Result()).To(gomega.BeZero())
+	g.Expect(op.Timeout()).To(gomega.BeZero())
+
+	// The operation is requeued at its deadline
+	startedAt, deadline = Start(time.Minute * 20)
+	g.Expect(deadline.Sub(startedAt.Time)).To(gomega.Equal(time.Minute * 20))
+	op = &Operation{InProgress: true, StartedAt: startedAt, Deadline: deadline}
+	g.Expect(op.Expired()).To(gomega.BeFalse())
+	g.Expect(op.Result().RequeueAfter).To(gomega.BeNumerically(">", time.Minute*19))
+	g.Expect(op.Timeout()).To(gomega.Equal(time.Minute * 20))
+
+	// The operation expires after its deadline unless completed
+	past := metav1.NewTime(time.Now().Add(-time.Minute))
+	op.Deadline = &past
+	g.Expect(op.Expired()).To(gomega.BeTrue())
+	g.Expect(op.Result()).To(gomega.Equal(ctrl.Result{Requeue: true}))
+	op.InProgress = false
+	g.Expect(op.Expired()).To(gomega.BeFalse())
+	g.Expect(op.Result()).To(gomega.BeZero())
+
+	// An object without operation never expires
+	var none *Operation
+	g.Expect(none.Expired()).To(gomega.BeFalse())
+	g.Expect(none.Result()).To(gomega.BeZero())
+}
+
+func TestFail(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	scheme := runtime.NewScheme()
+	g.Expect(osbv1alpha1.AddToScheme(scheme)).To(gomega.Succeed())
+
+	startedAt, deadline := Start(time.Minute * 20)
+	instance := &osbv1alpha1.SFServiceInstance{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "instance-id",
+			Namespace: "default",
+		},
+		Status: osbv1alpha1.SFServiceInstanceStatus{
+			State:              "in progress",
+			OperationStartedAt: startedAt,
+			OperationDeadline:  deadline,
+		},
+	}
+	c := fake.NewFakeClientWithScheme(scheme, instance)
+	recorder := record.NewFakeRecorder(10)
+	operation := func() *Operation {
+		return &Operation{
+			Kind:       "SFServiceInstance",
+			Type:       osbv1alpha1.ProvisionOperation,
+			PlanID:     "fail-plan-id",
+			InProgress: instance.GetState() == "in progress",
+			StartedAt:  instance.Status.OperationStartedAt,
+			Deadline:   instance.Status.OperationDeadline,
+		}
+	}
+	fail := func(err error) {
+		instance.SetState("failed")
+		instance.Status.Error = err.Error()
+	}
+
+	// The object is not failed before the deadline
+	g.Expect(Fail(c, recorder, instance, operation, fail, 0)).To(gomega.Succeed())
+	g.Expect(instance.GetState()).To(gomega.Equal("in progress"))
+	g.Expect(recorder.Events).To(gomega.BeEmpty())
+
+	// The object is failed after the deadline
+	expiredStartedAt := metav1.NewTime(time.Now().Add(-time.Minute * 21))
+	expiredDeadline := metav1.NewTime(expiredStartedAt.Add(time.Minute * 20))
+	instance.Status.OperationStartedAt = &expiredStartedAt
+	instance.Status.OperationDeadline = &expiredDeadline
+	g.Expect(c.Update(context.TODO(), instance)).To(gomega.Succeed())
+	g.Expect(Fail(c, recorder, instance, operation, fail, 0)).To(gomega.Succeed())
+
+	failed := &osbv1alpha1.SFServiceInstance{}
+	g.Expect(c.Get(context.TODO(), types.NamespacedName{Name: "instance-id", Namespace: "default"}, failed)).To(gomega.Succeed())
+	g.Expect(failed.GetState()).To(gomega.Equal("failed"))
+	g.Expect(failed.Status.Error).To(gomega.Equal("provision operation timed out after 20m0s"))
+	g.Expect(recorder.Events).To(gomega.Receive(gomega.Equal("Warning OperationTimeout provision operation timed out after 20m0s")))
+	g.Expect(testutil.ToFloat64(operationTimeouts.WithLabelValues("SFServiceInstance", osbv1alpha1.ProvisionOperation, "fail-plan-id"))).
+		To(gomega.Equal(float64(1)))
+
+	// Objects which are gone are not failed
+	g.Expect(c.Delete(context.TODO(), failed)).To(gomega.Succeed())
+	err := Fail(c, recorder, instance, operation, fail, 0)
+	g.Expect(apierrors.IsNotFound(err)).To(gomega.BeTrue())
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"net/http"
+
+	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	kubernetes "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// +kubebuilder:webhook:path=/validate-osb-servicefabrik-io-v1alpha1-sfservicebinding,mutating=false,failurePolicy=fail,groups=osb.servicefabrik.io,resources=sfservicebindings,verbs=create;update,versions=v1alpha1,name=vsfservicebinding.osb.servicefabrik.io
+
+// SFServiceBindingValidator validates the parameters of a SFServiceBinding
+// against the binding create schema of its SFPlan
+type SFServiceBindingValidator struct {
+	client  kubernetes.Client
+	decoder *admission.Decoder
+}
+
+// Handle validates the SFServiceBinding in the admission request. The
+// parameters are validated on create and on update if the parameters
+// are changed.
+func (v *SFServiceBindingValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	binding := &osbv1alpha1.SFServiceBinding{}
+	err := v.decoder.Decode(req, binding)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if !binding.GetDeletionTimestamp().IsZero() {
+		return admission.Allowed("")
+	}
+
+	switch req.Operation {
+	case admissionv1beta1.Create:
+	case admissionv1beta1.Update:
+		oldBinding := &osbv1alpha1.SFServiceBinding{}
+		err = v.decoder.DecodeRaw(req.OldObject, oldBinding)
+		if err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if oldBinding.Spec.PlanID == binding.Spec.PlanID &&
+			!parametersChanged(oldBinding.Spec.RawParameters, binding.Spec.RawParameters) {
+			return admission.Allowed("")
+		}
+	default:
+		return admission.Allowed("")
+	}
+
+	specPath := field.NewPath("spec")
+	plan, errs, err := findPlan(v.client, binding.Spec.ServiceID, binding.Spec.PlanID, specPath)
+	if err != nil {
+		log.Error(err, "failed to find plan", "bindingID", binding.GetName(),
+			"serviceID", binding.Spec.ServiceID, "planID", binding.Spec.PlanID)
+		return errored(err)
+	}
+	if len(errs) != 0 {
+		return invalid("SFServiceBinding", binding.GetName(), errs)
+	}
+	if plan.Spec.Schemas == nil {
+		return admission.Allowed("")
+	}
+
+	errs, err = validateParameters(plan.Spec.Schemas.Binding.Create, binding.Spec.RawParameters, specPath.Child("parameters"))
+	if err != nil {
+		log.Error(err, "failed to validate parameters", "bindingID", binding.GetName(), "planID", plan.Spec.ID)
+		return errored(err)
+	}
+	if len(errs) != 0 {
+		return invalid("SFServiceBinding", binding.GetName(), errs)
+	}
+	return admission.Allowed("")
+}
+
+// InjectClient injects the client into the validator
+func (v *SFServiceBindingValidator) InjectClient(c kubernetes.Client) error {
+	v.client = c
+	return nil
+}
+
+// InjectDecoder injects the decoder into the validator
+func (v *SFServiceBindingValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
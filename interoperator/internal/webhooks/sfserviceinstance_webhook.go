@@ -0,0 +1,110 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"net/http"
+
+	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	kubernetes "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// +kubebuilder:webhook:path=/validate-osb-servicefabrik-io-v1alpha1-sfserviceinstance,mutating=false,failurePolicy=fail,groups=osb.servicefabrik.io,resources=sfserviceinstances,verbs=create;update,versions=v1alpha1,name=vsfserviceinstance.osb.servicefabrik.io
+
+// SFServiceInstanceValidator validates the parameters of a SFServiceInstance
+// against the instance create or update schema of its SFPlan
+type SFServiceInstanceValidator struct {
+	client  kubernetes.Client
+	decoder *admission.Decoder
+}
+
+// Handle validates the SFServiceInstance in the admission request. The
+// parameters are validated on create and on update if the parameters or
+// the plan are changed.
+func (v *SFServiceInstanceValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	instance := &osbv1alpha1.SFServiceInstance{}
+	err := v.decoder.Decode(req, instance)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if !instance.GetDeletionTimestamp().IsZero() {
+		return admission.Allowed("")
+	}
+
+	isUpdate := false
+	switch req.Operation {
+	case admissionv1beta1.Create:
+	case admissionv1beta1.Update:
+		oldInstance := &osbv1alpha1.SFServiceInstance{}
+		err = v.decoder.DecodeRaw(req.OldObject, oldInstance)
+		if err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if oldInstance.Spec.PlanID == instance.Spec.PlanID &&
+			!parametersChanged(oldInstance.Spec.RawParameters, instance.Spec.RawParameters) {
+			return admission.Allowed("")
+		}
+		isUpdate = true
+	default:
+		return admission.Allowed("")
+	}
+
+	specPath := field.NewPath("spec")
+	plan, errs, err := findPlan(v.client, instance.Spec.ServiceID, instance.Spec.PlanID, specPath)
+	if err != nil {
+		log.Error(err, "failed to find plan", "instanceID", instance.GetName(),
+			"serviceID", instance.Spec.ServiceID, "planID", instance.Spec.PlanID)
+		return errored(err)
+	}
+	if len(errs) != 0 {
+		return invalid("SFServiceInstance", instance.GetName(), errs)
+	}
+	if plan.Spec.Schemas == nil {
+		return admission.Allowed("")
+	}
+
+	s := plan.Spec.Schemas.Instance.Create
+	if isUpdate {
+		s = plan.Spec.Schemas.Instance.Update
+	}
+	errs, err = validateParameters(s, instance.Spec.RawParameters, specPath.Child("parameters"))
+	if err != nil {
+		log.Error(err, "failed to validate parameters", "instanceID", instance.GetName(), "planID", plan.Spec.ID)
+		return errored(err)
+	}
+	if len(errs) != 0 {
+		return invalid("SFServiceInstance", instance.GetName(), errs)
+	}
+	return admission.Allowed("")
+}
+
+// InjectClient injects the client into the validator
+func (v *SFServiceInstanceValidator) InjectClient(c kubernetes.Client) error {
+	v.client = c
+	return nil
+}
+
+// InjectDecoder injects the decoder into the validator
+func (v *SFServiceInstanceValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhooks has the admission webhooks of interoperator.
+package webhooks
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"reflect"
+
+	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/schema"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/services"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	kubernetes "sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var log = logf.Log.WithName("webhooks")
+
+// Paths the admission webhooks are served at
+const (
+	SFServiceInstanceValidatorPath = "/validate-osb-servicefabrik-io-v1alpha1-sfserviceinstance"
+	SFServiceBindingValidatorPath  = "/validate-osb-servicefabrik-io-v1alpha1-sfservicebinding"
+)
+
+// SetupWithManager registers the admission webhooks with the
+// webhook server of the manager
+func SetupWithManager(mgr ctrl.Manager) error {
+	server := mgr.GetWebhookServer()
+	server.Register(SFServiceInstanceValidatorPath, &webhook.Admission{Handler: &SFServiceInstanceValidator{}})
+	server.Register(SFServiceBindingValidatorPath, &webhook.Admission{Handler: &SFServiceBindingValidator{}})
+	return nil
+}
+
+// findPlan fetches the SFPlan from the service fabrik namespace. Field
+// errors are returned if the service or the plan does not exist.
+func findPlan(client kubernetes.Client, serviceID, planID string, fldPath *field.Path) (*osbv1alpha1.SFPlan, field.ErrorList, error) {
+	sfNamespace := os.Getenv(constants.NamespaceEnvKey)
+	if sfNamespace == "" {
+		sfNamespace = constants.DefaultServiceFabrikNamespace
+	}
+	_, plan, err := services.FindServiceInfo(client, serviceID, planID, sfNamespace)
+	if err != nil {
+		switch {
+		case errors.SFServiceNotFound(err):
+			return nil, field.ErrorList{field.NotFound(fldPath.Child("serviceId"), serviceID)}, nil
+		case errors.SFPlanNotFound(err):
+			return nil, field.ErrorList{field.NotFound(fldPath.Child("planId"), planID)}, nil
+		}
+		return nil, nil, err
+	}
+	return plan, nil, nil
+}
+
+// validateParameters validates the parameters against the JSON schema
+func validateParameters(s osbv1alpha1.Schema, params *runtime.RawExtension, fldPath *field.Path) (field.ErrorList, error) {
+	if s.Parameters == nil {
+		return nil, nil
+	}
+	var raw []byte
+	if params != nil {
+		raw = params.Raw
+	}
+	return schema.Validate(s.Parameters.Raw, raw, fldPath)
+}
+
+// parametersChanged returns true if the parameters are semantically different
+func parametersChanged(oldParams, newParams *runtime.RawExtension) bool {
+	return !reflect.DeepEqual(decodeParameters(oldParams), decodeParameters(newParams))
+}
+
+func decodeParameters(params *runtime.RawExtension) interface{} {
+	if params == nil || len(params.Raw) == 0 {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(params.Raw, &value); err != nil {
+		return string(params.Raw)
+	}
+	return value
+}
+
+// invalid returns a response denying the request with the field errors
+// as causes of the status
+func invalid(kind, name string, errs field.ErrorList) admission.Response {
+	statusErr := apiErrors.NewInvalid(osbv1alpha1.GroupVersion.WithKind(kind).GroupKind(), name, errs)
+	return admission.Response{
+		AdmissionResponse: admissionv1beta1.AdmissionResponse{
+			Allowed: false,
+			Result:  &statusErr.ErrStatus,
+		},
+	}
+}
+
+// errored returns a response for an error occurred while validating
+func errored(err error) admission.Response {
+	if errors.UnmarshalError(err) || errors.InputError(err) {
+		// The schema of the plan is broken
+		return admission.Errored(http.StatusUnprocessableEntity, err)
+	}
+	return admission.Errored(http.StatusInternalServerError, err)
+}
@@ -0,0 +1,334 @@
+/*
+Copyright 2019 The Service Fabrik Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	stdlog "log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
+
+	"github.com/onsi/gomega"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var cfg *rest.Config
+var c client.Client
+var decoder *admission.Decoder
+
+func TestMain(m *testing.M) {
+	t := &envtest.Environment{
+		CRDDirectoryPaths: []string{filepath.Join("..", "..", "config", "crd", "bases")},
+	}
+
+	var err error
+
+	err = osbv1alpha1.AddToScheme(scheme.Scheme)
+	if err != nil {
+		stdlog.Fatal(err)
+	}
+
+	if cfg, err = t.Start(); err != nil {
+		stdlog.Fatal(err)
+	}
+
+	if c, err = client.New(cfg, client.Options{Scheme: scheme.Scheme}); err != nil {
+		stdlog.Fatal(err)
+	}
+
+	if decoder, err = admission.NewDecoder(scheme.Scheme); err != nil {
+		stdlog.Fatal(err)
+	}
+
+	code := m.Run()
+	t.Stop()
+	os.Exit(code)
+}
+
+func setupPlan(g *gomega.GomegaWithT) func() {
+	service := &osbv1alpha1.SFService{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "service-id",
+			Namespace: "default",
+			Labels: map[string]string{
+				"serviceId": "service-id",
+			},
+		},
+		Spec: osbv1alpha1.SFServiceSpec{
+			Name:        "service-name",
+			ID:          "service-id",
+			Description: "description",
+			Bindable:    true,
+			Metadata:    &runtime.RawExtension{Raw: []byte(`{}`)},
+		},
+	}
+	plan := &osbv1alpha1.SFPlan{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "plan-id",
+			Namespace: "default",
+			Labels: map[string]string{
+				"serviceId": "service-id",
+				"planId":    "plan-id",
+			},
+		},
+		Spec: osbv1alpha1.SFPlanSpec{
+			Name:        "plan-name",
+			ID:          "plan-id",
+			Description: "description",
+			Free:        false,
+			Bindable:    true,
+			Metadata:    &runtime.RawExtension{Raw: []byte(`{}`)},
+			Manager:     &runtime.RawExtension{Raw: []byte(`{}`)},
+			Schemas: &osbv1alpha1.ServiceSchemas{
+				Instance: osbv1alpha1.ServiceInstanceSchema{
+					Create: osbv1alpha1.Schema{
+						Parameters: &runtime.RawExtension{
+							Raw: []byte(`{"type": "object", "required": ["size"],
+								"properties": {"size": {"type": "string", "enum": ["small", "large"]}}}`),
+						},
+					},
+					Update: osbv1alpha1.Schema{
+						Parameters: &runtime.RawExtension{
+							Raw: []byte(`{"type": "object", "additionalProperties": false,
+								"properties": {"size": {"type": "string", "enum": ["large"]}}}`),
+						},
+					},
+				},
+				Binding: osbv1alpha1.ServiceBindingSchema{
+					Create: osbv1alpha1.Schema{
+						Parameters: &runtime.RawExtension{
+							Raw: []byte(`{"type": "object",
+								"properties": {"user": {"type": "string", "pattern": "^[a-z]+$"}}}`),
+						},
+					},
+				},
+			},
+			Templates:  []osbv1alpha1.TemplateSpec{},
+			ServiceID:  "service-id",
+			RawContext: &runtime.RawExtension{Raw: []byte(`{}`)},
+		},
+	}
+	g.Expect(c.Create(context.TODO(), service)).NotTo(gomega.HaveOccurred())
+	g.Expect(c.Create(context.TODO(), plan)).NotTo(gomega.HaveOccurred())
+	return func() {
+		g.Expect(c.Delete(context.TODO(), plan)).NotTo(gomega.HaveOccurred())
+		g.Expect(c.Delete(context.TODO(), service)).NotTo(gomega.HaveOccurred())
+	}
+}
+
+func admissionRequest(g *gomega.GomegaWithT, operation admissionv1beta1.Operation, obj, oldObj runtime.Object) admission.Request {
+	req := admission.Request{
+		AdmissionRequest: admissionv1beta1.AdmissionRequest{
+			Operation: operation,
+		},
+	}
+	raw, err := json.Marshal(obj)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	req.Object = runtime.RawExtension{Raw: raw}
+	if oldObj != nil {
+		raw, err = json.Marshal(oldObj)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		req.OldObject = runtime.RawExtension{Raw: raw}
+	}
+	return req
+}
+
+func causes(resp admission.Response) []string {
+	fields := []string{}
+	if resp.Result == nil || resp.Result.Details == nil {
+		return fields
+	}
+	for _, cause := range resp.Result.Details.Causes {
+		fields = append(fields, cause.Field)
+	}
+	return fields
+}
+
+func TestSFServiceInstanceValidator_Handle(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	defer setupPlan(g)()
+
+	newInstance := func(planID, params string) *osbv1alpha1.SFServiceInstance {
+		instance := &osbv1alpha1.SFServiceInstance{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "instance-id",
+				Namespace: "default",
+			},
+			Spec: osbv1alpha1.SFServiceInstanceSpec{
+				ServiceID: "service-id",
+				PlanID:    planID,
+			},
+		}
+		if params != "" {
+			instance.Spec.RawParameters = &runtime.RawExtension{Raw: []byte(params)}
+		}
+		return instance
+	}
+
+	validator := &SFServiceInstanceValidator{}
+	g.Expect(validator.InjectClient(c)).NotTo(gomega.HaveOccurred())
+	g.Expect(validator.InjectDecoder(decoder)).NotTo(gomega.HaveOccurred())
+
+	tests := []struct {
+		name      string
+		operation admissionv1beta1.Operation
+		instance  *osbv1alpha1.SFServiceInstance
+		old       *osbv1alpha1.SFServiceInstance
+		allowed   bool
+		causes    []string
+	}{
+		{
+			name:      "allow valid parameters on create",
+			operation: admissionv1beta1.Create,
+			instance:  newInstance("plan-id", `{"size": "small"}`),
+			allowed:   true,
+			causes:    []string{},
+		},
+		{
+			name:      "deny missing parameters on create",
+			operation: admissionv1beta1.Create,
+			instance:  newInstance("plan-id", ""),
+			allowed:   false,
+			causes:    []string{"spec.parameters.size"},
+		},
+		{
+			name:      "deny unknown plan on create",
+			operation: admissionv1beta1.Create,
+			instance:  newInstance("unknown-plan-id", `{"size": "small"}`),
+			allowed:   false,
+			causes:    []string{"spec.planId"},
+		},
+		{
+			name:      "validate against the update schema on update",
+			operation: admissionv1beta1.Update,
+			instance:  newInstance("plan-id", `{"size": "small"}`),
+			old:       newInstance("plan-id", `{"size": "large"}`),
+			allowed:   false,
+			causes:    []string{"spec.parameters.size"},
+		},
+		{
+			name:      "allow update if parameters are not changed",
+			operation: admissionv1beta1.Update,
+			instance:  newInstance("plan-id", `{ "size":"small" }`),
+			old:       newInstance("plan-id", `{"size": "small"}`),
+			allowed:   true,
+			causes:    []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+			var old runtime.Object
+			if tt.old != nil {
+				old = tt.old
+			}
+			resp := validator.Handle(context.TODO(), admissionRequest(g, tt.operation, tt.instance, old))
+			g.Expect(resp.Allowed).To(gomega.Equal(tt.allowed))
+			g.Expect(causes(resp)).To(gomega.Equal(tt.causes))
+		})
+	}
+}
+
+func TestSFServiceBindingValidator_Handle(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	defer setupPlan(g)()
+
+	newBinding := func(params string) *osbv1alpha1.SFServiceBinding {
+		binding := &osbv1alpha1.SFServiceBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "binding-id",
+				Namespace: "default",
+			},
+			Spec: osbv1alpha1.SFServiceBindingSpec{
+				ID:         "binding-id",
+				InstanceID: "instance-id",
+				ServiceID:  "service-id",
+				PlanID:     "plan-id",
+			},
+		}
+		if params != "" {
+			binding.Spec.RawParameters = &runtime.RawExtension{Raw: []byte(params)}
+		}
+		return binding
+	}
+
+	validator := &SFServiceBindingValidator{}
+	g.Expect(validator.InjectClient(c)).NotTo(gomega.HaveOccurred())
+	g.Expect(validator.InjectDecoder(decoder)).NotTo(gomega.HaveOccurred())
+
+	tests := []struct {
+		name      string
+		operation admissionv1beta1.Operation
+		binding   *osbv1alpha1.SFServiceBinding
+		old       *osbv1alpha1.SFServiceBinding
+		allowed   bool
+		causes    []string
+	}{
+		{
+			name:      "allow valid parameters on create",
+			operation: admissionv1beta1.Create,
+			binding:   newBinding(`{"user": "admin"}`),
+			allowed:   true,
+			causes:    []string{},
+		},
+		{
+			name:      "deny invalid parameters on create",
+			operation: admissionv1beta1.Create,
+			binding:   newBinding(`{"user": "Admin"}`),
+			allowed:   false,
+			causes:    []string{"spec.parameters.user"},
+		},
+		{
+			name:      "deny invalid parameters on update",
+			operation: admissionv1beta1.Update,
+			binding:   newBinding(`{"user": 1}`),
+			old:       newBinding(`{"user": "admin"}`),
+			allowed:   false,
+			causes:    []string{"spec.parameters.user"},
+		},
+		{
+			name:      "allow delete",
+			operation: admissionv1beta1.Delete,
+			binding:   newBinding(`{"user": 1}`),
+			allowed:   true,
+			causes:    []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+			var old runtime.Object
+			if tt.old != nil {
+				old = tt.old
+			}
+			resp := validator.Handle(context.TODO(), admissionRequest(g, tt.operation, tt.binding, old))
+			g.Expect(resp.Allowed).To(gomega.Equal(tt.allowed))
+			g.Expect(causes(resp)).To(gomega.Equal(tt.causes))
+		})
+	}
+}
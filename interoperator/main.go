@@ -25,6 +25,8 @@ import (
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/controllers/multiclusterdeploy"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/controllers/provisioners"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/controllers/schedulers"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/renderer/gotemplate"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/internal/webhooks"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
 
 	"k8s.io/apimachinery/pkg/runtime"
@@ -51,9 +53,12 @@ func init() {
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
+	var enableWebhooks bool
 	flag.StringVar(&metricsAddr, "metrics-addr", ":9877", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", true,
 		"Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false,
+		"Enable the admission webhooks. The serving certificate is read from /tmp/k8s-webhook-server/serving-certs.")
 	flag.Parse()
 
 	ctrl.SetLogger(zap.Logger(true))
@@ -76,6 +81,10 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Templates are read from ConfigMaps without a cache, a cached client
+	// would watch every ConfigMap of the cluster
+	gotemplate.InitTemplateLoader(mgr.GetAPIReader())
+
 	if err = provisioners.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create provisioners")
 		os.Exit(1)
@@ -90,6 +99,13 @@ func main() {
 		setupLog.Error(err, "unable to create schedulers")
 		os.Exit(1)
 	}
+
+	if enableWebhooks {
+		if err = webhooks.SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhooks")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	setupLog.Info("starting manager")
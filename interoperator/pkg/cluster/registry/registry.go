@@ -2,12 +2,16 @@ package registry
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"sync"
 
 	resourceV1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/resource/v1alpha1"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/constants"
 	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
 
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
 	apiErrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -15,11 +19,29 @@ import (
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	kubernetes "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 )
 
 var log = logf.Log.WithName("cluster.registry")
 
+var (
+	clientCacheHits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "interoperator_cluster_client_cache_hits_total",
+		Help: "Number of times a cached client was reused for a cluster",
+	}, []string{"cluster"})
+
+	clientCacheMisses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "interoperator_cluster_client_cache_misses_total",
+		Help: "Number of times a new client was created for a cluster",
+	}, []string{"cluster"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(clientCacheHits, clientCacheMisses)
+}
+
 // ClusterRegistry keep track of clusters and gets client for them
 //go:generate mockgen -source registry.go -destination ./mock_registry/mock_registry.go
 type ClusterRegistry interface {
@@ -29,15 +51,42 @@ type ClusterRegistry interface {
 }
 
 type clusterRegistry struct {
-	scheme     *runtime.Scheme
-	mapper     meta.RESTMapper
-	kubeConfig *rest.Config
-	c          kubernetes.Client
-	namespace  string
+	scheme       *runtime.Scheme
+	mapper       meta.RESTMapper
+	kubeConfig   *rest.Config
+	c            kubernetes.Client
+	reader       kubernetes.Reader
+	secretReader kubernetes.Reader
+	namespace    string
+
+	mux     sync.Mutex // Locking clients map
+	clients map[string]*cachedClient
 }
 
+// cachedClient is a client for a cluster along with the secretRef of the
+// SFCluster and the resourceVersion of the secret used to create it
+type cachedClient struct {
+	client                kubernetes.Client
+	secretRef             string
+	secretResourceVersion string
+}
+
+var (
+	sharedMux sync.Mutex // Locking shared map
+	shared    = make(map[manager.Manager]ClusterRegistry)
+)
+
 // New returns a new ClusterRegistry using the provided manager
 func New(kubeConfig *rest.Config, scheme *runtime.Scheme, mapper meta.RESTMapper) (ClusterRegistry, error) {
+	return NewWithReader(kubeConfig, scheme, mapper, nil, nil)
+}
+
+// NewWithReader returns a new ClusterRegistry which reads the SFClusters
+// with reader and their kubeconfig secrets with secretReader in GetClient.
+// reader is usually the informer cache of the manager and secretReader its
+// api reader, as a cache would watch every Secret of the cluster. The api
+// server is read directly if a reader is nil.
+func NewWithReader(kubeConfig *rest.Config, scheme *runtime.Scheme, mapper meta.RESTMapper, reader kubernetes.Reader, secretReader kubernetes.Reader) (ClusterRegistry, error) {
 	if kubeConfig == nil {
 		return nil, errors.NewInputError("New ClusterRegistry", "kubeConfig", nil)
 	}
@@ -59,16 +108,43 @@ func New(kubeConfig *rest.Config, scheme *runtime.Scheme, mapper meta.RESTMapper
 		sfNamespace = constants.DefaultServiceFabrikNamespace
 	}
 
+	if reader == nil {
+		reader = c
+	}
+	if secretReader == nil {
+		secretReader = c
+	}
+
 	r := &clusterRegistry{
-		scheme:     scheme,
-		mapper:     mapper,
-		kubeConfig: kubeConfig,
-		c:          c,
-		namespace:  sfNamespace,
+		scheme:       scheme,
+		mapper:       mapper,
+		kubeConfig:   kubeConfig,
+		c:            c,
+		reader:       reader,
+		secretReader: secretReader,
+		namespace:    sfNamespace,
+		clients:      make(map[string]*cachedClient),
 	}
 	return r, nil
 }
 
+// ForManager returns the ClusterRegistry shared by all controllers of the
+// manager, so that each cluster gets a single client. The SFClusters are
+// read from the cache of the manager and their secrets from the api server.
+func ForManager(mgr manager.Manager) (ClusterRegistry, error) {
+	sharedMux.Lock()
+	defer sharedMux.Unlock()
+	if r, ok := shared[mgr]; ok {
+		return r, nil
+	}
+	r, err := NewWithReader(mgr.GetConfig(), mgr.GetScheme(), mgr.GetRESTMapper(), mgr.GetCache(), mgr.GetAPIReader())
+	if err != nil {
+		return nil, err
+	}
+	shared[mgr] = r
+	return r, nil
+}
+
 func (r *clusterRegistry) createClient(cfg *rest.Config) (kubernetes.Client, error) {
 	c, err := client.New(cfg, client.Options{
 		Scheme: r.scheme,
@@ -80,13 +156,46 @@ func (r *clusterRegistry) createClient(cfg *rest.Config) (kubernetes.Client, err
 	return c, nil
 }
 
-// GetClient returns a kubernetes client for a cluster
+// GetClient returns a kubernetes client for a cluster. The client is
+// cached and reused till the secretRef of the SFCluster or the secret is
+// changed. Status and metadata updates of the SFCluster keep the client.
 func (r *clusterRegistry) GetClient(clusterID string) (kubernetes.Client, error) {
-	cluster, err := r.GetCluster(clusterID)
+	cluster, err := r.readCluster(r.reader, clusterID)
+	if err != nil {
+		if errors.SFClusterNotFound(err) {
+			r.removeCachedClient(clusterID)
+		}
+		return nil, err
+	}
+
+	secret := &corev1.Secret{}
+	var secretKey = types.NamespacedName{
+		Name:      cluster.Spec.SecretRef,
+		Namespace: cluster.GetNamespace(),
+	}
+	err = r.secretReader.Get(context.TODO(), secretKey, secret)
 	if err != nil {
+		log.Error(err, "unable to get kubeconfig secret", "clusterID", clusterID)
+		if apiErrors.IsNotFound(err) {
+			r.removeCachedClient(clusterID)
+			return nil, errors.NewClusterRegistryError(fmt.Sprintf(
+				"secret %s not found for cluster %s",
+				cluster.Spec.SecretRef, clusterID), err)
+		}
 		return nil, err
 	}
-	cfg, err := cluster.GetKubeConfig(r.c)
+
+	r.mux.Lock()
+	cached, ok := r.clients[clusterID]
+	r.mux.Unlock()
+	if ok && cached.secretRef == cluster.Spec.SecretRef &&
+		cached.secretResourceVersion == secret.GetResourceVersion() {
+		clientCacheHits.WithLabelValues(clusterID).Inc()
+		return cached.client, nil
+	}
+	clientCacheMisses.WithLabelValues(clusterID).Inc()
+
+	cfg, err := cluster.GetKubeConfig(r.secretReader)
 	if err != nil {
 		log.Error(err, "unable to get kubeconfig", "clusterID", clusterID)
 		return nil, err
@@ -97,17 +206,40 @@ func (r *clusterRegistry) GetClient(clusterID string) (kubernetes.Client, error)
 		log.Error(err, "unable to create k8s client", "clusterID", clusterID)
 		return nil, err
 	}
+
+	r.mux.Lock()
+	r.clients[clusterID] = &cachedClient{
+		client:                c,
+		secretRef:             cluster.Spec.SecretRef,
+		secretResourceVersion: secret.GetResourceVersion(),
+	}
+	r.mux.Unlock()
+	log.V(1).Info("created new client", "clusterID", clusterID)
 	return c, nil
 }
 
+func (r *clusterRegistry) removeCachedClient(clusterID string) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	delete(r.clients, clusterID)
+}
+
 // GetCluster returns a cluster detail
 func (r *clusterRegistry) GetCluster(clusterID string) (resourceV1alpha1.SFClusterInterface, error) {
+	return r.getCluster(clusterID)
+}
+
+func (r *clusterRegistry) getCluster(clusterID string) (*resourceV1alpha1.SFCluster, error) {
+	return r.readCluster(r.c, clusterID)
+}
+
+func (r *clusterRegistry) readCluster(reader kubernetes.Reader, clusterID string) (*resourceV1alpha1.SFCluster, error) {
 	cluster := &resourceV1alpha1.SFCluster{}
 	var clusterKey = types.NamespacedName{
 		Name:      clusterID,
 		Namespace: r.namespace,
 	}
-	err := r.c.Get(context.TODO(), clusterKey, cluster)
+	err := reader.Get(context.TODO(), clusterKey, cluster)
 	if err != nil {
 		if apiErrors.IsNotFound(err) {
 			return nil, errors.NewSFClusterNotFound(clusterID, err)
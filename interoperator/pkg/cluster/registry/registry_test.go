@@ -16,6 +16,8 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/rest"
 	kubernetes "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
 func TestNew(t *testing.T) {
@@ -152,6 +154,139 @@ func Test_clusterRegistry_GetClient(t *testing.T) {
 	}
 }
 
+func Test_clusterRegistry_GetClientCached(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	cluster := _getDummyCluster()
+	secret := _getDummySecret()
+	cluster.Spec.SecretRef = secret.GetName()
+	r, err := New(kubeConfig, sch, mapper)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	g.Expect(c.Create(context.TODO(), cluster)).NotTo(gomega.HaveOccurred())
+	g.Expect(c.Create(context.TODO(), secret)).NotTo(gomega.HaveOccurred())
+	defer func() {
+		g.Expect(c.Delete(context.TODO(), secret)).NotTo(gomega.HaveOccurred())
+		secret.SetResourceVersion("")
+	}()
+
+	client1, err := r.GetClient("cluster-id")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	// Client is reused if cluster and secret are not changed
+	client2, err := r.GetClient("cluster-id")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(client2).To(gomega.BeIdenticalTo(client1))
+
+	// Client is recreated if secret is changed
+	secret.Data["foo"] = []byte("bar")
+	g.Expect(c.Update(context.TODO(), secret)).NotTo(gomega.HaveOccurred())
+	client3, err := r.GetClient("cluster-id")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(client3).NotTo(gomega.BeIdenticalTo(client1))
+
+	// Client is reused if only the metadata of the cluster is changed
+	cluster.SetLabels(map[string]string{"foo": "bar"})
+	g.Expect(c.Update(context.TODO(), cluster)).NotTo(gomega.HaveOccurred())
+	client4, err := r.GetClient("cluster-id")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(client4).To(gomega.BeIdenticalTo(client3))
+
+	// Client is recreated if the secretRef of the cluster is changed
+	secret2 := _getDummySecret()
+	secret2.SetName("cluster-id-2")
+	g.Expect(c.Create(context.TODO(), secret2)).NotTo(gomega.HaveOccurred())
+	defer func() {
+		g.Expect(c.Delete(context.TODO(), secret2)).NotTo(gomega.HaveOccurred())
+	}()
+	cluster.Spec.SecretRef = secret2.GetName()
+	g.Expect(c.Update(context.TODO(), cluster)).NotTo(gomega.HaveOccurred())
+	client5, err := r.GetClient("cluster-id")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(client5).NotTo(gomega.BeIdenticalTo(client4))
+
+	// Cached client is dropped if cluster is deleted
+	g.Expect(c.Delete(context.TODO(), cluster)).NotTo(gomega.HaveOccurred())
+	cluster.SetResourceVersion("")
+	_, err = r.GetClient("cluster-id")
+	g.Expect(err).To(gomega.HaveOccurred())
+	g.Expect(r.(*clusterRegistry).clients).NotTo(gomega.HaveKey("cluster-id"))
+}
+
+func Test_clusterRegistry_GetClientWithReader(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	host := "https://localhost:6443"
+	cluster := _getDummyCluster()
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cluster-id",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"kubeconfig": _getDummyKubeConfig(host),
+		},
+	}
+	cluster.Spec.SecretRef = secret.GetName()
+
+	testScheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(testScheme)).To(gomega.Succeed())
+	g.Expect(resourceV1alpha1.AddToScheme(testScheme)).To(gomega.Succeed())
+	reader := fake.NewFakeClientWithScheme(testScheme, cluster)
+	secretReader := fake.NewFakeClientWithScheme(testScheme, secret)
+
+	r, err := NewWithReader(&rest.Config{Host: host}, testScheme, meta.NewDefaultRESTMapper(nil), reader, secretReader)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	// The cluster is read with the reader and its secret with the secret
+	// reader
+	client1, err := r.GetClient("cluster-id")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(client1).NotTo(gomega.BeNil())
+
+	// Client is reused if the status of the cluster is changed
+	cluster.Status.InstancesCount = 5
+	g.Expect(reader.Update(context.TODO(), cluster)).NotTo(gomega.HaveOccurred())
+	client2, err := r.GetClient("cluster-id")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(client2).To(gomega.BeIdenticalTo(client1))
+
+	// Cached client is dropped if the secret is deleted
+	g.Expect(secretReader.Delete(context.TODO(), secret)).NotTo(gomega.HaveOccurred())
+	_, err = r.GetClient("cluster-id")
+	g.Expect(err).To(gomega.HaveOccurred())
+	g.Expect(r.(*clusterRegistry).clients).NotTo(gomega.HaveKey("cluster-id"))
+}
+
+func TestForManager(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	testScheme := runtime.NewScheme()
+	g.Expect(resourceV1alpha1.AddToScheme(testScheme)).To(gomega.Succeed())
+	newManager := func() manager.Manager {
+		mgr, err := manager.New(&rest.Config{Host: "https://localhost:6443"}, manager.Options{
+			Scheme:             testScheme,
+			MetricsBindAddress: "0",
+			MapperProvider: func(*rest.Config) (meta.RESTMapper, error) {
+				return meta.NewDefaultRESTMapper(nil), nil
+			},
+		})
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		return mgr
+	}
+	mgr := newManager()
+
+	// The controllers of a manager share the registry and its clients
+	r1, err := ForManager(mgr)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	r2, err := ForManager(mgr)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(r2).To(gomega.BeIdenticalTo(r1))
+	g.Expect(r1.(*clusterRegistry).reader).To(gomega.Equal(mgr.GetCache()))
+	g.Expect(r1.(*clusterRegistry).secretReader).To(gomega.Equal(mgr.GetAPIReader()))
+
+	r3, err := ForManager(newManager())
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(r3).NotTo(gomega.BeIdenticalTo(r1))
+}
+
 func Test_clusterRegistry_GetCluster(t *testing.T) {
 	g := gomega.NewGomegaWithT(t)
 	cluster := _getDummyCluster()
@@ -9,14 +9,29 @@ const (
 	FinalizerName    = "interoperator.servicefabrik.io"
 	ErrorCountKey    = "interoperator.servicefabrik.io/error"
 	LastOperationKey = "interoperator.servicefabrik.io/lastoperation"
+	PruneKey         = "interoperator.servicefabrik.io/prune"
 	ErrorThreshold   = 10
 
+	RoundRobinConfigMapName = "interoperator-round-robin"
+
+	OrderKey          = "interoperator.servicefabrik.io/order"
+	WaitKey           = "interoperator.servicefabrik.io/wait"
+	HelmHookWeightKey = "helm.sh/hook-weight"
+	RotateKey         = "interoperator.servicefabrik.io/rotate"
+	OperationIDKey    = "interoperator.servicefabrik.io/operationid"
+	DeleteRequestKey  = "interoperator.servicefabrik.io/deleterequested"
+
 	ConfigMapName          = "interoperator-config"
 	ConfigMapKey           = "config"
 	NamespaceEnvKey        = "POD_NAMESPACE"
 	OwnClusterIDEnvKey     = "CLUSTER_ID"
+	RandSeedEnvKey         = "RAND_SEED"
+	KustomizeBinaryEnvKey  = "KUSTOMIZE_BINARY"
+	VaultTokenEnvKey       = "VAULT_TOKEN"
+	TemplateRootEnvKey     = "TEMPLATE_ROOT"
 	DefaultMasterClusterID = "1"
 	ProvisionerName        = "provisioner"
+	FieldManager           = "interoperator"
 
 	MultiClusterWatchTimeout = 28800 // 8 hours in seconds
 
@@ -26,11 +41,48 @@ const (
 	DefaultSchedulerWorkerCount   = 10
 	DefaultProvisionerWorkerCount = 10
 
+	DefaultClusterReconcileInterval = "5m"
+	DefaultBindingRotationOverlap   = "1h"
+	DefaultBindingOperationTimeout  = "30m"
+
 	DefaultSchedulerType       = "default"
 	RoundRobinSchedulerType    = "round-robin"
 	LeastUtilizedSchedulerType = "least-utilized"
 	LabelSelectorSchedulerType = "label-selector"
+	ResourceAwareSchedulerType = "resource-aware"
+	FrameworkSchedulerType     = "framework"
 	GoTemplateType             = "gotemplate"
+	KustomizeType              = "kustomize"
+	DefaultKustomizeBinary     = "kustomize"
+	JsonnetType                = "jsonnet"
+	CueType                    = "cue"
+	KubernetesSecretStoreType  = "kubernetes"
+	VaultSecretStoreType       = "vault"
+	DefaultSecretStoreType     = KubernetesSecretStoreType
+	DefaultVaultMount          = "secret"
+	DefaultVaultPathPrefix     = "interoperator"
+	DefaultTemplateRoot        = "/templates"
 
-	PlanWatchDrainTimeout = time.Second * 2
+	MaxTemplateSize = 10 << 20  // 10 MiB
+	MaxChartSize    = 100 << 20 // 100 MiB
+
+	PlanWatchDrainTimeout  = time.Second * 2
+	ClusterProbeTimeout    = time.Second * 10
+	MigrationPollInterval  = time.Second * 10
+	RendererCommandTimeout = time.Minute * 2
+	RendererFetchTimeout   = time.Minute * 2
+	ResourceWaitTimeout    = time.Minute * 2
+	ResourceWaitInterval   = time.Second * 2
+	SecretStoreTimeout     = time.Second * 10
+	KeyProviderTimeout     = time.Second * 10
 )
+
+// SFCrdNames is the list of the service fabrik CRDs registered
+// by interoperator in the member clusters
+var SFCrdNames = []string{
+	"sfplans.osb.servicefabrik.io",
+	"sfservices.osb.servicefabrik.io",
+	"sfserviceinstances.osb.servicefabrik.io",
+	"sfservicebindings.osb.servicefabrik.io",
+	"sfclusters.resource.servicefabrik.io",
+}
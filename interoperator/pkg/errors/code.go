@@ -8,11 +8,18 @@ const (
 	CodeSFServiceBindingNotFound  = "SFServiceBindingNotFound"
 	CodeSFClusterNotFound         = "SFClusterNotFound"
 	CodeTemplateNotFound          = "TemplateNotFound"
+	CodeSecretNotFound            = "SecretNotFound"
 	CodeSchedulerFailed           = "CodeSchedulerFailed"
 
 	CodeOperationInProgress = "OperationInProgress"
-
-	CodeRendererError = "RendererError"
+	CodeOperationTimeout    = "OperationTimeout"
+
+	CodeRendererError    = "RendererError"
+	CodeApplyConflict    = "ApplyConflict"
+	CodeResourceNotReady = "ResourceNotReady"
+	CodeWaitTimeout      = "WaitTimeout"
+	CodeSecretStoreError = "SecretStoreError"
+	CodeEncryptionError  = "EncryptionError"
 
 	CodeClusterRegistryError = "ClusterRegistryError"
 	CodeClusterIDNotSet      = "ClusterIDNotSet"
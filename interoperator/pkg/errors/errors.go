@@ -1,6 +1,9 @@
 package errors
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // InteroperatorError generic error implementation used by interoperator
 type InteroperatorError struct {
@@ -248,3 +251,108 @@ func NewSchedulerFailed(schedulerType, message string, err error) *Interoperator
 func SchedulerFailed(err error) bool {
 	return ErrorCode(err) == CodeSchedulerFailed
 }
+
+// NewApplyConflict returns a new error which indicates that applying a
+// resource conflicts with fields managed by other controllers
+func NewApplyConflict(kind, name, message string, err error) *InteroperatorError {
+	return &InteroperatorError{
+		Err:     err,
+		Code:    CodeApplyConflict,
+		Message: fmt.Sprintf("apply of %s %s conflicts with fields managed by other controllers: %s", kind, name, message),
+	}
+}
+
+// ApplyConflict is true if the error indicates an ApplyConflict.
+func ApplyConflict(err error) bool {
+	return ErrorCode(err) == CodeApplyConflict
+}
+
+// NewResourceNotReady returns a new error which indicates that a resource
+// is not ready or not deleted yet and is waited for
+func NewResourceNotReady(kind, name, message string, err error) *InteroperatorError {
+	return &InteroperatorError{
+		Err:     err,
+		Code:    CodeResourceNotReady,
+		Message: fmt.Sprintf("%s %s not ready: %s", kind, name, message),
+	}
+}
+
+// ResourceNotReady is true if the error indicates a ResourceNotReady.
+func ResourceNotReady(err error) bool {
+	return ErrorCode(err) == CodeResourceNotReady
+}
+
+// NewWaitTimeout returns a new error which indicates that a resource
+// did not become ready or was not deleted in time
+func NewWaitTimeout(kind, name string, timeout time.Duration, err error) *InteroperatorError {
+	return &InteroperatorError{
+		Err:     err,
+		Code:    CodeWaitTimeout,
+		Message: fmt.Sprintf("%s %s not ready within %s", kind, name, timeout),
+	}
+}
+
+// WaitTimeout is true if the error indicates a WaitTimeout.
+func WaitTimeout(err error) bool {
+	return ErrorCode(err) == CodeWaitTimeout
+}
+
+// NewSecretNotFound returns a new error which indicates that a secret is
+// not found in the secret store
+func NewSecretNotFound(name string, err error) *InteroperatorError {
+	return &InteroperatorError{
+		Err:     err,
+		Code:    CodeSecretNotFound,
+		Message: fmt.Sprintf("Secret %s not found", name),
+	}
+}
+
+// SecretNotFound is true if the error indicates the requested secret is not found.
+func SecretNotFound(err error) bool {
+	return ErrorCode(err) == CodeSecretNotFound
+}
+
+// NewSecretStoreError returns a new error which indicates that a request
+// to the secret store failed
+func NewSecretStoreError(message string, err error) *InteroperatorError {
+	return &InteroperatorError{
+		Err:     err,
+		Code:    CodeSecretStoreError,
+		Message: message,
+	}
+}
+
+// SecretStoreError is true if the error indicates a SecretStoreError.
+func SecretStoreError(err error) bool {
+	return ErrorCode(err) == CodeSecretStoreError
+}
+
+// NewEncryptionError returns a new error which indicates that encrypting
+// or decrypting credentials failed
+func NewEncryptionError(message string, err error) *InteroperatorError {
+	return &InteroperatorError{
+		Err:     err,
+		Code:    CodeEncryptionError,
+		Message: message,
+	}
+}
+
+// EncryptionError is true if the error indicates an EncryptionError.
+func EncryptionError(err error) bool {
+	return ErrorCode(err) == CodeEncryptionError
+}
+
+// NewOperationTimeout returns a new error which indicates that an
+// operation did not complete within its timeout
+func NewOperationTimeout(operation string, timeout time.Duration, err error) *InteroperatorError {
+	return &InteroperatorError{
+		Err:     err,
+		Code:    CodeOperationTimeout,
+		Message: fmt.Sprintf("%s operation timed out after %s", operation, timeout),
+	}
+}
+
+// OperationTimeout is true if the error indicates an OperationTimeout.
+func OperationTimeout(err error) bool {
+	return ErrorCode(err) == CodeOperationTimeout
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 )
 
 var message = "some message"
@@ -966,3 +967,472 @@ func TestInputError(t *testing.T) {
 		})
 	}
 }
+
+func TestNewApplyConflict(t *testing.T) {
+	type args struct {
+		kind    string
+		name    string
+		message string
+		err     error
+	}
+	tests := []struct {
+		name string
+		args args
+		want *InteroperatorError
+	}{
+		{
+			name: "return ApplyConflict",
+			args: args{
+				kind:    "Deployment",
+				name:    name,
+				message: message,
+				err:     nil,
+			},
+			want: &InteroperatorError{
+				Err:     nil,
+				Code:    CodeApplyConflict,
+				Message: fmt.Sprintf("apply of Deployment %s conflicts with fields managed by other controllers: %s", name, message),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewApplyConflict(tt.args.kind, tt.args.name, tt.args.message, tt.args.err); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewApplyConflict() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyConflict(t *testing.T) {
+	type args struct {
+		err error
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{
+			name: "return true if ApplyConflict",
+			args: args{
+				err: NewApplyConflict("Deployment", name, message, nil),
+			},
+			want: true,
+		},
+		{
+			name: "return false if not ApplyConflict",
+			args: args{
+				err: NewInputError(name, message, nil),
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ApplyConflict(tt.args.err); got != tt.want {
+				t.Errorf("ApplyConflict() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewResourceNotReady(t *testing.T) {
+	type args struct {
+		kind    string
+		name    string
+		message string
+		err     error
+	}
+	tests := []struct {
+		name string
+		args args
+		want *InteroperatorError
+	}{
+		{
+			name: "return ResourceNotReady",
+			args: args{
+				kind:    "StatefulSet",
+				name:    name,
+				message: message,
+				err:     nil,
+			},
+			want: &InteroperatorError{
+				Err:     nil,
+				Code:    CodeResourceNotReady,
+				Message: fmt.Sprintf("StatefulSet %s not ready: %s", name, message),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewResourceNotReady(tt.args.kind, tt.args.name, tt.args.message, tt.args.err); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewResourceNotReady() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResourceNotReady(t *testing.T) {
+	type args struct {
+		err error
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{
+			name: "return true if ResourceNotReady",
+			args: args{
+				err: NewResourceNotReady("StatefulSet", name, message, nil),
+			},
+			want: true,
+		},
+		{
+			name: "return false if not ResourceNotReady",
+			args: args{
+				err: NewInputError(name, message, nil),
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResourceNotReady(tt.args.err); got != tt.want {
+				t.Errorf("ResourceNotReady() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewWaitTimeout(t *testing.T) {
+	type args struct {
+		kind    string
+		name    string
+		timeout time.Duration
+		err     error
+	}
+	tests := []struct {
+		name string
+		args args
+		want *InteroperatorError
+	}{
+		{
+			name: "return WaitTimeout",
+			args: args{
+				kind:    "StatefulSet",
+				name:    name,
+				timeout: 2 * time.Minute,
+				err:     nil,
+			},
+			want: &InteroperatorError{
+				Err:     nil,
+				Code:    CodeWaitTimeout,
+				Message: fmt.Sprintf("StatefulSet %s not ready within 2m0s", name),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewWaitTimeout(tt.args.kind, tt.args.name, tt.args.timeout, tt.args.err); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewWaitTimeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	type args struct {
+		err error
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{
+			name: "return true if WaitTimeout",
+			args: args{
+				err: NewWaitTimeout("StatefulSet", name, time.Minute, nil),
+			},
+			want: true,
+		},
+		{
+			name: "return false if not WaitTimeout",
+			args: args{
+				err: NewResourceNotReady("StatefulSet", name, message, nil),
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WaitTimeout(tt.args.err); got != tt.want {
+				t.Errorf("WaitTimeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSecretNotFound(t *testing.T) {
+	type args struct {
+		name string
+		err  error
+	}
+	tests := []struct {
+		name string
+		args args
+		want *InteroperatorError
+	}{
+		{
+			name: "return SecretNotFound",
+			args: args{
+				name: name,
+				err:  nil,
+			},
+			want: &InteroperatorError{
+				Err:     nil,
+				Code:    CodeSecretNotFound,
+				Message: fmt.Sprintf("Secret %s not found", name),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewSecretNotFound(tt.args.name, tt.args.err); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewSecretNotFound() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecretNotFound(t *testing.T) {
+	type args struct {
+		err error
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{
+			name: "return true if SecretNotFound",
+			args: args{
+				err: NewSecretNotFound(name, nil),
+			},
+			want: true,
+		},
+		{
+			name: "return false if not SecretNotFound",
+			args: args{
+				err: NewSFServiceBindingNotFound(name, nil),
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SecretNotFound(tt.args.err); got != tt.want {
+				t.Errorf("SecretNotFound() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSecretStoreError(t *testing.T) {
+	type args struct {
+		message string
+		err     error
+	}
+	tests := []struct {
+		name string
+		args args
+		want *InteroperatorError
+	}{
+		{
+			name: "return SecretStoreError",
+			args: args{
+				message: message,
+				err:     nil,
+			},
+			want: &InteroperatorError{
+				Err:     nil,
+				Code:    CodeSecretStoreError,
+				Message: message,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewSecretStoreError(tt.args.message, tt.args.err); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewSecretStoreError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecretStoreError(t *testing.T) {
+	type args struct {
+		err error
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{
+			name: "return true if SecretStoreError",
+			args: args{
+				err: NewSecretStoreError(message, nil),
+			},
+			want: true,
+		},
+		{
+			name: "return false if not SecretStoreError",
+			args: args{
+				err: NewInputError(name, message, nil),
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SecretStoreError(tt.args.err); got != tt.want {
+				t.Errorf("SecretStoreError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewEncryptionError(t *testing.T) {
+	type args struct {
+		message string
+		err     error
+	}
+	tests := []struct {
+		name string
+		args args
+		want *InteroperatorError
+	}{
+		{
+			name: "return EncryptionError",
+			args: args{
+				message: message,
+				err:     nil,
+			},
+			want: &InteroperatorError{
+				Err:     nil,
+				Code:    CodeEncryptionError,
+				Message: message,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewEncryptionError(tt.args.message, tt.args.err); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewEncryptionError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncryptionError(t *testing.T) {
+	type args struct {
+		err error
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{
+			name: "return true if EncryptionError",
+			args: args{
+				err: NewEncryptionError(message, nil),
+			},
+			want: true,
+		},
+		{
+			name: "return false if not EncryptionError",
+			args: args{
+				err: NewSecretStoreError(message, nil),
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EncryptionError(tt.args.err); got != tt.want {
+				t.Errorf("EncryptionError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewOperationTimeout(t *testing.T) {
+	type args struct {
+		operation string
+		timeout   time.Duration
+		err       error
+	}
+	tests := []struct {
+		name string
+		args args
+		want *InteroperatorError
+	}{
+		{
+			name: "return OperationTimeout",
+			args: args{
+				operation: "bind",
+				timeout:   time.Minute * 30,
+				err:       nil,
+			},
+			want: &InteroperatorError{
+				Err:     nil,
+				Code:    CodeOperationTimeout,
+				Message: "bind operation timed out after 30m0s",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewOperationTimeout(tt.args.operation, tt.args.timeout, tt.args.err); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewOperationTimeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOperationTimeout(t *testing.T) {
+	type args struct {
+		err error
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{
+			name: "return true if OperationTimeout",
+			args: args{
+				err: NewOperationTimeout("bind", time.Minute, nil),
+			},
+			want: true,
+		},
+		{
+			name: "return false if not OperationTimeout",
+			args: args{
+				err: NewOperationInProgress("instance", nil),
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := OperationTimeout(tt.args.err); got != tt.want {
+				t.Errorf("OperationTimeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package watches
+
+import (
+	"sync"
+
+	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var (
+	listenersMux sync.Mutex
+	listeners    []chan struct{}
+)
+
+// GetWatchUpdateChannel returns a channel which receives an event whenever
+// the watch lists in the interoperator config are changed at runtime.
+// Multiple updates which are not yet consumed are coalesced into one event.
+func GetWatchUpdateChannel() <-chan struct{} {
+	listenersMux.Lock()
+	defer listenersMux.Unlock()
+	ch := make(chan struct{}, 1)
+	listeners = append(listeners, ch)
+	return ch
+}
+
+// NotifyWatchUpdate notifies all the listeners that the watch lists
+// in the interoperator config have changed.
+func NotifyWatchUpdate() {
+	listenersMux.Lock()
+	defer listenersMux.Unlock()
+	for _, ch := range listeners {
+		select {
+		case ch <- struct{}{}:
+		default:
+			// An update is already pending for this listener
+		}
+	}
+}
+
+// SubResourceWatcher keeps the watches of a controller on its sub resources
+// in sync with a watch list. It is added to the manager as a Runnable and
+// refreshes the watches on every event from GetWatchUpdateChannel.
+type SubResourceWatcher interface {
+	manager.Runnable
+	Update(watchList []osbv1alpha1.APIVersionKind) error
+}
+
+type subResourceWatcher struct {
+	controller   controller.Controller
+	owner        runtime.Object
+	getWatchList func() []osbv1alpha1.APIVersionKind
+	updates      <-chan struct{}
+
+	mux     sync.RWMutex // Locking watched map
+	watched map[osbv1alpha1.APIVersionKind]bool
+}
+
+// NewSubResourceWatcher returns a SubResourceWatcher for the controller c.
+// Events on the sub resources are mapped to the owner of type owner.
+// getWatchList is called to fetch the current watch list on each update.
+func NewSubResourceWatcher(c controller.Controller, owner runtime.Object, getWatchList func() []osbv1alpha1.APIVersionKind) (SubResourceWatcher, error) {
+	if c == nil {
+		return nil, errors.NewInputError("NewSubResourceWatcher", "controller", nil)
+	}
+	if owner == nil {
+		return nil, errors.NewInputError("NewSubResourceWatcher", "owner", nil)
+	}
+	if getWatchList == nil {
+		return nil, errors.NewInputError("NewSubResourceWatcher", "getWatchList", nil)
+	}
+	return &subResourceWatcher{
+		controller:   c,
+		owner:        owner,
+		getWatchList: getWatchList,
+		updates:      GetWatchUpdateChannel(),
+		watched:      make(map[osbv1alpha1.APIVersionKind]bool),
+	}, nil
+}
+
+// Update starts watches for the kinds in watchList not yet watched and
+// retires the watches for kinds no longer in watchList. Informers can not
+// be stopped once started, so retired watches are only filtered out and
+// are reactivated if the kind is added back to the watch list.
+func (w *subResourceWatcher) Update(watchList []osbv1alpha1.APIVersionKind) error {
+	expected := make(map[osbv1alpha1.APIVersionKind]struct{})
+	for _, gvk := range watchList {
+		expected[gvk] = struct{}{}
+	}
+
+	toWatch := make([]osbv1alpha1.APIVersionKind, 0)
+	w.mux.Lock()
+	for gvk, active := range w.watched {
+		if _, ok := expected[gvk]; !ok && active {
+			w.watched[gvk] = false
+			log.Info("Retired watch on sub resource", "kind", gvk.GetKind(), "apiVersion", gvk.GetAPIVersion())
+		}
+	}
+	for gvk := range expected {
+		active, ok := w.watched[gvk]
+		if !ok {
+			// Mark the kind active before starting the watch, the informer
+			// replays the existing objects as soon as the handler is added
+			w.watched[gvk] = true
+			toWatch = append(toWatch, gvk)
+		} else if !active {
+			w.watched[gvk] = true
+			log.Info("Reactivated watch on sub resource", "kind", gvk.GetKind(), "apiVersion", gvk.GetAPIVersion())
+		}
+	}
+	w.mux.Unlock()
+
+	// Watch waits for the informer to sync once the manager is started.
+	// So do not hold the lock while starting the watches.
+	for i, gvk := range toWatch {
+		object := &unstructured.Unstructured{}
+		object.SetKind(gvk.GetKind())
+		object.SetAPIVersion(gvk.GetAPIVersion())
+		err := w.controller.Watch(&source.Kind{Type: object}, &handler.EnqueueRequestForOwner{
+			OwnerType:    w.owner,
+			IsController: true,
+		}, w.predicate(gvk))
+		if err != nil {
+			log.Error(err, "failed to start watch on sub resource", "kind", gvk.GetKind(), "apiVersion", gvk.GetAPIVersion())
+			// Forget the kinds not watched yet so that their watches are
+			// started on the next update
+			w.mux.Lock()
+			for _, pending := range toWatch[i:] {
+				delete(w.watched, pending)
+			}
+			w.mux.Unlock()
+			return err
+		}
+		log.Info("Started watch on sub resource", "kind", gvk.GetKind(), "apiVersion", gvk.GetAPIVersion())
+	}
+	return nil
+}
+
+// Start refreshes the watches whenever the watch lists are updated
+// till the stop channel is closed.
+func (w *subResourceWatcher) Start(stop <-chan struct{}) error {
+	for {
+		select {
+		case <-w.updates:
+			err := w.Update(w.getWatchList())
+			if err != nil {
+				// Not failing here. Watches will be refreshed on next update
+				log.Error(err, "failed to refresh watches on sub resources")
+			}
+		case <-stop:
+			return nil
+		}
+	}
+}
+
+func (w *subResourceWatcher) isActive(gvk osbv1alpha1.APIVersionKind) bool {
+	w.mux.RLock()
+	defer w.mux.RUnlock()
+	return w.watched[gvk]
+}
+
+func (w *subResourceWatcher) predicate(gvk osbv1alpha1.APIVersionKind) predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc: func(event.CreateEvent) bool {
+			return w.isActive(gvk)
+		},
+		DeleteFunc: func(event.DeleteEvent) bool {
+			return w.isActive(gvk)
+		},
+		UpdateFunc: func(event.UpdateEvent) bool {
+			return w.isActive(gvk)
+		},
+		GenericFunc: func(event.GenericEvent) bool {
+			return w.isActive(gvk)
+		},
+	}
+}
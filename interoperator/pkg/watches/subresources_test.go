@@ -0,0 +1,222 @@
+package watches
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	osbv1alpha1 "github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/api/osb/v1alpha1"
+	"github.com/cloudfoundry-incubator/service-fabrik-broker/interoperator/pkg/errors"
+
+	"github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// fakeController records the watches started on it and whether the
+// create events replayed when a watch starts pass its predicates
+type fakeController struct {
+	mux        sync.Mutex
+	watches    map[string]int
+	predicates map[string][]predicate.Predicate
+	replayed   map[string]bool
+	watchErr   map[string]error
+}
+
+func newFakeController() *fakeController {
+	return &fakeController{
+		watches:    make(map[string]int),
+		predicates: make(map[string][]predicate.Predicate),
+		replayed:   make(map[string]bool),
+		watchErr:   make(map[string]error),
+	}
+}
+
+func (c *fakeController) Reconcile(reconcile.Request) (reconcile.Result, error) {
+	return reconcile.Result{}, nil
+}
+
+func (c *fakeController) Watch(src source.Source, eventhandler handler.EventHandler, predicates ...predicate.Predicate) error {
+	object := src.(*source.Kind).Type.(*unstructured.Unstructured)
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if err := c.watchErr[object.GetKind()]; err != nil {
+		return err
+	}
+	c.watches[object.GetKind()]++
+	c.predicates[object.GetKind()] = predicates
+	replayed := true
+	for _, p := range predicates {
+		replayed = replayed && p.Create(event.CreateEvent{})
+	}
+	c.replayed[object.GetKind()] = replayed
+	return nil
+}
+
+func (c *fakeController) Start(<-chan struct{}) error {
+	return nil
+}
+
+func (c *fakeController) watchCount(kind string) int {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.watches[kind]
+}
+
+// replayedCreate is true if the create events replayed when the watch on
+// kind started passed its predicates
+func (c *fakeController) replayedCreate(kind string) bool {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.replayed[kind]
+}
+
+// accepts is true if the predicates of the watch on kind pass an event
+func (c *fakeController) accepts(kind string) bool {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	predicates, ok := c.predicates[kind]
+	if !ok {
+		return false
+	}
+	for _, p := range predicates {
+		if !p.Create(event.CreateEvent{}) || !p.Update(event.UpdateEvent{}) ||
+			!p.Delete(event.DeleteEvent{}) || !p.Generic(event.GenericEvent{}) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestNotifyWatchUpdate(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	ch1 := GetWatchUpdateChannel()
+	ch2 := GetWatchUpdateChannel()
+
+	NotifyWatchUpdate()
+	NotifyWatchUpdate()
+
+	// Pending updates are coalesced
+	g.Expect(ch1).Should(gomega.Receive())
+	g.Expect(ch1).ShouldNot(gomega.Receive())
+	g.Expect(ch2).Should(gomega.Receive())
+	g.Expect(ch2).ShouldNot(gomega.Receive())
+}
+
+func TestNewSubResourceWatcher(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	getWatchList := func() []osbv1alpha1.APIVersionKind {
+		return nil
+	}
+
+	_, err := NewSubResourceWatcher(nil, &osbv1alpha1.SFServiceInstance{}, getWatchList)
+	g.Expect(err).To(gomega.HaveOccurred())
+	g.Expect(errors.InputError(err)).To(gomega.BeTrue())
+}
+
+func Test_subResourceWatcher_Update(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	deployment := osbv1alpha1.APIVersionKind{APIVersion: "apps/v1", Kind: "Deployment"}
+	configMap := osbv1alpha1.APIVersionKind{APIVersion: "v1", Kind: "ConfigMap"}
+
+	c := newFakeController()
+	getWatchList := func() []osbv1alpha1.APIVersionKind {
+		return nil
+	}
+	w, err := NewSubResourceWatcher(c, &osbv1alpha1.SFServiceInstance{}, getWatchList)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	// A watch is added for a new kind, the existing objects replayed when
+	// the watch starts are not dropped
+	g.Expect(w.Update([]osbv1alpha1.APIVersionKind{deployment})).To(gomega.Succeed())
+	g.Expect(c.watchCount("Deployment")).To(gomega.Equal(1))
+	g.Expect(c.accepts("Deployment")).To(gomega.BeTrue())
+	g.Expect(c.replayedCreate("Deployment")).To(gomega.BeTrue())
+
+	// The watch is retired once no plan renders the kind
+	g.Expect(w.Update([]osbv1alpha1.APIVersionKind{configMap})).To(gomega.Succeed())
+	g.Expect(c.watchCount("ConfigMap")).To(gomega.Equal(1))
+	g.Expect(c.accepts("ConfigMap")).To(gomega.BeTrue())
+	g.Expect(c.accepts("Deployment")).To(gomega.BeFalse())
+
+	// The retired watch is reactivated without starting a new watch
+	g.Expect(w.Update([]osbv1alpha1.APIVersionKind{deployment, configMap})).To(gomega.Succeed())
+	g.Expect(c.watchCount("Deployment")).To(gomega.Equal(1))
+	g.Expect(c.accepts("Deployment")).To(gomega.BeTrue())
+	g.Expect(c.accepts("ConfigMap")).To(gomega.BeTrue())
+
+	// A kind whose watch failed to start is watched on the next update
+	secret := osbv1alpha1.APIVersionKind{APIVersion: "v1", Kind: "Secret"}
+	c.watchErr["Secret"] = fmt.Errorf("watch failed")
+	g.Expect(w.Update([]osbv1alpha1.APIVersionKind{deployment, configMap, secret})).NotTo(gomega.Succeed())
+	g.Expect(c.accepts("Secret")).To(gomega.BeFalse())
+	delete(c.watchErr, "Secret")
+	g.Expect(w.Update([]osbv1alpha1.APIVersionKind{deployment, configMap, secret})).To(gomega.Succeed())
+	g.Expect(c.watchCount("Secret")).To(gomega.Equal(1))
+	g.Expect(c.accepts("Secret")).To(gomega.BeTrue())
+}
+
+func Test_subResourceWatcher_Start(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	deployment := osbv1alpha1.APIVersionKind{APIVersion: "apps/v1", Kind: "Deployment"}
+
+	var mux sync.Mutex
+	var watchList []osbv1alpha1.APIVersionKind
+	getWatchList := func() []osbv1alpha1.APIVersionKind {
+		mux.Lock()
+		defer mux.Unlock()
+		return watchList
+	}
+	setWatchList := func(list []osbv1alpha1.APIVersionKind) {
+		mux.Lock()
+		defer mux.Unlock()
+		watchList = list
+	}
+
+	c := newFakeController()
+	w, err := NewSubResourceWatcher(c, &osbv1alpha1.SFServiceInstance{}, getWatchList)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	stop := make(chan struct{})
+	stopped := make(chan error)
+	go func() {
+		stopped <- w.Start(stop)
+	}()
+
+	// A plan update adds a watch for a new kind
+	setWatchList([]osbv1alpha1.APIVersionKind{deployment})
+	NotifyWatchUpdate()
+	g.Eventually(func() int {
+		return c.watchCount("Deployment")
+	}, time.Second*5).Should(gomega.Equal(1))
+	g.Eventually(func() bool {
+		return c.accepts("Deployment")
+	}, time.Second*5).Should(gomega.BeTrue())
+
+	// A plan update which no longer renders the kind retires the watch
+	setWatchList(nil)
+	NotifyWatchUpdate()
+	g.Eventually(func() bool {
+		return c.accepts("Deployment")
+	}, time.Second*5).Should(gomega.BeFalse())
+
+	// A plan update rendering the kind again reactivates the watch
+	setWatchList([]osbv1alpha1.APIVersionKind{deployment})
+	NotifyWatchUpdate()
+	g.Eventually(func() bool {
+		return c.accepts("Deployment")
+	}, time.Second*5).Should(gomega.BeTrue())
+	g.Expect(c.watchCount("Deployment")).To(gomega.Equal(1))
+
+	close(stop)
+	g.Eventually(stopped, time.Second*5).Should(gomega.Receive(gomega.BeNil()))
+}
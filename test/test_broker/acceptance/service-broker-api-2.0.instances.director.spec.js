@@ -1340,6 +1340,57 @@ describe('service-broker-api-2.0', function () {
               done();
             });
         });
+        it('credentials in vault : returns 201 Created', function () {
+          const token = process.env.VAULT_TOKEN;
+          const secretRef = `vault:secret/data/interoperator/default/sf-${binding_id}`;
+          _.set(config, 'apiserver.binding_secret_store.vault.address', mocks.vault.vaultUrl);
+          process.env.VAULT_TOKEN = mocks.vault.vaultToken;
+          mocks.apiServerEventMesh.nockGetResource(CONST.APISERVER.RESOURCE_GROUPS.LOCK, CONST.APISERVER.RESOURCE_TYPES.DEPLOYMENT_LOCKS, instance_id, {
+            spec: {
+              options: '{}'
+            }
+          });
+          mocks.apiServerEventMesh.nockCreateResource(CONST.APISERVER.RESOURCE_GROUPS.INTEROPERATOR, CONST.APISERVER.RESOURCE_TYPES.INTEROPERATOR_SERVICEBINDINGS, {});
+          mocks.apiServerEventMesh.nockGetResource(CONST.APISERVER.RESOURCE_GROUPS.INTEROPERATOR, CONST.APISERVER.RESOURCE_TYPES.INTEROPERATOR_SERVICEBINDINGS, binding_id, {
+            status: {
+              state: 'succeeded',
+              response: {
+                secretRef: secretRef
+              }
+            }
+          });
+          mocks.vault.nockGetSecret(`secret/data/interoperator/default/sf-${binding_id}`, {
+            response: JSON.stringify({credentials: mocks.agent.credentials})
+          });
+          return chai.request(app)
+            .put(`${base_url}/service_instances/${instance_id}/service_bindings/${binding_id}`)
+            .set('X-Broker-API-Version', api_version)
+            .auth(config.username, config.password)
+            .send({
+              service_id: service_id,
+              plan_id: plan_id,
+              app_guid: app_guid,
+              bind_resource: {
+                app_guid: app_guid
+              }
+            })
+            .catch(err => err.response)
+            .then(res => {
+              expect(res).to.have.status(201);
+              expect(res.body).to.eql({
+                credentials: mocks.agent.credentials
+              });
+              mocks.verify();
+            })
+            .finally(() => {
+              _.unset(config, 'apiserver.binding_secret_store');
+              if (_.isUndefined(token)) {
+                delete process.env.VAULT_TOKEN;
+              } else {
+                process.env.VAULT_TOKEN = token;
+              }
+            });
+        });
         it('returns 201 Created', function (done) {
           const context = {
             platform: 'cloudfoundry',
@@ -1507,6 +1558,223 @@ describe('service-broker-api-2.0', function () {
             });
         });
 
+        it('returns 202 Accepted if accepts_incomplete', function () {
+          mocks.apiServerEventMesh.nockGetResource(CONST.APISERVER.RESOURCE_GROUPS.LOCK, CONST.APISERVER.RESOURCE_TYPES.DEPLOYMENT_LOCKS, instance_id, {
+            spec: {
+              options: '{}'
+            }
+          });
+          let operationId;
+          mocks.apiServerEventMesh.nockCreateResource(CONST.APISERVER.RESOURCE_GROUPS.INTEROPERATOR, CONST.APISERVER.RESOURCE_TYPES.INTEROPERATOR_SERVICEBINDINGS, {}, 1, body => {
+            operationId = body.metadata.annotations[CONST.APISERVER.ANNOTATIONS.OPERATION_ID];
+            expect(operationId).to.be.a('string');
+            return true;
+          });
+          return chai.request(app)
+            .put(`${base_url}/service_instances/${instance_id}/service_bindings/${binding_id}?accepts_incomplete=true`)
+            .set('X-Broker-API-Version', api_version)
+            .auth(config.username, config.password)
+            .send({
+              service_id: service_id,
+              plan_id: plan_id,
+              app_guid: app_guid,
+              bind_resource: {
+                app_guid: app_guid
+              }
+            })
+            .catch(err => err.response)
+            .then(res => {
+              expect(res).to.have.status(202);
+              expect(res.body).to.eql({
+                operation: utils.encodeBase64({
+                  'type': 'create',
+                  'id': operationId
+                })
+              });
+              mocks.verify();
+            });
+        });
+
+      });
+
+      describe('#getBinding', function () {
+        it('returns 200 OK with the credentials', function () {
+          mocks.apiServerEventMesh.nockGetResource(CONST.APISERVER.RESOURCE_GROUPS.INTEROPERATOR, CONST.APISERVER.RESOURCE_TYPES.INTEROPERATOR_SERVICEBINDINGS, binding_id, {
+            status: {
+              state: 'succeeded',
+              response: {
+                secretRef: 'secret-name'
+              }
+            }
+          });
+          mocks.apiServerEventMesh.nockGetSecret('secret-name', 'default', {
+            data: {
+              response: utils.encodeBase64({credentials: mocks.agent.credentials})
+            }
+          });
+          return chai.request(app)
+            .get(`${base_url}/service_instances/${instance_id}/service_bindings/${binding_id}`)
+            .set('X-Broker-API-Version', api_version)
+            .auth(config.username, config.password)
+            .catch(err => err.response)
+            .then(res => {
+              expect(res).to.have.status(200);
+              expect(res.body).to.eql({
+                credentials: mocks.agent.credentials
+              });
+              mocks.verify();
+            });
+        });
+        it('returns 200 OK with the current credentials while they are rotated', function () {
+          mocks.apiServerEventMesh.nockGetResource(CONST.APISERVER.RESOURCE_GROUPS.INTEROPERATOR, CONST.APISERVER.RESOURCE_TYPES.INTEROPERATOR_SERVICEBINDINGS, binding_id, {
+            status: {
+              state: 'in progress',
+              response: {
+                secretRef: 'secret-name'
+              },
+              rotation: {
+                id: 'rotation-id',
+                state: 'in progress',
+                previousSecretRef: 'secret-name-previous'
+              }
+            }
+          });
+          mocks.apiServerEventMesh.nockGetSecret('secret-name', 'default', {
+            data: {
+              response: utils.encodeBase64({credentials: mocks.agent.credentials})
+            }
+          });
+          return chai.request(app)
+            .get(`${base_url}/service_instances/${instance_id}/service_bindings/${binding_id}`)
+            .set('X-Broker-API-Version', api_version)
+            .auth(config.username, config.password)
+            .catch(err => err.response)
+            .then(res => {
+              expect(res).to.have.status(200);
+              expect(res.body).to.eql({
+                credentials: mocks.agent.credentials
+              });
+              mocks.verify();
+            });
+        });
+        it('returns 404 Not Found while the bind operation is in progress', function () {
+          mocks.apiServerEventMesh.nockGetResource(CONST.APISERVER.RESOURCE_GROUPS.INTEROPERATOR, CONST.APISERVER.RESOURCE_TYPES.INTEROPERATOR_SERVICEBINDINGS, binding_id, {
+            status: {
+              state: 'in progress'
+            }
+          });
+          return chai.request(app)
+            .get(`${base_url}/service_instances/${instance_id}/service_bindings/${binding_id}`)
+            .set('X-Broker-API-Version', api_version)
+            .auth(config.username, config.password)
+            .catch(err => err.response)
+            .then(res => {
+              expect(res).to.have.status(404);
+              mocks.verify();
+            });
+        });
+      });
+
+      describe('#getLastBindingOperation', function () {
+        it('returns 200 OK with the state of the last operation', function () {
+          mocks.apiServerEventMesh.nockGetResource(CONST.APISERVER.RESOURCE_GROUPS.INTEROPERATOR, CONST.APISERVER.RESOURCE_TYPES.INTEROPERATOR_SERVICEBINDINGS, binding_id, {
+            status: {
+              state: 'failed',
+              error: 'bind operation timed out after 30m0s',
+              lastOperation: {
+                id: 'operation-id',
+                type: 'bind',
+                state: 'failed',
+                description: 'bind operation timed out after 30m0s'
+              }
+            }
+          });
+          return chai.request(app)
+            .get(`${base_url}/service_instances/${instance_id}/service_bindings/${binding_id}/last_operation`)
+            .query({
+              operation: utils.encodeBase64({
+                'type': 'create',
+                'id': 'operation-id'
+              })
+            })
+            .set('X-Broker-API-Version', api_version)
+            .auth(config.username, config.password)
+            .catch(err => err.response)
+            .then(res => {
+              expect(res).to.have.status(200);
+              expect(res.body).to.eql({
+                state: 'failed',
+                description: 'bind operation timed out after 30m0s'
+              });
+              mocks.verify();
+            });
+        });
+        it('returns 200 OK with state in progress while the last operation is an earlier one', function () {
+          mocks.apiServerEventMesh.nockGetResource(CONST.APISERVER.RESOURCE_GROUPS.INTEROPERATOR, CONST.APISERVER.RESOURCE_TYPES.INTEROPERATOR_SERVICEBINDINGS, binding_id, {
+            status: {
+              state: 'delete',
+              lastOperation: {
+                id: 'bind-operation-id',
+                type: 'bind',
+                state: 'succeeded'
+              }
+            }
+          });
+          return chai.request(app)
+            .get(`${base_url}/service_instances/${instance_id}/service_bindings/${binding_id}/last_operation`)
+            .query({
+              operation: utils.encodeBase64({
+                'type': 'delete',
+                'id': 'unbind-operation-id'
+              })
+            })
+            .set('X-Broker-API-Version', api_version)
+            .auth(config.username, config.password)
+            .catch(err => err.response)
+            .then(res => {
+              expect(res).to.have.status(200);
+              expect(res.body).to.eql({
+                state: 'in progress'
+              });
+              mocks.verify();
+            });
+        });
+        it('returns 200 OK with state in progress', function () {
+          mocks.apiServerEventMesh.nockGetResource(CONST.APISERVER.RESOURCE_GROUPS.INTEROPERATOR, CONST.APISERVER.RESOURCE_TYPES.INTEROPERATOR_SERVICEBINDINGS, binding_id, {
+            status: {
+              state: 'in_queue'
+            }
+          });
+          return chai.request(app)
+            .get(`${base_url}/service_instances/${instance_id}/service_bindings/${binding_id}/last_operation`)
+            .set('X-Broker-API-Version', api_version)
+            .auth(config.username, config.password)
+            .catch(err => err.response)
+            .then(res => {
+              expect(res).to.have.status(200);
+              expect(res.body).to.eql({
+                state: 'in progress'
+              });
+              mocks.verify();
+            });
+        });
+        it('returns 410 Gone once the binding is deleted', function () {
+          mocks.apiServerEventMesh.nockGetResource(CONST.APISERVER.RESOURCE_GROUPS.INTEROPERATOR, CONST.APISERVER.RESOURCE_TYPES.INTEROPERATOR_SERVICEBINDINGS, binding_id, {}, 1, 404);
+          return chai.request(app)
+            .get(`${base_url}/service_instances/${instance_id}/service_bindings/${binding_id}/last_operation`)
+            .query({
+              operation: utils.encodeBase64({
+                'type': 'delete'
+              })
+            })
+            .set('X-Broker-API-Version', api_version)
+            .auth(config.username, config.password)
+            .catch(err => err.response)
+            .then(res => {
+              expect(res).to.have.status(410);
+              mocks.verify();
+            });
+        });
       });
 
 
@@ -1599,6 +1867,43 @@ describe('service-broker-api-2.0', function () {
               mocks.verify();
             });
         });
+        it('returns 202 Accepted if accepts_incomplete', function () {
+          mocks.apiServerEventMesh.nockGetResource(CONST.APISERVER.RESOURCE_GROUPS.LOCK, CONST.APISERVER.RESOURCE_TYPES.DEPLOYMENT_LOCKS, instance_id, {
+            spec: {
+              options: '{}'
+            }
+          });
+          mocks.apiServerEventMesh.nockDeleteResource(CONST.APISERVER.RESOURCE_GROUPS.INTEROPERATOR, CONST.APISERVER.RESOURCE_TYPES.INTEROPERATOR_SERVICEBINDINGS, binding_id, {});
+          let operationId;
+          mocks.apiServerEventMesh.nockPatchResource(CONST.APISERVER.RESOURCE_GROUPS.INTEROPERATOR, CONST.APISERVER.RESOURCE_TYPES.INTEROPERATOR_SERVICEBINDINGS, binding_id, {}, 1, body => {
+            expect(body.spec).to.eql({
+              acceptsIncomplete: true
+            });
+            operationId = body.metadata.annotations[CONST.APISERVER.ANNOTATIONS.OPERATION_ID];
+            expect(operationId).to.be.a('string');
+            return true;
+          });
+          return chai.request(app)
+            .delete(`${base_url}/service_instances/${instance_id}/service_bindings/${binding_id}`)
+            .query({
+              service_id: service_id,
+              plan_id: plan_id,
+              accepts_incomplete: true
+            })
+            .set('X-Broker-API-Version', api_version)
+            .auth(config.username, config.password)
+            .catch(err => err.response)
+            .then(res => {
+              expect(res).to.have.status(202);
+              expect(res.body).to.eql({
+                operation: utils.encodeBase64({
+                  'type': 'delete',
+                  'id': operationId
+                })
+              });
+              mocks.verify();
+            });
+        });
       });
 
     });
@@ -17,6 +17,7 @@ const serviceFabrikClient = require('./serviceFabrikClient');
 const serviceBrokerClient = require('./serviceBrokerClient');
 const deploymentHookClient = require('./deploymentHookClient');
 const apiServerEventMesh = require('./apiServerEventMesh');
+const vault = require('./vault');
 const logger = require('../../../common/logger');
 
 exports = module.exports = init;
@@ -34,6 +35,7 @@ exports.serviceFabrikClient = serviceFabrikClient;
 exports.serviceBrokerClient = serviceBrokerClient;
 exports.deploymentHookClient = deploymentHookClient;
 exports.apiServerEventMesh = apiServerEventMesh;
+exports.vault = vault;
 exports.verify = verify;
 exports.setup = setup;
 exports.reset = reset;
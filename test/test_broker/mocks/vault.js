@@ -0,0 +1,24 @@
+'use strict';
+
+const nock = require('nock');
+const vaultUrl = 'https://vault.example.com:8200';
+const vaultToken = 'vault-token';
+
+exports.vaultUrl = vaultUrl;
+exports.vaultToken = vaultToken;
+exports.nockGetSecret = nockGetSecret;
+
+function nockGetSecret(secretPath, data, times, expectedStatusCode) {
+  return nock(vaultUrl, {
+    reqheaders: {
+      'X-Vault-Token': vaultToken
+    }
+  })
+    .get(`/v1/${secretPath}`)
+    .times(times || 1)
+    .reply(expectedStatusCode || 200, {
+      data: {
+        data: data
+      }
+    });
+}
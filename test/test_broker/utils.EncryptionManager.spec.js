@@ -1,6 +1,12 @@
 'use strict';
 
+const _ = require('lodash');
+const os = require('os');
+const fs = require('fs');
+const path = require('path');
+const crypto = require('crypto');
 const proxyquire = require('proxyquire');
+const config = require('../../common/config');
 
 class MockKeyGenerator {
   constructor(id) {
@@ -47,6 +53,68 @@ describe('utils', () => {
         expect(JSON.parse(decryptedText)).to.deep.equal(testObj);
       });
     });
+    describe('decrypt-credentials', () => {
+      const keyFile = path.join(os.tmpdir(), 'credential-encryption-key');
+      const kek = crypto.randomBytes(32);
+      const credentials = {
+        credentials: {
+          username: 'user',
+          password: 'secret'
+        }
+      };
+
+      function seal(key, plaintext) {
+        const nonce = crypto.randomBytes(12);
+        const cipher = crypto.createCipheriv('aes-256-gcm', key, nonce);
+        const ciphertext = Buffer.concat([cipher.update(plaintext), cipher.final(), cipher.getAuthTag()]);
+        return [nonce, ciphertext];
+      }
+
+      function envelope(keyId) {
+        const key = crypto.randomBytes(32);
+        const [nonce, ciphertext] = seal(key, Buffer.from(JSON.stringify(credentials)));
+        const wrappedKey = Buffer.concat(seal(kek, key));
+        return {
+          sfEnvelope: {
+            version: 'v1',
+            keyProvider: 'file',
+            keyID: keyId,
+            encryptedKey: wrappedKey.toString('base64'),
+            nonce: nonce.toString('base64'),
+            ciphertext: ciphertext.toString('base64')
+          }
+        };
+      }
+
+      before(() => {
+        fs.writeFileSync(keyFile, kek.toString('base64'));
+        _.set(config, 'apiserver.credential_encryption.key_file', keyFile);
+      });
+      after(() => {
+        fs.unlinkSync(keyFile);
+        _.unset(config, 'apiserver.credential_encryption');
+      });
+
+      it('returns credentials which are not encrypted', () => {
+        const manager = new EncryptionManager();
+        return manager.decryptCredentials(credentials)
+          .then(out => expect(out).to.eql(credentials));
+      });
+      it('decrypts credentials encrypted with the key file', () => {
+        const manager = new EncryptionManager();
+        const keyId = crypto.createHash('sha256').update(kek).digest().slice(0, 8).toString('hex');
+        return manager.decryptCredentials(envelope(keyId))
+          .then(out => expect(out).to.eql(credentials));
+      });
+      it('fails for credentials encrypted with another key', () => {
+        const manager = new EncryptionManager();
+        return manager.decryptCredentials(envelope('0000000000000000'))
+          .then(() => {
+            throw new Error('expected decryption to fail');
+          })
+          .catch(err => expect(err.message).to.eql('Unknown key encryption key 0000000000000000'));
+      });
+    });
     describe('generate-ssh-keypair', () => {
       it('returns an ssh keypair with private and public keys', () => {
         const manager = new EncryptionManager();
@@ -0,0 +1,86 @@
+'use strict';
+
+const _ = require('lodash');
+const os = require('os');
+const fs = require('fs');
+const path = require('path');
+const config = require('../../common/config');
+const VaultClient = require('../../common/utils/VaultClient');
+
+describe('utils', () => {
+  describe('VaultClient', () => {
+    const tokenFile = path.join(os.tmpdir(), 'vault-token');
+    const secretRef = 'vault:secret/data/interoperator/default/sf-binding-id';
+    const data = {
+      response: JSON.stringify({
+        credentials: {
+          password: 'secret'
+        }
+      })
+    };
+
+    before(() => {
+      fs.writeFileSync(tokenFile, `${mocks.vault.vaultToken}\n`);
+      _.set(config, 'apiserver.binding_secret_store.vault', {
+        address: mocks.vault.vaultUrl,
+        token_file: tokenFile
+      });
+    });
+    after(() => {
+      fs.unlinkSync(tokenFile);
+      _.unset(config, 'apiserver.binding_secret_store');
+    });
+    afterEach(() => {
+      mocks.reset();
+    });
+
+    describe('isVaultRef', () => {
+      it('returns true only for vault references', () => {
+        expect(VaultClient.isVaultRef(secretRef)).to.eql(true);
+        expect(VaultClient.isVaultRef('sf-binding-id')).to.eql(false);
+        expect(VaultClient.isVaultRef(undefined)).to.eql(false);
+      });
+    });
+
+    describe('getSecret', () => {
+      it('reads the secret with the token of the token file', () => {
+        mocks.vault.nockGetSecret('secret/data/interoperator/default/sf-binding-id', data);
+        return new VaultClient().getSecret(secretRef)
+          .then(out => {
+            expect(out).to.eql(data);
+            mocks.verify();
+          });
+      });
+      it('reads the secret with the token of the environment', () => {
+        const token = process.env.VAULT_TOKEN;
+        _.unset(config, 'apiserver.binding_secret_store.vault.token_file');
+        process.env.VAULT_TOKEN = mocks.vault.vaultToken;
+        mocks.vault.nockGetSecret('secret/data/interoperator/default/sf-binding-id', data);
+        return new VaultClient().getSecret(secretRef)
+          .then(out => {
+            expect(out).to.eql(data);
+            mocks.verify();
+          })
+          .finally(() => {
+            _.set(config, 'apiserver.binding_secret_store.vault.token_file', tokenFile);
+            if (_.isUndefined(token)) {
+              delete process.env.VAULT_TOKEN;
+            } else {
+              process.env.VAULT_TOKEN = token;
+            }
+          });
+      });
+      it('fails if the secret is not found', () => {
+        mocks.vault.nockGetSecret('secret/data/interoperator/default/sf-binding-id', {}, 1, 404);
+        return new VaultClient().getSecret(secretRef)
+          .then(() => {
+            throw new Error('expected reading the secret to fail');
+          })
+          .catch(err => {
+            expect(err.status).to.eql(404);
+            mocks.verify();
+          });
+      });
+    });
+  });
+});